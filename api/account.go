@@ -1,18 +1,33 @@
 package api
 
 import (
-	"database/sql"
 	"errors"
 	"net/http"
 
 	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
 )
 
+// accountResponse adds the balance as a decimal string in units of the account currency.
+type accountResponse struct {
+	db.Account
+	BalanceDecimal string `json:"balance_decimal"`
+}
+
+func newAccountResponse(account db.Account) accountResponse {
+	return accountResponse{
+		Account:        account,
+		BalanceDecimal: util.FormatMinorUnits(account.Balance, account.Currency),
+	}
+}
+
 type createAccountRequest struct {
-	Owner    string `json:"owner" binding:"required"`
+	// Owner defaults to the authenticated user; only admins may open accounts for others.
+	Owner    string `json:"owner"`
 	Currency string `json:"currency" binding:"required,currency"`
+	// Type defaults to checking.
+	Type string `json:"type" binding:"omitempty,oneof=checking savings"`
 }
 
 func (server *Server) createAccount(ctx *gin.Context) {
@@ -22,25 +37,39 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		return
 	}
 
-	var account, err = server.store.CreateAccount(ctx, db.CreateAccountParams{
+	var payload = authPayload(ctx)
+	if req.Owner == "" {
+		req.Owner = payload.Username
+	}
+	if req.Owner != payload.Username && payload.Role != util.AdminRole {
+		var err = errors.New("cannot create an account for another user")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return
+	}
+	if !server.enabledCurrency(ctx, req.Currency) {
+		return
+	}
+	if req.Type == "" {
+		req.Type = db.AccountTypeChecking
+	}
+
+	var account, err = server.store.CreateAccountTx(ctx, db.CreateAccountParams{
 		Owner:    req.Owner,
 		Balance:  0,
 		Currency: req.Currency,
+		Type:     req.Type,
 	})
 	if err != nil {
-		var pqErr *pq.Error
-		if errors.As(err, &pqErr) {
-			switch pqErr.Code.Name() {
-			case "foreign_key_violation", "unique_violation":
-				ctx.JSON(http.StatusForbidden, errResponse(err))
-				return
-			}
+		switch db.ErrorCode(err) {
+		case db.ForeignKeyViolation, db.UniqueViolation:
+			ctx.JSON(http.StatusForbidden, errResponse(err))
+			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
 }
 
 type getAccountRequest struct {
@@ -54,22 +83,19 @@ func (server *Server) getAccount(ctx *gin.Context) {
 		return
 	}
 
-	var account, err = server.store.GetAccount(ctx, req.ID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+	var account, ok = server.ownedAccount(ctx, req.ID, authPayload(ctx))
+	if !ok {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
 }
 
 type listAccountRequest struct {
-	PageID   int32 `form:"page_id" binding:"required,min=1"`
-	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+	// Owner defaults to the authenticated user; only admins may list the accounts of others.
+	Owner    string `form:"owner"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=10"`
 }
 
 func (server *Server) listAccount(ctx *gin.Context) {
@@ -79,7 +105,18 @@ func (server *Server) listAccount(ctx *gin.Context) {
 		return
 	}
 
+	var payload = authPayload(ctx)
+	if req.Owner == "" {
+		req.Owner = payload.Username
+	}
+	if req.Owner != payload.Username && payload.Role != util.AdminRole {
+		var err = errors.New("cannot list the accounts of another user")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return
+	}
+
 	var accounts, err = server.store.ListAccounts(ctx, db.ListAccountsParams{
+		Owner:  req.Owner,
 		Limit:  req.PageSize,
 		Offset: (req.PageID - 1) * req.PageSize,
 	})
@@ -88,7 +125,11 @@ func (server *Server) listAccount(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, accounts)
+	var rsp = make([]accountResponse, len(accounts))
+	for i, account := range accounts {
+		rsp[i] = newAccountResponse(account)
+	}
+	ctx.JSON(http.StatusOK, rsp)
 }
 
 type deleteAccountRequest struct {
@@ -103,6 +144,10 @@ func (server *Server) deleteAccount(ctx *gin.Context) {
 		return
 	}
 
+	if _, ok := server.ownedAccount(ctx, req.ID, authPayload(ctx)); !ok {
+		return
+	}
+
 	if err := server.store.DeleteAccount(ctx, req.ID); err != nil {
 		ctx.JSON(http.StatusInternalServerError, errResponse(err))
 		return
@@ -116,6 +161,7 @@ type updateAccountRequest struct {
 	Balance int64 `json:"balance" binding:"required"`
 }
 
+// updateAccount sets the balance of an account, booking the difference as an entry. Only admins may use it.
 func (server *Server) updateAccount(ctx *gin.Context) {
 	var req updateAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -123,12 +169,19 @@ func (server *Server) updateAccount(ctx *gin.Context) {
 		return
 	}
 
-	var account, err = server.store.UpdateAccount(ctx, db.UpdateAccountParams{
-		ID:      req.ID,
-		Balance: req.Balance,
+	var payload = authPayload(ctx)
+	if payload.Role != util.AdminRole {
+		var err = errors.New("only admins can update account balances")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return
+	}
+
+	var result, err = server.store.AdjustBalanceTx(ctx, db.AdjustBalanceTxParams{
+		AccountID: req.ID,
+		Balance:   req.Balance,
 	})
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, db.ErrRecordNotFound) {
 			ctx.JSON(http.StatusNotFound, errResponse(err))
 			return
 		}
@@ -136,5 +189,5 @@ func (server *Server) updateAccount(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, newAccountResponse(result.Account))
 }
@@ -9,10 +9,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	mockdb "github.com/Ma-hiru/simplebank/db/mock"
 	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/token"
 	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
@@ -23,21 +27,135 @@ func randomAccount() db.Account {
 		Owner:    util.RandomOwner(),
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
+		Type:     db.AccountTypeChecking,
+	}
+}
+
+func TestCreateAccountType(t *testing.T) {
+	var user, _ = randomUser(t)
+
+	var testCases = []struct {
+		name          string
+		body          map[string]any
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "DefaultChecking",
+			body: map[string]any{"owner": user.Username, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				var arg = db.CreateAccountParams{Owner: user.Username, Currency: util.USD, Type: db.AccountTypeChecking}
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.Account{ID: 1, Owner: user.Username, Currency: util.USD, Type: db.AccountTypeChecking}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"type":"checking"`)
+			},
+		},
+		{
+			name: "Savings",
+			body: map[string]any{"owner": user.Username, "currency": util.USD, "type": db.AccountTypeSavings},
+			buildStubs: func(store *mockdb.MockStore) {
+				var arg = db.CreateAccountParams{Owner: user.Username, Currency: util.USD, Type: db.AccountTypeSavings}
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.Account{ID: 2, Owner: user.Username, Currency: util.USD, Type: db.AccountTypeSavings}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"type":"savings"`)
+			},
+		},
+		{
+			name: "OwnerDefaultsToCaller",
+			body: map[string]any{"currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				var arg = db.CreateAccountParams{Owner: user.Username, Currency: util.USD, Type: db.AccountTypeChecking}
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.Account{ID: 3, Owner: user.Username, Currency: util.USD, Type: db.AccountTypeChecking}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "OtherOwner",
+			body: map[string]any{"owner": util.RandomOwner(), "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "Internal",
+			body: map[string]any{"owner": user.Username, "currency": util.USD, "type": db.AccountTypeInternal},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "DuplicateType",
+			body: map[string]any{"owner": user.Username, "currency": util.USD, "type": db.AccountTypeSavings},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.Account{}, &pgconn.PgError{Code: db.UniqueViolation})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var store = mockdb.NewMockStore(ctrl)
+			expectAuthLookup(store, user)
+			expectCurrencies(store)
+			tc.buildStubs(store)
+
+			var server = newTestServer(t, store, nil)
+			var data, err = json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
 	}
 }
 
 func TestGetAccount(t *testing.T) {
+	var user, _ = randomUser(t)
 	var account = randomAccount()
+	account.Owner = user.Username
+
+	var withAuth = func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+		expectAuthLookup(store, user)
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+	}
 
 	var testCases = []struct {
 		name          string
 		accountID     int64
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore)
 		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, response *httptest.ResponseRecorder)
 	}{
 		{
 			name:      "OK",
 			accountID: account.ID,
+			setupAuth: withAuth,
 			buildStubs: func(store *mockdb.MockStore) {
 				store.
 					EXPECT().
@@ -50,15 +168,35 @@ func TestGetAccount(t *testing.T) {
 				requireBodyMatchAccount(t, response.Body, account)
 			},
 		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				var other, _ = randomUser(t)
+				expectAuthLookup(store, other)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, other.Username, other.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.
+					EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, response *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, response.Code)
+			},
+		},
 		{
 			name:      "NotFound",
 			accountID: account.ID,
+			setupAuth: withAuth,
 			buildStubs: func(store *mockdb.MockStore) {
 				store.
 					EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
-					Return(db.Account{}, sql.ErrNoRows)
+					Return(db.Account{}, db.ErrRecordNotFound)
 			},
 			checkResponse: func(t *testing.T, response *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusNotFound, response.Code)
@@ -67,6 +205,7 @@ func TestGetAccount(t *testing.T) {
 		{
 			name:      "InternalError",
 			accountID: account.ID,
+			setupAuth: withAuth,
 			buildStubs: func(store *mockdb.MockStore) {
 				store.
 					EXPECT().
@@ -81,6 +220,7 @@ func TestGetAccount(t *testing.T) {
 		{
 			name:      "InvalidID",
 			accountID: 0,
+			setupAuth: withAuth,
 			buildStubs: func(store *mockdb.MockStore) {
 				store.
 					EXPECT().
@@ -91,6 +231,41 @@ func TestGetAccount(t *testing.T) {
 				require.Equal(t, http.StatusBadRequest, response.Code)
 			},
 		},
+		{
+			name:      "NoAuthorization",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.
+					EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, response *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, response.Code)
+			},
+		},
+		{
+			name:      "MissingScope",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				expectAuthLookup(store, user)
+				var accessToken, _, err = tokenMaker.CreateToken(user.Username, user.Role, time.Minute, token.WithScopes(scopeTransfersWrite))
+				require.NoError(t, err)
+				request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.
+					EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, response *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, response.Code)
+				require.Contains(t, response.Body.String(), scopeAccountsRead)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -109,7 +284,8 @@ func TestGetAccount(t *testing.T) {
 			var request, err = http.NewRequest(http.MethodGet, url, nil)
 			require.NoError(t, err)
 
-			var server = NewServer(store)
+			var server = newTestServer(t, store, nil)
+			tc.setupAuth(t, request, server.tokenMaker, store)
 			// send the request to the server and record the response using the recorder
 			server.router.ServeHTTP(response, request)
 			tc.checkResponse(t, response)
@@ -117,15 +293,142 @@ func TestGetAccount(t *testing.T) {
 	}
 }
 
+func TestListAccounts(t *testing.T) {
+	var user, _ = randomUser(t)
+	var admin, _ = randomUser(t)
+	admin.Role = util.AdminRole
+	var account = randomAccount()
+	account.Owner = user.Username
+
+	runFeeTestCases(t, []feeTestCase{
+		{
+			name:   "OwnAccounts",
+			caller: user,
+			method: http.MethodGet,
+			url:    "/accounts?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				var arg = db.ListAccountsParams{Owner: user.Username, Limit: 5, Offset: 0}
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.Account{account}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp []accountResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, []accountResponse{newAccountResponse(account)}, rsp)
+			},
+		},
+		{
+			name:   "OtherOwner",
+			caller: user,
+			method: http.MethodGet,
+			url:    "/accounts?page_id=1&page_size=5&owner=" + admin.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "AdminListsOtherOwner",
+			caller: admin,
+			method: http.MethodGet,
+			url:    "/accounts?page_id=2&page_size=5&owner=" + user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				var arg = db.ListAccountsParams{Owner: user.Username, Limit: 5, Offset: 5}
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.Account{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	})
+}
+
+func TestDeleteAccount(t *testing.T) {
+	var user, _ = randomUser(t)
+	var other, _ = randomUser(t)
+	var account = randomAccount()
+	account.Owner = user.Username
+
+	runFeeTestCases(t, []feeTestCase{
+		{
+			name:   "OK",
+			caller: user,
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "UnauthorizedUser",
+			caller: other,
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	})
+}
+
+func TestUpdateAccount(t *testing.T) {
+	var user, _ = randomUser(t)
+	var admin, _ = randomUser(t)
+	admin.Role = util.AdminRole
+	var account = randomAccount()
+	account.Owner = user.Username
+
+	runFeeTestCases(t, []feeTestCase{
+		{
+			name:   "Admin",
+			caller: admin,
+			method: http.MethodPut,
+			url:    "/accounts",
+			body:   gin.H{"id": account.ID, "balance": account.Balance},
+			buildStubs: func(store *mockdb.MockStore) {
+				var arg = db.AdjustBalanceTxParams{AccountID: account.ID, Balance: account.Balance}
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.AdjustBalanceTxResult{Account: account}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "NotAdmin",
+			caller: user,
+			method: http.MethodPut,
+			url:    "/accounts",
+			body:   gin.H{"id": account.ID, "balance": account.Balance},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	})
+}
+
 func requireBodyMatchAccount(t *testing.T, body *bytes.Buffer, account db.Account) {
 	var data, err = io.ReadAll(body)
 	require.NoError(t, err)
 
-	var gotAccount db.Account
+	var gotAccount accountResponse
 	err = json.Unmarshal(data, &gotAccount)
 	require.NoError(t, err)
 
-	require.Equal(t, account, gotAccount)
+	require.Equal(t, newAccountResponse(account), gotAccount)
 }
 
 //TODO add more tests
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/token"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errAPIKeyManagement = errors.New("api keys cannot be managed with an api key")
+
+type apiKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPIKeyResponse(apiKey db.ApiKey) apiKeyResponse {
+	var rsp = apiKeyResponse{
+		ID:        apiKey.ID,
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		Scopes:    apiKey.Scopes,
+		ExpiresAt: apiKey.ExpiresAt,
+		CreatedAt: apiKey.CreatedAt,
+	}
+	if apiKey.LastUsedAt.Valid {
+		rsp.LastUsedAt = &apiKey.LastUsedAt.Time
+	}
+	if apiKey.RevokedAt.Valid {
+		rsp.RevokedAt = &apiKey.RevokedAt.Time
+	}
+	return rsp
+}
+
+type apiKeyUserURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type createAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=64"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,scope"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"required,min=1,max=365"`
+}
+
+type createAPIKeyResponse struct {
+	Key    string         `json:"key"`
+	APIKey apiKeyResponse `json:"api_key"`
+}
+
+// createAPIKey issues a new API key for the user. The key is returned once and only its hash is stored.
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var uri apiKeyUserURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var payload = authPayload(ctx)
+	if payload.Type == token.TokenTypeAPIKey {
+		ctx.JSON(http.StatusForbidden, errResponse(errAPIKeyManagement))
+		return
+	}
+	if payload.Username != uri.Username {
+		var err = errors.New("cannot create api keys for another user")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return
+	}
+
+	var key, prefix, err = util.RandomAPIKey()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	apiKey, err := server.store.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+		ID:        id,
+		Username:  uri.Username,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   util.HashSecret(key),
+		Scopes:    req.Scopes,
+		ExpiresAt: time.Now().AddDate(0, 0, req.ExpiresInDays),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	server.securityLog.Info("api key created",
+		"username", apiKey.Username,
+		"prefix", apiKey.Prefix,
+		"scopes", apiKey.Scopes,
+	)
+	ctx.JSON(http.StatusOK, createAPIKeyResponse{
+		Key:    key,
+		APIKey: newAPIKeyResponse(apiKey),
+	})
+}
+
+// listAPIKeys lists the API keys of a user, including revoked and expired ones.
+// Users can list their own keys and admins can list anyone's.
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	var uri apiKeyUserURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	if !server.authorizeAPIKeyOwner(ctx, uri.Username) {
+		return
+	}
+
+	var apiKeys, err = server.store.ListAPIKeys(ctx, uri.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	var rsp = make([]apiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		rsp = append(rsp, newAPIKeyResponse(apiKey))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type revokeAPIKeyURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+	ID       string `uri:"id" binding:"required,uuid"`
+}
+
+// revokeAPIKey revokes an API key right away. Users can revoke their own keys and admins can revoke anyone's.
+func (server *Server) revokeAPIKey(ctx *gin.Context) {
+	var uri revokeAPIKeyURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	if !server.authorizeAPIKeyOwner(ctx, uri.Username) {
+		return
+	}
+
+	var apiKey, err = server.store.RevokeAPIKey(ctx, db.RevokeAPIKeyParams{
+		ID:       uuid.MustParse(uri.ID),
+		Username: uri.Username,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errResponse(errors.New("api key not found or already revoked")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	server.securityLog.Info("api key revoked",
+		"username", apiKey.Username,
+		"prefix", apiKey.Prefix,
+		"by", authPayload(ctx).Username,
+	)
+	ctx.JSON(http.StatusOK, newAPIKeyResponse(apiKey))
+}
+
+// authorizeAPIKeyOwner allows the owner of the keys or an admin, signed in with a token rather than an API key.
+func (server *Server) authorizeAPIKeyOwner(ctx *gin.Context, username string) bool {
+	var payload = authPayload(ctx)
+	if payload.Type == token.TokenTypeAPIKey {
+		ctx.JSON(http.StatusForbidden, errResponse(errAPIKeyManagement))
+		return false
+	}
+	if payload.Username != username && payload.Role != util.AdminRole {
+		var err = errors.New("cannot manage api keys of another user")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return false
+	}
+	return true
+}
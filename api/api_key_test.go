@@ -0,0 +1,525 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/token"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// randomAPIKey returns a stored API key of the user together with the key shown to the user.
+func randomAPIKey(t *testing.T, username string, scopes ...string) (db.ApiKey, string) {
+	var key, prefix, err = util.RandomAPIKey()
+	require.NoError(t, err)
+
+	return db.ApiKey{
+		ID:        uuid.New(),
+		Username:  username,
+		Name:      util.RandomString(8),
+		Prefix:    prefix,
+		KeyHash:   util.HashSecret(key),
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now().Add(-time.Hour),
+	}, key
+}
+
+func addAPIKeyAuthorization(request *http.Request, key string) {
+	request.Header.Set(authorizationHeaderKey, "ApiKey "+key)
+}
+
+func TestAPIKeyAuthMiddleware(t *testing.T) {
+	var user, _ = randomUser(t)
+	var apiKey, key = randomAPIKey(t, user.Username, scopeAccountsRead)
+
+	var testCases = []struct {
+		name          string
+		key           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(db.GetAPIKeyByPrefixRow{ApiKey: apiKey, Role: user.Role}, nil)
+				store.EXPECT().
+					TouchAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).
+					Times(1)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var payload token.Payload
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &payload))
+				require.Equal(t, apiKey.ID, payload.ID)
+				require.Equal(t, user.Username, payload.Username)
+				require.Equal(t, user.Role, payload.Role)
+				require.Equal(t, token.TokenTypeAPIKey, payload.Type)
+				require.Equal(t, []string{scopeAccountsRead}, payload.Scopes)
+			},
+		},
+		{
+			name: "Malformed",
+			key:  "not-an-api-key",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownPrefix",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(db.GetAPIKeyByPrefixRow{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "WrongSecret",
+			key:  fmt.Sprintf("sbk_%s_%s", apiKey.Prefix, util.RandomString(43)),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(db.GetAPIKeyByPrefixRow{ApiKey: apiKey, Role: user.Role}, nil)
+				store.EXPECT().
+					TouchAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidAPIKey.Error())
+			},
+		},
+		{
+			name: "Revoked",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				var revoked = apiKey
+				revoked.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(db.GetAPIKeyByPrefixRow{ApiKey: revoked, Role: user.Role}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errAPIKeyRevoked.Error())
+			},
+		},
+		{
+			name: "Expired",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				var expired = apiKey
+				expired.ExpiresAt = time.Now().Add(-time.Minute)
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(db.GetAPIKeyByPrefixRow{ApiKey: expired, Role: user.Role}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errAPIKeyExpired.Error())
+			},
+		},
+		{
+			name: "InternalError",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetAPIKeyByPrefixRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			var server = newTestServer(t, store, nil)
+			var authPath = "/auth"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.tokenMaker, store, server.passwordChanged),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, authPayload(ctx))
+				},
+			)
+
+			var request, err = http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+			addAPIKeyAuthorization(request, tc.key)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	var user, _ = randomUser(t)
+	var other, _ = randomUser(t)
+
+	var testCases = []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, server *Server, store *mockdb.MockStore)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"name":            "payroll",
+				"scopes":          []string{scopeAccountsRead, scopeTransfersWrite},
+				"expires_in_days": 30,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, server *Server, store *mockdb.MockStore) {
+				expectAuthLookup(store, user)
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, "payroll", arg.Name)
+						require.Equal(t, []string{scopeAccountsRead, scopeTransfersWrite}, arg.Scopes)
+						require.WithinDuration(t, time.Now().AddDate(0, 0, 30), arg.ExpiresAt, time.Second)
+						return db.ApiKey{
+							ID:        arg.ID,
+							Username:  arg.Username,
+							Name:      arg.Name,
+							Prefix:    arg.Prefix,
+							KeyHash:   arg.KeyHash,
+							Scopes:    arg.Scopes,
+							ExpiresAt: arg.ExpiresAt,
+							CreatedAt: time.Now(),
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "key_hash")
+
+				var rsp createAPIKeyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				var prefix, ok = util.APIKeyPrefix(rsp.Key)
+				require.True(t, ok)
+				require.Equal(t, prefix, rsp.APIKey.Prefix)
+				require.Equal(t, "payroll", rsp.APIKey.Name)
+			},
+		},
+		{
+			name: "UnknownScope",
+			body: gin.H{
+				"name":            "payroll",
+				"scopes":          []string{"everything"},
+				"expires_in_days": 30,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, server *Server, store *mockdb.MockStore) {
+				expectAuthLookup(store, user)
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "is not a known scope")
+			},
+		},
+		{
+			name: "NoScopes",
+			body: gin.H{
+				"name":            "payroll",
+				"scopes":          []string{},
+				"expires_in_days": 30,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, server *Server, store *mockdb.MockStore) {
+				expectAuthLookup(store, user)
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "OtherUser",
+			body: gin.H{
+				"name":            "payroll",
+				"scopes":          []string{scopeAccountsRead},
+				"expires_in_days": 30,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, server *Server, store *mockdb.MockStore) {
+				expectAuthLookup(store, other)
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, other.Username, other.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "WithAPIKey",
+			body: gin.H{
+				"name":            "payroll",
+				"scopes":          []string{scopeAccountsRead},
+				"expires_in_days": 30,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, server *Server, store *mockdb.MockStore) {
+				var apiKey, key = randomAPIKey(t, user.Username, scopeUsersWrite)
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(db.GetAPIKeyByPrefixRow{ApiKey: apiKey, Role: user.Role}, nil)
+				store.EXPECT().
+					TouchAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).
+					Times(1)
+				addAPIKeyAuthorization(request, key)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errAPIKeyManagement.Error())
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			var server = newTestServer(t, store, nil)
+			tc.buildStubs(store)
+
+			var data, err = json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/"+user.Username+"/api_keys", bytes.NewReader(data))
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server, store)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListAPIKeys(t *testing.T) {
+	var user, _ = randomUser(t)
+	var other, _ = randomUser(t)
+	var admin, _ = randomUser(t)
+	admin.Role = util.AdminRole
+
+	var apiKey, _ = randomAPIKey(t, user.Username, scopeAccountsRead)
+	apiKey.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	var apiKeys = []db.ApiKey{apiKey}
+
+	var testCases = []struct {
+		name          string
+		authUser      db.User
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			authUser: user,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAPIKeys(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(apiKeys, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp []apiKeyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 1)
+				require.Equal(t, apiKey.ID, rsp[0].ID)
+				require.NotNil(t, rsp[0].RevokedAt)
+				require.Nil(t, rsp[0].LastUsedAt)
+			},
+		},
+		{
+			name:     "Admin",
+			authUser: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAPIKeys(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(apiKeys, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "OtherUser",
+			authUser: other,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAPIKeys(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			var server = newTestServer(t, store, nil)
+			expectAuthLookup(store, tc.authUser)
+			tc.buildStubs(store)
+
+			var request, err = http.NewRequest(http.MethodGet, "/users/"+user.Username+"/api_keys", nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.authUser.Username, tc.authUser.Role, time.Minute)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	var user, _ = randomUser(t)
+	var apiKey, _ = randomAPIKey(t, user.Username, scopeAccountsRead)
+
+	var testCases = []struct {
+		name          string
+		id            string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			id:   apiKey.ID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				var revoked = apiKey
+				revoked.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Eq(db.RevokeAPIKeyParams{ID: apiKey.ID, Username: user.Username})).
+					Times(1).
+					Return(revoked, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp apiKeyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotNil(t, rsp.RevokedAt)
+			},
+		},
+		{
+			name: "NotFound",
+			id:   apiKey.ID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidID",
+			id:   "42",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			var server = newTestServer(t, store, nil)
+			expectAuthLookup(store, user)
+			tc.buildStubs(store)
+
+			var url = fmt.Sprintf("/users/%s/api_keys/%s", user.Username, tc.id)
+			var request, err = http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
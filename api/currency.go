@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+// currencyCache keeps the enabled currencies of the currencies table for a short TTL,
+// so opening an account does not query the store.
+// A zero TTL disables caching.
+type currencyCache struct {
+	store     db.Store
+	ttl       time.Duration
+	mu        sync.Mutex
+	enabled   map[string]bool
+	expiresAt time.Time
+	// generation counts the invalidations, so a load that raced with one is not cached.
+	generation uint64
+}
+
+func newCurrencyCache(store db.Store, ttl time.Duration) *currencyCache {
+	return &currencyCache{store: store, ttl: ttl}
+}
+
+// isEnabled reports whether new accounts are accepted in the currency.
+// When the table cannot be read, the currencies loaded last are used.
+// The lock is not held while the table is read, so a slow read only delays the requests that need it.
+func (cache *currencyCache) isEnabled(ctx context.Context, code string) (bool, error) {
+	var now = time.Now()
+	cache.mu.Lock()
+	var enabled, generation = cache.enabled, cache.generation
+	var fresh = enabled != nil && now.Before(cache.expiresAt)
+	cache.mu.Unlock()
+	if fresh {
+		return enabled[code], nil
+	}
+
+	var currencies, err = cache.store.ListCurrencies(ctx)
+	if err != nil {
+		if enabled == nil {
+			return false, err
+		}
+		return enabled[code], nil
+	}
+	enabled = make(map[string]bool, len(currencies))
+	for _, currency := range currencies {
+		enabled[currency.Code] = currency.Enabled
+	}
+
+	cache.mu.Lock()
+	if cache.generation == generation {
+		cache.enabled = enabled
+		cache.expiresAt = now.Add(cache.ttl)
+	}
+	cache.mu.Unlock()
+	return enabled[code], nil
+}
+
+// invalidate reloads the currencies on the next lookup after they changed on this replica.
+func (cache *currencyCache) invalidate() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.expiresAt = time.Time{}
+	cache.generation++
+}
+
+// enabledCurrency answers 400 and returns false unless new accounts are accepted in the currency.
+func (server *Server) enabledCurrency(ctx *gin.Context, code string) bool {
+	var enabled, err = server.currencies.isEnabled(ctx, code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return false
+	}
+	if !enabled {
+		ctx.JSON(http.StatusBadRequest, fieldErrResponse("currency", errCurrencyNotEnabled))
+		return false
+	}
+	return true
+}
+
+// currencyResponse adds the ISO 4217 details of the currency.
+type currencyResponse struct {
+	util.Currency
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newCurrencyResponse(currency db.Currency) currencyResponse {
+	var iso, ok = util.LookupCurrency(currency.Code)
+	if !ok {
+		iso = util.Currency{Code: currency.Code}
+	}
+	return currencyResponse{
+		Currency:  iso,
+		Enabled:   currency.Enabled,
+		UpdatedAt: currency.UpdatedAt,
+	}
+}
+
+// listCurrencies returns the currencies accounts can be opened in, and the ones disabled since.
+func (server *Server) listCurrencies(ctx *gin.Context) {
+	var currencies, err = server.store.ListCurrencies(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	var rsp = make([]currencyResponse, len(currencies))
+	for i, currency := range currencies {
+		rsp[i] = newCurrencyResponse(currency)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type updateCurrencyURI struct {
+	Code string `uri:"code" binding:"required,len=3,uppercase"`
+}
+
+type updateCurrencyRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// updateCurrency lets an admin enable or disable a currency.
+// Disabling a currency stops new accounts in it. The accounts already open in it stay readable
+// and keep transferring, since the currency binding tag only checks the ISO 4217 registry.
+func (server *Server) updateCurrency(ctx *gin.Context) {
+	var uri updateCurrencyURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	var req updateCurrencyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var payload = authPayload(ctx)
+	if payload.Role != util.AdminRole {
+		var err = errors.New("only admins can update currencies")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return
+	}
+	if _, ok := util.LookupCurrency(uri.Code); !ok {
+		var err = fmt.Errorf("%w %q", util.ErrUnknownCurrency, uri.Code)
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var currency, err = server.store.UpsertCurrency(ctx, db.UpsertCurrencyParams{
+		Code:    uri.Code,
+		Enabled: *req.Enabled,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	server.currencies.invalidate()
+
+	server.securityLog.Info("currency updated",
+		"code", currency.Code,
+		"enabled", currency.Enabled,
+		"admin", payload.Username,
+	)
+	ctx.JSON(http.StatusOK, newCurrencyResponse(currency))
+}
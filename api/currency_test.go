@@ -0,0 +1,355 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// testCurrencies are the rows of the currencies table seen by the tests: the seeded ones plus a disabled JPY.
+var testCurrencies = []db.Currency{
+	{Code: util.CAD, Enabled: true},
+	{Code: util.CNY, Enabled: true},
+	{Code: util.EUR, Enabled: true},
+	{Code: "JPY", Enabled: false},
+	{Code: util.USD, Enabled: true},
+}
+
+// expectCurrencies lets createAccount read the currencies table any number of times.
+func expectCurrencies(store *mockdb.MockStore) {
+	store.EXPECT().ListCurrencies(gomock.Any()).AnyTimes().Return(testCurrencies, nil)
+}
+
+func TestCurrencyCache(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+	var ctx = context.Background()
+
+	var cache = newCurrencyCache(store, time.Minute)
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return(testCurrencies, nil)
+	for _, code := range []string{util.USD, util.EUR} {
+		var enabled, err = cache.isEnabled(ctx, code)
+		require.NoError(t, err)
+		require.True(t, enabled)
+	}
+	var enabled, err = cache.isEnabled(ctx, "JPY")
+	require.NoError(t, err)
+	require.False(t, enabled)
+	enabled, err = cache.isEnabled(ctx, "GBP")
+	require.NoError(t, err)
+	require.False(t, enabled)
+
+	// the currencies loaded last are used while the table cannot be read
+	cache.invalidate()
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+	enabled, err = cache.isEnabled(ctx, util.USD)
+	require.NoError(t, err)
+	require.True(t, enabled)
+
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return([]db.Currency{{Code: util.USD, Enabled: false}}, nil)
+	enabled, err = cache.isEnabled(ctx, util.USD)
+	require.NoError(t, err)
+	require.False(t, enabled)
+}
+
+func TestCurrencyCacheInvalidatedDuringLoad(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+
+	// the currency is disabled while the first load reads the table, so its result must not be cached
+	var cache = newCurrencyCache(store, time.Minute)
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(1).DoAndReturn(func(ctx context.Context) ([]db.Currency, error) {
+		cache.invalidate()
+		return testCurrencies, nil
+	})
+	var enabled, err = cache.isEnabled(context.Background(), util.USD)
+	require.NoError(t, err)
+	require.True(t, enabled)
+
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return([]db.Currency{{Code: util.USD, Enabled: false}}, nil)
+	enabled, err = cache.isEnabled(context.Background(), util.USD)
+	require.NoError(t, err)
+	require.False(t, enabled)
+}
+
+func TestCurrencyCacheError(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+
+	var cache = newCurrencyCache(store, 0)
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+	var _, err = cache.isEnabled(context.Background(), util.USD)
+	require.ErrorIs(t, err, sql.ErrConnDone)
+
+	// without a TTL every lookup reads the table
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(2).Return(testCurrencies, nil)
+	for range 2 {
+		var enabled, err = cache.isEnabled(context.Background(), util.USD)
+		require.NoError(t, err)
+		require.True(t, enabled)
+	}
+}
+
+func TestCreateAccountCurrency(t *testing.T) {
+	var user, _ = randomUser(t)
+
+	var testCases = []struct {
+		name          string
+		currency      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Enabled",
+			currency: util.EUR,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Eq(db.CreateAccountParams{Owner: user.Username, Currency: util.EUR, Type: db.AccountTypeChecking})).
+					Times(1).
+					Return(db.Account{ID: 1, Owner: user.Username, Currency: util.EUR}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "Disabled",
+			currency: "JPY",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "is not an enabled currency")
+			},
+		},
+		{
+			name:     "NotInRegistry",
+			currency: "XXY",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "is not an ISO 4217 currency")
+			},
+		},
+		{
+			name:     "Unknown",
+			currency: "GBP",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var store = mockdb.NewMockStore(ctrl)
+			expectAuthLookup(store, user)
+			expectCurrencies(store)
+			tc.buildStubs(store)
+
+			var server = newTestServer(t, store, nil)
+			var data, err = json.Marshal(gin.H{"owner": user.Username, "currency": tc.currency})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListCurrencies(t *testing.T) {
+	var user, _ = randomUser(t)
+
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+	expectAuthLookup(store, user)
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return(testCurrencies, nil)
+
+	var server = newTestServer(t, store, nil)
+	var request, err = http.NewRequest(http.MethodGet, "/currencies", nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+	var recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp []currencyResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Len(t, rsp, len(testCurrencies))
+	require.Equal(t, "JPY", rsp[3].Code)
+	require.Equal(t, "392", rsp[3].Numeric)
+	require.Equal(t, 0, rsp[3].MinorUnits)
+	require.False(t, rsp[3].Enabled)
+	require.Equal(t, "$", rsp[4].Symbol)
+	require.True(t, rsp[4].Enabled)
+}
+
+func TestUpdateCurrency(t *testing.T) {
+	var user, _ = randomUser(t)
+	var admin, _ = randomUser(t)
+	admin.Role = util.AdminRole
+
+	var testCases = []struct {
+		name          string
+		caller        db.User
+		code          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string)
+	}{
+		{
+			name:   "Disable",
+			caller: admin,
+			code:   util.CAD,
+			body:   gin.H{"enabled": false},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertCurrency(gomock.Any(), gomock.Eq(db.UpsertCurrencyParams{Code: util.CAD, Enabled: false})).
+					Times(1).
+					Return(db.Currency{Code: util.CAD, Enabled: false}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp currencyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, util.CAD, rsp.Code)
+				require.False(t, rsp.Enabled)
+				require.Contains(t, securityLog, `"msg":"currency updated"`)
+				require.Contains(t, securityLog, `"admin":"`+admin.Username+`"`)
+			},
+		},
+		{
+			name:   "EnableNew",
+			caller: admin,
+			code:   "GBP",
+			body:   gin.H{"enabled": true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertCurrency(gomock.Any(), gomock.Eq(db.UpsertCurrencyParams{Code: "GBP", Enabled: true})).
+					Times(1).
+					Return(db.Currency{Code: "GBP", Enabled: true}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "NotAdmin",
+			caller: user,
+			code:   util.CAD,
+			body:   gin.H{"enabled": false},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertCurrency(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Empty(t, securityLog)
+			},
+		},
+		{
+			name:   "UnknownCode",
+			caller: admin,
+			code:   "ABC",
+			body:   gin.H{"enabled": true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertCurrency(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "LowercaseCode",
+			caller: admin,
+			code:   "usd",
+			body:   gin.H{"enabled": true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertCurrency(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "MissingEnabled",
+			caller: admin,
+			code:   util.USD,
+			body:   gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertCurrency(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			caller: admin,
+			code:   util.USD,
+			body:   gin.H{"enabled": true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertCurrency(gomock.Any(), gomock.Any()).Times(1).Return(db.Currency{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var store = mockdb.NewMockStore(ctrl)
+			expectAuthLookup(store, tc.caller)
+			tc.buildStubs(store)
+
+			var server = newTestServer(t, store, nil)
+			var securityLog bytes.Buffer
+			server.securityLog = slog.New(slog.NewJSONHandler(&securityLog, nil))
+
+			var data, err = json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPut, "/currencies/"+tc.code, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.caller.Username, tc.caller.Role, time.Minute)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, securityLog.String())
+		})
+	}
+}
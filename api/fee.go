@@ -0,0 +1,307 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// feeScheduleResponse adds the amounts as decimal strings in units of the schedule currency.
+type feeScheduleResponse struct {
+	db.FeeSchedule
+	FlatAmountDecimal string  `json:"flat_amount_decimal"`
+	MinAmountDecimal  string  `json:"min_amount_decimal"`
+	MaxAmountDecimal  *string `json:"max_amount_decimal"`
+}
+
+func newFeeScheduleResponse(schedule db.FeeSchedule) feeScheduleResponse {
+	var rsp = feeScheduleResponse{
+		FeeSchedule:       schedule,
+		FlatAmountDecimal: util.FormatMinorUnits(schedule.FlatAmount, schedule.Currency),
+		MinAmountDecimal:  util.FormatMinorUnits(schedule.MinAmount, schedule.Currency),
+	}
+	if schedule.MaxAmount.Valid {
+		var maxAmount = util.FormatMinorUnits(schedule.MaxAmount.Int64, schedule.Currency)
+		rsp.MaxAmountDecimal = &maxAmount
+	}
+	return rsp
+}
+
+type feeResponse struct {
+	db.Fee
+	AmountDecimal string `json:"amount_decimal"`
+}
+
+func newFeeResponses(charges []db.FeeCharge, currency string) []feeResponse {
+	var rsp = make([]feeResponse, len(charges))
+	for i, charge := range charges {
+		rsp[i] = feeResponse{Fee: charge.Fee, AmountDecimal: util.FormatMinorUnits(charge.Fee.Amount, currency)}
+	}
+	return rsp
+}
+
+// listFeeSchedules shows every user the fees the bank charges.
+func (server *Server) listFeeSchedules(ctx *gin.Context) {
+	var schedules, err = server.store.ListFeeSchedules(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	var rsp = make([]feeScheduleResponse, len(schedules))
+	for i, schedule := range schedules {
+		rsp[i] = newFeeScheduleResponse(schedule)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type feeScheduleURI struct {
+	Kind        string `uri:"kind" binding:"required,oneof=transfer maintenance"`
+	Currency    string `uri:"currency" binding:"required,len=3,uppercase"`
+	AccountType string `uri:"account_type" binding:"required,oneof=checking savings"`
+}
+
+type updateFeeScheduleRequest struct {
+	FlatAmount int64 `json:"flat_amount" binding:"min=0"`
+	RateBps    int64 `json:"rate_bps" binding:"min=0,max=10000"`
+	MinAmount  int64 `json:"min_amount" binding:"min=0"`
+	// MaxAmount caps the fee, there is no cap when it is omitted.
+	MaxAmount *int64 `json:"max_amount" binding:"omitempty,min=0"`
+}
+
+// updateFeeSchedule lets an admin set the fee of a kind for the accounts of a type and currency.
+// The amounts are in minor units of the currency.
+func (server *Server) updateFeeSchedule(ctx *gin.Context) {
+	var uri feeScheduleURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	var req updateFeeScheduleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var payload = authPayload(ctx)
+	if payload.Role != util.AdminRole {
+		var err = errors.New("only admins can update fee schedules")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return
+	}
+	if uri.Kind == db.FeeKindMaintenance && req.RateBps != 0 {
+		var err = errors.New("maintenance fees cannot have a rate")
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var arg = db.UpsertFeeScheduleParams{
+		Kind:        uri.Kind,
+		Currency:    uri.Currency,
+		AccountType: uri.AccountType,
+		FlatAmount:  req.FlatAmount,
+		RateBps:     req.RateBps,
+		MinAmount:   req.MinAmount,
+	}
+	if req.MaxAmount != nil {
+		if *req.MaxAmount < req.MinAmount {
+			var err = errors.New("max_amount must not be below min_amount")
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
+		arg.MaxAmount = pgtype.Int8{Int64: *req.MaxAmount, Valid: true}
+	}
+
+	var schedule, err = server.store.UpsertFeeSchedule(ctx, arg)
+	if err != nil {
+		if db.ErrorCode(err) == db.ForeignKeyViolation {
+			err = fmt.Errorf("%w %q", util.ErrUnknownCurrency, uri.Currency)
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	server.securityLog.Info("fee schedule updated",
+		"kind", schedule.Kind,
+		"currency", schedule.Currency,
+		"account_type", schedule.AccountType,
+		"flat_amount", schedule.FlatAmount,
+		"rate_bps", schedule.RateBps,
+		"admin", payload.Username,
+	)
+	ctx.JSON(http.StatusOK, newFeeScheduleResponse(schedule))
+}
+
+// deleteFeeSchedule lets an admin stop charging a fee.
+func (server *Server) deleteFeeSchedule(ctx *gin.Context) {
+	var uri feeScheduleURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var payload = authPayload(ctx)
+	if payload.Role != util.AdminRole {
+		var err = errors.New("only admins can delete fee schedules")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return
+	}
+
+	var rows, err = server.store.DeleteFeeSchedule(ctx, db.DeleteFeeScheduleParams{
+		Kind:        uri.Kind,
+		Currency:    uri.Currency,
+		AccountType: uri.AccountType,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if rows == 0 {
+		ctx.JSON(http.StatusNotFound, errResponse(errors.New("fee schedule not found")))
+		return
+	}
+
+	server.securityLog.Info("fee schedule deleted",
+		"kind", uri.Kind,
+		"currency", uri.Currency,
+		"account_type", uri.AccountType,
+		"admin", payload.Username,
+	)
+	ctx.Status(http.StatusNoContent)
+}
+
+type listFeeWaiversURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// listFeeWaivers shows the fee waivers of a user to that user and to admins.
+func (server *Server) listFeeWaivers(ctx *gin.Context) {
+	var uri listFeeWaiversURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var payload = authPayload(ctx)
+	if payload.Username != uri.Username && payload.Role != util.AdminRole {
+		var err = errors.New("cannot view the fee waivers of another user")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return
+	}
+
+	var waivers, err = server.store.ListFeeWaivers(ctx, uri.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, waivers)
+}
+
+type feeWaiverURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+	Kind     string `uri:"kind" binding:"required,oneof=transfer maintenance"`
+}
+
+type updateFeeWaiverRequest struct {
+	Reason string `json:"reason" binding:"required,max=200"`
+	// ExpiresAt ends the waiver, it never expires when omitted.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// updateFeeWaiver lets an admin exempt a user from a kind of fee on all their accounts.
+func (server *Server) updateFeeWaiver(ctx *gin.Context) {
+	var uri feeWaiverURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	var req updateFeeWaiverRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var payload = authPayload(ctx)
+	if payload.Role != util.AdminRole {
+		var err = errors.New("only admins can waive fees")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return
+	}
+
+	var arg = db.UpsertFeeWaiverParams{
+		Username:  uri.Username,
+		Kind:      uri.Kind,
+		Reason:    req.Reason,
+		CreatedBy: payload.Username,
+	}
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			var err = errors.New("expires_at must be in the future")
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
+		arg.ExpiresAt = pgtype.Timestamptz{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	var waiver, err = server.store.UpsertFeeWaiver(ctx, arg)
+	if err != nil {
+		if db.ErrorCode(err) == db.ForeignKeyViolation {
+			ctx.JSON(http.StatusNotFound, errResponse(fmt.Errorf("user %q not found", uri.Username)))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	server.securityLog.Info("fee waived",
+		"username", waiver.Username,
+		"kind", waiver.Kind,
+		"reason", waiver.Reason,
+		"admin", payload.Username,
+	)
+	ctx.JSON(http.StatusOK, waiver)
+}
+
+// deleteFeeWaiver lets an admin end a fee waiver before it expires.
+func (server *Server) deleteFeeWaiver(ctx *gin.Context) {
+	var uri feeWaiverURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var payload = authPayload(ctx)
+	if payload.Role != util.AdminRole {
+		var err = errors.New("only admins can delete fee waivers")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return
+	}
+
+	var rows, err = server.store.DeleteFeeWaiver(ctx, db.DeleteFeeWaiverParams{
+		Username: uri.Username,
+		Kind:     uri.Kind,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if rows == 0 {
+		ctx.JSON(http.StatusNotFound, errResponse(errors.New("fee waiver not found")))
+		return
+	}
+
+	server.securityLog.Info("fee waiver deleted",
+		"username", uri.Username,
+		"kind", uri.Kind,
+		"admin", payload.Username,
+	)
+	ctx.Status(http.StatusNoContent)
+}
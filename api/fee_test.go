@@ -0,0 +1,418 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type feeTestCase struct {
+	name          string
+	caller        db.User
+	method        string
+	url           string
+	body          gin.H
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string)
+}
+
+func runFeeTestCases(t *testing.T, testCases []feeTestCase) {
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var store = mockdb.NewMockStore(ctrl)
+			expectAuthLookup(store, tc.caller)
+			tc.buildStubs(store)
+
+			var server = newTestServer(t, store, nil)
+			var securityLog bytes.Buffer
+			server.securityLog = slog.New(slog.NewJSONHandler(&securityLog, nil))
+
+			var body []byte
+			if tc.body != nil {
+				var err error
+				body, err = json.Marshal(tc.body)
+				require.NoError(t, err)
+			}
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader(body))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.caller.Username, tc.caller.Role, time.Minute)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, securityLog.String())
+		})
+	}
+}
+
+func TestFeeSchedules(t *testing.T) {
+	var user, _ = randomUser(t)
+	var admin, _ = randomUser(t)
+	admin.Role = util.AdminRole
+
+	var schedule = db.FeeSchedule{
+		Kind:        db.FeeKindTransfer,
+		Currency:    util.USD,
+		AccountType: db.AccountTypeChecking,
+		FlatAmount:  25,
+		RateBps:     100,
+		MinAmount:   50,
+		MaxAmount:   pgtype.Int8{Int64: 1000, Valid: true},
+	}
+	const scheduleURL = "/fees/transfer/USD/checking"
+
+	runFeeTestCases(t, []feeTestCase{
+		{
+			name:   "List",
+			caller: user,
+			method: http.MethodGet,
+			url:    "/fees",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListFeeSchedules(gomock.Any()).Times(1).Return([]db.FeeSchedule{schedule}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp []feeScheduleResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 1)
+				require.Equal(t, "0.25", rsp[0].FlatAmountDecimal)
+				require.Equal(t, "0.50", rsp[0].MinAmountDecimal)
+				require.Equal(t, "10.00", *rsp[0].MaxAmountDecimal)
+			},
+		},
+		{
+			name:   "Update",
+			caller: admin,
+			method: http.MethodPut,
+			url:    scheduleURL,
+			body:   gin.H{"flat_amount": 25, "rate_bps": 100, "min_amount": 50, "max_amount": 1000},
+			buildStubs: func(store *mockdb.MockStore) {
+				var arg = db.UpsertFeeScheduleParams{
+					Kind:        schedule.Kind,
+					Currency:    schedule.Currency,
+					AccountType: schedule.AccountType,
+					FlatAmount:  schedule.FlatAmount,
+					RateBps:     schedule.RateBps,
+					MinAmount:   schedule.MinAmount,
+					MaxAmount:   schedule.MaxAmount,
+				}
+				store.EXPECT().UpsertFeeSchedule(gomock.Any(), gomock.Eq(arg)).Times(1).Return(schedule, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, securityLog, `"msg":"fee schedule updated"`)
+				require.Contains(t, securityLog, `"admin":"`+admin.Username+`"`)
+			},
+		},
+		{
+			name:   "UpdateWithoutCap",
+			caller: admin,
+			method: http.MethodPut,
+			url:    "/fees/maintenance/EUR/savings",
+			body:   gin.H{"flat_amount": 300},
+			buildStubs: func(store *mockdb.MockStore) {
+				var arg = db.UpsertFeeScheduleParams{
+					Kind:        db.FeeKindMaintenance,
+					Currency:    util.EUR,
+					AccountType: db.AccountTypeSavings,
+					FlatAmount:  300,
+				}
+				store.EXPECT().UpsertFeeSchedule(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.FeeSchedule{Kind: arg.Kind, Currency: arg.Currency, AccountType: arg.AccountType, FlatAmount: 300}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"max_amount_decimal":null`)
+			},
+		},
+		{
+			name:   "UpdateNotAdmin",
+			caller: user,
+			method: http.MethodPut,
+			url:    scheduleURL,
+			body:   gin.H{"flat_amount": 25},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Empty(t, securityLog)
+			},
+		},
+		{
+			name:   "MaintenanceRate",
+			caller: admin,
+			method: http.MethodPut,
+			url:    "/fees/maintenance/USD/checking",
+			body:   gin.H{"rate_bps": 10},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "MaxBelowMin",
+			caller: admin,
+			method: http.MethodPut,
+			url:    scheduleURL,
+			body:   gin.H{"min_amount": 100, "max_amount": 50},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "RateTooHigh",
+			caller: admin,
+			method: http.MethodPut,
+			url:    scheduleURL,
+			body:   gin.H{"rate_bps": 10001},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "InternalAccountType",
+			caller: admin,
+			method: http.MethodPut,
+			url:    "/fees/transfer/USD/internal",
+			body:   gin.H{"flat_amount": 25},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "UnknownCurrency",
+			caller: admin,
+			method: http.MethodPut,
+			url:    "/fees/transfer/XYZ/checking",
+			body:   gin.H{"flat_amount": 25},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertFeeSchedule(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.FeeSchedule{}, &pgconn.PgError{Code: db.ForeignKeyViolation})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "Delete",
+			caller: admin,
+			method: http.MethodDelete,
+			url:    scheduleURL,
+			buildStubs: func(store *mockdb.MockStore) {
+				var arg = db.DeleteFeeScheduleParams{Kind: schedule.Kind, Currency: schedule.Currency, AccountType: schedule.AccountType}
+				store.EXPECT().DeleteFeeSchedule(gomock.Any(), gomock.Eq(arg)).Times(1).Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+				require.Contains(t, securityLog, `"msg":"fee schedule deleted"`)
+			},
+		},
+		{
+			name:   "DeleteNotFound",
+			caller: admin,
+			method: http.MethodDelete,
+			url:    scheduleURL,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteFeeSchedule(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "DeleteNotAdmin",
+			caller: user,
+			method: http.MethodDelete,
+			url:    scheduleURL,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	})
+}
+
+func TestFeeWaivers(t *testing.T) {
+	var user, _ = randomUser(t)
+	var other, _ = randomUser(t)
+	var admin, _ = randomUser(t)
+	admin.Role = util.AdminRole
+
+	var waiverURL = "/users/" + user.Username + "/fee_waivers/maintenance"
+	var expiresAt = time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second).UTC()
+
+	runFeeTestCases(t, []feeTestCase{
+		{
+			name:   "ListOwn",
+			caller: user,
+			method: http.MethodGet,
+			url:    "/users/" + user.Username + "/fee_waivers",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListFeeWaivers(gomock.Any(), gomock.Eq(user.Username)).Times(1).
+					Return([]db.FeeWaiver{{Username: user.Username, Kind: db.FeeKindMaintenance}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"kind":"maintenance"`)
+			},
+		},
+		{
+			name:   "ListOtherUser",
+			caller: other,
+			method: http.MethodGet,
+			url:    "/users/" + user.Username + "/fee_waivers",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListFeeWaivers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "Waive",
+			caller: admin,
+			method: http.MethodPut,
+			url:    waiverURL,
+			body:   gin.H{"reason": "student account", "expires_at": expiresAt},
+			buildStubs: func(store *mockdb.MockStore) {
+				var arg = db.UpsertFeeWaiverParams{
+					Username:  user.Username,
+					Kind:      db.FeeKindMaintenance,
+					Reason:    "student account",
+					ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+					CreatedBy: admin.Username,
+				}
+				store.EXPECT().UpsertFeeWaiver(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.FeeWaiver{Username: arg.Username, Kind: arg.Kind, Reason: arg.Reason, ExpiresAt: arg.ExpiresAt, CreatedBy: arg.CreatedBy}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, securityLog, `"msg":"fee waived"`)
+				require.Contains(t, securityLog, `"username":"`+user.Username+`"`)
+			},
+		},
+		{
+			name:   "WaiveNotAdmin",
+			caller: user,
+			method: http.MethodPut,
+			url:    waiverURL,
+			body:   gin.H{"reason": "please"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertFeeWaiver(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "WaiveExpired",
+			caller: admin,
+			method: http.MethodPut,
+			url:    waiverURL,
+			body:   gin.H{"reason": "student account", "expires_at": time.Now().Add(-time.Hour)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertFeeWaiver(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "WaiveWithoutReason",
+			caller: admin,
+			method: http.MethodPut,
+			url:    waiverURL,
+			body:   gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertFeeWaiver(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "WaiveUnknownUser",
+			caller: admin,
+			method: http.MethodPut,
+			url:    waiverURL,
+			body:   gin.H{"reason": "student account"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertFeeWaiver(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.FeeWaiver{}, &pgconn.PgError{Code: db.ForeignKeyViolation})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "WaiveInternalError",
+			caller: admin,
+			method: http.MethodPut,
+			url:    waiverURL,
+			body:   gin.H{"reason": "student account"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertFeeWaiver(gomock.Any(), gomock.Any()).Times(1).Return(db.FeeWaiver{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:   "Delete",
+			caller: admin,
+			method: http.MethodDelete,
+			url:    waiverURL,
+			buildStubs: func(store *mockdb.MockStore) {
+				var arg = db.DeleteFeeWaiverParams{Username: user.Username, Kind: db.FeeKindMaintenance}
+				store.EXPECT().DeleteFeeWaiver(gomock.Any(), gomock.Eq(arg)).Times(1).Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+				require.Contains(t, securityLog, `"msg":"fee waiver deleted"`)
+			},
+		},
+		{
+			name:   "DeleteNotFound",
+			caller: admin,
+			method: http.MethodDelete,
+			url:    waiverURL,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteFeeWaiver(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	})
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	statusOK       = "ok"
+	statusDegraded = "degraded"
+
+	defaultReadinessTimeout = 2 * time.Second
+)
+
+// HealthCheck reports whether a component the server depends on is working.
+// It returns nil when the component is healthy.
+type HealthCheck func(ctx context.Context) error
+
+// RegisterWorker adds a background worker to the readiness report.
+// It must be called before the server is started.
+func (server *Server) RegisterWorker(name string, check HealthCheck) {
+	server.workers[name] = check
+}
+
+type checkResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type migrationResponse struct {
+	checkResponse
+	Version  int64 `json:"version"`
+	Expected int64 `json:"expected"`
+	Dirty    bool  `json:"dirty"`
+}
+
+// databaseResponse adds the transaction counters of the store to the database check.
+type databaseResponse struct {
+	checkResponse
+	Transactions db.TxStats `json:"transactions"`
+}
+
+type readinessResponse struct {
+	Status    string                   `json:"status"`
+	Database  databaseResponse         `json:"database"`
+	Migration migrationResponse        `json:"migration"`
+	Workers   map[string]checkResponse `json:"workers"`
+}
+
+func newCheckResponse(err error) checkResponse {
+	if err != nil {
+		return checkResponse{Status: statusDegraded, Error: err.Error()}
+	}
+	return checkResponse{Status: statusOK}
+}
+
+// healthz reports that the process is alive. It never touches any dependency.
+func (server *Server) healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, checkResponse{Status: statusOK})
+}
+
+// readyz reports whether the server can take traffic: the database answers in time,
+// its schema is at the version the queries expect and every background worker is running.
+func (server *Server) readyz(ctx *gin.Context) {
+	var timeout = server.config.ReadinessTimeout
+	if timeout <= 0 {
+		timeout = defaultReadinessTimeout
+	}
+	var checkCtx, cancel = context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var rsp = readinessResponse{
+		Status: statusOK,
+		Database: databaseResponse{
+			checkResponse: newCheckResponse(server.store.Ping(checkCtx)),
+			Transactions:  server.store.TxStats(),
+		},
+		Migration: server.checkMigration(checkCtx),
+		Workers:   make(map[string]checkResponse, len(server.workers)),
+	}
+	for name, check := range server.workers {
+		rsp.Workers[name] = newCheckResponse(check(checkCtx))
+	}
+
+	var degraded = rsp.Database.Status != statusOK || rsp.Migration.Status != statusOK
+	for _, worker := range rsp.Workers {
+		degraded = degraded || worker.Status != statusOK
+	}
+	if degraded {
+		rsp.Status = statusDegraded
+		ctx.JSON(http.StatusServiceUnavailable, rsp)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+func (server *Server) checkMigration(ctx context.Context) migrationResponse {
+	var rsp = migrationResponse{Expected: db.SchemaVersion}
+
+	var migration, err = server.store.GetSchemaMigration(ctx)
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		err = errors.New("no migration has been applied")
+	case err != nil:
+	case migration.Dirty:
+		err = fmt.Errorf("migration %d is dirty", migration.Version)
+	case migration.Version != db.SchemaVersion:
+		err = fmt.Errorf("schema version %d does not match expected version %d", migration.Version, db.SchemaVersion)
+	}
+
+	rsp.checkResponse = newCheckResponse(err)
+	rsp.Version = migration.Version
+	rsp.Dirty = migration.Dirty
+	return rsp
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHealthz(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// liveness must not depend on the database
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().Ping(gomock.Any()).Times(0)
+
+	var server = newTestServer(t, store, nil)
+	var recorder = httptest.NewRecorder()
+	var request, err = http.NewRequest(http.MethodGet, "/healthz", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestReadyz(t *testing.T) {
+	var currentMigration = db.SchemaMigration{Version: db.SchemaVersion}
+	var txStats = db.TxStats{Attempts: 12, Retries: 3, Exhausted: 1}
+
+	var testCases = []struct {
+		name          string
+		workers       map[string]HealthCheck
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp readinessResponse)
+		status        int
+	}{
+		{
+			name: "OK",
+			workers: map[string]HealthCheck{
+				"task_processor": func(ctx context.Context) error { return nil },
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().GetSchemaMigration(gomock.Any()).Times(1).Return(currentMigration, nil)
+			},
+			checkResponse: func(t *testing.T, rsp readinessResponse) {
+				require.Equal(t, statusOK, rsp.Status)
+				require.Equal(t, statusOK, rsp.Database.Status)
+				require.Equal(t, txStats, rsp.Database.Transactions)
+				require.Equal(t, statusOK, rsp.Migration.Status)
+				require.Equal(t, db.SchemaVersion, rsp.Migration.Version)
+				require.Equal(t, statusOK, rsp.Workers["task_processor"].Status)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "DatabaseDown",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(sql.ErrConnDone)
+				store.EXPECT().GetSchemaMigration(gomock.Any()).Times(1).Return(db.SchemaMigration{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rsp readinessResponse) {
+				require.Equal(t, statusDegraded, rsp.Status)
+				require.Equal(t, statusDegraded, rsp.Database.Status)
+				require.Equal(t, sql.ErrConnDone.Error(), rsp.Database.Error)
+			},
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name: "NoMigration",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().GetSchemaMigration(gomock.Any()).Times(1).Return(db.SchemaMigration{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, rsp readinessResponse) {
+				require.Equal(t, statusDegraded, rsp.Status)
+				require.Equal(t, statusOK, rsp.Database.Status)
+				require.Equal(t, statusDegraded, rsp.Migration.Status)
+			},
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name: "MigrationBehind",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().GetSchemaMigration(gomock.Any()).Times(1).
+					Return(db.SchemaMigration{Version: db.SchemaVersion - 1}, nil)
+			},
+			checkResponse: func(t *testing.T, rsp readinessResponse) {
+				require.Equal(t, statusDegraded, rsp.Migration.Status)
+				require.Equal(t, db.SchemaVersion-1, rsp.Migration.Version)
+				require.Equal(t, db.SchemaVersion, rsp.Migration.Expected)
+			},
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name: "MigrationDirty",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().GetSchemaMigration(gomock.Any()).Times(1).
+					Return(db.SchemaMigration{Version: db.SchemaVersion, Dirty: true}, nil)
+			},
+			checkResponse: func(t *testing.T, rsp readinessResponse) {
+				require.Equal(t, statusDegraded, rsp.Migration.Status)
+				require.True(t, rsp.Migration.Dirty)
+			},
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name: "WorkerDown",
+			workers: map[string]HealthCheck{
+				"task_processor": func(ctx context.Context) error { return errors.New("not running") },
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().GetSchemaMigration(gomock.Any()).Times(1).Return(currentMigration, nil)
+			},
+			checkResponse: func(t *testing.T, rsp readinessResponse) {
+				require.Equal(t, statusDegraded, rsp.Status)
+				require.Equal(t, statusOK, rsp.Database.Status)
+				require.Equal(t, statusDegraded, rsp.Workers["task_processor"].Status)
+				require.Equal(t, "not running", rsp.Workers["task_processor"].Error)
+			},
+			status: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var store = mockdb.NewMockStore(ctrl)
+			store.EXPECT().TxStats().Times(1).Return(txStats)
+			tc.buildStubs(store)
+
+			var server = newTestServer(t, store, nil)
+			for name, check := range tc.workers {
+				server.RegisterWorker(name, check)
+			}
+
+			var recorder = httptest.NewRecorder()
+			var request, err = http.NewRequest(http.MethodGet, "/readyz", nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.status, recorder.Code)
+
+			var rsp readinessResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			tc.checkResponse(t, rsp)
+		})
+	}
+}
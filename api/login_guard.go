@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Failed logins are tracked per username and per client IP.
+const (
+	loginScopeUsername = "username"
+	loginScopeIP       = "ip"
+)
+
+// maxLoginBackoff caps the backoff when no LOGIN_BACKOFF_MAX is configured.
+const maxLoginBackoff = 24 * time.Hour
+
+var errLoginBlocked = errors.New("too many failed login attempts, try again later")
+
+// loginGuardEnabled reports whether failed logins are tracked at all.
+func (server *Server) loginGuardEnabled() bool {
+	return server.config.LoginBackoffBase > 0 ||
+		server.config.LoginMaxFailuresPerUser > 0 ||
+		server.config.LoginMaxFailuresPerIP > 0
+}
+
+// validateLoginGuard refuses a configuration tracking failed logins without a failure window,
+// with which the count would restart at every failure and never reach the lockout.
+func validateLoginGuard(config util.Config) error {
+	var guarded = config.LoginBackoffBase > 0 || config.LoginMaxFailuresPerUser > 0 || config.LoginMaxFailuresPerIP > 0
+	if guarded && config.LoginFailureWindow <= 0 {
+		return errors.New("LOGIN_FAILURE_WINDOW must be positive when failed logins are tracked")
+	}
+	return nil
+}
+
+// loginAttempt is an attempt counted against a username or a client IP before its credentials are checked.
+type loginAttempt struct {
+	scope       string
+	subject     string
+	failures    int32
+	maxFailures int32
+}
+
+// beginLoginAttempt counts the attempt against the subject and returns it appended to the attempts begun before.
+// The check and the count are a single statement, so concurrent guesses cannot all get through:
+// the attempts past the maximum are refused and, with a backoff, a username is held until the attempt ends.
+// When the attempt is refused it takes back the attempts begun before, answers the request and returns false.
+func (server *Server) beginLoginAttempt(ctx *gin.Context, attempts []loginAttempt, scope string, subject string) ([]loginAttempt, bool) {
+	var attempt = loginAttempt{scope: scope, subject: subject, maxFailures: server.config.LoginMaxFailuresPerIP}
+	if scope == loginScopeUsername {
+		attempt.maxFailures = server.config.LoginMaxFailuresPerUser
+	}
+
+	var blockedUntil, err = server.recordLoginAttempt(ctx, &attempt)
+	if err == nil && !blockedUntil.IsZero() {
+		// a refused attempt is not a guess
+		err = server.releaseLoginAttempts(ctx, attempts)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return nil, false
+	}
+	if !blockedUntil.IsZero() {
+		abortLoginBlocked(ctx, blockedUntil)
+		return nil, false
+	}
+	return append(attempts, attempt), true
+}
+
+// recordLoginAttempt counts the attempt and returns until when the subject is blocked if it is refused.
+func (server *Server) recordLoginAttempt(ctx context.Context, attempt *loginAttempt) (time.Time, error) {
+	var arg = db.RecordLoginAttemptParams{
+		Scope:       attempt.scope,
+		Subject:     attempt.subject,
+		ResetBefore: time.Now().Add(-server.config.LoginFailureWindow),
+	}
+	// an IP is shared by many users, so only a username is held
+	if attempt.scope == loginScopeUsername && server.config.LoginBackoffBase > 0 {
+		arg.HoldUntil = pgtype.Timestamptz{Time: time.Now().Add(server.config.LoginBackoffBase), Valid: true}
+	}
+
+	var failure, err = server.store.RecordLoginAttempt(ctx, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return server.loginBlockedUntil(ctx, attempt.scope, attempt.subject)
+		}
+		return time.Time{}, err
+	}
+	attempt.failures = failure.Failures
+
+	// attempts made concurrently with the one reaching the maximum are past it
+	if attempt.maxFailures > 0 && attempt.failures > attempt.maxFailures {
+		return server.blockLogin(ctx, *attempt)
+	}
+	return time.Time{}, nil
+}
+
+// loginBlockedUntil returns until when the subject may not log in, or the zero time if it is not blocked.
+func (server *Server) loginBlockedUntil(ctx context.Context, scope string, subject string) (time.Time, error) {
+	var arg db.GetLoginBlockParams
+	if scope == loginScopeUsername {
+		arg.Username = subject
+	} else {
+		arg.Ip = subject
+	}
+
+	var block, err = server.store.GetLoginBlock(ctx, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			// the block ended in the meantime
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return block.BlockedUntil.Time, nil
+}
+
+// abortLoginBlocked answers a login attempt made while blocked.
+func abortLoginBlocked(ctx *gin.Context, blockedUntil time.Time) {
+	var retryAfter = int(math.Ceil(time.Until(blockedUntil).Seconds()))
+	ctx.Header("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+	ctx.JSON(http.StatusTooManyRequests, errResponse(errLoginBlocked))
+}
+
+// failLoginAttempts blocks the subjects of attempts whose credentials were wrong
+// for an exponentially growing delay, and for the lockout duration once they reach their maximum failures.
+// The state lives in the database so every replica enforces it.
+func (server *Server) failLoginAttempts(ctx context.Context, attempts []loginAttempt) error {
+	for _, attempt := range attempts {
+		var blockedUntil, err = server.blockLogin(ctx, attempt)
+		if err != nil {
+			return err
+		}
+
+		if attempt.maxFailures > 0 && attempt.failures >= attempt.maxFailures {
+			server.securityLog.Warn("login locked",
+				"scope", attempt.scope,
+				"subject", attempt.subject,
+				"failures", attempt.failures,
+				"locked_until", blockedUntil,
+			)
+		}
+	}
+	return nil
+}
+
+// blockLogin blocks the subject of the attempt for the delay of its failures and returns until when.
+func (server *Server) blockLogin(ctx context.Context, attempt loginAttempt) (time.Time, error) {
+	var delay = loginBackoff(server.config.LoginBackoffBase, server.config.LoginBackoffMax, attempt.failures)
+	if attempt.maxFailures > 0 && attempt.failures >= attempt.maxFailures {
+		delay = max(delay, server.config.LoginLockoutDuration)
+	}
+	if delay <= 0 {
+		return time.Time{}, nil
+	}
+
+	var blockedUntil = time.Now().Add(delay)
+	var err = server.store.BlockLogin(ctx, db.BlockLoginParams{
+		Scope:        attempt.scope,
+		Subject:      attempt.subject,
+		BlockedUntil: pgtype.Timestamptz{Time: blockedUntil, Valid: true},
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return blockedUntil, nil
+}
+
+// releaseLoginAttempts takes back attempts whose credentials were valid, and the hold on the username.
+func (server *Server) releaseLoginAttempts(ctx context.Context, attempts []loginAttempt) error {
+	for _, attempt := range attempts {
+		var err = server.store.ReleaseLoginAttempt(ctx, db.ReleaseLoginAttemptParams{
+			Scope:       attempt.scope,
+			Subject:     attempt.subject,
+			ReleaseHold: attempt.scope == loginScopeUsername,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loginBackoff returns base doubled for every failure after the first, capped at maxDelay.
+func loginBackoff(base time.Duration, maxDelay time.Duration, failures int32) time.Duration {
+	if base <= 0 || failures <= 0 {
+		return 0
+	}
+	if maxDelay <= 0 {
+		maxDelay = maxLoginBackoff
+	}
+
+	var delay = base
+	for i := int32(1); i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+type unlockUserRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type unlockUserResponse struct {
+	Username string `json:"username"`
+	Unlocked bool   `json:"unlocked"`
+}
+
+// unlockUser lets an admin clear the failed logins and lockout of a username.
+func (server *Server) unlockUser(ctx *gin.Context) {
+	var req unlockUserRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var payload = authPayload(ctx)
+	if payload.Role != util.AdminRole {
+		var err = errors.New("only admins can unlock users")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return
+	}
+
+	var rows, err = server.store.DeleteLoginFailures(ctx, db.DeleteLoginFailuresParams{
+		Scope:   loginScopeUsername,
+		Subject: req.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	server.securityLog.Info("login unlocked",
+		"scope", loginScopeUsername,
+		"subject", req.Username,
+		"admin", payload.Username,
+	)
+	ctx.JSON(http.StatusOK, unlockUserResponse{
+		Username: req.Username,
+		Unlocked: rows > 0,
+	})
+}
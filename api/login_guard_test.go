@@ -0,0 +1,418 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/token"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const testClientIP = "203.0.113.7"
+
+func newLoginGuardConfig() util.Config {
+	var config = newTestConfig()
+	config.LoginBackoffBase = time.Second
+	config.LoginBackoffMax = 5 * time.Minute
+	config.LoginMaxFailuresPerUser = 3
+	config.LoginMaxFailuresPerIP = 100
+	config.LoginLockoutDuration = 30 * time.Minute
+	config.LoginFailureWindow = time.Hour
+	return config
+}
+
+// eqBlockLogin matches a BlockLogin call blocking the subject for about delay from now.
+func eqBlockLogin(scope string, subject string, delay time.Duration) gomock.Matcher {
+	return gomock.Cond(func(x any) bool {
+		var arg, ok = x.(db.BlockLoginParams)
+		if !ok || arg.Scope != scope || arg.Subject != subject || !arg.BlockedUntil.Valid {
+			return false
+		}
+		var diff = time.Until(arg.BlockedUntil.Time) - delay
+		return diff > -time.Second && diff < time.Second
+	})
+}
+
+// expectLoginAttempt expects an attempt counted against the subject, with failures recorded so far.
+func expectLoginAttempt(store *mockdb.MockStore, scope string, subject string, failures int32) {
+	store.EXPECT().
+		RecordLoginAttempt(gomock.Any(), gomock.Cond(func(x any) bool {
+			var arg, ok = x.(db.RecordLoginAttemptParams)
+			// only a username is held while its attempt runs
+			var held = arg.HoldUntil.Valid && time.Until(arg.HoldUntil.Time) > 0
+			return ok && arg.Scope == scope && arg.Subject == subject &&
+				time.Since(arg.ResetBefore) > 59*time.Minute &&
+				held == (scope == loginScopeUsername)
+		})).
+		Times(1).
+		Return(db.LoginFailure{Scope: scope, Subject: subject, Failures: failures}, nil)
+}
+
+func expectLoginFailure(store *mockdb.MockStore, scope string, subject string, failures int32, delay time.Duration) {
+	expectLoginAttempt(store, scope, subject, failures)
+	store.EXPECT().
+		BlockLogin(gomock.Any(), eqBlockLogin(scope, subject, delay)).
+		Times(1).
+		Return(nil)
+}
+
+func expectLoginRelease(store *mockdb.MockStore, scope string, subject string) {
+	store.EXPECT().
+		ReleaseLoginAttempt(gomock.Any(), gomock.Eq(db.ReleaseLoginAttemptParams{
+			Scope:       scope,
+			Subject:     subject,
+			ReleaseHold: scope == loginScopeUsername,
+		})).
+		Times(1).
+		Return(nil)
+}
+
+func TestLoginUserGuard(t *testing.T) {
+	var user, password = randomUser(t)
+
+	var testCases = []struct {
+		name          string
+		password      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string)
+	}{
+		{
+			name:     "OKClearsFailures",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAttempt(store, loginScopeIP, testClientIP, 1)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectLoginAttempt(store, loginScopeUsername, user.Username, 2)
+				expectLoginRelease(store, loginScopeIP, testClientIP)
+				expectLoginRelease(store, loginScopeUsername, user.Username)
+				store.EXPECT().
+					BlockLogin(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					DeleteLoginFailures(gomock.Any(), gomock.Eq(db.DeleteLoginFailuresParams{Scope: loginScopeUsername, Subject: user.Username})).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "BlockedIP",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RecordLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginFailure{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					GetLoginBlock(gomock.Any(), gomock.Eq(db.GetLoginBlockParams{Ip: testClientIP})).
+					Times(1).
+					Return(db.LoginFailure{
+						Scope:        loginScopeIP,
+						Subject:      testClientIP,
+						BlockedUntil: pgtype.Timestamptz{Time: time.Now().Add(30 * time.Second), Valid: true},
+					}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				var retryAfter, err = strconv.Atoi(recorder.Header().Get("Retry-After"))
+				require.NoError(t, err)
+				require.InDelta(t, 30, retryAfter, 1)
+			},
+		},
+		{
+			name:     "BlockedUsername",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAttempt(store, loginScopeIP, testClientIP, 1)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RecordLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginFailure{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					GetLoginBlock(gomock.Any(), gomock.Eq(db.GetLoginBlockParams{Username: user.Username})).
+					Times(1).
+					Return(db.LoginFailure{
+						Scope:        loginScopeUsername,
+						Subject:      user.Username,
+						BlockedUntil: pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true},
+					}, nil)
+				// the refused attempt does not count against the IP
+				expectLoginRelease(store, loginScopeIP, testClientIP)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				var retryAfter, err = strconv.Atoi(recorder.Header().Get("Retry-After"))
+				require.NoError(t, err)
+				require.InDelta(t, 60, retryAfter, 1)
+			},
+		},
+		{
+			name:     "ConcurrentAttemptPastMaximum",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAttempt(store, loginScopeIP, testClientIP, 1)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				// another attempt reached the maximum before this one was checked
+				expectLoginFailure(store, loginScopeUsername, user.Username, 4, 30*time.Minute)
+				expectLoginRelease(store, loginScopeIP, testClientIP)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+		{
+			name:     "WrongPasswordBacksOff",
+			password: "incorrect",
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginFailure(store, loginScopeIP, testClientIP, 1, time.Second)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectLoginFailure(store, loginScopeUsername, user.Username, 2, 2*time.Second)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Empty(t, securityLog)
+			},
+		},
+		{
+			name:     "WrongPasswordLocksOut",
+			password: "incorrect",
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginFailure(store, loginScopeIP, testClientIP, 3, 4*time.Second)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectLoginFailure(store, loginScopeUsername, user.Username, 3, 30*time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, securityLog, `"msg":"login locked"`)
+				require.Contains(t, securityLog, `"subject":"`+user.Username+`"`)
+				require.NotContains(t, securityLog, testClientIP)
+			},
+		},
+		{
+			name:     "UnknownUserCountsAgainstIP",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginFailure(store, loginScopeIP, testClientIP, 1, time.Second)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RecordLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginFailure{}, sql.ErrConnDone)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			var server = newTestServerWithConfig(t, newLoginGuardConfig(), store, nil)
+			var securityLog bytes.Buffer
+			server.securityLog = slog.New(slog.NewJSONHandler(&securityLog, nil))
+
+			var data, err = json.Marshal(gin.H{"username": user.Username, "password": tc.password})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+			request.RemoteAddr = testClientIP + ":51234"
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, securityLog.String())
+		})
+	}
+}
+
+func TestLoginGuardRequiresWindow(t *testing.T) {
+	var config = newLoginGuardConfig()
+	config.LoginFailureWindow = 0
+	var _, err = NewServer(config, nil, nil)
+	require.ErrorContains(t, err, "LOGIN_FAILURE_WINDOW")
+
+	// without tracking the window is not used
+	config = newTestConfig()
+	_, err = NewServer(config, nil, nil)
+	require.NoError(t, err)
+}
+
+func TestUnlockUser(t *testing.T) {
+	var user, _ = randomUser(t)
+	var admin, _ = randomUser(t)
+	admin.Role = util.AdminRole
+
+	var testCases = []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthLookup(store, admin)
+				store.EXPECT().
+					DeleteLoginFailures(gomock.Any(), gomock.Eq(db.DeleteLoginFailuresParams{Scope: loginScopeUsername, Subject: user.Username})).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"username":"`+user.Username+`","unlocked":true}`, recorder.Body.String())
+				require.Contains(t, securityLog, `"msg":"login unlocked"`)
+				require.Contains(t, securityLog, `"admin":"`+admin.Username+`"`)
+			},
+		},
+		{
+			name: "NotLocked",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthLookup(store, admin)
+				store.EXPECT().
+					DeleteLoginFailures(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"username":"`+user.Username+`","unlocked":false}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "NotAdmin",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthLookup(store, user)
+				store.EXPECT().
+					DeleteLoginFailures(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Empty(t, securityLog)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteLoginFailures(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthLookup(store, admin)
+				store.EXPECT().
+					DeleteLoginFailures(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			var server = newTestServer(t, store, nil)
+			var securityLog bytes.Buffer
+			server.securityLog = slog.New(slog.NewJSONHandler(&securityLog, nil))
+
+			var request, err = http.NewRequest(http.MethodPost, "/users/"+user.Username+"/unlock", nil)
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, securityLog.String())
+		})
+	}
+}
+
+func TestLoginBackoff(t *testing.T) {
+	require.Zero(t, loginBackoff(0, time.Minute, 5))
+	require.Zero(t, loginBackoff(time.Second, time.Minute, 0))
+	require.Equal(t, time.Second, loginBackoff(time.Second, time.Minute, 1))
+	require.Equal(t, 2*time.Second, loginBackoff(time.Second, time.Minute, 2))
+	require.Equal(t, 32*time.Second, loginBackoff(time.Second, time.Minute, 6))
+	require.Equal(t, time.Minute, loginBackoff(time.Second, time.Minute, 7))
+	require.Equal(t, time.Minute, loginBackoff(time.Second, time.Minute, 1<<30))
+	require.Equal(t, maxLoginBackoff, loginBackoff(time.Second, 0, 1<<30))
+}
@@ -3,10 +3,37 @@ package api
 import (
 	"os"
 	"testing"
+	"time"
 
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/Ma-hiru/simplebank/worker"
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
 )
 
+func newTestConfig() util.Config {
+	return util.Config{
+		TokenSymmetricKey:    util.RandomString(32),
+		TOTPEncryptionKey:    util.RandomString(32),
+		WebhookEncryptionKey: util.RandomString(32),
+		MFATokenDuration:     time.Minute,
+		AccessTokenDuration:  time.Minute,
+		ReadinessTimeout:     time.Second,
+	}
+}
+
+func newTestServer(t *testing.T, store db.Store, taskDistributor worker.TaskDistributor) *Server {
+	return newTestServerWithConfig(t, newTestConfig(), store, taskDistributor)
+}
+
+func newTestServerWithConfig(t *testing.T, config util.Config, store db.Store, taskDistributor worker.TaskDistributor) *Server {
+	var server, err = NewServer(config, store, taskDistributor)
+	require.NoError(t, err)
+
+	return server
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
 	os.Exit(m.Run())
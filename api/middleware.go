@@ -0,0 +1,152 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/token"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+	authorizationTypeAPIKey = "apikey"
+	authorizationPayloadKey = "authorization_payload"
+)
+
+var (
+	errInvalidAPIKey = errors.New("invalid api key")
+	errAPIKeyRevoked = errors.New("api key has been revoked")
+	errAPIKeyExpired = errors.New("api key has expired")
+)
+
+// authMiddleware verifies the bearer token or API key of the request and stores its payload in the context.
+// Tokens issued before the user's last password change are rejected.
+func authMiddleware(tokenMaker token.Maker, store db.Store, passwordChanged *passwordChangedCache, opts ...token.VerifyOption) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var authorizationHeader = ctx.GetHeader(authorizationHeaderKey)
+		if len(authorizationHeader) == 0 {
+			var err = errors.New("authorization header is not provided")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			return
+		}
+
+		var fields = strings.Fields(authorizationHeader)
+		if len(fields) != 2 {
+			var err = errors.New("invalid authorization header format")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			return
+		}
+
+		var payload *token.Payload
+		var ok bool
+		switch authorizationType := strings.ToLower(fields[0]); authorizationType {
+		case authorizationTypeBearer:
+			payload, ok = verifyBearerToken(ctx, tokenMaker, passwordChanged, fields[1], opts)
+		case authorizationTypeAPIKey:
+			payload, ok = verifyAPIKey(ctx, store, fields[1])
+		default:
+			var err = fmt.Errorf("unsupported authorization type %s", authorizationType)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			return
+		}
+		if !ok {
+			return
+		}
+
+		ctx.Set(authorizationPayloadKey, payload)
+		ctx.Next()
+	}
+}
+
+// verifyBearerToken checks an access token, aborting the request when it is not valid.
+func verifyBearerToken(ctx *gin.Context, tokenMaker token.Maker, passwordChanged *passwordChangedCache, accessToken string, opts []token.VerifyOption) (*token.Payload, bool) {
+	var payload, err = tokenMaker.VerifyToken(accessToken, opts...)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+		return nil, false
+	}
+
+	if payload.Type != token.TokenTypeAccess {
+		var err = fmt.Errorf("%s token cannot be used for authorization", payload.Type)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+		return nil, false
+	}
+
+	changedAt, err := passwordChanged.get(ctx, payload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(errors.New("user no longer exists")))
+			return nil, false
+		}
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errResponse(err))
+		return nil, false
+	}
+	if payload.IssuedBefore(changedAt) {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(token.ErrTokenRevoked))
+		return nil, false
+	}
+
+	return payload, true
+}
+
+// verifyAPIKey resolves an API key to a payload carrying its owner and scopes, aborting the request
+// when the key is unknown, revoked or expired. API keys are separate credentials and survive
+// password changes until they are revoked.
+func verifyAPIKey(ctx *gin.Context, store db.Store, key string) (*token.Payload, bool) {
+	var prefix, ok = util.APIKeyPrefix(key)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(errInvalidAPIKey))
+		return nil, false
+	}
+
+	var row, err = store.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(errInvalidAPIKey))
+			return nil, false
+		}
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errResponse(err))
+		return nil, false
+	}
+
+	var apiKey = row.ApiKey
+	if subtle.ConstantTimeCompare([]byte(util.HashSecret(key)), []byte(apiKey.KeyHash)) != 1 {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(errInvalidAPIKey))
+		return nil, false
+	}
+	if apiKey.RevokedAt.Valid {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(errAPIKeyRevoked))
+		return nil, false
+	}
+	if time.Now().After(apiKey.ExpiresAt) {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(errAPIKeyExpired))
+		return nil, false
+	}
+
+	// last_used_at is informational, so failing to record it does not fail the request
+	_ = store.TouchAPIKey(ctx, apiKey.ID)
+
+	return &token.Payload{
+		ID:        apiKey.ID,
+		Username:  apiKey.Username,
+		Role:      row.Role,
+		Type:      token.TokenTypeAPIKey,
+		Scopes:    apiKey.Scopes,
+		IssuedAt:  apiKey.CreatedAt,
+		ExpiredAt: apiKey.ExpiresAt,
+		Issuer:    "simplebank",
+	}, true
+}
+
+// authPayload returns the payload stored by authMiddleware.
+func authPayload(ctx *gin.Context) *token.Payload {
+	return ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+}
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/token"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func addAuthorization(
+	t *testing.T,
+	request *http.Request,
+	tokenMaker token.Maker,
+	authorizationType string,
+	username string,
+	role string,
+	duration time.Duration,
+) {
+	var accessToken, payload, err = tokenMaker.CreateToken(username, role, duration, token.WithScopes(userScopes...))
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	var authorizationHeader = fmt.Sprintf("%s %s", authorizationType, accessToken)
+	request.Header.Set(authorizationHeaderKey, authorizationHeader)
+}
+
+// expectAuthLookup stubs the password_changed_at lookup done by authMiddleware.
+func expectAuthLookup(store *mockdb.MockStore, user db.User) {
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(user, nil)
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	var user, _ = randomUser(t)
+	user.PasswordChangedAt = time.Now().Add(-time.Hour)
+	var changedUser = user
+	changedUser.PasswordChangedAt = time.Now().Add(time.Hour)
+
+	var makers = map[string]func(t *testing.T) token.Maker{
+		"JWT": func(t *testing.T) token.Maker {
+			var maker, err = token.NewJWTMaker(util.RandomString(32))
+			require.NoError(t, err)
+			return maker
+		},
+		"Paseto": func(t *testing.T) token.Maker {
+			var maker, err = token.NewPasetoMaker(paseto.NewV4SymmetricKey())
+			require.NoError(t, err)
+			return maker
+		},
+	}
+
+	var testCases = []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthLookup(store, user)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UnsupportedAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, "unsupported", user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidAuthorizationFormat",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, "", user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, -time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MFAPendingToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				var mfaToken, _, err = tokenMaker.CreateToken(user.Username, user.Role, time.Minute, token.WithType(token.TokenTypeMFAPending))
+				require.NoError(t, err)
+				request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+mfaToken)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "IssuedBeforePasswordChange",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, 2*time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthLookup(store, changedUser)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), token.ErrTokenRevoked.Error())
+			},
+		},
+		{
+			name: "UserDeleted",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for makerName, newMaker := range makers {
+		for _, tc := range testCases {
+			t.Run(makerName+"/"+tc.name, func(t *testing.T) {
+				var ctrl = gomock.NewController(t)
+				defer ctrl.Finish()
+				var store = mockdb.NewMockStore(ctrl)
+				tc.buildStubs(store)
+
+				var server = newTestServer(t, store, nil)
+				var tokenMaker = newMaker(t)
+				var authPath = "/auth"
+				server.router.GET(
+					authPath,
+					authMiddleware(tokenMaker, store, server.passwordChanged),
+					func(ctx *gin.Context) {
+						require.Equal(t, user.Username, authPayload(ctx).Username)
+						ctx.JSON(http.StatusOK, gin.H{})
+					},
+				)
+
+				var recorder = httptest.NewRecorder()
+				var request, err = http.NewRequest(http.MethodGet, authPath, nil)
+				require.NoError(t, err)
+
+				tc.setupAuth(t, request, tokenMaker)
+				server.router.ServeHTTP(recorder, request)
+				tc.checkResponse(t, recorder)
+			})
+		}
+	}
+}
+
+func TestAuthMiddlewareAudience(t *testing.T) {
+	var user, password = randomUser(t)
+
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+
+	var config = newTestConfig()
+	config.TokenAudience = "simplebank"
+	var server = newTestServerWithConfig(t, config, store, nil)
+	var authPath = "/auth"
+	server.router.GET(authPath, authMiddleware(server.tokenMaker, store, server.passwordChanged, server.verifyOptions()...), func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, authPayload(ctx))
+	})
+
+	// tokens from a login are meant for us and carry every user scope
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		Times(2).
+		Return(user, nil)
+	var data, err = json.Marshal(gin.H{"username": user.Username, "password": password})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+	require.NoError(t, err)
+	var recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp loginUserResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	request, err = http.NewRequest(http.MethodGet, authPath, nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+rsp.AccessToken)
+	recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var payload token.Payload
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &payload))
+	require.Equal(t, []string{"simplebank"}, payload.Audience)
+	require.Equal(t, userScopes, payload.Scopes)
+
+	// tokens meant for another service are rejected
+	otherToken, _, err := server.tokenMaker.CreateToken(user.Username, user.Role, time.Minute, token.WithAudience("ledger"))
+	require.NoError(t, err)
+	request, err = http.NewRequest(http.MethodGet, authPath, nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+otherToken)
+	recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.Contains(t, recorder.Body.String(), token.ErrInvalidAudience.Error())
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/Ma-hiru/simplebank/util"
+)
+
+var (
+	errAmountRequired  = errors.New("one of amount or amount_decimal is required")
+	errAmountAmbiguous = errors.New("only one of amount or amount_decimal may be set")
+)
+
+// requestAmount returns the amount of a request in minor units. Clients send either amount,
+// in minor units such as cents, or amountDecimal, in units of the currency such as "12.34".
+func requestAmount(amount int64, amountDecimal string, currency string) (int64, error) {
+	switch {
+	case amountDecimal == "" && amount == 0:
+		return 0, errAmountRequired
+	case amountDecimal == "":
+		return amount, nil
+	case amount != 0:
+		return 0, errAmountAmbiguous
+	}
+
+	var money, err = util.ParseMoney(amountDecimal, currency)
+	return money.Amount, err
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+)
+
+// maxPasswordChangedEntries caps the cache so a flood of distinct users cannot grow it forever.
+const maxPasswordChangedEntries = 10000
+
+type passwordChangedEntry struct {
+	changedAt time.Time
+	expiresAt time.Time
+}
+
+// passwordChangedCache remembers users' password_changed_at for a short TTL,
+// so the auth path does not query the store on every request.
+// A zero TTL disables caching.
+type passwordChangedCache struct {
+	store   db.Store
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]passwordChangedEntry
+	// generation counts the invalidations, so a load that raced with one is not cached.
+	generation uint64
+}
+
+func newPasswordChangedCache(store db.Store, ttl time.Duration) *passwordChangedCache {
+	return &passwordChangedCache{
+		store:   store,
+		ttl:     ttl,
+		entries: make(map[string]passwordChangedEntry),
+	}
+}
+
+// get returns when the user last changed their password.
+// A value loaded while the cache was invalidated is returned but not cached,
+// it may predate the password change.
+func (cache *passwordChangedCache) get(ctx context.Context, username string) (time.Time, error) {
+	var now = time.Now()
+
+	cache.mu.Lock()
+	var entry, ok = cache.entries[username]
+	var generation = cache.generation
+	cache.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.changedAt, nil
+	}
+
+	var user, err = cache.store.GetUser(ctx, username)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if cache.ttl <= 0 {
+		return user.PasswordChangedAt, nil
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.generation != generation {
+		return user.PasswordChangedAt, nil
+	}
+	if len(cache.entries) >= maxPasswordChangedEntries {
+		cache.prune(now)
+	}
+	cache.entries[username] = passwordChangedEntry{
+		changedAt: user.PasswordChangedAt,
+		expiresAt: now.Add(cache.ttl),
+	}
+	return user.PasswordChangedAt, nil
+}
+
+// invalidate drops the cached value after the user's password changed on this replica.
+func (cache *passwordChangedCache) invalidate(username string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	delete(cache.entries, username)
+	cache.generation++
+}
+
+// prune removes expired entries and starts over if the cache is still full.
+// The caller must hold cache.mu.
+func (cache *passwordChangedCache) prune(now time.Time) {
+	for username, entry := range cache.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(cache.entries, username)
+		}
+	}
+	if len(cache.entries) >= maxPasswordChangedEntries {
+		cache.entries = make(map[string]passwordChangedEntry)
+	}
+}
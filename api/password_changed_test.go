@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPasswordChangedCache(t *testing.T) {
+	var user, _ = randomUser(t)
+	user.PasswordChangedAt = time.Now().Add(-time.Hour)
+
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+
+	// the second lookup is served from the cache, the third follows an invalidation
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		Times(2).
+		Return(user, nil)
+
+	var cache = newPasswordChangedCache(store, time.Minute)
+	for range 2 {
+		var changedAt, err = cache.get(context.Background(), user.Username)
+		require.NoError(t, err)
+		require.Equal(t, user.PasswordChangedAt, changedAt)
+	}
+
+	cache.invalidate(user.Username)
+	var changedAt, err = cache.get(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.PasswordChangedAt, changedAt)
+}
+
+func TestPasswordChangedCacheInvalidatedDuringLoad(t *testing.T) {
+	var user, _ = randomUser(t)
+	var stale = user
+	stale.PasswordChangedAt = time.Now().Add(-time.Hour)
+	user.PasswordChangedAt = time.Now()
+
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+
+	// the password changes while the first load reads the user, so its result must not be cached
+	var cache = newPasswordChangedCache(store, time.Minute)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, username string) (db.User, error) {
+			cache.invalidate(username)
+			return stale, nil
+		})
+	var changedAt, err = cache.get(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, stale.PasswordChangedAt, changedAt)
+	require.Empty(t, cache.entries)
+
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(user, nil)
+	changedAt, err = cache.get(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.PasswordChangedAt, changedAt)
+}
+
+func TestPasswordChangedCacheDisabled(t *testing.T) {
+	var user, _ = randomUser(t)
+
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		Times(2).
+		Return(user, nil)
+
+	var cache = newPasswordChangedCache(store, 0)
+	for range 2 {
+		var _, err = cache.get(context.Background(), user.Username)
+		require.NoError(t, err)
+	}
+	require.Empty(t, cache.entries)
+}
+
+func TestPasswordChangedCacheExpires(t *testing.T) {
+	var user, _ = randomUser(t)
+
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		Times(2).
+		Return(user, nil)
+
+	var cache = newPasswordChangedCache(store, time.Minute)
+	var _, err = cache.get(context.Background(), user.Username)
+	require.NoError(t, err)
+
+	// pretend the entry was cached long ago
+	var entry = cache.entries[user.Username]
+	entry.expiresAt = time.Now().Add(-time.Second)
+	cache.entries[user.Username] = entry
+
+	_, err = cache.get(context.Background(), user.Username)
+	require.NoError(t, err)
+}
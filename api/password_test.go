@@ -0,0 +1,262 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/Ma-hiru/simplebank/worker"
+	mockwk "github.com/Ma-hiru/simplebank/worker/mock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestForgotPassword(t *testing.T) {
+	var user, _ = randomUser(t)
+
+	var testCases = []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+				taskDistributor.EXPECT().
+					DistributeTaskSendResetPassword(gomock.Any(), gomock.Any(), gomock.Eq(&worker.PayloadSendResetPassword{Username: user.Username})).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownEmail",
+			body: gin.H{"email": util.RandomEmail()},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				taskDistributor.EXPECT().
+					DistributeTaskSendResetPassword(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				// indistinguishable from a registered email
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name: "DistributeError",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				taskDistributor.EXPECT().
+					DistributeTaskSendResetPassword(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "invalid-email"},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			var taskDistributor = mockwk.NewMockTaskDistributor(ctrl)
+			tc.buildStubs(store, taskDistributor)
+
+			var data, err = json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/password/forgot", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			var server = newTestServer(t, store, taskDistributor)
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func expectResetUser(store *mockdb.MockStore, token string, user db.User) {
+	store.EXPECT().
+		GetPasswordResetUser(gomock.Any(), gomock.Eq(util.HashSecret(token))).
+		Times(1).
+		Return(user, nil)
+}
+
+func TestResetPassword(t *testing.T) {
+	var user, _ = randomUser(t)
+	var token = util.RandomString(43)
+	var newPassword = util.RandomString(8)
+
+	var testCases = []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"token": token, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectResetUser(store, token, user)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ResetPasswordTxParams) (db.ResetPasswordTxResult, error) {
+						// only the hash of the token is looked up
+						require.Equal(t, util.HashSecret(token), arg.TokenHash)
+						require.NoError(t, util.CheckPassword(newPassword, arg.HashPassword))
+						return db.ResetPasswordTxResult{User: user}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchUser(t, recorder.Body, user)
+			},
+		},
+		{
+			name: "InvalidOrExpiredToken",
+			body: gin.H{"token": token, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPasswordResetUser(gomock.Any(), gomock.Eq(util.HashSecret(token))).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "TokenUsedConcurrently",
+			body: gin.H{"token": token, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectResetUser(store, token, user)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ResetPasswordTxResult{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"token": token, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectResetUser(store, token, user)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ResetPasswordTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "PasswordTooShort",
+			body: gin.H{"token": token, "new_password": "123"},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectResetUser(store, token, user)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "PasswordContainsUsername",
+			body: gin.H{"token": token, "new_password": "x1" + user.Username},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectResetUser(store, token, user)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), util.ErrPasswordContainsIdentifier.Error())
+			},
+		},
+		{
+			name: "PasswordContainsEmail",
+			body: gin.H{"token": token, "new_password": "x1" + user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectResetUser(store, token, user)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), util.ErrPasswordContainsIdentifier.Error())
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			var data, err = json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/password/reset", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			var server = newTestServer(t, store, nil)
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+// Execution modes of a payroll batch.
+const (
+	payrollModeAtomic     = "atomic"
+	payrollModeBestEffort = "best_effort"
+)
+
+const (
+	maxPayrollFileSize = 1 << 20
+	maxPayrollRows     = 1000
+	maxReferenceLength = 140
+)
+
+// payrollColumns are the columns the header of a payroll file must have, in any order.
+var payrollColumns = []string{"to_account_id", "amount", "currency", "reference"}
+
+var errInvalidPayroll = errors.New("invalid payroll file")
+
+// payrollRowError reports why a row of the payroll file was rejected.
+type payrollRowError struct {
+	Row   int32  `json:"row"`
+	Error string `json:"error"`
+}
+
+type createPayrollRequest struct {
+	FromAccountID int64  `form:"from_account_id" binding:"required,min=1"`
+	Mode          string `form:"mode" binding:"omitempty,oneof=atomic best_effort"`
+}
+
+// createPayroll pays every row of an uploaded CSV file from one of the caller's accounts.
+// All rows are validated before anything is paid, and the batch is rejected with the errors of every invalid row.
+func (server *Server) createPayroll(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxPayrollFileSize)
+
+	var req createPayrollRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = payrollModeAtomic
+	}
+	var fileHeader, err = ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(fmt.Errorf("file: %w", err)))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(fmt.Errorf("file: %w", err)))
+		return
+	}
+	defer file.Close()
+
+	var payload = authPayload(ctx)
+	fromAccount, ok := server.ownedAccount(ctx, req.FromAccountID, payload)
+	if !ok {
+		return
+	}
+
+	rows, rowErrs, err := parsePayrollCSV(file, fromAccount)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(fmt.Errorf("%w: %w", errInvalidPayroll, err)))
+		return
+	}
+	invalid, err := server.invalidDestinations(ctx, rows)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	rowErrs = append(rowErrs, invalid...)
+	if len(rowErrs) > 0 {
+		slices.SortFunc(rowErrs, func(a, b payrollRowError) int { return int(a.Row - b.Row) })
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": errInvalidPayroll.Error(),
+			"rows":  rowErrs,
+		})
+		return
+	}
+
+	result, err := server.store.PayrollTx(ctx, db.PayrollTxParams{
+		FromAccountID: fromAccount.ID,
+		CreatedBy:     payload.Username,
+		Atomic:        req.Mode == payrollModeAtomic,
+		Rows:          rows,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// parsePayrollCSV reads the rows of a payroll file paid from the account.
+// Invalid rows are reported in the row errors; the error is only set when the file cannot be read at all.
+func parsePayrollCSV(r io.Reader, from db.Account) ([]db.PayrollRowParams, []payrollRowError, error) {
+	var reader = csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	// short rows are reported like any other invalid row
+	reader.FieldsPerRecord = -1
+
+	var header, err = reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var index = make(map[string]int, len(header))
+	for i, column := range header {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, column := range payrollColumns {
+		if _, ok := index[column]; !ok {
+			return nil, nil, fmt.Errorf("missing column %s", column)
+		}
+	}
+
+	var rows []db.PayrollRowParams
+	var rowErrs []payrollRowError
+	for {
+		var record, err = reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		var line, _ = reader.FieldPos(0)
+		if len(rows)+len(rowErrs) == maxPayrollRows {
+			return nil, nil, fmt.Errorf("more than %d rows", maxPayrollRows)
+		}
+
+		if len(record) < len(header) {
+			rowErrs = append(rowErrs, payrollRowError{Row: int32(line), Error: fmt.Sprintf("row has %d fields, want %d", len(record), len(header))})
+			continue
+		}
+		var row, rowErr = parsePayrollRow(record, index, from)
+		row.RowNumber = int32(line)
+		if rowErr != nil {
+			rowErrs = append(rowErrs, payrollRowError{Row: int32(line), Error: rowErr.Error()})
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows)+len(rowErrs) == 0 {
+		return nil, nil, errors.New("file has no rows")
+	}
+	return rows, rowErrs, nil
+}
+
+func parsePayrollRow(record []string, index map[string]int, from db.Account) (db.PayrollRowParams, error) {
+	var field = func(column string) string {
+		return strings.TrimSpace(record[index[column]])
+	}
+
+	var row = db.PayrollRowParams{
+		Currency:  field("currency"),
+		Reference: field("reference"),
+	}
+	var err error
+	row.ToAccountID, err = strconv.ParseInt(field("to_account_id"), 10, 64)
+	if err != nil || row.ToAccountID < 1 {
+		return row, errors.New("to_account_id must be a positive integer")
+	}
+	if row.ToAccountID == from.ID {
+		return row, errors.New("to_account_id is the account paying")
+	}
+	row.Amount, err = strconv.ParseInt(field("amount"), 10, 64)
+	if err != nil || row.Amount <= 0 {
+		return row, errors.New("amount must be a positive integer")
+	}
+	if _, ok := util.LookupCurrency(row.Currency); !ok {
+		return row, fmt.Errorf("%w %q", util.ErrUnknownCurrency, row.Currency)
+	}
+	if row.Currency != from.Currency {
+		return row, fmt.Errorf("currency %s does not match the %s of the paying account", row.Currency, from.Currency)
+	}
+	if len(row.Reference) > maxReferenceLength {
+		return row, fmt.Errorf("reference is longer than %d characters", maxReferenceLength)
+	}
+	return row, nil
+}
+
+// invalidDestinations reports the rows paying an account that does not exist or holds another currency.
+func (server *Server) invalidDestinations(ctx *gin.Context, rows []db.PayrollRowParams) ([]payrollRowError, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	var ids = make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ToAccountID
+	}
+	var accounts, err = server.store.ListAccountsByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	var found = make(map[int64]db.Account, len(accounts))
+	for _, account := range accounts {
+		found[account.ID] = account
+	}
+
+	var rowErrs []payrollRowError
+	for _, row := range rows {
+		var account, ok = found[row.ToAccountID]
+		switch {
+		case !ok:
+			rowErrs = append(rowErrs, payrollRowError{Row: row.RowNumber, Error: fmt.Sprintf("account %d not found", row.ToAccountID)})
+		case account.Currency != row.Currency:
+			rowErrs = append(rowErrs, payrollRowError{
+				Row:   row.RowNumber,
+				Error: fmt.Sprintf("account %d holds %s, not %s", row.ToAccountID, account.Currency, row.Currency),
+			})
+		}
+	}
+	return rowErrs, nil
+}
+
+type getPayrollRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getPayroll returns a payroll batch with the status of every row, to its creator or an admin.
+func (server *Server) getPayroll(ctx *gin.Context) {
+	var req getPayrollRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var batch, err = server.store.GetPayrollBatch(ctx, req.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	var payload = authPayload(ctx)
+	if batch.CreatedBy != payload.Username && payload.Role != util.AdminRole {
+		// not found rather than forbidden, so batch ids cannot be probed
+		ctx.JSON(http.StatusNotFound, errResponse(db.ErrRecordNotFound))
+		return
+	}
+
+	rows, err := server.store.ListPayrollRows(ctx, batch.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, db.PayrollTxResult{Batch: batch, Rows: rows})
+}
@@ -0,0 +1,372 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// newPayrollRequest builds the multipart form uploading the CSV file.
+func newPayrollRequest(t *testing.T, fields map[string]string, file string) *http.Request {
+	var body bytes.Buffer
+	var writer = multipart.NewWriter(&body)
+	for name, value := range fields {
+		require.NoError(t, writer.WriteField(name, value))
+	}
+	if file != "" {
+		var part, err = writer.CreateFormFile("file", "payroll.csv")
+		require.NoError(t, err)
+		_, err = part.Write([]byte(file))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	var request, err = http.NewRequest(http.MethodPost, "/transfers/payroll", &body)
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
+
+func TestCreatePayroll(t *testing.T) {
+	var user, _ = randomUser(t)
+	var from = db.Account{ID: 1, Owner: user.Username, Balance: 1000, Currency: util.USD}
+	var employee1 = db.Account{ID: 2, Owner: util.RandomOwner(), Currency: util.USD}
+	var employee2 = db.Account{ID: 3, Owner: util.RandomOwner(), Currency: util.USD}
+
+	var file = "to_account_id,amount,currency,reference\n" +
+		"2,100,USD,May salary\n" +
+		"3,200,USD,\"May salary, bonus\"\n"
+	var rows = []db.PayrollRowParams{
+		{RowNumber: 2, ToAccountID: 2, Amount: 100, Currency: util.USD, Reference: "May salary"},
+		{RowNumber: 3, ToAccountID: 3, Amount: 200, Currency: util.USD, Reference: "May salary, bonus"},
+	}
+	var fromField = fmt.Sprint(from.ID)
+
+	var testCases = []struct {
+		name          string
+		fields        map[string]string
+		file          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Atomic",
+			fields: map[string]string{"from_account_id": fromField},
+			file:   file,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().
+					ListAccountsByID(gomock.Any(), gomock.Eq([]int64{2, 3})).
+					Times(1).
+					Return([]db.Account{employee1, employee2}, nil)
+				store.EXPECT().
+					PayrollTx(gomock.Any(), gomock.Eq(db.PayrollTxParams{
+						FromAccountID: from.ID,
+						CreatedBy:     user.Username,
+						Atomic:        true,
+						Rows:          rows,
+					})).
+					Times(1).
+					Return(db.PayrollTxResult{Batch: db.PayrollBatch{ID: 9, Status: db.PayrollStatusCompleted}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp db.PayrollTxResult
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, int64(9), rsp.Batch.ID)
+				require.Equal(t, db.PayrollStatusCompleted, rsp.Batch.Status)
+			},
+		},
+		{
+			name:   "BestEffort",
+			fields: map[string]string{"from_account_id": fromField, "mode": payrollModeBestEffort},
+			file:   file,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().ListAccountsByID(gomock.Any(), gomock.Any()).Times(1).Return([]db.Account{employee1, employee2}, nil)
+				store.EXPECT().
+					PayrollTx(gomock.Any(), gomock.Cond(func(x any) bool {
+						var arg, ok = x.(db.PayrollTxParams)
+						return ok && !arg.Atomic
+					})).
+					Times(1).
+					Return(db.PayrollTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidRows",
+			fields: map[string]string{"from_account_id": fromField},
+			file: "reference,amount,to_account_id,currency\n" +
+				"ok,100,2,USD\n" +
+				"negative,-5,2,USD\n" +
+				"self,5,1,USD\n" +
+				"euro,5,3,EUR\n" +
+				"unknown,5,42,USD\n" +
+				"short,5\n",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().
+					ListAccountsByID(gomock.Any(), gomock.Eq([]int64{2, 42})).
+					Times(1).
+					Return([]db.Account{employee1}, nil)
+				store.EXPECT().PayrollTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+				var rsp struct {
+					Rows []payrollRowError `json:"rows"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp.Rows, 5)
+				for i, row := range rsp.Rows {
+					require.Equal(t, int32(i+3), row.Row)
+				}
+				require.Contains(t, rsp.Rows[0].Error, "amount")
+				require.Contains(t, rsp.Rows[1].Error, "paying")
+				require.Contains(t, rsp.Rows[2].Error, "currency")
+				require.Contains(t, rsp.Rows[3].Error, "account 42 not found")
+				require.Contains(t, rsp.Rows[4].Error, "fields")
+			},
+		},
+		{
+			name:   "DestinationCurrencyMismatch",
+			fields: map[string]string{"from_account_id": fromField},
+			file:   file,
+			buildStubs: func(store *mockdb.MockStore) {
+				var euroEmployee = employee2
+				euroEmployee.Currency = util.EUR
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().
+					ListAccountsByID(gomock.Any(), gomock.Eq([]int64{2, 3})).
+					Times(1).
+					Return([]db.Account{employee1, euroEmployee}, nil)
+				store.EXPECT().PayrollTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+				var rsp struct {
+					Rows []payrollRowError `json:"rows"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp.Rows, 1)
+				require.Equal(t, int32(3), rsp.Rows[0].Row)
+				require.Contains(t, rsp.Rows[0].Error, "account 3 holds EUR, not USD")
+			},
+		},
+		{
+			name:   "MissingColumn",
+			fields: map[string]string{"from_account_id": fromField},
+			file:   "to_account_id,amount,currency\n2,100,USD\n",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().PayrollTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "missing column reference")
+			},
+		},
+		{
+			name:   "NoRows",
+			fields: map[string]string{"from_account_id": fromField},
+			file:   "to_account_id,amount,currency,reference\n",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().PayrollTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "TooManyRows",
+			fields: map[string]string{"from_account_id": fromField},
+			file:   "to_account_id,amount,currency,reference\n" + strings.Repeat("2,1,USD,x\n", maxPayrollRows+1),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().PayrollTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "more than")
+			},
+		},
+		{
+			name:   "NoFile",
+			fields: map[string]string{"from_account_id": fromField},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidMode",
+			fields: map[string]string{"from_account_id": fromField, "mode": "sometimes"},
+			file:   file,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "NotOwner",
+			fields: map[string]string{"from_account_id": fromField},
+			file:   file,
+			buildStubs: func(store *mockdb.MockStore) {
+				var other = from
+				other.Owner = util.RandomOwner()
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(other, nil)
+				store.EXPECT().PayrollTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var store = mockdb.NewMockStore(ctrl)
+			expectAuthLookup(store, user)
+			tc.buildStubs(store)
+
+			var server = newTestServer(t, store, nil)
+			var request = newPayrollRequest(t, tc.fields, tc.file)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetPayroll(t *testing.T) {
+	var user, _ = randomUser(t)
+	var admin, _ = randomUser(t)
+	admin.Role = util.AdminRole
+	var other, _ = randomUser(t)
+
+	var batch = db.PayrollBatch{ID: 5, FromAccountID: 1, CreatedBy: user.Username, Atomic: false, Status: db.PayrollStatusPartiallyCompleted}
+	var rows = []db.PayrollRow{
+		{BatchID: 5, RowNumber: 2, ToAccountID: 2, Amount: 100, Status: db.PayrollStatusCompleted},
+		{BatchID: 5, RowNumber: 3, ToAccountID: 3, Amount: 100, Status: db.PayrollStatusFailed, Error: "boom"},
+	}
+
+	var testCases = []struct {
+		name          string
+		caller        db.User
+		batchID       int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "OK",
+			caller:  user,
+			batchID: batch.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayrollBatch(gomock.Any(), gomock.Eq(batch.ID)).Times(1).Return(batch, nil)
+				store.EXPECT().ListPayrollRows(gomock.Any(), gomock.Eq(batch.ID)).Times(1).Return(rows, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp db.PayrollTxResult
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, batch.Status, rsp.Batch.Status)
+				require.Equal(t, rows, rsp.Rows)
+			},
+		},
+		{
+			name:    "Admin",
+			caller:  admin,
+			batchID: batch.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayrollBatch(gomock.Any(), gomock.Eq(batch.ID)).Times(1).Return(batch, nil)
+				store.EXPECT().ListPayrollRows(gomock.Any(), gomock.Eq(batch.ID)).Times(1).Return(rows, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:    "OtherUser",
+			caller:  other,
+			batchID: batch.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayrollBatch(gomock.Any(), gomock.Eq(batch.ID)).Times(1).Return(batch, nil)
+				store.EXPECT().ListPayrollRows(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:    "NotFound",
+			caller:  user,
+			batchID: 6,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayrollBatch(gomock.Any(), gomock.Eq(int64(6))).Times(1).Return(db.PayrollBatch{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:    "InvalidID",
+			caller:  user,
+			batchID: 0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayrollBatch(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var store = mockdb.NewMockStore(ctrl)
+			expectAuthLookup(store, tc.caller)
+			tc.buildStubs(store)
+
+			var server = newTestServer(t, store, nil)
+			var request, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/transfers/payroll/%d", tc.batchID), nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.caller.Username, tc.caller.Role, time.Minute)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/ratelimit"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+// Route groups sharing a rate limit.
+const (
+	rateLimitLogin     = "login"
+	rateLimitAccounts  = "accounts"
+	rateLimitTransfers = "transfers"
+	rateLimitUsers     = "users"
+)
+
+var errRateLimited = errors.New("too many requests")
+
+// newRateLimits parses the limit of every route group from the config.
+func newRateLimits(config util.Config) (map[string]ratelimit.Limit, error) {
+	var settings = map[string]string{
+		rateLimitLogin:     config.RateLimitLogin,
+		rateLimitAccounts:  config.RateLimitAccounts,
+		rateLimitTransfers: config.RateLimitTransfers,
+		rateLimitUsers:     config.RateLimitUsers,
+	}
+
+	var limits = make(map[string]ratelimit.Limit, len(settings))
+	for group, setting := range settings {
+		var limit, err = ratelimit.ParseLimit(setting)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", group, err)
+		}
+		limits[group] = limit
+	}
+	return limits, nil
+}
+
+// rateLimit counts the requests of a route group against the limit configured for it.
+// Requests are keyed by the authenticated username when authMiddleware ran before, by client IP otherwise.
+// When the limiter fails the request is let through, as an outage of the backend should not take the API down.
+func (server *Server) rateLimit(group string) gin.HandlerFunc {
+	var limit = server.rateLimits[group]
+	if !limit.Enabled() {
+		return func(ctx *gin.Context) { ctx.Next() }
+	}
+	var policy = fmt.Sprintf("%d;w=%d", limit.Burst, int64(limit.Period.Seconds()))
+
+	return func(ctx *gin.Context) {
+		var key = group + ":ip:" + ctx.ClientIP()
+		if _, ok := ctx.Get(authorizationPayloadKey); ok {
+			key = group + ":user:" + authPayload(ctx).Username
+		}
+
+		var result, err = server.rateLimiter.Allow(ctx, key, limit)
+		if err != nil {
+			server.securityLog.Error("rate limiter failed",
+				"group", group,
+				"key", key,
+				"error", err.Error(),
+			)
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("RateLimit-Policy", policy)
+		ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("RateLimit-Reset", seconds(result.Reset))
+		if !result.Allowed {
+			ctx.Header("Retry-After", seconds(max(result.RetryAfter, time.Second)))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, errResponse(errRateLimited))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// seconds formats a duration as whole seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
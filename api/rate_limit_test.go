@@ -0,0 +1,175 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRateLimitByIP(t *testing.T) {
+	var config = newTestConfig()
+	config.RateLimitLogin = "2/1m"
+	var server = newTestServerWithConfig(t, config, nil, nil)
+
+	var login = func(clientIP string) *httptest.ResponseRecorder {
+		var request, err = http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader([]byte("{}")))
+		require.NoError(t, err)
+		request.RemoteAddr = clientIP + ":51234"
+
+		var recorder = httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	var recorder = login(testClientIP)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Equal(t, "2;w=60", recorder.Header().Get("RateLimit-Policy"))
+	require.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "30", recorder.Header().Get("RateLimit-Reset"))
+
+	recorder = login(testClientIP)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+
+	recorder = login(testClientIP)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "30", recorder.Header().Get("Retry-After"))
+	require.JSONEq(t, `{"error":"too many requests"}`, recorder.Body.String())
+
+	recorder = login("198.51.100.1")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestRateLimitTrustedProxies(t *testing.T) {
+	var login = func(server *Server, forwardedFor string) *httptest.ResponseRecorder {
+		var request, err = http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader([]byte("{}")))
+		require.NoError(t, err)
+		request.RemoteAddr = testClientIP + ":51234"
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+
+		var recorder = httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// by default a client cannot pick another IP, and so another bucket, with X-Forwarded-For
+	var config = newTestConfig()
+	config.RateLimitLogin = "1/1m"
+	var server = newTestServerWithConfig(t, config, nil, nil)
+	require.Equal(t, http.StatusBadRequest, login(server, "198.51.100.1").Code)
+	require.Equal(t, http.StatusTooManyRequests, login(server, "198.51.100.2").Code)
+
+	// behind a trusted proxy every forwarded client IP has its own bucket
+	config.TrustedProxies = testClientIP + "/32, 10.0.0.0/8"
+	server = newTestServerWithConfig(t, config, nil, nil)
+	require.Equal(t, http.StatusBadRequest, login(server, "198.51.100.1").Code)
+	require.Equal(t, http.StatusBadRequest, login(server, "198.51.100.2").Code)
+	require.Equal(t, http.StatusTooManyRequests, login(server, "198.51.100.2").Code)
+}
+
+func TestNewServerInvalidTrustedProxies(t *testing.T) {
+	var config = newTestConfig()
+	config.TrustedProxies = "not-an-ip"
+	var _, err = NewServer(config, nil, nil)
+	require.Error(t, err)
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	var user1, _ = randomUser(t)
+	var user2, _ = randomUser(t)
+	user1.PasswordChangedAt = time.Now().Add(-time.Hour)
+	user2.PasswordChangedAt = time.Now().Add(-time.Hour)
+
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user1.Username)).
+		Times(2).
+		Return(user1, nil)
+	expectAuthLookup(store, user2)
+
+	var config = newTestConfig()
+	config.RateLimitAccounts = "1/1h"
+	var server = newTestServerWithConfig(t, config, store, nil)
+	server.router.GET(
+		"/rate_limited",
+		authMiddleware(server.tokenMaker, server.store, server.passwordChanged),
+		server.rateLimit(rateLimitAccounts),
+		func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{})
+		},
+	)
+
+	var testCases = []struct {
+		username string
+		role     string
+		code     int
+	}{
+		{user1.Username, user1.Role, http.StatusOK},
+		{user1.Username, user1.Role, http.StatusTooManyRequests},
+		{user2.Username, user2.Role, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		var request, err = http.NewRequest(http.MethodGet, "/rate_limited", nil)
+		require.NoError(t, err)
+		request.RemoteAddr = testClientIP + ":51234"
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, tc.role, time.Minute)
+
+		var recorder = httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, tc.code, recorder.Code)
+	}
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		TakeRateLimitToken(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.TakeRateLimitTokenRow{}, sql.ErrConnDone)
+
+	var config = newTestConfig()
+	config.RateLimitBackend = ratelimit.BackendPostgres
+	config.RateLimitLogin = "10/1m"
+	var server = newTestServerWithConfig(t, config, store, nil)
+	var securityLog bytes.Buffer
+	server.securityLog = slog.New(slog.NewJSONHandler(&securityLog, nil))
+
+	var request, err = http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader([]byte("{}")))
+	require.NoError(t, err)
+
+	var recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+	require.Contains(t, securityLog.String(), `"msg":"rate limiter failed"`)
+}
+
+func TestNewServerInvalidRateLimit(t *testing.T) {
+	var config = newTestConfig()
+	config.RateLimitTransfers = "fast"
+	var _, err = NewServer(config, nil, nil)
+	require.ErrorContains(t, err, "transfers")
+
+	config = newTestConfig()
+	config.RateLimitBackend = "redis"
+	_, err = NewServer(config, nil, nil)
+	require.ErrorContains(t, err, "unsupported rate limit backend")
+}
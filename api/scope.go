@@ -0,0 +1,61 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// Scopes required by the routes. Access tokens from a login carry all of them,
+// API keys only the ones they were created with.
+const (
+	scopeAccountsRead   = "accounts:read"
+	scopeAccountsWrite  = "accounts:write"
+	scopeTransfersRead  = "transfers:read"
+	scopeTransfersWrite = "transfers:write"
+	scopeUsersRead      = "users:read"
+	scopeUsersWrite     = "users:write"
+)
+
+// userScopes are granted to a user signing in.
+var userScopes = []string{
+	scopeAccountsRead,
+	scopeAccountsWrite,
+	scopeTransfersRead,
+	scopeTransfersWrite,
+	scopeUsersRead,
+	scopeUsersWrite,
+}
+
+var knownScopes = map[string]bool{
+	scopeAccountsRead:   true,
+	scopeAccountsWrite:  true,
+	scopeTransfersRead:  true,
+	scopeTransfersWrite: true,
+	scopeUsersRead:      true,
+	scopeUsersWrite:     true,
+}
+
+// validScope implements the "scope" tag.
+var validScope validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	var scope, ok = fieldLevel.Field().Interface().(string)
+	return ok && knownScopes[scope]
+}
+
+// requireScopes only lets requests through whose token or API key was granted every scope.
+// It must run after authMiddleware.
+func requireScopes(scopes ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload = authPayload(ctx)
+		for _, scope := range scopes {
+			if !payload.HasScope(scope) {
+				var err = fmt.Errorf("missing scope %s", scope)
+				ctx.AbortWithStatusJSON(http.StatusForbidden, errResponse(err))
+				return
+			}
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRequireScopesWithAPIKey(t *testing.T) {
+	var user, _ = randomUser(t)
+	var account = randomAccount()
+	account.Owner = user.Username
+	var apiKey, key = randomAPIKey(t, user.Username, scopeAccountsRead)
+
+	var testCases = []struct {
+		name          string
+		newRequest    func(t *testing.T) *http.Request
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "GrantedScope",
+			newRequest: func(t *testing.T) *http.Request {
+				var request, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil)
+				require.NoError(t, err)
+				return request
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "MissingScope",
+			newRequest: func(t *testing.T) *http.Request {
+				var data, err = json.Marshal(gin.H{
+					"from_account_id": account.ID,
+					"to_account_id":   account.ID + 1,
+					"amount":          10,
+					"currency":        account.Currency,
+				})
+				require.NoError(t, err)
+				request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+				require.NoError(t, err)
+				return request
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "missing scope "+scopeTransfersWrite)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			var server = newTestServer(t, store, nil)
+
+			store.EXPECT().
+				GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+				Times(1).
+				Return(db.GetAPIKeyByPrefixRow{ApiKey: apiKey, Role: user.Role}, nil)
+			store.EXPECT().
+				TouchAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).
+				Times(1)
+			tc.buildStubs(store)
+
+			var request = tc.newRequest(t)
+			addAPIKeyAuthorization(request, key)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestReadScopesWithAPIKey(t *testing.T) {
+	var user, _ = randomUser(t)
+	var apiKey, key = randomAPIKey(t, user.Username, scopeAccountsRead, scopeTransfersRead, scopeUsersRead)
+
+	var testCases = []struct {
+		name          string
+		newRequest    func(t *testing.T) *http.Request
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ListWebhooks",
+			newRequest: func(t *testing.T) *http.Request {
+				var request, err = http.NewRequest(http.MethodGet, "/users/"+user.Username+"/webhooks", nil)
+				require.NoError(t, err)
+				return request
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListWebhookSubscriptions(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return([]db.WebhookSubscription{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "GetPayroll",
+			newRequest: func(t *testing.T) *http.Request {
+				var request, err = http.NewRequest(http.MethodGet, "/transfers/payroll/7", nil)
+				require.NoError(t, err)
+				return request
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPayrollBatch(gomock.Any(), gomock.Eq(int64(7))).
+					Times(1).
+					Return(db.PayrollBatch{ID: 7, CreatedBy: user.Username}, nil)
+				store.EXPECT().
+					ListPayrollRows(gomock.Any(), gomock.Eq(int64(7))).
+					Times(1).
+					Return([]db.PayrollRow{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "WriteNeedsWriteScope",
+			newRequest: func(t *testing.T) *http.Request {
+				var data, err = json.Marshal(gin.H{
+					"url":         "https://example.com/hooks",
+					"event_types": []string{db.EventAccountCreated},
+				})
+				require.NoError(t, err)
+				request, err := http.NewRequest(http.MethodPost, "/users/"+user.Username+"/webhooks", bytes.NewReader(data))
+				require.NoError(t, err)
+				return request
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "missing scope "+scopeUsersWrite)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			var server = newTestServer(t, store, nil)
+
+			store.EXPECT().
+				GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+				Times(1).
+				Return(db.GetAPIKeyByPrefixRow{ApiKey: apiKey, Role: user.Role}, nil)
+			store.EXPECT().
+				TouchAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).
+				Times(1)
+			tc.buildStubs(store)
+
+			var request = tc.newRequest(t)
+			addAPIKeyAuthorization(request, key)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/Ma-hiru/simplebank/util"
+)
+
+// newSecurityLogger creates the JSON logger for security events such as account lockouts.
+// It appends to SECURITY_LOG_FILE, or writes to stderr when no file is configured.
+func newSecurityLogger(config util.Config) (*slog.Logger, error) {
+	var writer io.Writer = os.Stderr
+	if config.SecurityLogFile != "" {
+		var file, err = os.OpenFile(config.SecurityLogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open security log: %w", err)
+		}
+		writer = file
+	}
+
+	return slog.New(slog.NewJSONHandler(writer, nil)).With("log", "security"), nil
+}
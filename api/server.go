@@ -1,7 +1,15 @@
 package api
 
 import (
+	"fmt"
+	"log/slog"
+	"strings"
+
 	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/ratelimit"
+	"github.com/Ma-hiru/simplebank/token"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/Ma-hiru/simplebank/worker"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
@@ -9,18 +17,90 @@ import (
 
 // Server serves HTTP requests for banking service.
 type Server struct {
-	store  db.Store
-	router *gin.Engine
+	config          util.Config
+	store           db.Store
+	taskDistributor worker.TaskDistributor
+	tokenMaker      token.Maker
+	passwordHasher  util.PasswordHasher
+	passwords       *passwordValidator
+	secretBox       *util.SecretBox
+	webhookSecrets  *util.SecretBox
+	passwordChanged *passwordChangedCache
+	currencies      *currencyCache
+	securityLog     *slog.Logger
+	rateLimiter     ratelimit.Limiter
+	rateLimits      map[string]ratelimit.Limit
+	router          *gin.Engine
+	workers         map[string]HealthCheck
 }
 
 // NewServer creates a new HTTP server and setup routing.
-func NewServer(store db.Store) *Server {
-	var server = &Server{store: store, router: gin.Default()}
+func NewServer(config util.Config, store db.Store, taskDistributor worker.TaskDistributor) (*Server, error) {
+	var tokenMaker, err = newTokenMaker(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token maker: %w", err)
+	}
+	passwordHasher, err := util.NewPasswordHasher(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password hasher: %w", err)
+	}
+	passwords, err := newPasswordValidator(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password validator: %w", err)
+	}
+	secretBox, err := util.NewSecretBox([]byte(config.TOTPEncryptionKey))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create TOTP secret box: %w", err)
+	}
+	var webhookSecrets *util.SecretBox
+	if config.WebhookEncryptionKey != "" {
+		webhookSecrets, err = util.NewSecretBox([]byte(config.WebhookEncryptionKey))
+		if err != nil {
+			return nil, fmt.Errorf("cannot create webhook secret box: %w", err)
+		}
+	}
+	if err = validateLoginGuard(config); err != nil {
+		return nil, err
+	}
+	securityLog, err := newSecurityLogger(config)
+	if err != nil {
+		return nil, err
+	}
+	rateLimiter, err := ratelimit.NewLimiter(config, store)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate limiter: %w", err)
+	}
+	rateLimits, err := newRateLimits(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse rate limits: %w", err)
+	}
+	var router = gin.Default()
+	if err = router.SetTrustedProxies(trustedProxies(config)); err != nil {
+		return nil, fmt.Errorf("cannot set trusted proxies: %w", err)
+	}
+
+	var server = &Server{
+		config:          config,
+		store:           store,
+		taskDistributor: taskDistributor,
+		tokenMaker:      tokenMaker,
+		passwordHasher:  passwordHasher,
+		passwords:       passwords,
+		secretBox:       secretBox,
+		webhookSecrets:  webhookSecrets,
+		passwordChanged: newPasswordChangedCache(store, config.PasswordChangedCacheTTL),
+		currencies:      newCurrencyCache(store, config.CurrencyCacheTTL),
+		securityLog:     securityLog,
+		rateLimiter:     rateLimiter,
+		rateLimits:      rateLimits,
+		router:          router,
+		workers:         make(map[string]HealthCheck),
+	}
 
 	configureValidator()
 	configureRouter(server)
 
-	return server
+	return server, nil
 }
 
 // Start runs the HTTP server on a specific address.
@@ -28,30 +108,101 @@ func (server *Server) Start(address string) error {
 	return server.router.Run(address)
 }
 
+// trustedProxies parses config.TrustedProxies. It returns nil when no proxy is trusted,
+// so the client IP is always the address of the peer.
+func trustedProxies(config util.Config) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(config.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 func configureValidator() {
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(fieldName)
 		var err = v.RegisterValidation("currency", validCurrency)
 		if err != nil {
 			panic(err)
 		}
+		err = v.RegisterValidation("scope", validScope)
+		if err != nil {
+			panic(err)
+		}
 	}
 }
 
 func configureRouter(server *Server) {
-	server.router.POST("/accounts", server.createAccount)
-	server.router.GET("/accounts", server.listAccount)
-	server.router.PUT("/accounts", server.updateAccount)
-	server.router.GET("/accounts/:id", server.getAccount)
-	server.router.DELETE("/accounts/:id", server.deleteAccount)
+	server.router.GET("/healthz", server.healthz)
+	server.router.GET("/readyz", server.readyz)
+	server.router.GET("/.well-known/jwks.json", server.jwks)
 
-	server.router.POST("/transfers", server.createTransfer)
-
-	server.router.POST("/users", server.createUser)
+	server.router.POST("/users", server.rateLimit(rateLimitUsers), server.createUser)
 	server.router.GET("/users/:username", server.getUser)
+	server.router.GET("/verify_email", server.verifyEmail)
+
+	var loginRoutes = server.router.Group("/", server.rateLimit(rateLimitLogin))
+	loginRoutes.POST("/users/password/forgot", server.forgotPassword)
+	loginRoutes.POST("/users/password/reset", server.resetPassword)
+	loginRoutes.POST("/users/login", server.loginUser)
+	loginRoutes.POST("/users/login/mfa", server.loginMFA)
+
+	var authRoutes = server.router.Group("/", authMiddleware(server.tokenMaker, server.store, server.passwordChanged, server.verifyOptions()...))
+
+	var accountRoutes = authRoutes.Group("/", server.rateLimit(rateLimitAccounts))
+	accountRoutes.POST("/accounts", requireScopes(scopeAccountsWrite), server.createAccount)
+	accountRoutes.GET("/accounts", requireScopes(scopeAccountsRead), server.listAccount)
+	accountRoutes.PUT("/accounts", requireScopes(scopeAccountsWrite), server.updateAccount)
+	accountRoutes.GET("/accounts/:id", requireScopes(scopeAccountsRead), server.getAccount)
+	accountRoutes.GET("/accounts/:id/statement", requireScopes(scopeAccountsRead), server.getStatement)
+	accountRoutes.DELETE("/accounts/:id", requireScopes(scopeAccountsWrite), server.deleteAccount)
+	accountRoutes.GET("/currencies", requireScopes(scopeAccountsRead), server.listCurrencies)
+	accountRoutes.PUT("/currencies/:code", requireScopes(scopeAccountsWrite), server.updateCurrency)
+	accountRoutes.GET("/fees", requireScopes(scopeAccountsRead), server.listFeeSchedules)
+	accountRoutes.PUT("/fees/:kind/:currency/:account_type", requireScopes(scopeAccountsWrite), server.updateFeeSchedule)
+	accountRoutes.DELETE("/fees/:kind/:currency/:account_type", requireScopes(scopeAccountsWrite), server.deleteFeeSchedule)
+
+	var transferRoutes = authRoutes.Group("/", server.rateLimit(rateLimitTransfers))
+	transferRoutes.POST("/transfers", requireScopes(scopeTransfersWrite), server.createTransfer)
+	transferRoutes.POST("/transfers/batch", requireScopes(scopeTransfersWrite), server.createBatchTransfer)
+	transferRoutes.POST("/transfers/payroll", requireScopes(scopeTransfersWrite), server.createPayroll)
+	transferRoutes.GET("/transfers/payroll/:id", requireScopes(scopeTransfersRead), server.getPayroll)
+
+	var userRoutes = authRoutes.Group("/", server.rateLimit(rateLimitUsers))
+	userRoutes.PATCH("/users/:username", requireScopes(scopeUsersWrite), server.updateUser)
+	userRoutes.POST("/users/:username/unlock", requireScopes(scopeUsersWrite), server.unlockUser)
+	userRoutes.POST("/users/:username/totp", requireScopes(scopeUsersWrite), server.enrollTOTP)
+	userRoutes.POST("/users/:username/totp/confirm", requireScopes(scopeUsersWrite), server.confirmTOTP)
+	userRoutes.POST("/users/:username/api_keys", requireScopes(scopeUsersWrite), server.createAPIKey)
+	userRoutes.GET("/users/:username/api_keys", requireScopes(scopeUsersRead), server.listAPIKeys)
+	userRoutes.DELETE("/users/:username/api_keys/:id", requireScopes(scopeUsersWrite), server.revokeAPIKey)
+	userRoutes.GET("/users/:username/fee_waivers", requireScopes(scopeUsersRead), server.listFeeWaivers)
+	userRoutes.PUT("/users/:username/fee_waivers/:kind", requireScopes(scopeUsersWrite), server.updateFeeWaiver)
+	userRoutes.DELETE("/users/:username/fee_waivers/:kind", requireScopes(scopeUsersWrite), server.deleteFeeWaiver)
+	userRoutes.POST("/users/:username/webhooks", requireScopes(scopeUsersWrite), server.createWebhook)
+	userRoutes.GET("/users/:username/webhooks", requireScopes(scopeUsersRead), server.listWebhooks)
+	userRoutes.DELETE("/users/:username/webhooks/:id", requireScopes(scopeUsersWrite), server.deleteWebhook)
+	userRoutes.GET("/users/:username/webhooks/:id/deliveries", requireScopes(scopeUsersRead), server.listWebhookDeliveries)
+	userRoutes.GET("/users/:username/webhooks/:id/deliveries/:delivery_id/attempts", requireScopes(scopeUsersRead), server.listWebhookAttempts)
+	userRoutes.POST("/users/:username/webhooks/:id/deliveries/:delivery_id/replay", requireScopes(scopeUsersWrite), server.replayWebhookDelivery)
 }
 
 func errResponse(err error) gin.H {
-	return gin.H{
+	var rsp = gin.H{
 		"error": err.Error(),
 	}
+	if fields := validationErrors(err); fields != nil {
+		rsp["fields"] = fields
+	}
+	return rsp
+}
+
+// fieldErrResponse reports an error about a single request field.
+func fieldErrResponse(field string, err error) gin.H {
+	return gin.H{
+		"error":  fmt.Sprintf("%s %s", field, err.Error()),
+		"fields": map[string]string{field: err.Error()},
+	}
 }
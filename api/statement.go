@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/statement"
+	"github.com/gin-gonic/gin"
+)
+
+type getStatementURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getStatementQuery selects the days of the statement; both From and To are included.
+type getStatementQuery struct {
+	Format string    `form:"format" binding:"required,oneof=csv ofx camt053"`
+	From   time.Time `form:"from" binding:"required" time_format:"2006-01-02" time_utc:"1"`
+	To     time.Time `form:"to" binding:"required,gtefield=From" time_format:"2006-01-02" time_utc:"1"`
+}
+
+func (server *Server) getStatement(ctx *gin.Context) {
+	var uri getStatementURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	var req getStatementQuery
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var account, ok = server.ownedAccount(ctx, uri.ID, authPayload(ctx))
+	if !ok {
+		return
+	}
+
+	var encoder, err = statement.NewEncoder(req.Format, ctx.Writer)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var header = statement.Header{
+		AccountID:   account.ID,
+		Owner:       account.Owner,
+		Currency:    account.Currency,
+		From:        req.From,
+		To:          req.To.AddDate(0, 0, 1),
+		GeneratedAt: time.Now(),
+	}
+	var started = false
+	err = server.store.StatementTx(ctx, db.StatementTxParams{
+		AccountID: account.ID,
+		From:      header.From,
+		To:        header.To,
+		WriteHeader: func(balances db.GetStatementBalancesRow) error {
+			header.OpeningBalance = balances.OpeningBalance
+			header.ClosingBalance = balances.ClosingBalance
+
+			ctx.Header("Content-Type", statement.ContentType(req.Format))
+			ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", statement.FileName(req.Format, header)))
+			ctx.Status(http.StatusOK)
+			started = true
+			return encoder.WriteHeader(header)
+		},
+		WriteLine: func(line db.ListStatementLinesRow) error {
+			return encoder.WriteLine(newStatementLine(account.ID, line))
+		},
+	})
+	if err == nil {
+		err = encoder.Close()
+	}
+	if err == nil {
+		return
+	}
+
+	if started {
+		// the status is already sent, so the statement is left incomplete
+		_ = ctx.Error(err)
+		return
+	}
+	if errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, errResponse(err))
+}
+
+func newStatementLine(accountID int64, row db.ListStatementLinesRow) statement.Line {
+	var line = statement.Line{
+		EntryID:     row.ID,
+		BookedAt:    row.CreatedAt,
+		Amount:      row.Amount,
+		TransferID:  row.TransferID.Int64,
+		Description: row.Description.String,
+	}
+	if row.TransferID.Valid {
+		line.CounterpartyAccountID = row.FromAccountID.Int64
+		if row.FromAccountID.Int64 == accountID {
+			line.CounterpartyAccountID = row.ToAccountID.Int64
+		}
+	}
+	return line
+}
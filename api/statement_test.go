@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/statement"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// writeStatement returns a StatementTx stub that writes the balances and the lines, then fails with err.
+func writeStatement(balances db.GetStatementBalancesRow, lines []db.ListStatementLinesRow, err error) func(context.Context, db.StatementTxParams) error {
+	return func(_ context.Context, arg db.StatementTxParams) error {
+		if e := arg.WriteHeader(balances); e != nil {
+			return e
+		}
+		for _, line := range lines {
+			if e := arg.WriteLine(line); e != nil {
+				return e
+			}
+		}
+		return err
+	}
+}
+
+func TestGetStatement(t *testing.T) {
+	var user, _ = randomUser(t)
+	var account = randomAccount()
+	account.Owner = user.Username
+
+	var from = time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	var to = time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	var balances = db.GetStatementBalancesRow{OpeningBalance: 10000, ClosingBalance: 8000}
+	var lines = []db.ListStatementLinesRow{
+		{
+			ID:            11,
+			Amount:        -5000,
+			CreatedAt:     from.Add(time.Hour),
+			TransferID:    pgtype.Int8{Int64: 4, Valid: true},
+			FromAccountID: pgtype.Int8{Int64: account.ID, Valid: true},
+			ToAccountID:   pgtype.Int8{Int64: 1000, Valid: true},
+		},
+		{
+			ID:              12,
+			Amount:          3000,
+			CreatedAt:       from.Add(48 * time.Hour),
+			TransferGroupID: pgtype.Int8{Int64: 2, Valid: true},
+			Description:     pgtype.Text{String: "dinner", Valid: true},
+		},
+	}
+
+	var testCases = []struct {
+		name          string
+		accountID     int64
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "CSV",
+			accountID: account.ID,
+			query:     "format=csv&from=2026-05-01&to=2026-05-31",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					StatementTx(gomock.Any(), gomock.Cond(func(x any) bool {
+						var arg, ok = x.(db.StatementTxParams)
+						return ok && arg.AccountID == account.ID && arg.From.Equal(from) && arg.To.Equal(to)
+					})).
+					Times(1).
+					DoAndReturn(writeStatement(balances, lines, nil))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, statement.ContentType(statement.FormatCSV), recorder.Header().Get("Content-Type"))
+				require.Equal(t,
+					fmt.Sprintf(`attachment; filename="statement-%d-2026-05-01-2026-06-01.csv"`, account.ID),
+					recorder.Header().Get("Content-Disposition"))
+
+				var currency = account.Currency
+				require.Equal(t, "booked_at,entry_id,transfer_id,counterparty_account_id,description,amount,currency,balance\n"+
+					"2026-05-01T01:00:00Z,11,4,1000,,-50.00,"+currency+",50.00\n"+
+					"2026-05-03T00:00:00Z,12,,,dinner,30.00,"+currency+",80.00\n", recorder.Body.String())
+			},
+		},
+		{
+			name:      "CAMT053",
+			accountID: account.ID,
+			query:     "format=camt053&from=2026-05-01&to=2026-05-31",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().StatementTx(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(writeStatement(balances, lines, nil))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "application/xml", recorder.Header().Get("Content-Type"))
+				require.Contains(t, recorder.Body.String(), "<Cd>OPBD</Cd>")
+				require.Contains(t, recorder.Body.String(), "</Document>")
+			},
+		},
+		{
+			name:      "FailedMidway",
+			accountID: account.ID,
+			query:     "format=ofx&from=2026-05-01&to=2026-05-31",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().StatementTx(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(writeStatement(balances, lines[:1], sql.ErrConnDone))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "<FITID>11</FITID>")
+				require.NotContains(t, recorder.Body.String(), "</OFX>")
+			},
+		},
+		{
+			name:      "InternalError",
+			accountID: account.ID,
+			query:     "format=ofx&from=2026-05-01&to=2026-05-31",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().StatementTx(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:      "NotFound",
+			accountID: account.ID,
+			query:     "format=csv&from=2026-05-01&to=2026-05-31",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().StatementTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "NotOwner",
+			accountID: account.ID,
+			query:     "format=csv&from=2026-05-01&to=2026-05-31",
+			buildStubs: func(store *mockdb.MockStore) {
+				var other = account
+				other.Owner = "someone"
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(other, nil)
+				store.EXPECT().StatementTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidFormat",
+			accountID: account.ID,
+			query:     "format=pdf&from=2026-05-01&to=2026-05-31",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "ToBeforeFrom",
+			accountID: account.ID,
+			query:     "format=csv&from=2026-05-31&to=2026-05-01",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidDate",
+			accountID: account.ID,
+			query:     "format=csv&from=yesterday&to=2026-05-01",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidID",
+			accountID: 0,
+			query:     "format=csv&from=2026-05-01&to=2026-05-31",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var store = mockdb.NewMockStore(ctrl)
+			expectAuthLookup(store, user)
+			tc.buildStubs(store)
+
+			var server = newTestServer(t, store, nil)
+			var url = fmt.Sprintf("/accounts/%d/statement?%s", tc.accountID, tc.query)
+			var request, err = http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
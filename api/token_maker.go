@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/Ma-hiru/simplebank/token"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+// Token makers selectable with TOKEN_MAKER.
+const (
+	tokenMakerPaseto       = "paseto"
+	tokenMakerJWT          = "jwt"
+	tokenMakerPasetoPublic = "paseto_public"
+	tokenMakerJWTEdDSA     = "jwt_eddsa"
+)
+
+// newTokenMaker creates the token maker selected by the config, PASETO v4.local by default.
+// Keys come from TOKEN_KEYRING_FILE when it is set.
+func newTokenMaker(config util.Config) (token.Maker, error) {
+	if config.TokenKeyringFile != "" {
+		var set, err = token.LoadKeySet(config.TokenKeyringFile)
+		if err != nil {
+			return nil, err
+		}
+		return newTokenMakerWithKeys(config.TokenMaker, set)
+	}
+
+	switch config.TokenMaker {
+	case "", tokenMakerPaseto:
+		var symmetricKey, err = paseto.V4SymmetricKeyFromBytes([]byte(config.TokenSymmetricKey))
+		if err != nil {
+			return nil, err
+		}
+		return token.NewPasetoMaker(symmetricKey)
+	case tokenMakerJWT:
+		return token.NewJWTMaker(config.TokenSymmetricKey)
+	case tokenMakerPasetoPublic:
+		var privateKey, err = token.ParseEd25519Seed(config.TokenSigningKey)
+		if err != nil {
+			return nil, err
+		}
+		return token.NewPasetoPublicMaker(privateKey)
+	case tokenMakerJWTEdDSA:
+		var privateKey, err = token.ParseEd25519Seed(config.TokenSigningKey)
+		if err != nil {
+			return nil, err
+		}
+		return token.NewJWTEdDSAMaker(privateKey)
+	default:
+		return nil, fmt.Errorf("unknown token maker %q", config.TokenMaker)
+	}
+}
+
+func newTokenMakerWithKeys(name string, set token.KeySet) (token.Maker, error) {
+	switch name {
+	case "", tokenMakerPaseto:
+		return token.NewPasetoMakerWithKeys(set)
+	case tokenMakerJWT:
+		return token.NewJWTMakerWithKeys(set)
+	case tokenMakerPasetoPublic:
+		return token.NewPasetoPublicMakerWithKeys(set)
+	case tokenMakerJWTEdDSA:
+		return token.NewJWTEdDSAMakerWithKeys(set)
+	default:
+		return nil, fmt.Errorf("unknown token maker %q", name)
+	}
+}
+
+// tokenOptions adds our audience, when configured, to the options of a new token.
+func (server *Server) tokenOptions(opts ...token.Option) []token.Option {
+	if server.config.TokenAudience != "" {
+		opts = append(opts, token.WithAudience(server.config.TokenAudience))
+	}
+	return opts
+}
+
+// verifyOptions requires our audience, when configured, from the tokens we accept.
+func (server *Server) verifyOptions() []token.VerifyOption {
+	if server.config.TokenAudience == "" {
+		return nil
+	}
+	return []token.VerifyOption{token.ExpectAudience(server.config.TokenAudience)}
+}
+
+// ReloadTokenKeys reads TOKEN_KEYRING_FILE again and swaps the keys of the token maker.
+// The current keys are kept when the file is invalid.
+func (server *Server) ReloadTokenKeys() error {
+	if server.config.TokenKeyringFile == "" {
+		return errors.New("no token keyring file is configured")
+	}
+	var rotator, ok = server.tokenMaker.(token.KeyRotator)
+	if !ok {
+		return errors.New("token maker does not support key rotation")
+	}
+
+	var set, err = token.LoadKeySet(server.config.TokenKeyringFile)
+	if err != nil {
+		return err
+	}
+	return rotator.SetKeys(set)
+}
+
+// jwks publishes the public keys that verify our tokens. Symmetric token makers have nothing
+// to publish, so the key set is empty.
+func (server *Server) jwks(ctx *gin.Context) {
+	var jwks = token.JWKS{Keys: []token.JWK{}}
+	if publisher, ok := server.tokenMaker.(token.KeyPublisher); ok {
+		jwks = publisher.JWKS()
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, jwks)
+}
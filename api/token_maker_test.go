@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	"github.com/Ma-hiru/simplebank/token"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newTestSigningKey() string {
+	return hex.EncodeToString([]byte(util.RandomString(32)))
+}
+
+func TestNewTokenMaker(t *testing.T) {
+	for _, name := range []string{"", tokenMakerPaseto, tokenMakerJWT, tokenMakerPasetoPublic, tokenMakerJWTEdDSA} {
+		t.Run(name, func(t *testing.T) {
+			var config = newTestConfig()
+			config.TokenMaker = name
+			config.TokenSigningKey = newTestSigningKey()
+
+			var maker, err = newTokenMaker(config)
+			require.NoError(t, err)
+
+			accessToken, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+			require.NoError(t, err)
+			_, err = maker.VerifyToken(accessToken)
+			require.NoError(t, err)
+		})
+	}
+
+	var config = newTestConfig()
+	config.TokenMaker = "rot13"
+	var _, err = newTokenMaker(config)
+	require.Error(t, err)
+
+	config.TokenMaker = tokenMakerJWTEdDSA
+	config.TokenSigningKey = "abcd"
+	_, err = newTokenMaker(config)
+	require.Error(t, err)
+}
+
+func TestJWKS(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		tokenMaker    string
+		checkResponse func(t *testing.T, server *Server, jwks token.JWKS)
+	}{
+		{
+			name:       "Asymmetric",
+			tokenMaker: tokenMakerJWTEdDSA,
+			checkResponse: func(t *testing.T, server *Server, jwks token.JWKS) {
+				require.Len(t, jwks.Keys, 1)
+
+				// other services verify our tokens with the published keys only
+				var verifier, err = token.NewVerifierFromJWKS(jwks)
+				require.NoError(t, err)
+				accessToken, _, err := server.tokenMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+				require.NoError(t, err)
+				_, err = verifier.VerifyToken(accessToken)
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:       "Symmetric",
+			tokenMaker: tokenMakerPaseto,
+			checkResponse: func(t *testing.T, server *Server, jwks token.JWKS) {
+				require.NotNil(t, jwks.Keys)
+				require.Empty(t, jwks.Keys)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+
+			var config = newTestConfig()
+			config.TokenMaker = tc.tokenMaker
+			config.TokenSigningKey = newTestSigningKey()
+			var server = newTestServerWithConfig(t, config, store, nil)
+
+			var request, err = http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+			require.NoError(t, err)
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			var jwks token.JWKS
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &jwks))
+			tc.checkResponse(t, server, jwks)
+		})
+	}
+}
+
+func TestReloadTokenKeys(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "token_keys.json")
+	var writeKeys = func(set token.KeySet) {
+		var data, err = json.Marshal(set)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+	}
+	var oldKey = token.Key{ID: "old", Secret: util.RandomString(32)}
+	var newKey = token.Key{ID: "new", Secret: util.RandomString(32)}
+	writeKeys(token.KeySet{Active: oldKey.ID, Keys: []token.Key{oldKey}})
+
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var config = newTestConfig()
+	config.TokenKeyringFile = path
+	var server = newTestServerWithConfig(t, config, mockdb.NewMockStore(ctrl), nil)
+
+	var oldToken, _, err = server.tokenMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	writeKeys(token.KeySet{Active: newKey.ID, Keys: []token.Key{oldKey, newKey}})
+	require.NoError(t, server.ReloadTokenKeys())
+	newToken, _, err := server.tokenMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	_, err = server.tokenMaker.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	// a broken file keeps the current keys
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	require.Error(t, server.ReloadTokenKeys())
+	_, err = server.tokenMaker.VerifyToken(newToken)
+	require.NoError(t, err)
+
+	oldKey.Retired = true
+	writeKeys(token.KeySet{Active: newKey.ID, Keys: []token.Key{oldKey, newKey}})
+	require.NoError(t, server.ReloadTokenKeys())
+	_, err = server.tokenMaker.VerifyToken(oldToken)
+	require.ErrorIs(t, err, token.ErrKeyRetired)
+	_, err = server.tokenMaker.VerifyToken(newToken)
+	require.NoError(t, err)
+}
+
+func TestReloadTokenKeysWithoutFile(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var server = newTestServer(t, mockdb.NewMockStore(ctrl), nil)
+	require.Error(t, server.ReloadTokenKeys())
+}
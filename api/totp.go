@@ -0,0 +1,379 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/token"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	defaultTOTPIssuer = "Simple Bank"
+	recoveryCodeCount = 10
+	// totpPeriod is the length in seconds of a TOTP time step, the default of authenticator apps.
+	totpPeriod = 30
+)
+
+var (
+	errTOTPAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	errInvalidSecondFactor = errors.New("invalid two-factor authentication code")
+	errMFATokenUsed        = errors.New("mfa_token has already been used")
+)
+
+type totpURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type enrollTOTPResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// enrollTOTP generates a new TOTP secret for the user. It is stored encrypted and
+// only takes effect once confirmed with a first code.
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	var uri totpURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	if authPayload(ctx).Username != uri.Username {
+		var err = errors.New("cannot enrol two-factor authentication for another user")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return
+	}
+
+	var user, err = server.store.GetUser(ctx, uri.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if user.TotpEnabled {
+		ctx.JSON(http.StatusConflict, errResponse(errTOTPAlreadyEnabled))
+		return
+	}
+
+	var issuer = server.config.TOTPIssuer
+	if issuer == "" {
+		issuer = defaultTOTPIssuer
+	}
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: user.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	sealed, err := server.secretBox.Seal(key.Secret(), user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	_, err = server.store.SetUserTOTPSecret(ctx, db.SetUserTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: pgtype.Text{String: sealed, Valid: true},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollTOTPResponse{
+		Secret:     key.Secret(),
+		OTPAuthURI: key.URL(),
+	})
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type confirmTOTPResponse struct {
+	User          UserResponse `json:"user"`
+	RecoveryCodes []string     `json:"recovery_codes"`
+}
+
+// confirmTOTP enables two-factor authentication once the user proves their authenticator
+// works, and returns recovery codes that are never shown again.
+func (server *Server) confirmTOTP(ctx *gin.Context) {
+	var uri totpURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	if authPayload(ctx).Username != uri.Username {
+		var err = errors.New("cannot enrol two-factor authentication for another user")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return
+	}
+
+	var user, err = server.store.GetUser(ctx, uri.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if user.TotpEnabled {
+		ctx.JSON(http.StatusConflict, errResponse(errTOTPAlreadyEnabled))
+		return
+	}
+	if !user.TotpSecret.Valid {
+		var err = errors.New("two-factor authentication enrolment has not been started")
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	valid, err := server.validTOTPCode(ctx, user, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if !valid {
+		ctx.JSON(http.StatusUnauthorized, errResponse(errInvalidSecondFactor))
+		return
+	}
+
+	var recoveryCodes = make([]string, recoveryCodeCount)
+	var recoveryCodeHashes = make([]string, recoveryCodeCount)
+	for i := range recoveryCodes {
+		recoveryCodes[i], err = util.RandomRecoveryCode()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+			return
+		}
+		recoveryCodeHashes[i] = util.HashSecret(util.NormalizeRecoveryCode(recoveryCodes[i]))
+	}
+
+	result, err := server.store.EnableTOTPTx(ctx, db.EnableTOTPTxParams{
+		Username:           user.Username,
+		RecoveryCodeHashes: recoveryCodeHashes,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, confirmTOTPResponse{
+		User:          newUserResponse(result.User),
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+type loginMFARequiredResponse struct {
+	MFARequired       bool      `json:"mfa_required"`
+	MFAToken          string    `json:"mfa_token"`
+	MFATokenExpiresAt time.Time `json:"mfa_token_expires_at"`
+}
+
+// requireSecondFactor answers a correct password of a user with two-factor authentication
+// with a short-lived token that can only be exchanged at /users/login/mfa.
+func (server *Server) requireSecondFactor(ctx *gin.Context, user db.User) {
+	var mfaToken, mfaPayload, err = server.tokenMaker.CreateToken(
+		user.Username,
+		user.Role,
+		server.config.MFATokenDuration,
+		server.tokenOptions(token.WithType(token.TokenTypeMFAPending))...,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, loginMFARequiredResponse{
+		MFARequired:       true,
+		MFAToken:          mfaToken,
+		MFATokenExpiresAt: mfaPayload.ExpiredAt,
+	})
+}
+
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// loginMFA exchanges an mfa pending token and a TOTP or recovery code for an access token.
+// Wrong codes count as failed logins, and the token can only be exchanged once.
+func (server *Server) loginMFA(ctx *gin.Context) {
+	var req loginMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var payload, err = server.tokenMaker.VerifyToken(req.MFAToken, server.verifyOptions()...)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+		return
+	}
+	if payload.Type != token.TokenTypeMFAPending {
+		var err = errors.New("mfa_token is not an mfa pending token")
+		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnauthorized, errResponse(errors.New("user no longer exists")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if payload.IssuedBefore(user.PasswordChangedAt) {
+		ctx.JSON(http.StatusUnauthorized, errResponse(token.ErrTokenRevoked))
+		return
+	}
+	if !user.TotpEnabled {
+		var err = errors.New("two-factor authentication is not enabled")
+		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+		return
+	}
+
+	var attempts []loginAttempt
+	if server.loginGuardEnabled() {
+		var ok bool
+		if attempts, ok = server.beginLoginAttempt(ctx, attempts, loginScopeIP, ctx.ClientIP()); !ok {
+			return
+		}
+		if attempts, ok = server.beginLoginAttempt(ctx, attempts, loginScopeUsername, user.Username); !ok {
+			return
+		}
+	}
+
+	// the token is claimed together with the factor, so one token consumes at most one code
+	var arg = db.LoginMFATxParams{
+		MFAToken: db.UseMFATokenParams{
+			ID:        payload.ID,
+			ExpiredAt: payload.ExpiredAt,
+		},
+		Username: user.Username,
+	}
+	var valid = true
+	if isTOTPCode(req.Code) {
+		arg.TOTPStep, valid, err = server.totpCodeStep(user, req.Code)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+			return
+		}
+	} else {
+		arg.RecoveryCodeHash = util.HashSecret(util.NormalizeRecoveryCode(req.Code))
+	}
+	if valid {
+		err = server.store.LoginMFATx(ctx, arg)
+		if errors.Is(err, db.ErrRecordNotFound) {
+			valid, err = false, nil
+		}
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrMFATokenUsed) {
+			// a replayed token is not a guess of the code
+			if err := server.releaseLoginAttempts(ctx, attempts); err != nil {
+				ctx.JSON(http.StatusInternalServerError, errResponse(err))
+				return
+			}
+			ctx.JSON(http.StatusUnauthorized, errResponse(errMFATokenUsed))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if !valid {
+		if err := server.failLoginAttempts(ctx, attempts); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusUnauthorized, errResponse(errInvalidSecondFactor))
+		return
+	}
+	if err = server.releaseLoginAttempts(ctx, attempts); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	server.completeLogin(ctx, user)
+}
+
+// validTOTPCode checks the code against the user's decrypted TOTP secret.
+// A code is accepted once: its time step must be later than the step of the last accepted code,
+// so a code captured in flight cannot be replayed within its window.
+func (server *Server) validTOTPCode(ctx context.Context, user db.User, code string) (bool, error) {
+	var step, ok, err = server.totpCodeStep(user, code)
+	if err != nil || !ok {
+		return false, err
+	}
+	rows, err := server.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+		Username: user.Username,
+		Step:     step,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// totpCodeStep checks the code against the user's decrypted TOTP secret and returns its time step.
+func (server *Server) totpCodeStep(user db.User, code string) (int64, bool, error) {
+	var secret, err = server.secretBox.Open(user.TotpSecret.String, user.Username)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var step, ok = totpStep(secret, code, time.Now())
+	return step, ok, nil
+}
+
+// totpStep returns the time step the code was generated for, within one step of now as totp.Validate allows.
+// The latest step is tried first, so a code matching two steps is recorded with the later one.
+func totpStep(secret string, code string, now time.Time) (int64, bool) {
+	var current = now.Unix() / totpPeriod
+	for step := current + 1; step >= current-1; step-- {
+		var expected, err = totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
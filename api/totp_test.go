@@ -0,0 +1,561 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/token"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// withTOTPSecret returns a copy of user with a TOTP secret sealed by the server.
+func withTOTPSecret(t *testing.T, server *Server, user db.User, enabled bool) (db.User, string) {
+	var key, err = totp.Generate(totp.GenerateOpts{Issuer: defaultTOTPIssuer, AccountName: user.Username})
+	require.NoError(t, err)
+	sealed, err := server.secretBox.Seal(key.Secret(), user.Username)
+	require.NoError(t, err)
+
+	user.TotpSecret = pgtype.Text{String: sealed, Valid: true}
+	user.TotpEnabled = enabled
+	return user, key.Secret()
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	var user, _ = randomUser(t)
+	var other, _ = randomUser(t)
+
+	var testCases = []struct {
+		name          string
+		authUser      db.User
+		buildStubs    func(t *testing.T, server *Server, store *mockdb.MockStore)
+		checkResponse func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			authUser: user,
+			buildStubs: func(t *testing.T, server *Server, store *mockdb.MockStore) {
+				expectAuthLookup(store, user)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					SetUserTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.SetUserTOTPSecretParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.True(t, arg.TotpSecret.Valid)
+						var updated = user
+						updated.TotpSecret = arg.TotpSecret
+						return updated, nil
+					})
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp enrollTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.Secret)
+
+				var uri, err = url.Parse(rsp.OTPAuthURI)
+				require.NoError(t, err)
+				require.Equal(t, "otpauth", uri.Scheme)
+				require.Equal(t, "totp", uri.Host)
+				require.Equal(t, rsp.Secret, uri.Query().Get("secret"))
+				require.Equal(t, defaultTOTPIssuer, uri.Query().Get("issuer"))
+			},
+		},
+		{
+			name:     "AlreadyEnabled",
+			authUser: user,
+			buildStubs: func(t *testing.T, server *Server, store *mockdb.MockStore) {
+				var enabledUser, _ = withTOTPSecret(t, server, user, true)
+				expectAuthLookup(store, user)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(enabledUser, nil)
+				store.EXPECT().
+					SetUserTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "OtherUser",
+			authUser: other,
+			buildStubs: func(t *testing.T, server *Server, store *mockdb.MockStore) {
+				expectAuthLookup(store, other)
+				store.EXPECT().
+					SetUserTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			var server = newTestServer(t, store, nil)
+			tc.buildStubs(t, server, store)
+
+			var request, err = http.NewRequest(http.MethodPost, "/users/"+user.Username+"/totp", nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.authUser.Username, tc.authUser.Role, time.Minute)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, server, recorder)
+		})
+	}
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	var user, _ = randomUser(t)
+
+	var testCases = []struct {
+		name          string
+		code          func(secret string) string
+		buildStubs    func(store *mockdb.MockStore, pendingUser db.User)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: func(secret string) string {
+				var code, err = totp.GenerateCode(secret, time.Now())
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mockdb.MockStore, pendingUser db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(pendingUser, nil)
+				expectTOTPStep(store, user.Username, 1)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.EnableTOTPTxParams) (db.EnableTOTPTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Len(t, arg.RecoveryCodeHashes, recoveryCodeCount)
+						var enabledUser = pendingUser
+						enabledUser.TotpEnabled = true
+						return db.EnableTOTPTxResult{User: enabledUser}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp confirmTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.True(t, rsp.User.TOTPEnabled)
+				require.Len(t, rsp.RecoveryCodes, recoveryCodeCount)
+			},
+		},
+		{
+			name: "WrongCode",
+			code: func(secret string) string {
+				var code, err = totp.GenerateCode(secret, time.Now().Add(-time.Hour))
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mockdb.MockStore, pendingUser db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(pendingUser, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			code: func(secret string) string {
+				return "123456"
+			},
+			buildStubs: func(store *mockdb.MockStore, pendingUser db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			code: func(secret string) string {
+				return "12345a"
+			},
+			buildStubs: func(store *mockdb.MockStore, pendingUser db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			var server = newTestServer(t, store, nil)
+			var pendingUser, secret = withTOTPSecret(t, server, user, false)
+			expectAuthLookup(store, user)
+			tc.buildStubs(store, pendingUser)
+
+			var data, err = json.Marshal(gin.H{"code": tc.code(secret)})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/"+user.Username+"/totp/confirm", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginRequiresSecondFactor(t *testing.T) {
+	var user, password = randomUser(t)
+
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+	var server = newTestServer(t, store, nil)
+	var totpUser, _ = withTOTPSecret(t, server, user, true)
+
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(totpUser, nil)
+
+	var data, err = json.Marshal(gin.H{"username": user.Username, "password": password})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	var recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotContains(t, recorder.Body.String(), "access_token")
+
+	var rsp loginMFARequiredResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.True(t, rsp.MFARequired)
+
+	payload, err := server.tokenMaker.VerifyToken(rsp.MFAToken)
+	require.NoError(t, err)
+	require.Equal(t, token.TokenTypeMFAPending, payload.Type)
+	require.Equal(t, user.Username, payload.Username)
+	require.WithinDuration(t, time.Now().Add(time.Minute), rsp.MFATokenExpiresAt, time.Second)
+}
+
+func expectTOTPStep(store *mockdb.MockStore, username string, rows int64) {
+	var step = time.Now().Unix() / totpPeriod
+	store.EXPECT().
+		UseTOTPStep(gomock.Any(), gomock.Cond(func(x any) bool {
+			var arg, ok = x.(db.UseTOTPStepParams)
+			return ok && arg.Username == username && arg.Step >= step-1 && arg.Step <= step+1
+		})).
+		Times(1).
+		Return(rows, nil)
+}
+
+// expectLoginMFA expects the mfa token to be exchanged with a TOTP code of the current steps,
+// or with the recovery code when recoveryCode is not empty.
+func expectLoginMFA(store *mockdb.MockStore, username string, recoveryCode string, err error) {
+	var step = time.Now().Unix() / totpPeriod
+	store.EXPECT().
+		LoginMFATx(gomock.Any(), gomock.Cond(func(x any) bool {
+			var arg, ok = x.(db.LoginMFATxParams)
+			if !ok || arg.Username != username || arg.MFAToken.ID == uuid.Nil {
+				return false
+			}
+			if recoveryCode != "" {
+				return arg.TOTPStep == 0 && arg.RecoveryCodeHash == util.HashSecret(util.NormalizeRecoveryCode(recoveryCode))
+			}
+			return arg.RecoveryCodeHash == "" && arg.TOTPStep >= step-1 && arg.TOTPStep <= step+1
+		})).
+		Times(1).
+		Return(err)
+}
+
+func TestTOTPStep(t *testing.T) {
+	var key, err = totp.Generate(totp.GenerateOpts{Issuer: "test", AccountName: "test"})
+	require.NoError(t, err)
+	var now = time.Now()
+
+	for _, offset := range []int64{-1, 0, 1} {
+		var at = now.Add(time.Duration(offset*totpPeriod) * time.Second)
+		var code, err = totp.GenerateCode(key.Secret(), at)
+		require.NoError(t, err)
+		var step, ok = totpStep(key.Secret(), code, now)
+		require.True(t, ok)
+		require.Equal(t, at.Unix()/totpPeriod, step)
+	}
+
+	code, err := totp.GenerateCode(key.Secret(), now.Add(-time.Hour))
+	require.NoError(t, err)
+	var _, ok = totpStep(key.Secret(), code, now)
+	require.False(t, ok)
+}
+
+func TestLoginMFA(t *testing.T) {
+	var user, _ = randomUser(t)
+	var recoveryCode = "abcde-23456"
+
+	var testCases = []struct {
+		name          string
+		mfaToken      func(t *testing.T, tokenMaker token.Maker) string
+		code          func(t *testing.T, secret string) string
+		buildStubs    func(store *mockdb.MockStore, totpUser db.User)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "TOTPCode",
+			code: func(t *testing.T, secret string) string {
+				var code, err = totp.GenerateCode(secret, time.Now())
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mockdb.MockStore, totpUser db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpUser, nil)
+				expectLoginMFA(store, user.Username, "", nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.AccessToken)
+				require.Equal(t, user.Username, rsp.User.Username)
+			},
+		},
+		{
+			name: "RecoveryCode",
+			code: func(t *testing.T, secret string) string {
+				return "ABCDE 23456"
+			},
+			buildStubs: func(store *mockdb.MockStore, totpUser db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpUser, nil)
+				expectLoginMFA(store, user.Username, recoveryCode, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ReplayedTOTPCode",
+			code: func(t *testing.T, secret string) string {
+				var code, err = totp.GenerateCode(secret, time.Now())
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mockdb.MockStore, totpUser db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpUser, nil)
+				// a code of this step or a later one was already accepted
+				expectLoginMFA(store, user.Username, "", db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MFATokenAlreadyUsed",
+			code: func(t *testing.T, secret string) string {
+				return recoveryCode
+			},
+			buildStubs: func(store *mockdb.MockStore, totpUser db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpUser, nil)
+				// the transaction refuses the token before consuming the code
+				expectLoginMFA(store, user.Username, recoveryCode, db.ErrMFATokenUsed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errMFATokenUsed.Error())
+			},
+		},
+		{
+			name: "UsedRecoveryCode",
+			code: func(t *testing.T, secret string) string {
+				return recoveryCode
+			},
+			buildStubs: func(store *mockdb.MockStore, totpUser db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpUser, nil)
+				expectLoginMFA(store, user.Username, recoveryCode, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "WrongCode",
+			code: func(t *testing.T, secret string) string {
+				var code, err = totp.GenerateCode(secret, time.Now().Add(-time.Hour))
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mockdb.MockStore, totpUser db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpUser, nil)
+				// a code of no current step does not claim the token
+				store.EXPECT().
+					LoginMFATx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "AccessTokenInsteadOfMFAToken",
+			mfaToken: func(t *testing.T, tokenMaker token.Maker) string {
+				var accessToken, _, err = tokenMaker.CreateToken(user.Username, user.Role, time.Minute)
+				require.NoError(t, err)
+				return accessToken
+			},
+			code: func(t *testing.T, secret string) string {
+				var code, err = totp.GenerateCode(secret, time.Now())
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mockdb.MockStore, totpUser db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "PasswordChangedSinceLogin",
+			code: func(t *testing.T, secret string) string {
+				var code, err = totp.GenerateCode(secret, time.Now())
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mockdb.MockStore, totpUser db.User) {
+				totpUser.PasswordChangedAt = time.Now().Add(time.Hour)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpUser, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			var server = newTestServer(t, store, nil)
+			var totpUser, secret = withTOTPSecret(t, server, user, true)
+			tc.buildStubs(store, totpUser)
+
+			var mfaToken string
+			if tc.mfaToken != nil {
+				mfaToken = tc.mfaToken(t, server.tokenMaker)
+			} else {
+				var err error
+				mfaToken, _, err = server.tokenMaker.CreateToken(user.Username, user.Role, time.Minute, token.WithType(token.TokenTypeMFAPending))
+				require.NoError(t, err)
+			}
+
+			var data, err = json.Marshal(gin.H{"mfa_token": mfaToken, "code": tc.code(t, secret)})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/login/mfa", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginMFAWrongCodeCountsAsFailure(t *testing.T) {
+	var user, _ = randomUser(t)
+
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+	var server = newTestServerWithConfig(t, newLoginGuardConfig(), store, nil)
+	var totpUser, _ = withTOTPSecret(t, server, user, true)
+
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(totpUser, nil)
+	expectLoginFailure(store, loginScopeIP, testClientIP, 1, time.Second)
+	expectLoginFailure(store, loginScopeUsername, user.Username, 1, time.Second)
+	expectLoginMFA(store, user.Username, "wrong-code", db.ErrRecordNotFound)
+
+	var mfaToken, _, err = server.tokenMaker.CreateToken(user.Username, user.Role, time.Minute, token.WithType(token.TokenTypeMFAPending))
+	require.NoError(t, err)
+	data, err := json.Marshal(gin.H{"mfa_token": mfaToken, "code": "wrong-code"})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/users/login/mfa", bytes.NewReader(data))
+	require.NoError(t, err)
+	request.RemoteAddr = testClientIP + ":51234"
+
+	var recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
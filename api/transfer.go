@@ -1,52 +1,107 @@
 package api
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
 
 	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/token"
+	"github.com/Ma-hiru/simplebank/util"
 	"github.com/gin-gonic/gin"
 )
 
 type transferRequest struct {
-	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
-	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
-	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	FromAccountID int64 `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64 `json:"to_account_id" binding:"required,min=1"`
+	// Amount is in minor units; AmountDecimal is the same amount in units of the currency.
+	Amount        int64  `json:"amount" binding:"omitempty,gt=0"`
+	AmountDecimal string `json:"amount_decimal" binding:"omitempty,max=32"`
 	Currency      string `json:"currency" binding:"required,currency"`
 }
 
+// transferResponse adds the amounts as decimal strings in units of the currency.
+type transferResponse struct {
+	db.Transfer
+	AmountDecimal string `json:"amount_decimal"`
+}
+
+type entryResponse struct {
+	db.Entry
+	AmountDecimal string `json:"amount_decimal"`
+}
+
+type transferTxResponse struct {
+	Transfer    transferResponse `json:"transfer"`
+	FromEntry   entryResponse    `json:"from_entry"`
+	ToEntry     entryResponse    `json:"to_entry"`
+	FromAccount accountResponse  `json:"from_account"`
+	ToAccount   accountResponse  `json:"to_account"`
+	Fees        []feeResponse    `json:"fees"`
+}
+
+func newEntryResponse(entry db.Entry, currency string) entryResponse {
+	return entryResponse{Entry: entry, AmountDecimal: util.FormatMinorUnits(entry.Amount, currency)}
+}
+
+func newTransferTxResponse(result db.TransferTxResult, currency string) transferTxResponse {
+	return transferTxResponse{
+		Transfer: transferResponse{
+			Transfer:      result.Transfer,
+			AmountDecimal: util.FormatMinorUnits(result.Transfer.Amount, currency),
+		},
+		FromEntry:   newEntryResponse(result.FromEntry, currency),
+		ToEntry:     newEntryResponse(result.ToEntry, currency),
+		FromAccount: newAccountResponse(result.FromAccount),
+		ToAccount:   newAccountResponse(result.ToAccount),
+		Fees:        newFeeResponses(result.Fees, currency),
+	}
+}
+
 func (server *Server) createTransfer(ctx *gin.Context) {
 	var req transferRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errResponse(err))
 		return
 	}
+	var amount, err = requestAmount(req.Amount, req.AmountDecimal, req.Currency)
+	if err == nil && amount <= 0 {
+		err = errors.New("amount must be positive")
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
 
-	if !server.validAccount(ctx, req.FromAccountID, req.Currency) {
+	fromAccount, ok := server.ownedAccount(ctx, req.FromAccountID, authPayload(ctx))
+	if !ok || !matchCurrency(ctx, fromAccount, req.Currency) {
 		return
 	}
 	if !server.validAccount(ctx, req.ToAccountID, req.Currency) {
 		return
 	}
 
-	var result, err = server.store.TransferTx(ctx, db.TransferTxParams{
+	result, err := server.store.TransferTx(ctx, db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountID,
-		Amount:        req.Amount,
+		Amount:        amount,
 	})
 	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	ctx.JSON(http.StatusOK, newTransferTxResponse(result, req.Currency))
 }
 
 func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) bool {
 	var account, err = server.store.GetAccount(ctx, accountID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, db.ErrRecordNotFound) {
 			ctx.JSON(http.StatusNotFound, errResponse(err))
 			return false
 		}
@@ -54,11 +109,130 @@ func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency s
 		return false
 	}
 
+	return matchCurrency(ctx, account, currency)
+}
+
+// matchCurrency aborts the request unless the account holds the currency.
+func matchCurrency(ctx *gin.Context, account db.Account, currency string) bool {
 	if account.Currency != currency {
-		err = fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
+		var err = fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
 		ctx.JSON(http.StatusBadRequest, errResponse(err))
 		return false
 	}
 
 	return true
 }
+
+type transferLegRequest struct {
+	AccountID int64 `json:"account_id" binding:"required,min=1"`
+	// Amount is negative for the accounts paying and positive for the accounts paid.
+	// It is in minor units; AmountDecimal is the same amount in units of the currency.
+	Amount        int64  `json:"amount"`
+	AmountDecimal string `json:"amount_decimal" binding:"omitempty,max=32"`
+}
+
+type multiTransferTxResponse struct {
+	Group    db.TransferGroup  `json:"group"`
+	Entries  []entryResponse   `json:"entries"`
+	Accounts []accountResponse `json:"accounts"`
+}
+
+func newMultiTransferTxResponse(result db.MultiTransferTxResult) multiTransferTxResponse {
+	var rsp = multiTransferTxResponse{
+		Group:    result.Group,
+		Entries:  make([]entryResponse, len(result.Entries)),
+		Accounts: make([]accountResponse, len(result.Accounts)),
+	}
+	for i, entry := range result.Entries {
+		rsp.Entries[i] = newEntryResponse(entry, result.Group.Currency)
+	}
+	for i, account := range result.Accounts {
+		rsp.Accounts[i] = newAccountResponse(account)
+	}
+	return rsp
+}
+
+type batchTransferRequest struct {
+	Currency    string               `json:"currency" binding:"required,currency"`
+	Description string               `json:"description" binding:"max=140"`
+	Legs        []transferLegRequest `json:"legs" binding:"required,min=2,max=100,dive"`
+}
+
+// createBatchTransfer moves money between several accounts at once, such as a payment split between payees.
+// Every debited account must belong to the caller unless the caller is an admin.
+func (server *Server) createBatchTransfer(ctx *gin.Context) {
+	var req batchTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var arg = db.MultiTransferTxParams{
+		Currency:    req.Currency,
+		Description: req.Description,
+		Legs:        make([]db.TransferLeg, len(req.Legs)),
+	}
+	for i, leg := range req.Legs {
+		var amount, err = requestAmount(leg.Amount, leg.AmountDecimal, req.Currency)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errResponse(fmt.Errorf("leg %d: %w", i, err)))
+			return
+		}
+		arg.Legs[i] = db.TransferLeg{AccountID: leg.AccountID, Amount: amount}
+	}
+	if err := arg.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var payload = authPayload(ctx)
+	if payload.Role != util.AdminRole {
+		var checked = make(map[int64]bool)
+		for _, leg := range arg.Legs {
+			if leg.Amount > 0 || checked[leg.AccountID] {
+				continue
+			}
+			checked[leg.AccountID] = true
+			if _, ok := server.ownedAccount(ctx, leg.AccountID, payload); !ok {
+				return
+			}
+		}
+	}
+
+	var result, err = server.store.MultiTransferTx(ctx, arg)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrTransferUnbalanced), errors.Is(err, db.ErrTransferCurrencyMismatch),
+			errors.Is(err, db.ErrInsufficientFunds):
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+		case errors.Is(err, db.ErrRecordNotFound):
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newMultiTransferTxResponse(result))
+}
+
+// ownedAccount returns the account, aborting the request unless it exists and belongs to the user of the payload.
+// Admins may use any account.
+func (server *Server) ownedAccount(ctx *gin.Context, accountID int64, payload *token.Payload) (db.Account, bool) {
+	var account, err = server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errResponse(fmt.Errorf("account %d: %w", accountID, err)))
+			return account, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return account, false
+	}
+
+	if account.Owner != payload.Username && payload.Role != util.AdminRole {
+		err = fmt.Errorf("account [%d] does not belong to the authenticated user", account.ID)
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return account, false
+	}
+	return account, true
+}
@@ -0,0 +1,447 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateBatchTransfer(t *testing.T) {
+	var user, _ = randomUser(t)
+	var admin, _ = randomUser(t)
+	admin.Role = util.AdminRole
+
+	var payer = randomAccount()
+	payer.ID = 1
+	payer.Owner = user.Username
+	payer.Currency = util.USD
+	var payee1 = randomAccount()
+	payee1.ID = 2
+	payee1.Currency = util.USD
+	var payee2 = randomAccount()
+	payee2.ID = 3
+	payee2.Currency = util.USD
+
+	var splitLegs = []gin.H{
+		{"account_id": payer.ID, "amount": -30},
+		{"account_id": payee1.ID, "amount": 10},
+		{"account_id": payee2.ID, "amount": 20},
+	}
+	var splitArg = db.MultiTransferTxParams{
+		Currency:    util.USD,
+		Description: "dinner",
+		Legs: []db.TransferLeg{
+			{AccountID: payer.ID, Amount: -30},
+			{AccountID: payee1.ID, Amount: 10},
+			{AccountID: payee2.ID, Amount: 20},
+		},
+	}
+
+	var testCases = []struct {
+		name          string
+		caller        db.User
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			caller: user,
+			body:   gin.H{"currency": util.USD, "description": "dinner", "legs": splitLegs},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(payer.ID)).Times(1).Return(payer, nil)
+				store.EXPECT().
+					MultiTransferTx(gomock.Any(), gomock.Eq(splitArg)).
+					Times(1).
+					Return(db.MultiTransferTxResult{
+						Group:    db.TransferGroup{ID: 7, Currency: util.USD, Description: "dinner"},
+						Entries:  make([]db.Entry, 3),
+						Accounts: []db.Account{payer, payee1, payee2},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp db.MultiTransferTxResult
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, int64(7), rsp.Group.ID)
+				require.Len(t, rsp.Entries, 3)
+				require.Len(t, rsp.Accounts, 3)
+
+				var decimals struct {
+					Accounts []accountResponse `json:"accounts"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &decimals))
+				require.Equal(t, util.FormatMinorUnits(payer.Balance, util.USD), decimals.Accounts[0].BalanceDecimal)
+			},
+		},
+		{
+			name:   "DecimalAmounts",
+			caller: user,
+			body: gin.H{"currency": util.USD, "description": "dinner", "legs": []gin.H{
+				{"account_id": payer.ID, "amount_decimal": "-0.30"},
+				{"account_id": payee1.ID, "amount_decimal": "0.1"},
+				{"account_id": payee2.ID, "amount": 20},
+			}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(payer.ID)).Times(1).Return(payer, nil)
+				store.EXPECT().
+					MultiTransferTx(gomock.Any(), gomock.Eq(splitArg)).
+					Times(1).
+					Return(db.MultiTransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "AmbiguousAmount",
+			caller: user,
+			body: gin.H{"currency": util.USD, "legs": []gin.H{
+				{"account_id": payer.ID, "amount": -30, "amount_decimal": "-0.30"},
+				{"account_id": payee1.ID, "amount": 30},
+			}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().MultiTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "AdminSkipsOwnership",
+			caller: admin,
+			body:   gin.H{"currency": util.USD, "description": "dinner", "legs": splitLegs},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					MultiTransferTx(gomock.Any(), gomock.Eq(splitArg)).
+					Times(1).
+					Return(db.MultiTransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "Unbalanced",
+			caller: user,
+			body: gin.H{"currency": util.USD, "legs": []gin.H{
+				{"account_id": payer.ID, "amount": -30},
+				{"account_id": payee1.ID, "amount": 10},
+			}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().MultiTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "legs sum to -20")
+			},
+		},
+		{
+			name:   "SingleLeg",
+			caller: user,
+			body:   gin.H{"currency": util.USD, "legs": []gin.H{{"account_id": payer.ID, "amount": -30}}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().MultiTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "ZeroAmount",
+			caller: user,
+			body: gin.H{"currency": util.USD, "legs": []gin.H{
+				{"account_id": payer.ID, "amount": 0},
+				{"account_id": payee1.ID, "amount": 0},
+			}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().MultiTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidCurrency",
+			caller: user,
+			body:   gin.H{"currency": "XYZ", "legs": splitLegs},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().MultiTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "DebitFromOtherUser",
+			caller: user,
+			body: gin.H{"currency": util.USD, "legs": []gin.H{
+				{"account_id": payee1.ID, "amount": -10},
+				{"account_id": payer.ID, "amount": 10},
+			}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(payee1.ID)).Times(1).Return(payee1, nil)
+				store.EXPECT().MultiTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "DebitAccountNotFound",
+			caller: user,
+			body:   gin.H{"currency": util.USD, "legs": splitLegs},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(payer.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().MultiTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "CurrencyMismatch",
+			caller: user,
+			body:   gin.H{"currency": util.USD, "legs": splitLegs},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(payer.ID)).Times(1).Return(payer, nil)
+				store.EXPECT().
+					MultiTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.MultiTransferTxResult{}, fmt.Errorf("%w: account 3 is in EUR, not USD", db.ErrTransferCurrencyMismatch))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "CreditAccountNotFound",
+			caller: user,
+			body:   gin.H{"currency": util.USD, "legs": splitLegs},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(payer.ID)).Times(1).Return(payer, nil)
+				store.EXPECT().
+					MultiTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.MultiTransferTxResult{}, fmt.Errorf("account 3: %w", db.ErrRecordNotFound))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var store = mockdb.NewMockStore(ctrl)
+			expectAuthLookup(store, tc.caller)
+			expectCurrencies(store)
+			tc.buildStubs(store)
+
+			var server = newTestServer(t, store, nil)
+			var data, err = json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/transfers/batch", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.caller.Username, tc.caller.Role, time.Minute)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateTransfer(t *testing.T) {
+	var user, _ = randomUser(t)
+
+	var from = randomAccount()
+	from.ID = 1
+	from.Owner = user.Username
+	from.Currency = util.USD
+	var to = randomAccount()
+	to.ID = 2
+	to.Currency = util.USD
+
+	var testCases = []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "MinorUnits",
+			body: gin.H{"from_account_id": from.ID, "to_account_id": to.ID, "amount": 1234, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 1234})).
+					Times(1).
+					Return(db.TransferTxResult{
+						Transfer:    db.Transfer{ID: 5, FromAccountID: from.ID, ToAccountID: to.ID, Amount: 1234},
+						FromEntry:   db.Entry{AccountID: from.ID, Amount: -1234},
+						ToEntry:     db.Entry{AccountID: to.ID, Amount: 1234},
+						FromAccount: db.Account{ID: from.ID, Balance: 100, Currency: util.USD},
+						ToAccount:   db.Account{ID: to.ID, Balance: 2000, Currency: util.USD},
+						Fees: []db.FeeCharge{{
+							Fee: db.Fee{ID: 3, Kind: db.FeeKindTransfer, AccountID: from.ID, Amount: 25, TransferID: 6},
+						}},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp transferTxResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, int64(1234), rsp.Transfer.Amount)
+				require.Equal(t, "12.34", rsp.Transfer.AmountDecimal)
+				require.Equal(t, "-12.34", rsp.FromEntry.AmountDecimal)
+				require.Equal(t, "12.34", rsp.ToEntry.AmountDecimal)
+				require.Equal(t, "1.00", rsp.FromAccount.BalanceDecimal)
+				require.Equal(t, "20.00", rsp.ToAccount.BalanceDecimal)
+				require.Len(t, rsp.Fees, 1)
+				require.Equal(t, db.FeeKindTransfer, rsp.Fees[0].Kind)
+				require.Equal(t, "0.25", rsp.Fees[0].AmountDecimal)
+			},
+		},
+		{
+			name: "Decimal",
+			body: gin.H{"from_account_id": from.ID, "to_account_id": to.ID, "amount_decimal": "12.34", "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 1234})).
+					Times(1).
+					Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: gin.H{"from_account_id": from.ID, "to_account_id": to.ID, "amount": 1234, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("%w: savings account 1", db.ErrInsufficientFunds))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), db.ErrInsufficientFunds.Error())
+			},
+		},
+		{
+			name: "TooManyDecimals",
+			body: gin.H{"from_account_id": from.ID, "to_account_id": to.ID, "amount_decimal": "12.345", "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "DisabledCurrency",
+			body: gin.H{"from_account_id": from.ID, "to_account_id": to.ID, "amount": 1234, "currency": "JPY"},
+			buildStubs: func(store *mockdb.MockStore) {
+				// accounts opened before a currency was disabled can still transfer, so only the account currency counts
+				var jpyFrom, jpyTo = from, to
+				jpyFrom.Currency, jpyTo.Currency = "JPY", "JPY"
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(jpyFrom, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(jpyTo, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "FromAccountNotOwned",
+			body: gin.H{"from_account_id": to.ID, "to_account_id": from.ID, "amount": 1234, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NegativeDecimal",
+			body: gin.H{"from_account_id": from.ID, "to_account_id": to.ID, "amount_decimal": "-1", "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "BothAmounts",
+			body: gin.H{"from_account_id": from.ID, "to_account_id": to.ID, "amount": 1234, "amount_decimal": "12.34", "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errAmountAmbiguous.Error())
+			},
+		},
+		{
+			name: "NoAmount",
+			body: gin.H{"from_account_id": from.ID, "to_account_id": to.ID, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errAmountRequired.Error())
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var store = mockdb.NewMockStore(ctrl)
+			expectAuthLookup(store, user)
+			expectCurrencies(store)
+			tc.buildStubs(store)
+
+			var server = newTestServer(t, store, nil)
+			var data, err = json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -1,20 +1,27 @@
 package api
 
 import (
-	"database/sql"
 	"errors"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/token"
 	"github.com/Ma-hiru/simplebank/util"
+	"github.com/Ma-hiru/simplebank/worker"
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type UserResponse struct {
 	Username          string `json:"username"`
 	FullName          string `json:"full_name"`
 	Email             string `json:"email"`
+	Role              string `json:"role"`
+	TOTPEnabled       bool   `json:"totp_enabled"`
+	IsEmailVerified   bool   `json:"is_email_verified"`
 	PasswordChangedAt string `json:"password_changed_at"`
 	CreatedAt         string `json:"created_at"`
 }
@@ -24,14 +31,20 @@ func newUserResponse(user db.User) UserResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		Role:              user.Role,
+		TOTPEnabled:       user.TotpEnabled,
+		IsEmailVerified:   user.IsEmailVerified,
 		PasswordChangedAt: user.PasswordChangedAt.String(),
 		CreatedAt:         user.CreatedAt.String(),
 	}
 }
 
+// errUsernameReserved refuses the username of the user owning the internal accounts of the bank, in any case.
+var errUsernameReserved = errors.New("is reserved")
+
 type createUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 	FullName string `json:"full_name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 }
@@ -42,33 +55,44 @@ func (server *Server) createUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errResponse(err))
 		return
 	}
+	if strings.EqualFold(req.Username, db.BankUsername) {
+		ctx.JSON(http.StatusForbidden, fieldErrResponse("username", errUsernameReserved))
+		return
+	}
+	if !server.checkPassword(ctx, "password", req.Password, req.Username, req.Email) {
+		return
+	}
 
-	var hashedPassword, err = util.HashPassword(req.Password)
+	var hashedPassword, err = server.passwordHasher.Hash(req.Password)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errResponse(err))
 		return
 	}
 
-	user, err := server.store.CreateUser(ctx, db.CreateUserParams{
-		Username:     req.Username,
-		HashPassword: hashedPassword,
-		FullName:     req.FullName,
-		Email:        req.Email,
+	result, err := server.store.CreateUserTx(ctx, db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:     req.Username,
+			HashPassword: hashedPassword,
+			FullName:     req.FullName,
+			Email:        req.Email,
+		},
+		AfterCreate: func(q db.Querier, user db.User) error {
+			// enqueued in the same transaction, so the email is only sent if the user is really created
+			return server.taskDistributor.DistributeTaskSendVerifyEmail(ctx, q, &worker.PayloadSendVerifyEmail{
+				Username: user.Username,
+			})
+		},
 	})
 	if err != nil {
-		var pqErr *pq.Error
-		if errors.As(err, &pqErr) {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				ctx.JSON(http.StatusForbidden, errResponse(err))
-				return
-			}
+		if db.ErrorCode(err) == db.UniqueViolation {
+			ctx.JSON(http.StatusForbidden, errResponse(err))
+			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, newUserResponse(user))
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
 }
 
 type getUserRequest struct {
@@ -84,7 +108,7 @@ func (server *Server) getUser(ctx *gin.Context) {
 
 	var user, err = server.store.GetUser(ctx, req.Username)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, db.ErrRecordNotFound) {
 			ctx.JSON(http.StatusNotFound, errResponse(err))
 			return
 		}
@@ -94,3 +118,275 @@ func (server *Server) getUser(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, newUserResponse(user))
 }
+
+type verifyEmailRequest struct {
+	EmailID    int64  `form:"email_id" binding:"required,min=1"`
+	SecretCode string `form:"secret_code" binding:"required"`
+}
+
+type verifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var result, err = server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		EmailID:    req.EmailID,
+		SecretCode: req.SecretCode,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errResponse(errors.New("verification link is invalid or has expired")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, verifyEmailResponse{IsVerified: result.User.IsEmailVerified})
+}
+
+// errInvalidCredentials is returned for an unknown username and a wrong password alike,
+// so the login does not tell which usernames exist.
+var errInvalidCredentials = errors.New("incorrect username or password")
+
+// loginUserRequest only requires a password: the policy applies when a password is set,
+// and a short one is just another incorrect password here.
+type loginUserRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+	Password string `json:"password" binding:"required"`
+}
+
+type loginUserResponse struct {
+	AccessToken          string       `json:"access_token"`
+	AccessTokenExpiresAt time.Time    `json:"access_token_expires_at"`
+	User                 UserResponse `json:"user"`
+}
+
+func (server *Server) loginUser(ctx *gin.Context) {
+	var req loginUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var clientIP = ctx.ClientIP()
+	var guarded = server.loginGuardEnabled()
+	var attempts []loginAttempt
+	if guarded {
+		var ok bool
+		if attempts, ok = server.beginLoginAttempt(ctx, attempts, loginScopeIP, clientIP); !ok {
+			return
+		}
+	}
+
+	var user, err = server.store.GetUser(ctx, req.Username)
+	if err == nil && user.Role == util.SystemRole {
+		err = db.ErrRecordNotFound
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			if err := server.failLoginAttempts(ctx, attempts); err != nil {
+				ctx.JSON(http.StatusInternalServerError, errResponse(err))
+				return
+			}
+			ctx.JSON(http.StatusUnauthorized, errResponse(errInvalidCredentials))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if guarded {
+		var ok bool
+		if attempts, ok = server.beginLoginAttempt(ctx, attempts, loginScopeUsername, user.Username); !ok {
+			return
+		}
+	}
+
+	err = util.CheckPassword(req.Password, user.HashPassword)
+	if err != nil {
+		if err := server.failLoginAttempts(ctx, attempts); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusUnauthorized, errResponse(errInvalidCredentials))
+		return
+	}
+	// only this attempt is taken back: the earlier failures are cleared once the second factor is accepted,
+	// otherwise logging in with the password again would reset the count of wrong codes
+	if err = server.releaseLoginAttempts(ctx, attempts); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if server.passwordHasher.NeedsRehash(user.HashPassword) {
+		server.rehashPassword(ctx, user.Username, req.Password)
+	}
+
+	if user.TotpEnabled {
+		server.requireSecondFactor(ctx, user)
+		return
+	}
+	server.completeLogin(ctx, user)
+}
+
+// completeLogin clears the failed attempts of the user and responds with an access token.
+func (server *Server) completeLogin(ctx *gin.Context, user db.User) {
+	if server.loginGuardEnabled() {
+		var _, err = server.store.DeleteLoginFailures(ctx, db.DeleteLoginFailuresParams{
+			Scope:   loginScopeUsername,
+			Subject: user.Username,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+			return
+		}
+	}
+
+	var accessToken, accessPayload, err = server.tokenMaker.CreateToken(
+		user.Username,
+		user.Role,
+		server.config.AccessTokenDuration,
+		server.tokenOptions(token.WithScopes(userScopes...))...,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, loginUserResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+		User:                 newUserResponse(user),
+	})
+}
+
+// rehashPassword upgrades the stored hash to the preferred algorithm and parameters.
+// It does not touch password_changed_at, so existing tokens stay valid,
+// and a failure only delays the upgrade to the next login.
+func (server *Server) rehashPassword(ctx *gin.Context, username string, password string) {
+	var hashedPassword, err = server.passwordHasher.Hash(password)
+	if err == nil {
+		_, err = server.store.UpdateUser(ctx, db.UpdateUserParams{
+			Username:     username,
+			HashPassword: pgtype.Text{String: hashedPassword, Valid: true},
+		})
+	}
+	if err != nil {
+		log.Printf("cannot rehash password of user %s: %v", username, err)
+	}
+}
+
+type updateUserURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type updateUserRequest struct {
+	FullName        *string `json:"full_name" binding:"omitempty,min=1"`
+	Email           *string `json:"email" binding:"omitempty,email"`
+	Password        *string `json:"password"`
+	CurrentPassword *string `json:"current_password"`
+}
+
+// updateUser changes only the fields present in the request.
+// Users can update themselves and admins can update anyone,
+// but changing a password always requires the current one.
+func (server *Server) updateUser(ctx *gin.Context) {
+	var uri updateUserURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	var req updateUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var payload = authPayload(ctx)
+	if payload.Username != uri.Username && payload.Role != util.AdminRole {
+		var err = errors.New("cannot update other user's info")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return
+	}
+
+	var arg = db.UpdateUserParams{Username: uri.Username}
+	if req.FullName != nil {
+		arg.FullName = pgtype.Text{String: *req.FullName, Valid: true}
+	}
+	if req.Email != nil {
+		arg.Email = pgtype.Text{String: *req.Email, Valid: true}
+		// a new address has to be verified again
+		arg.IsEmailVerified = pgtype.Bool{Bool: false, Valid: true}
+	}
+	if req.Password != nil {
+		if req.CurrentPassword == nil {
+			var err = errors.New("current_password is required to change the password")
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
+
+		var user, err = server.store.GetUser(ctx, uri.Username)
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				ctx.JSON(http.StatusNotFound, errResponse(err))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+			return
+		}
+		if err = util.CheckPassword(*req.CurrentPassword, user.HashPassword); err != nil {
+			ctx.JSON(http.StatusUnauthorized, errResponse(err))
+			return
+		}
+		// the password must not contain the stored username and email, nor the new email
+		var identifiers = []string{user.Username, user.Email}
+		if req.Email != nil {
+			identifiers = append(identifiers, *req.Email)
+		}
+		if !server.checkPassword(ctx, "password", *req.Password, identifiers...) {
+			return
+		}
+
+		hashedPassword, err := server.passwordHasher.Hash(*req.Password)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+			return
+		}
+		arg.HashPassword = pgtype.Text{String: hashedPassword, Valid: true}
+		arg.PasswordChangedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+
+	var result, err = server.store.UpdateUserTx(ctx, db.UpdateUserTxParams{
+		UpdateUserParams: arg,
+		AfterUpdate: func(q db.Querier, user db.User) error {
+			if !arg.Email.Valid {
+				return nil
+			}
+			return server.taskDistributor.DistributeTaskSendVerifyEmail(ctx, q, &worker.PayloadSendVerifyEmail{
+				Username: user.Username,
+			})
+		},
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+			return
+		}
+		if db.ErrorCode(err) == db.UniqueViolation {
+			ctx.JSON(http.StatusConflict, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if arg.PasswordChangedAt.Valid {
+		server.passwordChanged.invalidate(result.User.Username)
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+}
@@ -2,19 +2,31 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	mockdb "github.com/Ma-hiru/simplebank/db/mock"
 	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/token"
 	"github.com/Ma-hiru/simplebank/util"
+	"github.com/Ma-hiru/simplebank/worker"
+	mockwk "github.com/Ma-hiru/simplebank/worker/mock"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func randomUser(t *testing.T) (user db.User, password string) {
@@ -27,6 +39,7 @@ func randomUser(t *testing.T) (user db.User, password string) {
 		HashPassword: hashedPassword,
 		FullName:     util.RandomOwner(),
 		Email:        util.RandomEmail(),
+		Role:         util.DepositorRole,
 	}
 	return
 }
@@ -44,17 +57,17 @@ func requireBodyMatchUser(t *testing.T, body *bytes.Buffer, user db.User) {
 	require.Equal(t, user.Email, gotUser.Email)
 }
 
-type eqCreateUserParamsMatcher struct {
-	arg      db.CreateUserParams
+type eqCreateUserTxParamsMatcher struct {
+	arg      db.CreateUserTxParams
 	password string
 }
 
-func (e eqCreateUserParamsMatcher) String() string {
+func (e eqCreateUserTxParamsMatcher) String() string {
 	return fmt.Sprintf("matches arg %v and password %v", e.arg, e.password)
 }
 
-func (e eqCreateUserParamsMatcher) Matches(x any) bool {
-	var arg, ok = x.(db.CreateUserParams)
+func (e eqCreateUserTxParamsMatcher) Matches(x any) bool {
+	var arg, ok = x.(db.CreateUserTxParams)
 	if !ok {
 		return false
 	}
@@ -65,11 +78,11 @@ func (e eqCreateUserParamsMatcher) Matches(x any) bool {
 	}
 
 	e.arg.HashPassword = arg.HashPassword
-	return reflect.DeepEqual(e.arg, arg)
+	return reflect.DeepEqual(e.arg.CreateUserParams, arg.CreateUserParams)
 }
 
-func EqCreateUserParams(arg db.CreateUserParams, password string) gomock.Matcher {
-	return eqCreateUserParamsMatcher{arg, password}
+func EqCreateUserTxParams(arg db.CreateUserTxParams, password string) gomock.Matcher {
+	return eqCreateUserTxParamsMatcher{arg, password}
 }
 
 func TestCreateUser(t *testing.T) {
@@ -77,7 +90,7 @@ func TestCreateUser(t *testing.T) {
 	var testCases = []struct {
 		name          string
 		body          createUserRequest
-		buildStubs    func(store *mockdb.MockStore)
+		buildStubs    func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor)
 		checkResponse func(recoder *httptest.ResponseRecorder)
 	}{
 		{
@@ -88,22 +101,107 @@ func TestCreateUser(t *testing.T) {
 				FullName: user.FullName,
 				Email:    user.Email,
 			},
-			buildStubs: func(store *mockdb.MockStore) {
-				var arg = db.CreateUserParams{
-					Username: user.Username,
-					FullName: user.FullName,
-					Email:    user.Email,
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				var arg = db.CreateUserTxParams{
+					CreateUserParams: db.CreateUserParams{
+						Username: user.Username,
+						FullName: user.FullName,
+						Email:    user.Email,
+					},
 				}
 				store.EXPECT().
-					CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
+					CreateUserTx(gomock.Any(), EqCreateUserTxParams(arg, password)).
 					Times(1).
-					Return(user, nil)
+					DoAndReturn(func(ctx context.Context, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+						// the verify email task must be enqueued inside the transaction
+						return db.CreateUserTxResult{User: user}, arg.AfterCreate(nil, user)
+					})
+
+				var payload = &worker.PayloadSendVerifyEmail{Username: user.Username}
+				taskDistributor.EXPECT().
+					DistributeTaskSendVerifyEmail(gomock.Any(), gomock.Any(), gomock.Eq(payload)).
+					Times(1).
+					Return(nil)
 			},
 			checkResponse: func(recoder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recoder.Code)
 				requireBodyMatchUser(t, recoder.Body, user)
 			},
 		},
+		{
+			name: "InternalError",
+			body: createUserRequest{
+				Username: user.Username,
+				Password: password,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateUserTxResult{}, sql.ErrConnDone)
+				taskDistributor.EXPECT().
+					DistributeTaskSendVerifyEmail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recoder.Code)
+			},
+		},
+		{
+			name: "DuplicateUsername",
+			body: createUserRequest{
+				Username: user.Username,
+				Password: password,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateUserTxResult{}, &pgconn.PgError{Code: db.UniqueViolation})
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recoder.Code)
+			},
+		},
+		{
+			name: "ReservedUsername",
+			body: createUserRequest{
+				Username: "SimpleBank",
+				Password: password,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recoder.Code)
+				require.Contains(t, recoder.Body.String(), errUsernameReserved.Error())
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: createUserRequest{
+				Username: user.Username,
+				Password: password,
+				FullName: user.FullName,
+				Email:    "invalid-email",
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recoder.Code)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -111,7 +209,8 @@ func TestCreateUser(t *testing.T) {
 			var ctrl = gomock.NewController(t)
 			defer ctrl.Finish()
 			var store = mockdb.NewMockStore(ctrl)
-			tc.buildStubs(store)
+			var taskDistributor = mockwk.NewMockTaskDistributor(ctrl)
+			tc.buildStubs(store, taskDistributor)
 
 			var data, err = json.Marshal(tc.body)
 			require.NoError(t, err)
@@ -119,7 +218,646 @@ func TestCreateUser(t *testing.T) {
 			var request, err2 = http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
 			require.NoError(t, err2)
 
-			var server = NewServer(store)
+			var server = newTestServer(t, store, taskDistributor)
+			var recoder = httptest.NewRecorder()
+			server.router.ServeHTTP(recoder, request)
+
+			tc.checkResponse(recoder)
+		})
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	var user, _ = randomUser(t)
+	var verifyEmail = db.VerifyEmail{
+		ID:         util.RandomInt(1, 1000),
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+	}
+
+	var testCases = []struct {
+		name          string
+		query         url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			query: url.Values{
+				"email_id":    {fmt.Sprint(verifyEmail.ID)},
+				"secret_code": {verifyEmail.SecretCode},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				var arg = db.VerifyEmailTxParams{
+					EmailID:    verifyEmail.ID,
+					SecretCode: verifyEmail.SecretCode,
+				}
+				var verifiedUser = user
+				verifiedUser.IsEmailVerified = true
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.VerifyEmailTxResult{User: verifiedUser, VerifyEmail: verifyEmail}, nil)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recoder.Code)
+				require.JSONEq(t, `{"is_verified":true}`, recoder.Body.String())
+			},
+		},
+		{
+			name: "InvalidOrExpiredCode",
+			query: url.Values{
+				"email_id":    {fmt.Sprint(verifyEmail.ID)},
+				"secret_code": {"wrong"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmailTxResult{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recoder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			query: url.Values{
+				"email_id":    {fmt.Sprint(verifyEmail.ID)},
+				"secret_code": {verifyEmail.SecretCode},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmailTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recoder.Code)
+			},
+		},
+		{
+			name: "MissingSecretCode",
+			query: url.Values{
+				"email_id": {fmt.Sprint(verifyEmail.ID)},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recoder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			var request, err = http.NewRequest(http.MethodGet, "/verify_email?"+tc.query.Encode(), nil)
+			require.NoError(t, err)
+
+			var server = newTestServer(t, store, nil)
+			var recoder = httptest.NewRecorder()
+			server.router.ServeHTTP(recoder, request)
+
+			tc.checkResponse(recoder)
+		})
+	}
+}
+
+func TestLoginUser(t *testing.T) {
+	var user, password = randomUser(t)
+	var legacyUser = user
+	var bcryptHash, err = util.NewBcryptHasher(bcrypt.MinCost).Hash(password)
+	require.NoError(t, err)
+	legacyUser.HashPassword = bcryptHash
+
+	var testCases = []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recoder.Code)
+
+				var rsp loginUserResponse
+				require.NoError(t, json.Unmarshal(recoder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.AccessToken)
+				require.Equal(t, user.Username, rsp.User.Username)
+			},
+		},
+		{
+			name: "RehashLegacyPassword",
+			body: gin.H{"username": legacyUser.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(legacyUser.Username)).
+					Times(1).
+					Return(legacyUser, nil)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserParams) (db.User, error) {
+						require.Equal(t, legacyUser.Username, arg.Username)
+						require.True(t, arg.HashPassword.Valid)
+						require.True(t, strings.HasPrefix(arg.HashPassword.String, "$argon2id$"))
+						require.NoError(t, util.CheckPassword(password, arg.HashPassword.String))
+						// a rehash is not a password change and must not revoke tokens
+						require.False(t, arg.PasswordChangedAt.Valid)
+						return legacyUser, nil
+					})
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recoder.Code)
+			},
+		},
+		{
+			name: "RehashFailureStillLogsIn",
+			body: gin.H{"username": legacyUser.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(legacyUser.Username)).
+					Times(1).
+					Return(legacyUser, nil)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recoder.Code)
+			},
+		},
+		{
+			name: "UserNotFound",
+			body: gin.H{"username": "NotFound", "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recoder.Code)
+				require.Contains(t, recoder.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name: "SystemUser",
+			body: gin.H{"username": db.BankUsername, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				var system = user
+				system.Username = db.BankUsername
+				system.Role = util.SystemRole
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(db.BankUsername)).
+					Times(1).
+					Return(system, nil)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recoder.Code)
+				require.Contains(t, recoder.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			body: gin.H{"username": user.Username, "password": "incorrect"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recoder.Code)
+				require.Contains(t, recoder.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name: "ShortPassword",
+			body: gin.H{"username": user.Username, "password": "abc"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recoder.Code)
+				require.Contains(t, recoder.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name: "MissingPassword",
+			body: gin.H{"username": user.Username},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recoder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recoder.Code)
+			},
+		},
+		{
+			name: "InvalidUsername",
+			body: gin.H{"username": "invalid-user#1", "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recoder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			var data, err = json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			var server = newTestServer(t, store, nil)
+			var recoder = httptest.NewRecorder()
+			server.router.ServeHTTP(recoder, request)
+
+			tc.checkResponse(recoder)
+		})
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	var user, password = randomUser(t)
+	var admin, _ = randomUser(t)
+	admin.Role = util.AdminRole
+	var other, _ = randomUser(t)
+
+	var newFullName = util.RandomOwner()
+	var newEmail = util.RandomEmail()
+	var newPassword = util.RandomString(8)
+
+	var testCases = []struct {
+		name          string
+		username      string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "UpdateFullName",
+			username: user.Username,
+			body:     gin.H{"full_name": newFullName},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				expectAuthLookup(store, user)
+				var arg = db.UpdateUserParams{
+					Username: user.Username,
+					FullName: pgtype.Text{String: newFullName, Valid: true},
+				}
+				var updated = user
+				updated.FullName = newFullName
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, txArg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.Equal(t, arg, txArg.UpdateUserParams)
+						return db.UpdateUserTxResult{User: updated}, txArg.AfterUpdate(nil, updated)
+					})
+				taskDistributor.EXPECT().
+					DistributeTaskSendVerifyEmail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recoder.Code)
+				var updated = user
+				updated.FullName = newFullName
+				requireBodyMatchUser(t, recoder.Body, updated)
+			},
+		},
+		{
+			name:     "UpdateEmailRequiresVerification",
+			username: user.Username,
+			body:     gin.H{"email": newEmail},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				expectAuthLookup(store, user)
+				var updated = user
+				updated.Email = newEmail
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, txArg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.Equal(t, pgtype.Text{String: newEmail, Valid: true}, txArg.Email)
+						require.Equal(t, pgtype.Bool{Bool: false, Valid: true}, txArg.IsEmailVerified)
+						return db.UpdateUserTxResult{User: updated}, txArg.AfterUpdate(nil, updated)
+					})
+				taskDistributor.EXPECT().
+					DistributeTaskSendVerifyEmail(gomock.Any(), gomock.Any(), gomock.Eq(&worker.PayloadSendVerifyEmail{Username: user.Username})).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recoder.Code)
+			},
+		},
+		{
+			name:     "DuplicateEmail",
+			username: user.Username,
+			body:     gin.H{"email": other.Email},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				expectAuthLookup(store, user)
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UpdateUserTxResult{}, &pgconn.PgError{Code: db.UniqueViolation})
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recoder.Code)
+			},
+		},
+		{
+			name:     "ChangePassword",
+			username: user.Username,
+			body:     gin.H{"password": newPassword, "current_password": password},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				expectAuthLookup(store, user)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, txArg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.True(t, txArg.HashPassword.Valid)
+						require.NoError(t, util.CheckPassword(newPassword, txArg.HashPassword.String))
+						require.True(t, txArg.PasswordChangedAt.Valid)
+						require.WithinDuration(t, time.Now(), txArg.PasswordChangedAt.Time, time.Second)
+						return db.UpdateUserTxResult{User: user}, nil
+					})
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recoder.Code)
+			},
+		},
+		{
+			name:     "ChangePasswordContainsUsername",
+			username: user.Username,
+			body:     gin.H{"password": "x1" + user.Username, "current_password": password},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				expectAuthLookup(store, user)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recoder.Code)
+				require.Contains(t, recoder.Body.String(), util.ErrPasswordContainsIdentifier.Error())
+			},
+		},
+		{
+			name:     "ChangePasswordContainsEmail",
+			username: user.Username,
+			body:     gin.H{"password": "x1" + user.Email, "current_password": password},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				expectAuthLookup(store, user)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recoder.Code)
+				require.Contains(t, recoder.Body.String(), util.ErrPasswordContainsIdentifier.Error())
+			},
+		},
+		{
+			name:     "ChangePasswordContainsNewEmail",
+			username: user.Username,
+			body:     gin.H{"password": "x1" + newEmail, "email": newEmail, "current_password": password},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				expectAuthLookup(store, user)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recoder.Code)
+				require.Contains(t, recoder.Body.String(), util.ErrPasswordContainsIdentifier.Error())
+			},
+		},
+		{
+			name:     "ChangePasswordWithoutCurrentPassword",
+			username: user.Username,
+			body:     gin.H{"password": newPassword},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				expectAuthLookup(store, user)
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recoder.Code)
+			},
+		},
+		{
+			name:     "ChangePasswordWrongCurrentPassword",
+			username: user.Username,
+			body:     gin.H{"password": newPassword, "current_password": "incorrect"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				expectAuthLookup(store, user)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recoder.Code)
+			},
+		},
+		{
+			name:     "AdminUpdatesOtherUser",
+			username: user.Username,
+			body:     gin.H{"full_name": newFullName},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				expectAuthLookup(store, admin)
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UpdateUserTxResult{User: user}, nil)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recoder.Code)
+			},
+		},
+		{
+			name:     "OtherUserForbidden",
+			username: user.Username,
+			body:     gin.H{"full_name": newFullName},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, other.Username, other.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				expectAuthLookup(store, other)
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recoder.Code)
+			},
+		},
+		{
+			name:     "NoAuthorization",
+			username: user.Username,
+			body:     gin.H{"full_name": newFullName},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recoder.Code)
+			},
+		},
+		{
+			name:     "UserNotFound",
+			username: user.Username,
+			body:     gin.H{"full_name": newFullName},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				expectAuthLookup(store, user)
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UpdateUserTxResult{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recoder.Code)
+			},
+		},
+		{
+			name:     "InvalidEmail",
+			username: user.Username,
+			body:     gin.H{"email": "invalid-email"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				expectAuthLookup(store, user)
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recoder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			var taskDistributor = mockwk.NewMockTaskDistributor(ctrl)
+			tc.buildStubs(store, taskDistributor)
+
+			var data, err = json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPatch, "/users/"+tc.username, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			var server = newTestServer(t, store, taskDistributor)
+			tc.setupAuth(t, request, server.tokenMaker)
 			var recoder = httptest.NewRecorder()
 			server.router.ServeHTTP(recoder, request)
 
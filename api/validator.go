@@ -1,15 +1,122 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
 	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
 
+var errCurrencyNotEnabled = errors.New("is not an enabled currency")
+
+// validCurrency implements the "currency" tag, which accepts the ISO 4217 currencies of the registry.
+// Whether accounts can be opened in the currency is checked by createAccount against the currencies table.
 var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
 	var currency, ok = fieldLevel.Field().Interface().(string)
 	if !ok {
-		return ok
+		return false
 	}
 
-	return util.IsSupportCurrency(currency)
+	_, ok = util.LookupCurrency(currency)
+	return ok
+}
+
+// passwordValidator refuses passwords that break the policy or appear in the breached password list.
+type passwordValidator struct {
+	policy   util.PasswordPolicy
+	breached *util.BreachedPasswords
+}
+
+func newPasswordValidator(config util.Config) (*passwordValidator, error) {
+	var v = &passwordValidator{policy: util.NewPasswordPolicy(config)}
+	if config.BreachedPasswordsFile != "" {
+		var breached, err = util.LoadBreachedPasswords(config.BreachedPasswordsFile)
+		if err != nil {
+			return nil, err
+		}
+		v.breached = breached
+	}
+	return v, nil
+}
+
+// check returns why the password is refused, or nil.
+func (v *passwordValidator) check(password string, identifiers ...string) error {
+	if err := v.policy.Validate(password, identifiers...); err != nil {
+		return err
+	}
+	if v.breached != nil && v.breached.Contains(password) {
+		return util.ErrBreachedPassword
+	}
+	return nil
+}
+
+// checkPassword answers 400 and returns false when the password is refused.
+// The identifiers are the username and email of the user the password is for,
+// which the password must not contain.
+func (server *Server) checkPassword(ctx *gin.Context, field string, password string, identifiers ...string) bool {
+	if err := server.passwords.check(password, identifiers...); err != nil {
+		ctx.JSON(http.StatusBadRequest, fieldErrResponse(field, err))
+		return false
+	}
+	return true
+}
+
+// fieldName names validation errors after the json, uri or form key of the field.
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "uri", "form"} {
+		var name, _, _ = strings.Cut(field.Tag.Get(tag), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// fieldErrorMessage explains a failed validation rule to the client.
+func fieldErrorMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "max":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fieldErr.Param())
+	case "email":
+		return "must be a valid email address"
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "currency":
+		return "is not an ISO 4217 currency"
+	case "scope":
+		return "is not a known scope"
+	default:
+		return fmt.Sprintf("failed on the %q rule", fieldErr.Tag())
+	}
+}
+
+// validationErrors maps each invalid field of a binding error to a message, or returns nil.
+func validationErrors(err error) map[string]string {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil
+	}
+
+	var fields = make(map[string]string, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		fields[fieldErr.Field()] = fieldErrorMessage(fieldErr)
+	}
+	return fields
 }
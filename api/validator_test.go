@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	mockwk "github.com/Ma-hiru/simplebank/worker/mock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateUserPasswordPolicy(t *testing.T) {
+	var user, _ = randomUser(t)
+
+	var config = newTestConfig()
+	config.PasswordMinLength = 10
+	config.PasswordRequireUpper = true
+	config.PasswordRequireLower = true
+	config.PasswordRequireDigit = true
+	config.BreachedPasswordsFile = "../data/breached_passwords.txt"
+
+	var testCases = []struct {
+		name     string
+		password string
+		message  string
+	}{
+		{
+			name:     "TooShort",
+			password: "Ab1",
+			message:  "must be at least 10 characters long",
+		},
+		{
+			name:     "NoUppercase",
+			password: "abcdefgh123",
+			message:  "must contain an uppercase letter",
+		},
+		{
+			name:     "NoDigit",
+			password: "Abcdefghijk",
+			message:  "must contain a digit",
+		},
+		{
+			name:     "ContainsUsername",
+			password: "X1" + user.Username + "Yz",
+			message:  "must not contain the username or email",
+		},
+		{
+			name:     "Breached",
+			password: "Password123",
+			message:  "has appeared in a data breach, choose a different one",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+			var store = mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				CreateUserTx(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			var data, err = json.Marshal(gin.H{
+				"username":  user.Username,
+				"password":  tc.password,
+				"full_name": user.FullName,
+				"email":     user.Email,
+			})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			var server = newTestServerWithConfig(t, config, store, mockwk.NewMockTaskDistributor(ctrl))
+			var recorder = httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+
+			require.Equal(t, http.StatusBadRequest, recorder.Code)
+			var rsp struct {
+				Fields map[string]string `json:"fields"`
+			}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			require.Equal(t, map[string]string{"password": tc.message}, rsp.Fields)
+		})
+	}
+}
+
+func TestFieldErrors(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+
+	var data, err = json.Marshal(gin.H{"username": "not-alphanum", "email": "invalid-email"})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/users", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	var server = newTestServer(t, store, nil)
+	var recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	var rsp struct {
+		Fields map[string]string `json:"fields"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Equal(t, map[string]string{
+		"username":  "must contain only letters and digits",
+		"password":  "is required",
+		"full_name": "is required",
+		"email":     "must be a valid email address",
+	}, rsp.Fields)
+}
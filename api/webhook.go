@@ -0,0 +1,367 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	errWebhooksDisabled = errors.New("webhooks are not enabled")
+	errWebhookNotFound  = errors.New("webhook not found")
+)
+
+const webhookSecretPrefix = "whsec_"
+
+type webhookResponse struct {
+	ID                  uuid.UUID `json:"id"`
+	URL                 string    `json:"url"`
+	EventTypes          []string  `json:"event_types"`
+	LowBalanceThreshold int64     `json:"low_balance_threshold"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+func newWebhookResponse(subscription db.WebhookSubscription) webhookResponse {
+	return webhookResponse{
+		ID:                  subscription.ID,
+		URL:                 subscription.Url,
+		EventTypes:          subscription.EventTypes,
+		LowBalanceThreshold: subscription.LowBalanceThreshold,
+		CreatedAt:           subscription.CreatedAt,
+	}
+}
+
+type webhookDeliveryResponse struct {
+	ID            int64     `json:"id"`
+	EventID       int64     `json:"event_id"`
+	EventType     string    `json:"event_type,omitempty"`
+	Status        string    `json:"status"`
+	Attempts      int32     `json:"attempts"`
+	LastError     string    `json:"last_error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func newWebhookDeliveryResponse(delivery db.WebhookDelivery, eventType string) webhookDeliveryResponse {
+	return webhookDeliveryResponse{
+		ID:            delivery.ID,
+		EventID:       delivery.EventID,
+		EventType:     eventType,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		LastError:     delivery.LastError,
+		NextAttemptAt: delivery.NextAttemptAt,
+		CreatedAt:     delivery.CreatedAt,
+		UpdatedAt:     delivery.UpdatedAt,
+	}
+}
+
+type webhookUserURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type createWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,url,max=2048"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=account.created transfer.created transfer_group.created balance.low"`
+	// LowBalanceThreshold is in minor units of the account currency, balance.low is sent when a debit goes below it.
+	LowBalanceThreshold int64 `json:"low_balance_threshold"`
+}
+
+type createWebhookResponse struct {
+	Secret  string          `json:"secret"`
+	Webhook webhookResponse `json:"webhook"`
+}
+
+// createWebhook subscribes a URL to the events of the user's accounts.
+// The signing secret is returned once, it is stored sealed so the worker can sign the deliveries with it.
+func (server *Server) createWebhook(ctx *gin.Context) {
+	var uri webhookUserURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	var req createWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	if target, err := url.Parse(req.URL); err != nil || target.Scheme != "https" {
+		ctx.JSON(http.StatusBadRequest, fieldErrResponse("url", errors.New("must be an https url")))
+		return
+	}
+	if !server.authorizeWebhookOwner(ctx, uri.Username) {
+		return
+	}
+	if server.webhookSecrets == nil {
+		ctx.JSON(http.StatusServiceUnavailable, errResponse(errWebhooksDisabled))
+		return
+	}
+
+	var id, err = uuid.NewRandom()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	secret, err := util.RandomSecret(32)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	secret = webhookSecretPrefix + secret
+	sealed, err := server.webhookSecrets.Seal(secret, id.String())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	subscription, err := server.store.CreateWebhookSubscription(ctx, db.CreateWebhookSubscriptionParams{
+		ID:                  id,
+		Username:            uri.Username,
+		Url:                 req.URL,
+		EventTypes:          req.EventTypes,
+		Secret:              sealed,
+		LowBalanceThreshold: req.LowBalanceThreshold,
+	})
+	if err != nil {
+		if db.ErrorCode(err) == db.ForeignKeyViolation {
+			ctx.JSON(http.StatusNotFound, errResponse(errors.New("user not found")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	server.securityLog.Info("webhook created",
+		"username", subscription.Username,
+		"webhook", subscription.ID,
+		"url", subscription.Url,
+		"by", authPayload(ctx).Username,
+	)
+	ctx.JSON(http.StatusOK, createWebhookResponse{
+		Secret:  secret,
+		Webhook: newWebhookResponse(subscription),
+	})
+}
+
+// listWebhooks lists the webhook subscriptions of a user.
+func (server *Server) listWebhooks(ctx *gin.Context) {
+	var uri webhookUserURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	if !server.authorizeWebhookOwner(ctx, uri.Username) {
+		return
+	}
+
+	var subscriptions, err = server.store.ListWebhookSubscriptions(ctx, uri.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	var rsp = make([]webhookResponse, len(subscriptions))
+	for i, subscription := range subscriptions {
+		rsp[i] = newWebhookResponse(subscription)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type webhookURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+	ID       string `uri:"id" binding:"required,uuid"`
+}
+
+// deleteWebhook removes a webhook subscription together with its pending deliveries.
+func (server *Server) deleteWebhook(ctx *gin.Context) {
+	var uri webhookURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	if !server.authorizeWebhookOwner(ctx, uri.Username) {
+		return
+	}
+
+	var rows, err = server.store.DeleteWebhookSubscription(ctx, db.DeleteWebhookSubscriptionParams{
+		ID:       uuid.MustParse(uri.ID),
+		Username: uri.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if rows == 0 {
+		ctx.JSON(http.StatusNotFound, errResponse(errWebhookNotFound))
+		return
+	}
+
+	server.securityLog.Info("webhook deleted",
+		"username", uri.Username,
+		"webhook", uri.ID,
+		"by", authPayload(ctx).Username,
+	)
+	ctx.Status(http.StatusNoContent)
+}
+
+type listWebhookDeliveriesRequest struct {
+	Status   string `form:"status" binding:"omitempty,oneof=pending sending succeeded dead"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// listWebhookDeliveries lists the deliveries of a webhook, newest first.
+func (server *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var uri webhookURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	var req listWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	var subscription, ok = server.ownedWebhook(ctx, uri)
+	if !ok {
+		return
+	}
+
+	var rows, err = server.store.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		Status:         pgtype.Text{String: req.Status, Valid: req.Status != ""},
+		PageSize:       req.PageSize,
+		PageOffset:     (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	var rsp = make([]webhookDeliveryResponse, len(rows))
+	for i, row := range rows {
+		rsp[i] = newWebhookDeliveryResponse(row.WebhookDelivery, row.EventType)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type webhookDeliveryURI struct {
+	Username   string `uri:"username" binding:"required,alphanum"`
+	ID         string `uri:"id" binding:"required,uuid"`
+	DeliveryID int64  `uri:"delivery_id" binding:"required,min=1"`
+}
+
+// listWebhookAttempts lists every attempt to send a delivery, with the response code and error.
+func (server *Server) listWebhookAttempts(ctx *gin.Context) {
+	var uri webhookDeliveryURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	var delivery, ok = server.ownedWebhookDelivery(ctx, uri)
+	if !ok {
+		return
+	}
+
+	var attempts, err = server.store.ListWebhookAttempts(ctx, delivery.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, attempts)
+}
+
+// replayWebhookDelivery sends a succeeded or dead delivery again, with a fresh set of attempts.
+func (server *Server) replayWebhookDelivery(ctx *gin.Context) {
+	var uri webhookDeliveryURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	var delivery, ok = server.ownedWebhookDelivery(ctx, uri)
+	if !ok {
+		return
+	}
+
+	delivery, err := server.store.ReplayWebhookDelivery(ctx, delivery.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusConflict, errResponse(errors.New("only succeeded or dead deliveries can be replayed")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	server.securityLog.Info("webhook delivery replayed",
+		"username", uri.Username,
+		"webhook", uri.ID,
+		"delivery", delivery.ID,
+		"by", authPayload(ctx).Username,
+	)
+	ctx.JSON(http.StatusOK, newWebhookDeliveryResponse(delivery, ""))
+}
+
+// authorizeWebhookOwner allows the owner of the webhooks or an admin.
+func (server *Server) authorizeWebhookOwner(ctx *gin.Context, username string) bool {
+	var payload = authPayload(ctx)
+	if payload.Username != username && payload.Role != util.AdminRole {
+		var err = errors.New("cannot manage webhooks of another user")
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+		return false
+	}
+	return true
+}
+
+// ownedWebhook returns the webhook of the URI, after checking the caller may see it.
+func (server *Server) ownedWebhook(ctx *gin.Context, uri webhookURI) (db.WebhookSubscription, bool) {
+	if !server.authorizeWebhookOwner(ctx, uri.Username) {
+		return db.WebhookSubscription{}, false
+	}
+
+	var subscription, err = server.store.GetWebhookSubscription(ctx, uuid.MustParse(uri.ID))
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errResponse(errWebhookNotFound))
+			return db.WebhookSubscription{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return db.WebhookSubscription{}, false
+	}
+	if subscription.Username != uri.Username {
+		ctx.JSON(http.StatusNotFound, errResponse(errWebhookNotFound))
+		return db.WebhookSubscription{}, false
+	}
+	return subscription, true
+}
+
+// ownedWebhookDelivery returns the delivery of the URI, after checking it belongs to a webhook the caller may see.
+func (server *Server) ownedWebhookDelivery(ctx *gin.Context, uri webhookDeliveryURI) (db.WebhookDelivery, bool) {
+	var subscription, ok = server.ownedWebhook(ctx, webhookURI{Username: uri.Username, ID: uri.ID})
+	if !ok {
+		return db.WebhookDelivery{}, false
+	}
+
+	var delivery, err = server.store.GetWebhookDelivery(ctx, uri.DeliveryID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errResponse(errors.New("delivery not found")))
+			return db.WebhookDelivery{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return db.WebhookDelivery{}, false
+	}
+	if delivery.SubscriptionID != subscription.ID {
+		ctx.JSON(http.StatusNotFound, errResponse(errors.New("delivery not found")))
+		return db.WebhookDelivery{}, false
+	}
+	return delivery, true
+}
@@ -0,0 +1,447 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomWebhook(username string) db.WebhookSubscription {
+	return db.WebhookSubscription{
+		ID:         uuid.New(),
+		Username:   username,
+		Url:        "https://example.com/hooks",
+		EventTypes: []string{db.EventTransferCreated},
+		Secret:     util.RandomString(32),
+		CreatedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestCreateWebhook(t *testing.T) {
+	var user, _ = randomUser(t)
+	var other, _ = randomUser(t)
+	var admin, _ = randomUser(t)
+	admin.Role = util.AdminRole
+	var url = "/users/" + user.Username + "/webhooks"
+	var body = gin.H{
+		"url":                   "https://example.com/hooks",
+		"event_types":           []string{db.EventTransferCreated, db.EventBalanceLow},
+		"low_balance_threshold": 1000,
+	}
+
+	var expectCreate = func(store *mockdb.MockStore) {
+		store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(1).
+			DoAndReturn(func(_ context.Context, arg db.CreateWebhookSubscriptionParams) (db.WebhookSubscription, error) {
+				require.Equal(t, user.Username, arg.Username)
+				require.Equal(t, "https://example.com/hooks", arg.Url)
+				require.Equal(t, []string{db.EventTransferCreated, db.EventBalanceLow}, arg.EventTypes)
+				require.Equal(t, int64(1000), arg.LowBalanceThreshold)
+				require.NotContains(t, arg.Secret, "whsec_")
+				return db.WebhookSubscription{
+					ID:                  arg.ID,
+					Username:            arg.Username,
+					Url:                 arg.Url,
+					EventTypes:          arg.EventTypes,
+					Secret:              arg.Secret,
+					LowBalanceThreshold: arg.LowBalanceThreshold,
+				}, nil
+			})
+	}
+
+	runFeeTestCases(t, []feeTestCase{
+		{
+			name:       "OK",
+			caller:     user,
+			method:     http.MethodPost,
+			url:        url,
+			body:       body,
+			buildStubs: expectCreate,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp createWebhookResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.True(t, strings.HasPrefix(rsp.Secret, "whsec_"))
+				require.Equal(t, "https://example.com/hooks", rsp.Webhook.URL)
+				require.Contains(t, securityLog, "webhook created")
+			},
+		},
+		{
+			name:       "Admin",
+			caller:     admin,
+			method:     http.MethodPost,
+			url:        url,
+			body:       body,
+			buildStubs: expectCreate,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "OtherUser",
+			caller: other,
+			method: http.MethodPost,
+			url:    url,
+			body:   body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidScheme",
+			caller: user,
+			method: http.MethodPost,
+			url:    url,
+			body: gin.H{
+				"url":         "ftp://example.com/hooks",
+				"event_types": []string{db.EventTransferCreated},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"url"`)
+			},
+		},
+		{
+			name:   "HTTPScheme",
+			caller: user,
+			method: http.MethodPost,
+			url:    url,
+			body: gin.H{
+				"url":         "http://example.com/hooks",
+				"event_types": []string{db.EventTransferCreated},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "must be an https url")
+			},
+		},
+		{
+			name:   "InvalidEventType",
+			caller: user,
+			method: http.MethodPost,
+			url:    url,
+			body: gin.H{
+				"url":         "https://example.com/hooks",
+				"event_types": []string{"transfer.deleted"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "NoEventTypes",
+			caller: user,
+			method: http.MethodPost,
+			url:    url,
+			body: gin.H{
+				"url":         "https://example.com/hooks",
+				"event_types": []string{},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			caller: user,
+			method: http.MethodPost,
+			url:    url,
+			body:   body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.WebhookSubscription{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	})
+}
+
+func TestCreateWebhookDisabled(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var user, _ = randomUser(t)
+	var store = mockdb.NewMockStore(ctrl)
+	expectAuthLookup(store, user)
+	store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+
+	var config = newTestConfig()
+	config.WebhookEncryptionKey = ""
+	var server = newTestServerWithConfig(t, config, store, nil)
+
+	var body = strings.NewReader(`{"url":"https://example.com/hooks","event_types":["account.created"]}`)
+	request, err := http.NewRequest(http.MethodPost, "/users/"+user.Username+"/webhooks", body)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+	var recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
+
+func TestWebhooks(t *testing.T) {
+	var user, _ = randomUser(t)
+	var other, _ = randomUser(t)
+	var webhook = randomWebhook(user.Username)
+	var webhookURL = fmt.Sprintf("/users/%s/webhooks/%s", user.Username, webhook.ID)
+
+	runFeeTestCases(t, []feeTestCase{
+		{
+			name:   "List",
+			caller: user,
+			method: http.MethodGet,
+			url:    "/users/" + user.Username + "/webhooks",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListWebhookSubscriptions(gomock.Any(), gomock.Eq(user.Username)).Times(1).
+					Return([]db.WebhookSubscription{webhook}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp []webhookResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, []webhookResponse{newWebhookResponse(webhook)}, rsp)
+				require.NotContains(t, recorder.Body.String(), webhook.Secret)
+			},
+		},
+		{
+			name:   "ListOtherUser",
+			caller: other,
+			method: http.MethodGet,
+			url:    "/users/" + user.Username + "/webhooks",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListWebhookSubscriptions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "Delete",
+			caller: user,
+			method: http.MethodDelete,
+			url:    webhookURL,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteWebhookSubscription(gomock.Any(), gomock.Eq(db.DeleteWebhookSubscriptionParams{
+					ID:       webhook.ID,
+					Username: user.Username,
+				})).Times(1).Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+				require.Contains(t, securityLog, "webhook deleted")
+			},
+		},
+		{
+			name:   "DeleteNotFound",
+			caller: user,
+			method: http.MethodDelete,
+			url:    webhookURL,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteWebhookSubscription(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "DeleteInvalidID",
+			caller: user,
+			method: http.MethodDelete,
+			url:    "/users/" + user.Username + "/webhooks/1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	})
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	var user, _ = randomUser(t)
+	var webhook = randomWebhook(user.Username)
+	var otherWebhook = randomWebhook(util.RandomOwner())
+	var webhookURL = fmt.Sprintf("/users/%s/webhooks/%s", user.Username, webhook.ID)
+	var delivery = db.WebhookDelivery{
+		ID:             util.RandomInt(1, 1000),
+		SubscriptionID: webhook.ID,
+		EventID:        util.RandomInt(1, 1000),
+		Status:         db.WebhookDeliveryDead,
+		Attempts:       15,
+		LastError:      "unexpected status 500",
+	}
+	var deliveryURL = fmt.Sprintf("%s/deliveries/%d", webhookURL, delivery.ID)
+
+	runFeeTestCases(t, []feeTestCase{
+		{
+			name:   "List",
+			caller: user,
+			method: http.MethodGet,
+			url:    webhookURL + "/deliveries?page_id=2&page_size=5&status=dead",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Eq(db.ListWebhookDeliveriesParams{
+					SubscriptionID: webhook.ID,
+					Status:         pgtype.Text{String: db.WebhookDeliveryDead, Valid: true},
+					PageSize:       5,
+					PageOffset:     5,
+				})).Times(1).Return([]db.ListWebhookDeliveriesRow{
+					{WebhookDelivery: delivery, EventType: db.EventTransferCreated},
+				}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp []webhookDeliveryResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 1)
+				require.Equal(t, delivery.ID, rsp[0].ID)
+				require.Equal(t, db.EventTransferCreated, rsp[0].EventType)
+				require.Equal(t, db.WebhookDeliveryDead, rsp[0].Status)
+			},
+		},
+		{
+			name:   "ListInvalidStatus",
+			caller: user,
+			method: http.MethodGet,
+			url:    webhookURL + "/deliveries?page_id=1&page_size=5&status=failed",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "ListWebhookOfOtherUser",
+			caller: user,
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/users/%s/webhooks/%s/deliveries?page_id=1&page_size=5", user.Username, otherWebhook.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(otherWebhook.ID)).Times(1).Return(otherWebhook, nil)
+				store.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "Attempts",
+			caller: user,
+			method: http.MethodGet,
+			url:    deliveryURL + "/attempts",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(delivery, nil)
+				store.EXPECT().ListWebhookAttempts(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).
+					Return([]db.WebhookAttempt{{ID: 1, DeliveryID: delivery.ID, Attempt: 1, ResponseCode: 500}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp []db.WebhookAttempt
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 1)
+				require.Equal(t, int32(500), rsp[0].ResponseCode)
+			},
+		},
+		{
+			name:   "AttemptsDeliveryOfOtherWebhook",
+			caller: user,
+			method: http.MethodGet,
+			url:    deliveryURL + "/attempts",
+			buildStubs: func(store *mockdb.MockStore) {
+				var foreign = delivery
+				foreign.SubscriptionID = otherWebhook.ID
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(foreign, nil)
+				store.EXPECT().ListWebhookAttempts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "Replay",
+			caller: user,
+			method: http.MethodPost,
+			url:    deliveryURL + "/replay",
+			buildStubs: func(store *mockdb.MockStore) {
+				var replayed = delivery
+				replayed.Status = db.WebhookDeliveryPending
+				replayed.Attempts = 0
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(delivery, nil)
+				store.EXPECT().ReplayWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(replayed, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp webhookDeliveryResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, db.WebhookDeliveryPending, rsp.Status)
+				require.Zero(t, rsp.Attempts)
+				require.Contains(t, securityLog, "webhook delivery replayed")
+			},
+		},
+		{
+			name:   "ReplayPending",
+			caller: user,
+			method: http.MethodPost,
+			url:    deliveryURL + "/replay",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(delivery, nil)
+				store.EXPECT().ReplayWebhookDelivery(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.WebhookDelivery{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:   "ReplayDeliveryNotFound",
+			caller: user,
+			method: http.MethodPost,
+			url:    deliveryURL + "/replay",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.WebhookDelivery{}, db.ErrRecordNotFound)
+				store.EXPECT().ReplayWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, securityLog string) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	})
+}
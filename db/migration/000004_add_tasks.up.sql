@@ -0,0 +1,20 @@
+CREATE TABLE "tasks"
+(
+    "id"           bigserial PRIMARY KEY,
+    "type"         varchar     NOT NULL,
+    "payload"      jsonb       NOT NULL,
+    "status"       varchar     NOT NULL DEFAULT 'pending',
+    "attempts"     int         NOT NULL DEFAULT 0,
+    "max_attempts" int         NOT NULL,
+    "last_error"   varchar     NOT NULL DEFAULT '',
+    "run_at"       timestamptz NOT NULL DEFAULT (now()),
+    "locked_at"    timestamptz,
+    "created_at"   timestamptz NOT NULL DEFAULT (now()),
+    "updated_at"   timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "tasks" ("status", "run_at");
+
+COMMENT ON COLUMN "tasks"."status" IS 'pending, running, completed or failed';
+
+COMMENT ON COLUMN "tasks"."locked_at" IS 'set while a processor is running the task';
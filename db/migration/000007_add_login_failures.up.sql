@@ -0,0 +1,13 @@
+CREATE TABLE "login_failures"
+(
+    "scope"          varchar     NOT NULL,
+    "subject"        varchar     NOT NULL,
+    "failures"       int         NOT NULL DEFAULT 0,
+    "last_failed_at" timestamptz NOT NULL DEFAULT (now()),
+    "blocked_until"  timestamptz,
+    PRIMARY KEY ("scope", "subject")
+);
+
+COMMENT ON COLUMN "login_failures"."scope" IS 'username or ip';
+
+COMMENT ON COLUMN "login_failures"."blocked_until" IS 'no login is accepted for the subject before this time';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "recovery_codes";
+
+ALTER TABLE IF EXISTS "users"
+    DROP COLUMN IF EXISTS "totp_secret",
+    DROP COLUMN IF EXISTS "totp_enabled";
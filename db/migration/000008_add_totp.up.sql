@@ -0,0 +1,21 @@
+ALTER TABLE "users"
+    ADD COLUMN "totp_secret"  varchar,
+    ADD COLUMN "totp_enabled" bool NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN "users"."totp_secret" IS 'AES-GCM encrypted TOTP secret, set on enrolment and enabled once confirmed with a code';
+
+CREATE TABLE "recovery_codes"
+(
+    "id"         bigserial PRIMARY KEY,
+    "username"   varchar     NOT NULL,
+    "code_hash"  varchar     NOT NULL,
+    "used_at"    timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "recovery_codes" ("username", "code_hash");
+
+COMMENT ON COLUMN "recovery_codes"."code_hash" IS 'sha256 of the recovery code, the code itself is only shown once';
+
+ALTER TABLE "recovery_codes"
+    ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
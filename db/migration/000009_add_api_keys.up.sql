@@ -0,0 +1,22 @@
+CREATE TABLE "api_keys"
+(
+    "id"           uuid PRIMARY KEY,
+    "username"     varchar        NOT NULL,
+    "name"         varchar        NOT NULL,
+    "prefix"       varchar UNIQUE NOT NULL,
+    "key_hash"     varchar        NOT NULL,
+    "scopes"       varchar[]      NOT NULL,
+    "expires_at"   timestamptz    NOT NULL,
+    "last_used_at" timestamptz,
+    "revoked_at"   timestamptz,
+    "created_at"   timestamptz    NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "api_keys" ("username");
+
+COMMENT ON COLUMN "api_keys"."prefix" IS 'public part of the key used to look it up';
+
+COMMENT ON COLUMN "api_keys"."key_hash" IS 'sha256 of the whole key, the key itself is only shown once';
+
+ALTER TABLE "api_keys"
+    ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
@@ -0,0 +1,11 @@
+CREATE TABLE "rate_limit_buckets"
+(
+    "key"        varchar PRIMARY KEY,
+    "tokens"     float8      NOT NULL,
+    "allowed"    bool        NOT NULL,
+    "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "rate_limit_buckets"."tokens" IS 'tokens left in the bucket at updated_at';
+
+COMMENT ON COLUMN "rate_limit_buckets"."allowed" IS 'whether the last request took a token';
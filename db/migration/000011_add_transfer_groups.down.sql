@@ -0,0 +1,4 @@
+ALTER TABLE IF EXISTS "entries"
+    DROP COLUMN IF EXISTS "transfer_group_id";
+
+DROP TABLE IF EXISTS "transfer_groups";
@@ -0,0 +1,17 @@
+CREATE TABLE "transfer_groups"
+(
+    "id"          bigserial PRIMARY KEY,
+    "currency"    varchar     NOT NULL,
+    "description" varchar     NOT NULL DEFAULT '',
+    "created_at"  timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "entries"
+    ADD COLUMN "transfer_group_id" bigint;
+
+CREATE INDEX ON "entries" ("transfer_group_id");
+
+COMMENT ON COLUMN "entries"."transfer_group_id" IS 'set on the entries of a multi-leg transfer';
+
+ALTER TABLE "entries"
+    ADD FOREIGN KEY ("transfer_group_id") REFERENCES "transfer_groups" ("id");
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "payroll_rows";
+
+DROP TABLE IF EXISTS "payroll_batches";
@@ -0,0 +1,49 @@
+CREATE TABLE "payroll_batches"
+(
+    "id"              bigserial PRIMARY KEY,
+    "from_account_id" bigint      NOT NULL,
+    "created_by"      varchar     NOT NULL,
+    "atomic"          bool        NOT NULL,
+    "status"          varchar     NOT NULL DEFAULT 'pending',
+    "created_at"      timestamptz NOT NULL DEFAULT (now()),
+    "completed_at"    timestamptz
+);
+
+CREATE TABLE "payroll_rows"
+(
+    "batch_id"      bigint  NOT NULL,
+    "row_number"    int     NOT NULL,
+    "to_account_id" bigint  NOT NULL,
+    "amount"        bigint  NOT NULL,
+    "currency"      varchar NOT NULL,
+    "reference"     varchar NOT NULL,
+    "status"        varchar NOT NULL DEFAULT 'pending',
+    "error"         varchar NOT NULL DEFAULT '',
+    "transfer_id"   bigint,
+    PRIMARY KEY ("batch_id", "row_number")
+);
+
+CREATE INDEX ON "payroll_batches" ("created_by");
+
+COMMENT ON COLUMN "payroll_batches"."atomic" IS 'all rows are transferred in one transaction, or none is';
+
+COMMENT ON COLUMN "payroll_batches"."status" IS 'pending, completed, partially_completed or failed';
+
+COMMENT ON COLUMN "payroll_rows"."row_number" IS 'line of the row in the uploaded file';
+
+COMMENT ON COLUMN "payroll_rows"."status" IS 'pending, completed or failed';
+
+ALTER TABLE "payroll_batches"
+    ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "payroll_batches"
+    ADD FOREIGN KEY ("created_by") REFERENCES "users" ("username");
+
+ALTER TABLE "payroll_rows"
+    ADD FOREIGN KEY ("batch_id") REFERENCES "payroll_batches" ("id");
+
+ALTER TABLE "payroll_rows"
+    ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "payroll_rows"
+    ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
@@ -0,0 +1,24 @@
+CREATE TABLE "currencies"
+(
+    "code"       varchar PRIMARY KEY,
+    "enabled"    bool        NOT NULL DEFAULT true,
+    "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "currencies"."code" IS 'ISO 4217 alphabetic code';
+
+COMMENT ON COLUMN "currencies"."enabled" IS 'new accounts and transfers are only accepted in enabled currencies';
+
+INSERT INTO "currencies" ("code")
+VALUES ('USD'),
+       ('EUR'),
+       ('CAD'),
+       ('CNY');
+
+INSERT INTO "currencies" ("code")
+SELECT DISTINCT "currency"
+FROM "accounts"
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE "accounts"
+    ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS "interest_postings";
+
+DROP TABLE IF EXISTS "interest_accruals";
+
+DROP TABLE IF EXISTS "interest_runs";
+
+DROP TABLE IF EXISTS "bank_accounts";
+
+DROP INDEX IF EXISTS "owner_currency_type_key";
+
+ALTER TABLE IF EXISTS "accounts"
+    ADD CONSTRAINT "owner_currency_key" UNIQUE ("owner", "currency");
+
+ALTER TABLE IF EXISTS "accounts"
+    DROP COLUMN IF EXISTS "type";
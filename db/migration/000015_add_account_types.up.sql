@@ -0,0 +1,84 @@
+ALTER TABLE "accounts"
+    ADD COLUMN "type" varchar NOT NULL DEFAULT 'checking';
+
+ALTER TABLE "accounts"
+    ADD CONSTRAINT "accounts_type_check" CHECK ("type" IN ('checking', 'savings', 'internal'));
+
+COMMENT ON COLUMN "accounts"."type" IS 'checking, savings, or internal for the ledger accounts of the bank';
+
+ALTER TABLE "accounts"
+    DROP CONSTRAINT "owner_currency_key";
+
+-- the bank may hold several internal accounts in a currency, one per purpose
+CREATE UNIQUE INDEX "owner_currency_type_key" ON "accounts" ("owner", "currency", "type")
+    WHERE "type" <> 'internal';
+
+INSERT INTO "users" ("username", "hash_password", "full_name", "email", "role")
+VALUES ('simplebank', '', 'Simple Bank', 'ledger@simplebank.local', 'system');
+
+CREATE TABLE "bank_accounts"
+(
+    "purpose"    varchar NOT NULL,
+    "currency"   varchar NOT NULL,
+    "account_id" bigint UNIQUE NOT NULL,
+    PRIMARY KEY ("purpose", "currency")
+);
+
+COMMENT ON TABLE "bank_accounts" IS 'internal accounts the bank books its own income and expenses on';
+
+ALTER TABLE "bank_accounts"
+    ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+CREATE TABLE "interest_runs"
+(
+    "accrual_date" date PRIMARY KEY,
+    "created_at"   timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON TABLE "interest_runs" IS 'days the interest accrual has been scheduled for';
+
+CREATE TABLE "interest_accruals"
+(
+    "account_id"    bigint      NOT NULL,
+    "accrual_date"  date        NOT NULL,
+    "balance"       bigint      NOT NULL,
+    "rate_bps"      bigint      NOT NULL,
+    "amount_micros" bigint      NOT NULL,
+    "created_at"    timestamptz NOT NULL DEFAULT (now()),
+    PRIMARY KEY ("account_id", "accrual_date")
+);
+
+COMMENT ON COLUMN "interest_accruals"."balance" IS 'end-of-day balance the interest is computed on';
+
+COMMENT ON COLUMN "interest_accruals"."rate_bps" IS 'annual interest rate in basis points';
+
+COMMENT ON COLUMN "interest_accruals"."amount_micros" IS 'interest in millionths of the minor unit';
+
+CREATE INDEX ON "interest_accruals" ("accrual_date");
+
+ALTER TABLE "interest_accruals"
+    ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+CREATE TABLE "interest_postings"
+(
+    "account_id"     bigint      NOT NULL,
+    "period"         date        NOT NULL,
+    "accrued_micros" bigint      NOT NULL,
+    "amount"         bigint      NOT NULL,
+    "carry_micros"   bigint      NOT NULL,
+    "transfer_id"    bigint,
+    "created_at"     timestamptz NOT NULL DEFAULT (now()),
+    PRIMARY KEY ("account_id", "period")
+);
+
+COMMENT ON COLUMN "interest_postings"."period" IS 'first day of the month the interest was accrued in';
+
+COMMENT ON COLUMN "interest_postings"."accrued_micros" IS 'interest of the month plus the carry of the previous posting';
+
+COMMENT ON COLUMN "interest_postings"."carry_micros" IS 'rounding remainder carried over to the next posting';
+
+ALTER TABLE "interest_postings"
+    ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "interest_postings"
+    ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "fee_runs";
+
+DROP TABLE IF EXISTS "fees";
+
+DROP TABLE IF EXISTS "fee_waivers";
+
+DROP TABLE IF EXISTS "fee_schedules";
@@ -0,0 +1,82 @@
+CREATE TABLE "fee_schedules"
+(
+    "kind"         varchar     NOT NULL,
+    "currency"     varchar     NOT NULL,
+    "account_type" varchar     NOT NULL,
+    "flat_amount"  bigint      NOT NULL DEFAULT 0,
+    "rate_bps"     bigint      NOT NULL DEFAULT 0,
+    "min_amount"   bigint      NOT NULL DEFAULT 0,
+    "max_amount"   bigint,
+    "updated_at"   timestamptz NOT NULL DEFAULT (now()),
+    PRIMARY KEY ("kind", "currency", "account_type"),
+    CHECK ("kind" IN ('transfer', 'maintenance')),
+    CHECK ("flat_amount" >= 0),
+    CHECK ("rate_bps" BETWEEN 0 AND 10000),
+    CHECK ("min_amount" >= 0),
+    CHECK ("max_amount" IS NULL OR "max_amount" >= "min_amount")
+);
+
+COMMENT ON COLUMN "fee_schedules"."kind" IS 'transfer, charged to the sender of a transfer, or maintenance, charged monthly';
+
+COMMENT ON COLUMN "fee_schedules"."rate_bps" IS 'percentage of the transferred amount in basis points';
+
+COMMENT ON COLUMN "fee_schedules"."max_amount" IS 'no cap when null';
+
+ALTER TABLE "fee_schedules"
+    ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
+
+CREATE TABLE "fee_waivers"
+(
+    "username"   varchar     NOT NULL,
+    "kind"       varchar     NOT NULL,
+    "reason"     varchar     NOT NULL,
+    "expires_at" timestamptz,
+    "created_by" varchar     NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    PRIMARY KEY ("username", "kind")
+);
+
+COMMENT ON COLUMN "fee_waivers"."expires_at" IS 'the waiver never expires when null';
+
+ALTER TABLE "fee_waivers"
+    ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "fees"
+(
+    "id"                  bigserial PRIMARY KEY,
+    "kind"                varchar     NOT NULL,
+    "account_id"          bigint      NOT NULL,
+    "amount"              bigint      NOT NULL,
+    "transfer_id"         bigint      NOT NULL,
+    "charged_transfer_id" bigint,
+    "period"              date,
+    "created_at"          timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "fees"."transfer_id" IS 'transfer moving the fee to the fee income account of the bank';
+
+COMMENT ON COLUMN "fees"."charged_transfer_id" IS 'transfer a transfer fee was charged on';
+
+COMMENT ON COLUMN "fees"."period" IS 'first day of the month a maintenance fee was charged for';
+
+CREATE INDEX ON "fees" ("account_id");
+
+CREATE UNIQUE INDEX ON "fees" ("account_id", "period")
+    WHERE "kind" = 'maintenance';
+
+ALTER TABLE "fees"
+    ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "fees"
+    ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "fees"
+    ADD FOREIGN KEY ("charged_transfer_id") REFERENCES "transfers" ("id");
+
+CREATE TABLE "fee_runs"
+(
+    "period"     date PRIMARY KEY,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON TABLE "fee_runs" IS 'months the maintenance fees have been scheduled for';
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "webhook_attempts";
+
+DROP TABLE IF EXISTS "webhook_deliveries";
+
+DROP TABLE IF EXISTS "webhook_events";
+
+DROP TABLE IF EXISTS "webhook_subscriptions";
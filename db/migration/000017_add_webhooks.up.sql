@@ -0,0 +1,78 @@
+CREATE TABLE "webhook_subscriptions"
+(
+    "id"                    uuid PRIMARY KEY,
+    "username"              varchar     NOT NULL,
+    "url"                   varchar     NOT NULL,
+    "event_types"           varchar[]   NOT NULL,
+    "secret"                varchar     NOT NULL,
+    "low_balance_threshold" bigint      NOT NULL DEFAULT 0,
+    "created_at"            timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "webhook_subscriptions" ("username");
+
+COMMENT ON COLUMN "webhook_subscriptions"."secret" IS 'signing secret sealed with the webhook encryption key, it is only shown once';
+
+COMMENT ON COLUMN "webhook_subscriptions"."low_balance_threshold" IS 'balance.low is sent when a debit takes a balance below it';
+
+ALTER TABLE "webhook_subscriptions"
+    ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "webhook_events"
+(
+    "id"         bigserial PRIMARY KEY,
+    "username"   varchar     NOT NULL,
+    "type"       varchar     NOT NULL,
+    "data"       jsonb       NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON TABLE "webhook_events" IS 'only recorded when the user has a subscription for the event type';
+
+ALTER TABLE "webhook_events"
+    ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "webhook_deliveries"
+(
+    "id"              bigserial PRIMARY KEY,
+    "subscription_id" uuid        NOT NULL,
+    "event_id"        bigint      NOT NULL,
+    "status"          varchar     NOT NULL DEFAULT 'pending',
+    "attempts"        int         NOT NULL DEFAULT 0,
+    "last_error"      varchar     NOT NULL DEFAULT '',
+    "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+    "locked_at"       timestamptz,
+    "created_at"      timestamptz NOT NULL DEFAULT (now()),
+    "updated_at"      timestamptz NOT NULL DEFAULT (now()),
+    CHECK ("status" IN ('pending', 'sending', 'succeeded', 'dead'))
+);
+
+CREATE INDEX ON "webhook_deliveries" ("status", "next_attempt_at");
+
+CREATE INDEX ON "webhook_deliveries" ("subscription_id", "id");
+
+COMMENT ON COLUMN "webhook_deliveries"."status" IS 'pending, sending, succeeded or dead once the attempts are exhausted';
+
+ALTER TABLE "webhook_deliveries"
+    ADD FOREIGN KEY ("subscription_id") REFERENCES "webhook_subscriptions" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "webhook_deliveries"
+    ADD FOREIGN KEY ("event_id") REFERENCES "webhook_events" ("id");
+
+CREATE TABLE "webhook_attempts"
+(
+    "id"            bigserial PRIMARY KEY,
+    "delivery_id"   bigint      NOT NULL,
+    "attempt"       int         NOT NULL,
+    "response_code" int         NOT NULL,
+    "error"         varchar     NOT NULL,
+    "duration_ms"   bigint      NOT NULL,
+    "created_at"    timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "webhook_attempts" ("delivery_id");
+
+COMMENT ON COLUMN "webhook_attempts"."response_code" IS '0 when no response was received';
+
+ALTER TABLE "webhook_attempts"
+    ADD FOREIGN KEY ("delivery_id") REFERENCES "webhook_deliveries" ("id") ON DELETE CASCADE;
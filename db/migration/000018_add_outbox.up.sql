@@ -0,0 +1,20 @@
+CREATE TABLE "outbox_events"
+(
+    "id"           bigserial PRIMARY KEY,
+    "account_id"   bigint      NOT NULL,
+    "type"         varchar     NOT NULL,
+    "payload"      jsonb       NOT NULL,
+    "created_at"   timestamptz NOT NULL DEFAULT (now()),
+    "published_at" timestamptz
+);
+
+CREATE INDEX ON "outbox_events" ("id")
+    WHERE "published_at" IS NULL;
+
+CREATE INDEX ON "outbox_events" ("published_at");
+
+COMMENT ON TABLE "outbox_events" IS 'domain events written in the transaction of the change, published by the outbox relay';
+
+COMMENT ON COLUMN "outbox_events"."account_id" IS 'the events of an account are published in id order';
+
+COMMENT ON COLUMN "outbox_events"."published_at" IS 'null until the relay published the event';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "used_mfa_tokens";
+
+ALTER TABLE "users"
+    DROP COLUMN IF EXISTS "totp_last_step";
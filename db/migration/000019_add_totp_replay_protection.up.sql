@@ -0,0 +1,14 @@
+ALTER TABLE "users"
+    ADD COLUMN "totp_last_step" bigint NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "users"."totp_last_step" IS 'time step of the last accepted TOTP code, a code of this step or an earlier one is refused';
+
+CREATE TABLE "used_mfa_tokens"
+(
+    "id"         uuid PRIMARY KEY,
+    "expired_at" timestamptz NOT NULL
+);
+
+CREATE INDEX ON "used_mfa_tokens" ("expired_at");
+
+COMMENT ON TABLE "used_mfa_tokens" IS 'mfa pending tokens already exchanged for an access token, kept until they expire';
@@ -0,0 +1,2 @@
+ALTER TABLE "interest_accruals"
+    DROP COLUMN IF EXISTS "posted_period";
@@ -0,0 +1,13 @@
+ALTER TABLE "interest_accruals"
+    ADD COLUMN "posted_period" date;
+
+COMMENT ON COLUMN "interest_accruals"."posted_period" IS 'period of the posting that credited the accrual, null until it is posted';
+
+UPDATE "interest_accruals" a
+SET "posted_period" = p."period"
+FROM "interest_postings" p
+WHERE p."account_id" = a."account_id"
+  AND a."accrual_date" >= p."period"
+  AND a."accrual_date" < p."period" + interval '1 month';
+
+CREATE INDEX ON "interest_accruals" ("account_id") WHERE "posted_period" IS NULL;
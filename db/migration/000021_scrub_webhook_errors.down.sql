@@ -0,0 +1 @@
+-- the scrubbed errors cannot be restored
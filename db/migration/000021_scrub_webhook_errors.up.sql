@@ -0,0 +1,12 @@
+-- the errors of the attempts were the raw errors of the requests, which show the addresses and ports
+-- the worker can reach; keep only the response statuses and the secret errors
+UPDATE "webhook_attempts"
+SET "error" = 'request failed'
+WHERE "error" <> ''
+  AND "error" NOT LIKE 'unexpected status %';
+
+UPDATE "webhook_deliveries"
+SET "last_error" = 'request failed'
+WHERE "last_error" <> ''
+  AND "last_error" NOT LIKE 'unexpected status %'
+  AND "last_error" NOT LIKE 'cannot open signing secret%';
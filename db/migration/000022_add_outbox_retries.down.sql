@@ -0,0 +1,8 @@
+ALTER TABLE "outbox_events"
+    DROP COLUMN IF EXISTS "dead_at",
+    DROP COLUMN IF EXISTS "next_attempt_at",
+    DROP COLUMN IF EXISTS "last_error",
+    DROP COLUMN IF EXISTS "attempts";
+
+CREATE INDEX IF NOT EXISTS "outbox_events_id_idx" ON "outbox_events" ("id")
+    WHERE "published_at" IS NULL;
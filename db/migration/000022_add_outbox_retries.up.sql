@@ -0,0 +1,19 @@
+ALTER TABLE "outbox_events"
+    ADD COLUMN "attempts"        int         NOT NULL DEFAULT 0,
+    ADD COLUMN "last_error"      varchar     NOT NULL DEFAULT '',
+    ADD COLUMN "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+    ADD COLUMN "dead_at"         timestamptz;
+
+COMMENT ON COLUMN "outbox_events"."attempts" IS 'failed attempts to publish the event';
+
+COMMENT ON COLUMN "outbox_events"."next_attempt_at" IS 'the event and the later events of its account wait until then after a failed attempt';
+
+COMMENT ON COLUMN "outbox_events"."dead_at" IS 'set when the attempts are exhausted, the event is then kept but no longer published';
+
+DROP INDEX IF EXISTS "outbox_events_id_idx";
+
+CREATE INDEX ON "outbox_events" ("id")
+    WHERE "published_at" IS NULL AND "dead_at" IS NULL;
+
+CREATE INDEX ON "outbox_events" ("account_id", "id")
+    WHERE "published_at" IS NULL AND "dead_at" IS NULL;
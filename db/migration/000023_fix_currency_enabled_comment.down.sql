@@ -0,0 +1 @@
+COMMENT ON COLUMN "currencies"."enabled" IS 'new accounts and transfers are only accepted in enabled currencies';
@@ -0,0 +1 @@
+COMMENT ON COLUMN "currencies"."enabled" IS 'new accounts are only opened in enabled currencies, the accounts already open keep transferring';
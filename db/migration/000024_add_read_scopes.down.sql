@@ -0,0 +1,2 @@
+UPDATE "api_keys"
+SET "scopes" = array_remove(array_remove("scopes", 'transfers:read'), 'users:read');
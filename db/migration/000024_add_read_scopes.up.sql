@@ -0,0 +1,11 @@
+-- the read-only routes of transfers and users moved to the new read scopes,
+-- the keys that could call them with the write scope keep that access
+UPDATE "api_keys"
+SET "scopes" = array_append("scopes", 'transfers:read')
+WHERE 'transfers:write' = ANY ("scopes")
+  AND NOT 'transfers:read' = ANY ("scopes");
+
+UPDATE "api_keys"
+SET "scopes" = array_append("scopes", 'users:read')
+WHERE 'users:write' = ANY ("scopes")
+  AND NOT 'users:read' = ANY ("scopes");
@@ -1,6 +1,6 @@
 -- name: CreateAccount :one
-INSERT INTO accounts (owner, balance, currency)
-VALUES ($1, $2, $3)
+INSERT INTO accounts (owner, balance, currency, type)
+VALUES ($1, $2, $3, $4)
 RETURNING *;
 
 -- name: GetAccount :one
@@ -15,11 +15,40 @@ FROM accounts
 WHERE id = $1
 LIMIT 1 FOR NO KEY UPDATE;
 
+-- name: ListAccountsByID :many
+SELECT *
+FROM accounts
+WHERE id = ANY (sqlc.arg(ids)::bigint[])
+ORDER BY id;
+
+-- name: ListAccountsForUpdate :many
+-- locks the accounts in ascending id order, so concurrent transactions cannot deadlock on them
+SELECT *
+FROM accounts
+WHERE id = ANY (sqlc.arg(ids)::bigint[])
+ORDER BY id
+FOR NO KEY UPDATE;
+
 -- name: ListAccounts :many
 SELECT *
 FROM accounts
+WHERE owner = $1
 ORDER BY id
-LIMIT $1 OFFSET $2;
+LIMIT $2 OFFSET $3;
+
+-- name: ListAccountBalancesAt :many
+-- balances of the accounts of a type at a point in time, from their current balance minus the later entries
+SELECT a.id,
+       a.currency,
+       (a.balance - COALESCE(SUM(e.amount), 0))::bigint AS balance
+FROM accounts a
+         LEFT JOIN entries e ON e.account_id = a.id AND e.created_at >= sqlc.arg(at)
+WHERE a.type = sqlc.arg(type)
+  AND a.created_at < sqlc.arg(at)
+  AND a.id > sqlc.arg(after_id)
+GROUP BY a.id
+ORDER BY a.id
+LIMIT sqlc.arg(page_size);
 
 -- name: UpdateAccount :one
 UPDATE accounts
@@ -0,0 +1,31 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (id, username, name, prefix, key_hash, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetAPIKeyByPrefix :one
+SELECT sqlc.embed(api_keys), users.role
+FROM api_keys
+         JOIN users ON users.username = api_keys.username
+WHERE api_keys.prefix = $1
+LIMIT 1;
+
+-- name: ListAPIKeys :many
+SELECT *
+FROM api_keys
+WHERE username = $1
+ORDER BY created_at;
+
+-- name: RevokeAPIKey :one
+UPDATE api_keys
+SET revoked_at = now()
+WHERE id = $1
+  AND username = $2
+  AND revoked_at IS NULL
+RETURNING *;
+
+-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = now()
+WHERE id = $1
+  AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
@@ -0,0 +1,18 @@
+-- name: GetCurrency :one
+SELECT *
+FROM currencies
+WHERE code = $1
+LIMIT 1;
+
+-- name: ListCurrencies :many
+SELECT *
+FROM currencies
+ORDER BY code;
+
+-- name: UpsertCurrency :one
+INSERT INTO currencies (code, enabled)
+VALUES ($1, $2)
+ON CONFLICT (code) DO UPDATE
+    SET enabled    = EXCLUDED.enabled,
+        updated_at = now()
+RETURNING *;
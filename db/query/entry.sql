@@ -14,4 +14,36 @@ SELECT *
 from entries
 WHERE account_id = $1
 ORDER BY id
-LIMIT $2 OFFSET $3;
\ No newline at end of file
+LIMIT $2 OFFSET $3;
+
+-- name: CreateTransferEntry :one
+INSERT INTO entries (account_id, amount, transfer_id)
+VALUES (sqlc.arg(account_id), sqlc.arg(amount), sqlc.arg(transfer_id)::bigint)
+RETURNING *;
+
+-- name: GetStatementBalances :one
+SELECT (a.balance - COALESCE(SUM(e.amount), 0))::bigint AS opening_balance,
+       (a.balance - COALESCE(SUM(e.amount) FILTER (WHERE e.created_at >= sqlc.arg(to_time)), 0))::bigint AS closing_balance
+FROM accounts a
+         LEFT JOIN entries e ON e.account_id = a.id AND e.created_at >= sqlc.arg(from_time)
+WHERE a.id = sqlc.arg(account_id)
+GROUP BY a.id;
+
+-- name: ListStatementLines :many
+SELECT e.id,
+       e.amount,
+       e.created_at,
+       e.transfer_id,
+       t.from_account_id,
+       t.to_account_id,
+       e.transfer_group_id,
+       g.description
+FROM entries e
+         LEFT JOIN transfers t ON t.id = e.transfer_id
+         LEFT JOIN transfer_groups g ON g.id = e.transfer_group_id
+WHERE e.account_id = sqlc.arg(account_id)
+  AND e.created_at >= sqlc.arg(from_time)
+  AND e.created_at < sqlc.arg(to_time)
+  AND (e.created_at, e.id) > (sqlc.arg(after_time), sqlc.arg(after_id)::bigint)
+ORDER BY e.created_at, e.id
+LIMIT sqlc.arg(page_size);
@@ -0,0 +1,95 @@
+-- name: GetFeeSchedule :one
+SELECT *
+FROM fee_schedules
+WHERE kind = $1
+  AND currency = $2
+  AND account_type = $3
+LIMIT 1;
+
+-- name: ListFeeSchedules :many
+SELECT *
+FROM fee_schedules
+ORDER BY kind, currency, account_type;
+
+-- name: UpsertFeeSchedule :one
+INSERT INTO fee_schedules (kind, currency, account_type, flat_amount, rate_bps, min_amount, max_amount)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (kind, currency, account_type) DO UPDATE
+    SET flat_amount = EXCLUDED.flat_amount,
+        rate_bps    = EXCLUDED.rate_bps,
+        min_amount  = EXCLUDED.min_amount,
+        max_amount  = EXCLUDED.max_amount,
+        updated_at  = now()
+RETURNING *;
+
+-- name: DeleteFeeSchedule :execrows
+DELETE
+FROM fee_schedules
+WHERE kind = $1
+  AND currency = $2
+  AND account_type = $3;
+
+-- name: GetActiveFeeWaiver :one
+SELECT *
+FROM fee_waivers
+WHERE username = $1
+  AND kind = $2
+  AND (expires_at IS NULL OR expires_at > now())
+LIMIT 1;
+
+-- name: ListFeeWaivers :many
+SELECT *
+FROM fee_waivers
+WHERE username = $1
+ORDER BY kind;
+
+-- name: UpsertFeeWaiver :one
+INSERT INTO fee_waivers (username, kind, reason, expires_at, created_by)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (username, kind) DO UPDATE
+    SET reason     = EXCLUDED.reason,
+        expires_at = EXCLUDED.expires_at,
+        created_by = EXCLUDED.created_by,
+        created_at = now()
+RETURNING *;
+
+-- name: DeleteFeeWaiver :execrows
+DELETE
+FROM fee_waivers
+WHERE username = $1
+  AND kind = $2;
+
+-- name: CreateFee :one
+INSERT INTO fees (kind, account_id, amount, transfer_id, charged_transfer_id, period)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetMaintenanceFee :one
+SELECT *
+FROM fees
+WHERE kind = 'maintenance'
+  AND account_id = $1
+  AND period = $2
+LIMIT 1;
+
+-- name: ListMaintenanceFeeAccounts :many
+-- accounts opened before the end of the period whose type and currency have a maintenance fee, after_id pages through them
+SELECT a.id
+FROM accounts a
+         JOIN fee_schedules s
+              ON s.kind = 'maintenance' AND s.currency = a.currency AND s.account_type = a.type
+WHERE a.created_at < sqlc.arg(period_end)
+  AND a.id > sqlc.arg(after_id)
+ORDER BY a.id
+LIMIT sqlc.arg(page_size);
+
+-- name: CreateFeeRun :execrows
+INSERT INTO fee_runs (period)
+VALUES ($1)
+ON CONFLICT DO NOTHING;
+
+-- name: GetLatestFeeRun :one
+SELECT *
+FROM fee_runs
+ORDER BY period DESC
+LIMIT 1;
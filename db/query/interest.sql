@@ -0,0 +1,81 @@
+-- name: GetBankAccount :one
+SELECT *
+FROM bank_accounts
+WHERE purpose = $1
+  AND currency = $2
+LIMIT 1;
+
+-- name: CreateBankAccount :one
+-- finds no row when another transaction opened the account of the purpose and currency first
+INSERT INTO bank_accounts (purpose, currency, account_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (purpose, currency) DO NOTHING
+RETURNING *;
+
+-- name: CreateInterestRun :execrows
+INSERT INTO interest_runs (accrual_date)
+VALUES ($1)
+ON CONFLICT DO NOTHING;
+
+-- name: GetLatestInterestRun :one
+SELECT *
+FROM interest_runs
+ORDER BY accrual_date DESC
+LIMIT 1;
+
+-- name: CreateInterestAccrual :execrows
+-- does nothing when the account already accrued interest on that day, so a rerun of the accrual is harmless
+INSERT INTO interest_accruals (account_id, accrual_date, balance, rate_bps, amount_micros)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT DO NOTHING;
+
+-- name: ListInterestAccruals :many
+SELECT *
+FROM interest_accruals
+WHERE account_id = sqlc.arg(account_id)
+  AND accrual_date >= sqlc.arg(from_date)
+  AND accrual_date < sqlc.arg(to_date)
+ORDER BY accrual_date;
+
+-- name: ListUnpostedInterestAccounts :many
+-- accounts with accruals before to_date not posted yet, after_id pages through them
+SELECT DISTINCT account_id
+FROM interest_accruals
+WHERE posted_period IS NULL
+  AND accrual_date < sqlc.arg(to_date)
+  AND account_id > sqlc.arg(after_id)
+ORDER BY account_id
+LIMIT sqlc.arg(page_size);
+
+-- name: PostInterestAccruals :one
+-- marks the accruals of the account before to_date not posted yet as posted in the period and sums them,
+-- so an accrual recorded after its month was posted is credited by the next posting
+WITH posted AS (
+    UPDATE interest_accruals
+        SET posted_period = sqlc.arg(period)::date
+        WHERE account_id = sqlc.arg(account_id)
+            AND posted_period IS NULL
+            AND accrual_date < sqlc.arg(to_date)
+        RETURNING amount_micros)
+SELECT COALESCE(SUM(amount_micros), 0)::bigint AS amount_micros
+FROM posted;
+
+-- name: GetInterestPosting :one
+SELECT *
+FROM interest_postings
+WHERE account_id = $1
+  AND period = $2
+LIMIT 1;
+
+-- name: GetPreviousInterestPosting :one
+SELECT *
+FROM interest_postings
+WHERE account_id = $1
+  AND period < $2
+ORDER BY period DESC
+LIMIT 1;
+
+-- name: CreateInterestPosting :one
+INSERT INTO interest_postings (account_id, period, accrued_micros, amount, carry_micros, transfer_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
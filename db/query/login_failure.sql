@@ -0,0 +1,44 @@
+-- name: RecordLoginAttempt :one
+-- RecordLoginAttempt counts an attempt as a failure before the credentials are checked,
+-- so concurrent attempts cannot get past the limits. No row is returned while the subject is blocked.
+-- hold_until blocks the subject until the attempt is failed or released.
+INSERT INTO login_failures (scope, subject, failures, last_failed_at, blocked_until)
+VALUES (sqlc.arg(scope), sqlc.arg(subject), 1, now(), sqlc.narg(hold_until))
+ON CONFLICT (scope, subject) DO UPDATE
+    SET failures       = CASE
+                             WHEN login_failures.last_failed_at < sqlc.arg(reset_before)::timestamptz THEN 1
+                             ELSE login_failures.failures + 1
+        END,
+        last_failed_at = now(),
+        blocked_until  = sqlc.narg(hold_until)
+WHERE login_failures.blocked_until IS NULL
+   OR login_failures.blocked_until <= now()
+RETURNING *;
+
+-- name: ReleaseLoginAttempt :exec
+-- ReleaseLoginAttempt takes back a recorded attempt whose credentials were valid.
+UPDATE login_failures
+SET failures      = greatest(failures - 1, 0),
+    blocked_until = CASE WHEN sqlc.arg(release_hold)::bool THEN NULL ELSE blocked_until END
+WHERE scope = sqlc.arg(scope)
+  AND subject = sqlc.arg(subject);
+
+-- name: BlockLogin :exec
+UPDATE login_failures
+SET blocked_until = sqlc.arg(blocked_until)
+WHERE scope = sqlc.arg(scope)
+  AND subject = sqlc.arg(subject);
+
+-- name: GetLoginBlock :one
+SELECT *
+FROM login_failures
+WHERE ((scope = 'username' AND subject = sqlc.arg(username)) OR (scope = 'ip' AND subject = sqlc.arg(ip)))
+  AND blocked_until > now()
+ORDER BY blocked_until DESC
+LIMIT 1;
+
+-- name: DeleteLoginFailures :execrows
+DELETE
+FROM login_failures
+WHERE scope = $1
+  AND subject = $2;
@@ -0,0 +1,11 @@
+-- name: UseMFAToken :execrows
+-- UseMFAToken marks an mfa pending token as exchanged and reports 0 rows if it already was.
+-- The tokens that expired are dropped on the way, they are refused anyway.
+WITH expired AS (
+    DELETE FROM used_mfa_tokens
+    WHERE expired_at < now()
+)
+INSERT
+INTO used_mfa_tokens (id, expired_at)
+VALUES ($1, $2)
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,44 @@
+-- name: CreateOutboxEvent :exec
+INSERT INTO outbox_events (account_id, type, payload)
+VALUES ($1, $2, $3);
+
+-- name: TryLockOutboxRelay :one
+-- TryLockOutboxRelay takes the lock of the outbox relay until the end of the transaction,
+-- it returns false when another relay holds it.
+SELECT pg_try_advisory_xact_lock(hashtext('outbox_relay'))::bool AS locked;
+
+-- name: ListUnpublishedOutboxEvents :many
+-- the events to publish in id order. The events of an account wait behind its first pending event until that one
+-- is due again, so an account that keeps failing does not fill the batches and hold back the other accounts.
+SELECT e.*
+FROM outbox_events e
+WHERE e.published_at IS NULL
+  AND e.dead_at IS NULL
+  AND NOT EXISTS (SELECT 1
+                  FROM outbox_events b
+                  WHERE b.account_id = e.account_id
+                    AND b.id <= e.id
+                    AND b.published_at IS NULL
+                    AND b.dead_at IS NULL
+                    AND b.next_attempt_at > now())
+ORDER BY e.id
+LIMIT $1;
+
+-- name: FailOutboxEvent :exec
+-- counts a failed attempt, the event is tried again at next_attempt_at unless it is dead-lettered
+UPDATE outbox_events
+SET attempts        = attempts + 1,
+    last_error      = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    dead_at         = CASE WHEN sqlc.arg(dead)::bool THEN now() END
+WHERE id = sqlc.arg(id);
+
+-- name: MarkOutboxEventsPublished :exec
+UPDATE outbox_events
+SET published_at = now()
+WHERE id = ANY (sqlc.arg(ids)::bigint[]);
+
+-- name: DeletePublishedOutboxEvents :execrows
+DELETE
+FROM outbox_events
+WHERE published_at < sqlc.arg(published_before)::timestamptz;
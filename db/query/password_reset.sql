@@ -0,0 +1,27 @@
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (username, token_hash, expired_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: UsePasswordReset :one
+UPDATE password_resets
+SET is_used = TRUE
+WHERE token_hash = $1
+  AND is_used = FALSE
+  AND expired_at > now()
+RETURNING *;
+
+-- name: InvalidatePasswordResets :exec
+UPDATE password_resets
+SET is_used = TRUE
+WHERE username = sqlc.arg(username)
+  AND is_used = FALSE
+  AND created_at <= sqlc.arg(issued_before);
+
+-- name: GetPasswordResetUser :one
+SELECT users.*
+FROM password_resets
+         JOIN users ON users.username = password_resets.username
+WHERE password_resets.token_hash = $1
+  AND password_resets.is_used = FALSE
+  AND password_resets.expired_at > now();
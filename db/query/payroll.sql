@@ -0,0 +1,47 @@
+-- name: CreatePayrollBatch :one
+INSERT INTO payroll_batches (from_account_id, created_by, atomic)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetPayrollBatch :one
+SELECT *
+FROM payroll_batches
+WHERE id = $1
+LIMIT 1;
+
+-- name: CompletePayrollBatch :one
+UPDATE payroll_batches
+SET status       = $2,
+    completed_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: CreatePayrollRow :one
+INSERT INTO payroll_rows (batch_id, row_number, to_account_id, amount, currency, reference)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListPayrollRows :many
+SELECT *
+FROM payroll_rows
+WHERE batch_id = $1
+ORDER BY row_number;
+
+-- name: CompletePayrollRow :one
+UPDATE payroll_rows
+SET status      = 'completed',
+    error       = '',
+    transfer_id = sqlc.arg(transfer_id)::bigint
+WHERE batch_id = sqlc.arg(batch_id)
+  AND row_number = sqlc.arg(row_number)
+RETURNING *;
+
+-- name: FailPayrollRows :many
+-- marks the rows still pending as failed; a null row_number fails every pending row of the batch
+UPDATE payroll_rows
+SET status = 'failed',
+    error  = sqlc.arg(error)
+WHERE batch_id = sqlc.arg(batch_id)
+  AND status = 'pending'
+  AND (sqlc.narg(row_number)::int IS NULL OR row_number = sqlc.narg(row_number))
+RETURNING *;
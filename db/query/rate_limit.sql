@@ -0,0 +1,22 @@
+-- name: TakeRateLimitToken :one
+-- refills the bucket for the time since its last update, then takes a token if one is left
+INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+VALUES (sqlc.arg(key), sqlc.arg(burst)::float8 - 1, TRUE, now())
+ON CONFLICT (key) DO UPDATE
+    SET tokens     = CASE
+                         WHEN least(sqlc.arg(burst)::float8,
+                                    b.tokens + extract(epoch FROM now() - b.updated_at) * sqlc.arg(rate)::float8) >= 1
+                             THEN least(sqlc.arg(burst)::float8,
+                                        b.tokens + extract(epoch FROM now() - b.updated_at) * sqlc.arg(rate)::float8) - 1
+                         ELSE least(sqlc.arg(burst)::float8,
+                                    b.tokens + extract(epoch FROM now() - b.updated_at) * sqlc.arg(rate)::float8)
+        END,
+        allowed    = least(sqlc.arg(burst)::float8,
+                           b.tokens + extract(epoch FROM now() - b.updated_at) * sqlc.arg(rate)::float8) >= 1,
+        updated_at = now()
+RETURNING tokens, allowed;
+
+-- name: DeleteRateLimitBuckets :execrows
+DELETE
+FROM rate_limit_buckets
+WHERE updated_at < sqlc.arg(updated_before);
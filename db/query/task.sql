@@ -0,0 +1,49 @@
+-- name: CreateTask :one
+INSERT INTO tasks (type, payload, max_attempts, run_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetTask :one
+SELECT *
+FROM tasks
+WHERE id = $1
+LIMIT 1;
+
+-- name: ClaimTasks :many
+UPDATE tasks
+SET status     = 'running',
+    attempts   = attempts + 1,
+    locked_at  = now(),
+    updated_at = now()
+WHERE id IN (SELECT t.id
+             FROM tasks t
+             WHERE (t.status = 'pending' AND t.run_at <= now())
+                OR (t.status = 'running' AND t.locked_at < sqlc.arg(stale_before)::timestamptz)
+             ORDER BY t.run_at
+             LIMIT sqlc.arg(max_tasks) FOR UPDATE SKIP LOCKED)
+RETURNING *;
+
+-- name: CompleteTask :exec
+UPDATE tasks
+SET status     = 'completed',
+    locked_at  = NULL,
+    last_error = '',
+    updated_at = now()
+WHERE id = $1;
+
+-- name: RetryTask :exec
+UPDATE tasks
+SET status     = 'pending',
+    locked_at  = NULL,
+    last_error = sqlc.arg(last_error),
+    run_at     = sqlc.arg(run_at),
+    updated_at = now()
+WHERE id = sqlc.arg(id);
+
+-- name: FailTask :exec
+UPDATE tasks
+SET status     = 'failed',
+    locked_at  = NULL,
+    last_error = sqlc.arg(last_error),
+    updated_at = now()
+WHERE id = sqlc.arg(id);
@@ -0,0 +1,21 @@
+-- name: CreateTransferGroup :one
+INSERT INTO transfer_groups (currency, description)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetTransferGroup :one
+SELECT *
+FROM transfer_groups
+WHERE id = $1
+LIMIT 1;
+
+-- name: CreateGroupEntry :one
+INSERT INTO entries (account_id, amount, transfer_group_id)
+VALUES (sqlc.arg(account_id), sqlc.arg(amount), sqlc.arg(transfer_group_id)::bigint)
+RETURNING *;
+
+-- name: ListGroupEntries :many
+SELECT *
+FROM entries
+WHERE transfer_group_id = sqlc.arg(transfer_group_id)::bigint
+ORDER BY id;
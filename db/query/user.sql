@@ -8,3 +8,53 @@ SELECT *
 FROM users
 WHERE username = $1
 LIMIT 1;
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET is_email_verified = TRUE
+WHERE username = $1
+RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT *
+FROM users
+WHERE email = $1
+LIMIT 1;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET hash_password       = $2,
+    password_changed_at = now()
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUser :one
+UPDATE users
+SET hash_password       = COALESCE(sqlc.narg(hash_password), hash_password),
+    password_changed_at = COALESCE(sqlc.narg(password_changed_at), password_changed_at),
+    full_name           = COALESCE(sqlc.narg(full_name), full_name),
+    email               = COALESCE(sqlc.narg(email), email),
+    is_email_verified   = COALESCE(sqlc.narg(is_email_verified), is_email_verified)
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: SetUserTOTPSecret :one
+UPDATE users
+SET totp_secret  = $2,
+    totp_enabled = FALSE
+WHERE username = $1
+RETURNING *;
+
+-- name: EnableUserTOTP :one
+UPDATE users
+SET totp_enabled = TRUE
+WHERE username = $1
+  AND totp_secret IS NOT NULL
+RETURNING *;
+
+-- name: UseTOTPStep :execrows
+-- UseTOTPStep accepts a TOTP code of the step only once, and never after a code of a later step.
+UPDATE users
+SET totp_last_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username)
+  AND totp_last_step < sqlc.arg(step);
@@ -0,0 +1,124 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (id, username, url, event_types, secret, low_balance_threshold)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetWebhookSubscription :one
+SELECT *
+FROM webhook_subscriptions
+WHERE id = $1
+LIMIT 1;
+
+-- name: ListWebhookSubscriptions :many
+SELECT *
+FROM webhook_subscriptions
+WHERE username = $1
+ORDER BY created_at;
+
+-- name: DeleteWebhookSubscription :execrows
+DELETE
+FROM webhook_subscriptions
+WHERE id = $1
+  AND username = $2;
+
+-- name: CreateWebhookEvent :execrows
+-- CreateWebhookEvent records the event and a delivery to every subscription of the user to its type.
+-- Nothing is written when there is none. The balances are only given for balance.low,
+-- which only goes to the subscriptions whose threshold the balance fell below.
+WITH subscriptions AS (SELECT s.id
+                       FROM webhook_subscriptions s
+                       WHERE s.username = sqlc.arg(username)
+                         AND sqlc.arg(type)::varchar = ANY (s.event_types)
+                         AND (sqlc.narg(balance_before)::bigint IS NULL
+                           OR (s.low_balance_threshold <= sqlc.narg(balance_before)::bigint
+                               AND s.low_balance_threshold > sqlc.narg(balance_after)::bigint))),
+     event AS (
+         INSERT INTO webhook_events (username, type, data)
+             SELECT sqlc.arg(username), sqlc.arg(type), sqlc.arg(data)::jsonb
+             WHERE EXISTS (SELECT 1 FROM subscriptions)
+             RETURNING id)
+INSERT
+INTO webhook_deliveries (subscription_id, event_id)
+SELECT subscriptions.id, event.id
+FROM subscriptions,
+     event;
+
+-- name: GetWebhookEvent :one
+SELECT *
+FROM webhook_events
+WHERE id = $1
+LIMIT 1;
+
+-- name: GetWebhookDelivery :one
+SELECT *
+FROM webhook_deliveries
+WHERE id = $1
+LIMIT 1;
+
+-- name: ListWebhookDeliveries :many
+SELECT sqlc.embed(webhook_deliveries), webhook_events.type AS event_type
+FROM webhook_deliveries
+         JOIN webhook_events ON webhook_events.id = webhook_deliveries.event_id
+WHERE webhook_deliveries.subscription_id = sqlc.arg(subscription_id)
+  AND (sqlc.narg(status)::varchar IS NULL OR webhook_deliveries.status = sqlc.narg(status))
+ORDER BY webhook_deliveries.id DESC
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
+
+-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET status     = 'sending',
+    attempts   = attempts + 1,
+    locked_at  = now(),
+    updated_at = now()
+WHERE id IN (SELECT d.id
+             FROM webhook_deliveries d
+             WHERE (d.status = 'pending' AND d.next_attempt_at <= now())
+                OR (d.status = 'sending' AND d.locked_at < sqlc.arg(stale_before)::timestamptz)
+             ORDER BY d.next_attempt_at
+             LIMIT sqlc.arg(max_deliveries) FOR UPDATE SKIP LOCKED)
+RETURNING *;
+
+-- name: CompleteWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status     = 'succeeded',
+    locked_at  = NULL,
+    last_error = '',
+    updated_at = now()
+WHERE id = $1;
+
+-- name: RetryWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status          = 'pending',
+    locked_at       = NULL,
+    last_error      = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    updated_at      = now()
+WHERE id = sqlc.arg(id);
+
+-- name: DeadLetterWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status     = 'dead',
+    locked_at  = NULL,
+    last_error = sqlc.arg(last_error),
+    updated_at = now()
+WHERE id = sqlc.arg(id);
+
+-- name: ReplayWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status          = 'pending',
+    attempts        = 0,
+    next_attempt_at = now(),
+    updated_at      = now()
+WHERE id = $1
+  AND status IN ('succeeded', 'dead')
+RETURNING *;
+
+-- name: CreateWebhookAttempt :exec
+INSERT INTO webhook_attempts (delivery_id, attempt, response_code, error, duration_ms)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ListWebhookAttempts :many
+SELECT *
+FROM webhook_attempts
+WHERE delivery_id = $1
+ORDER BY id;
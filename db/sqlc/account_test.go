@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 	"time"
 
@@ -10,13 +9,14 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func createRandomAccount(t *testing.T) Account {
+func createRandomAccount(t testing.TB) Account {
 	var user = createRandomUser(t)
 
 	var arg = CreateAccountParams{
 		Owner:    user.Username,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
+		Type:     AccountTypeChecking,
 	}
 	var account, err = testQueries.CreateAccount(context.Background(), arg)
 	require.NoError(t, err)
@@ -25,6 +25,7 @@ func createRandomAccount(t *testing.T) Account {
 	require.Equal(t, arg.Owner, account.Owner)
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
+	require.Equal(t, arg.Type, account.Type)
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
 	return account
@@ -72,25 +73,33 @@ func TestDeleteAccount(t *testing.T) {
 
 	var account2, err2 = testQueries.GetAccount(context.Background(), account1.ID)
 	require.Error(t, err2)
-	require.EqualError(t, err2, sql.ErrNoRows.Error())
+	require.EqualError(t, err2, ErrRecordNotFound.Error())
 	require.Empty(t, account2)
 }
 
 func TestListAccount(t *testing.T) {
-	for i := 0; i < 100; i++ {
-		createRandomAccount(t)
+	var user = createRandomUser(t)
+	for _, currency := range []string{util.USD, util.EUR, util.CAD, util.CNY} {
+		var _, err = testQueries.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:    user.Username,
+			Currency: currency,
+			Type:     AccountTypeChecking,
+		})
+		require.NoError(t, err)
 	}
+	createRandomAccount(t)
 
 	var arg = ListAccountsParams{
-		Limit:  50,
-		Offset: 50,
+		Owner:  user.Username,
+		Limit:  3,
+		Offset: 1,
 	}
 
 	var accounts, err = testQueries.ListAccounts(context.Background(), arg)
 	require.NoError(t, err)
-	require.Len(t, accounts, 50)
+	require.Len(t, accounts, 3)
 
 	for _, account := range accounts {
-		require.NotEmpty(t, account)
+		require.Equal(t, user.Username, account.Owner)
 	}
 }
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Types of an account.
+const (
+	AccountTypeChecking = "checking"
+	AccountTypeSavings  = "savings"
+	// AccountTypeInternal accounts belong to the bank, which books its own income and expenses on them.
+	AccountTypeInternal = "internal"
+)
+
+// BankUsername owns the internal accounts of the bank.
+const BankUsername = "simplebank"
+
+// ErrInsufficientFunds is returned when a transfer would overdraw an account whose type does not allow it.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// AccountTypeRules are what an account may do depending on its type.
+type AccountTypeRules struct {
+	// Openable accounts can be opened by users.
+	Openable bool
+	// Overdraft lets the balance go below zero.
+	Overdraft bool
+	// EarnsInterest accounts accrue interest daily at the savings rate of their currency.
+	EarnsInterest bool
+}
+
+var accountTypeRules = map[string]AccountTypeRules{
+	AccountTypeChecking: {Openable: true, Overdraft: true},
+	AccountTypeSavings:  {Openable: true, EarnsInterest: true},
+	AccountTypeInternal: {Overdraft: true},
+}
+
+// RulesOf returns the rules of an account type, which are all false for an unknown type.
+func RulesOf(accountType string) AccountTypeRules {
+	return accountTypeRules[accountType]
+}
+
+// checkBalance fails with ErrInsufficientFunds when the account went below zero without being allowed to.
+func checkBalance(account Account) error {
+	if account.Balance < 0 && !RulesOf(account.Type).Overdraft {
+		return fmt.Errorf("%w: %s account %d", ErrInsufficientFunds, account.Type, account.ID)
+	}
+	return nil
+}
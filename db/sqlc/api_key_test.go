@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAPIKey(t *testing.T, user User) ApiKey {
+	var key, prefix, err = util.RandomAPIKey()
+	require.NoError(t, err)
+
+	var arg = CreateAPIKeyParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		Name:      util.RandomString(8),
+		Prefix:    prefix,
+		KeyHash:   util.HashSecret(key),
+		Scopes:    []string{"accounts:read", "transfers:write"},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	apiKey, err := testQueries.CreateAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, apiKey.ID)
+	require.Equal(t, arg.Prefix, apiKey.Prefix)
+	require.Equal(t, arg.KeyHash, apiKey.KeyHash)
+	require.Equal(t, arg.Scopes, apiKey.Scopes)
+	require.WithinDuration(t, arg.ExpiresAt, apiKey.ExpiresAt, time.Second)
+	require.False(t, apiKey.LastUsedAt.Valid)
+	require.False(t, apiKey.RevokedAt.Valid)
+
+	return apiKey
+}
+
+func TestGetAPIKeyByPrefix(t *testing.T) {
+	var user = createRandomUser(t)
+	var apiKey = createRandomAPIKey(t, user)
+
+	var row, err = testQueries.GetAPIKeyByPrefix(context.Background(), apiKey.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, row.ApiKey.ID)
+	require.Equal(t, apiKey.Scopes, row.ApiKey.Scopes)
+	require.Equal(t, user.Role, row.Role)
+}
+
+func TestListAPIKeys(t *testing.T) {
+	var user = createRandomUser(t)
+	for range 3 {
+		createRandomAPIKey(t, user)
+	}
+
+	var apiKeys, err = testQueries.ListAPIKeys(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 3)
+	for _, apiKey := range apiKeys {
+		require.Equal(t, user.Username, apiKey.Username)
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	var user = createRandomUser(t)
+	var apiKey = createRandomAPIKey(t, user)
+
+	// only the owner's keys can be revoked
+	var _, err = testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{
+		ID:       apiKey.ID,
+		Username: util.RandomOwner(),
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	revoked, err := testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{
+		ID:       apiKey.ID,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.True(t, revoked.RevokedAt.Valid)
+
+	_, err = testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{
+		ID:       apiKey.ID,
+		Username: user.Username,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	var user = createRandomUser(t)
+	var apiKey = createRandomAPIKey(t, user)
+
+	var err = testQueries.TouchAPIKey(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+
+	row, err := testQueries.GetAPIKeyByPrefix(context.Background(), apiKey.Prefix)
+	require.NoError(t, err)
+	require.True(t, row.ApiKey.LastUsedAt.Valid)
+	require.WithinDuration(t, time.Now(), row.ApiKey.LastUsedAt.Time, time.Second)
+}
@@ -0,0 +1,71 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: currency.sql
+
+package db
+
+import (
+	"context"
+)
+
+const getCurrency = `-- name: GetCurrency :one
+SELECT code, enabled, updated_at
+FROM currencies
+WHERE code = $1
+LIMIT 1
+`
+
+func (q *Queries) GetCurrency(ctx context.Context, code string) (Currency, error) {
+	row := q.db.QueryRow(ctx, getCurrency, code)
+	var i Currency
+	err := row.Scan(&i.Code, &i.Enabled, &i.UpdatedAt)
+	return i, err
+}
+
+const listCurrencies = `-- name: ListCurrencies :many
+SELECT code, enabled, updated_at
+FROM currencies
+ORDER BY code
+`
+
+func (q *Queries) ListCurrencies(ctx context.Context) ([]Currency, error) {
+	rows, err := q.db.Query(ctx, listCurrencies)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Currency{}
+	for rows.Next() {
+		var i Currency
+		if err := rows.Scan(&i.Code, &i.Enabled, &i.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertCurrency = `-- name: UpsertCurrency :one
+INSERT INTO currencies (code, enabled)
+VALUES ($1, $2)
+ON CONFLICT (code) DO UPDATE
+    SET enabled    = EXCLUDED.enabled,
+        updated_at = now()
+RETURNING code, enabled, updated_at
+`
+
+type UpsertCurrencyParams struct {
+	Code    string `json:"code"`
+	Enabled bool   `json:"enabled"`
+}
+
+func (q *Queries) UpsertCurrency(ctx context.Context, arg UpsertCurrencyParams) (Currency, error) {
+	row := q.db.QueryRow(ctx, upsertCurrency, arg.Code, arg.Enabled)
+	var i Currency
+	err := row.Scan(&i.Code, &i.Enabled, &i.UpdatedAt)
+	return i, err
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeededCurrencies(t *testing.T) {
+	for _, code := range []string{util.USD, util.EUR, util.CAD, util.CNY} {
+		var currency, err = testQueries.GetCurrency(context.Background(), code)
+		require.NoError(t, err)
+		require.True(t, currency.Enabled)
+	}
+
+	var _, err = testQueries.GetCurrency(context.Background(), "XXX")
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUpsertCurrency(t *testing.T) {
+	var disabled, err = testQueries.UpsertCurrency(context.Background(), UpsertCurrencyParams{Code: "JPY", Enabled: false})
+	require.NoError(t, err)
+	require.Equal(t, "JPY", disabled.Code)
+	require.False(t, disabled.Enabled)
+
+	enabled, err := testQueries.UpsertCurrency(context.Background(), UpsertCurrencyParams{Code: "JPY", Enabled: true})
+	require.NoError(t, err)
+	require.True(t, enabled.Enabled)
+	require.False(t, enabled.UpdatedAt.Before(disabled.UpdatedAt))
+
+	currencies, err := testQueries.ListCurrencies(context.Background())
+	require.NoError(t, err)
+	var codes []string
+	for _, currency := range currencies {
+		codes = append(codes, currency.Code)
+	}
+	require.IsIncreasing(t, codes)
+	require.Contains(t, codes, "JPY")
+}
+
+func TestAccountCurrencyMustExist(t *testing.T) {
+	var user = createRandomUser(t)
+	var _, err = testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: "XXX",
+		Type:     AccountTypeChecking,
+	})
+	require.Equal(t, ForeignKeyViolation, ErrorCode(err))
+}
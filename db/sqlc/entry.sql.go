@@ -7,12 +7,15 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (account_id, amount)
 VALUES ($1, $2)
-RETURNING id, account_id, amount, created_at
+RETURNING id, account_id, amount, created_at, transfer_group_id, transfer_id
 `
 
 type CreateEntryParams struct {
@@ -21,38 +24,95 @@ type CreateEntryParams struct {
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRow(ctx, createEntry, arg.AccountID, arg.Amount)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferGroupID,
+		&i.TransferID,
+	)
+	return i, err
+}
+
+const createTransferEntry = `-- name: CreateTransferEntry :one
+INSERT INTO entries (account_id, amount, transfer_id)
+VALUES ($1, $2, $3::bigint)
+RETURNING id, account_id, amount, created_at, transfer_group_id, transfer_id
+`
+
+type CreateTransferEntryParams struct {
+	AccountID  int64 `json:"account_id"`
+	Amount     int64 `json:"amount"`
+	TransferID int64 `json:"transfer_id"`
+}
+
+func (q *Queries) CreateTransferEntry(ctx context.Context, arg CreateTransferEntryParams) (Entry, error) {
+	row := q.db.QueryRow(ctx, createTransferEntry, arg.AccountID, arg.Amount, arg.TransferID)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.TransferGroupID,
+		&i.TransferID,
 	)
 	return i, err
 }
 
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at
+SELECT id, account_id, amount, created_at, transfer_group_id, transfer_id
 from entries
 WHERE id = $1
 LIMIT 1
 `
 
 func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, getEntry, id)
+	row := q.db.QueryRow(ctx, getEntry, id)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferGroupID,
+		&i.TransferID,
 	)
 	return i, err
 }
 
+const getStatementBalances = `-- name: GetStatementBalances :one
+SELECT (a.balance - COALESCE(SUM(e.amount), 0))::bigint AS opening_balance,
+       (a.balance - COALESCE(SUM(e.amount) FILTER (WHERE e.created_at >= $1), 0))::bigint AS closing_balance
+FROM accounts a
+         LEFT JOIN entries e ON e.account_id = a.id AND e.created_at >= $2
+WHERE a.id = $3
+GROUP BY a.id
+`
+
+type GetStatementBalancesParams struct {
+	ToTime    time.Time `json:"to_time"`
+	FromTime  time.Time `json:"from_time"`
+	AccountID int64     `json:"account_id"`
+}
+
+type GetStatementBalancesRow struct {
+	OpeningBalance int64 `json:"opening_balance"`
+	ClosingBalance int64 `json:"closing_balance"`
+}
+
+func (q *Queries) GetStatementBalances(ctx context.Context, arg GetStatementBalancesParams) (GetStatementBalancesRow, error) {
+	row := q.db.QueryRow(ctx, getStatementBalances, arg.ToTime, arg.FromTime, arg.AccountID)
+	var i GetStatementBalancesRow
+	err := row.Scan(&i.OpeningBalance, &i.ClosingBalance)
+	return i, err
+}
+
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at
+SELECT id, account_id, amount, created_at, transfer_group_id, transfer_id
 from entries
 WHERE account_id = $1
 ORDER BY id
@@ -66,7 +126,7 @@ type ListEntriesParams struct {
 }
 
 func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
-	rows, err := q.db.QueryContext(ctx, listEntries, arg.AccountID, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listEntries, arg.AccountID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -79,14 +139,89 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.TransferGroupID,
+			&i.TransferID,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	return items, nil
+}
+
+const listStatementLines = `-- name: ListStatementLines :many
+SELECT e.id,
+       e.amount,
+       e.created_at,
+       e.transfer_id,
+       t.from_account_id,
+       t.to_account_id,
+       e.transfer_group_id,
+       g.description
+FROM entries e
+         LEFT JOIN transfers t ON t.id = e.transfer_id
+         LEFT JOIN transfer_groups g ON g.id = e.transfer_group_id
+WHERE e.account_id = $1
+  AND e.created_at >= $2
+  AND e.created_at < $3
+  AND (e.created_at, e.id) > ($4, $5::bigint)
+ORDER BY e.created_at, e.id
+LIMIT $6
+`
+
+type ListStatementLinesParams struct {
+	AccountID int64     `json:"account_id"`
+	FromTime  time.Time `json:"from_time"`
+	ToTime    time.Time `json:"to_time"`
+	AfterTime time.Time `json:"after_time"`
+	AfterID   int64     `json:"after_id"`
+	PageSize  int32     `json:"page_size"`
+}
+
+type ListStatementLinesRow struct {
+	ID              int64       `json:"id"`
+	Amount          int64       `json:"amount"`
+	CreatedAt       time.Time   `json:"created_at"`
+	TransferID      pgtype.Int8 `json:"transfer_id"`
+	FromAccountID   pgtype.Int8 `json:"from_account_id"`
+	ToAccountID     pgtype.Int8 `json:"to_account_id"`
+	TransferGroupID pgtype.Int8 `json:"transfer_group_id"`
+	Description     pgtype.Text `json:"description"`
+}
+
+func (q *Queries) ListStatementLines(ctx context.Context, arg ListStatementLinesParams) ([]ListStatementLinesRow, error) {
+	rows, err := q.db.Query(ctx, listStatementLines,
+		arg.AccountID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListStatementLinesRow{}
+	for rows.Next() {
+		var i ListStatementLinesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransferID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.TransferGroupID,
+			&i.Description,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,28 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PostgreSQL error codes handled by the store and the API, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	ForeignKeyViolation  = "23503"
+	UniqueViolation      = "23505"
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
+)
+
+// ErrRecordNotFound is returned by the :one queries when no row matches.
+var ErrRecordNotFound = pgx.ErrNoRows
+
+// ErrorCode returns the SQLSTATE code of a PostgreSQL error, or an empty string for any other error.
+func ErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
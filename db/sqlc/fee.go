@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Kinds of a fee.
+const (
+	// FeeKindTransfer is charged to the sender of a transfer, on the transferred amount.
+	FeeKindTransfer = "transfer"
+	// FeeKindMaintenance is charged to every account once a month. Only its flat and minimum amounts apply.
+	FeeKindMaintenance = "maintenance"
+)
+
+// BankPurposeFeeIncome is the internal account the fees are booked on.
+const BankPurposeFeeIncome = "fee_income"
+
+// Compute returns the fee on an amount: the flat amount plus the rate of the amount,
+// raised to the minimum and capped at the maximum.
+func (schedule FeeSchedule) Compute(amount int64) (int64, error) {
+	var fee, err = util.ApplyBasisPoints(amount, schedule.RateBps)
+	if err != nil {
+		return 0, err
+	}
+	if fee > math.MaxInt64-schedule.FlatAmount {
+		return 0, fmt.Errorf("%w: fee on %d", util.ErrAmountOverflow, amount)
+	}
+	fee = max(fee+schedule.FlatAmount, schedule.MinAmount)
+	if schedule.MaxAmount.Valid {
+		fee = min(fee, schedule.MaxAmount.Int64)
+	}
+	return fee, nil
+}
+
+// FeeCharge is a fee taken from an account and moved to the fee income account of the bank.
+type FeeCharge struct {
+	Fee      Fee      `json:"fee"`
+	Transfer Transfer `json:"transfer"`
+	// Entry is the debit of the charged account.
+	Entry Entry `json:"entry"`
+}
+
+// feeFor returns the fee of a kind the account owes on an amount,
+// zero when its type and currency have no fee schedule or its owner has a waiver.
+func feeFor(ctx context.Context, queries *Queries, kind string, account Account, amount int64) (int64, error) {
+	var schedule, err = queries.GetFeeSchedule(ctx, GetFeeScheduleParams{
+		Kind:        kind,
+		Currency:    account.Currency,
+		AccountType: account.Type,
+	})
+	if errors.Is(err, ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = queries.GetActiveFeeWaiver(ctx, GetActiveFeeWaiverParams{Username: account.Owner, Kind: kind})
+	if err == nil {
+		return 0, nil
+	}
+	if !errors.Is(err, ErrRecordNotFound) {
+		return 0, err
+	}
+	return schedule.Compute(amount)
+}
+
+// chargeFee transfers a fee from the account to the fee income account of the bank and records it.
+// It returns the charge and the account with the fee deducted.
+func chargeFee(
+	ctx context.Context,
+	queries *Queries,
+	account Account,
+	kind string,
+	amount int64,
+	chargedTransferID pgtype.Int8,
+	period pgtype.Date,
+) (FeeCharge, Account, error) {
+	var income, err = bankAccount(ctx, queries, BankPurposeFeeIncome, account.Currency)
+	if err != nil {
+		return FeeCharge{}, account, err
+	}
+	result, err := transfer(ctx, queries, TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   income.ID,
+		Amount:        amount,
+	})
+	if err != nil {
+		return FeeCharge{}, account, err
+	}
+
+	fee, err := queries.CreateFee(ctx, CreateFeeParams{
+		Kind:              kind,
+		AccountID:         account.ID,
+		Amount:            amount,
+		TransferID:        result.Transfer.ID,
+		ChargedTransferID: chargedTransferID,
+		Period:            period,
+	})
+	if err != nil {
+		return FeeCharge{}, account, err
+	}
+	return FeeCharge{Fee: fee, Transfer: result.Transfer, Entry: result.FromEntry}, result.FromAccount, nil
+}
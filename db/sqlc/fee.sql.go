@@ -0,0 +1,402 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: fee.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createFee = `-- name: CreateFee :one
+INSERT INTO fees (kind, account_id, amount, transfer_id, charged_transfer_id, period)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, kind, account_id, amount, transfer_id, charged_transfer_id, period, created_at
+`
+
+type CreateFeeParams struct {
+	Kind              string      `json:"kind"`
+	AccountID         int64       `json:"account_id"`
+	Amount            int64       `json:"amount"`
+	TransferID        int64       `json:"transfer_id"`
+	ChargedTransferID pgtype.Int8 `json:"charged_transfer_id"`
+	Period            pgtype.Date `json:"period"`
+}
+
+func (q *Queries) CreateFee(ctx context.Context, arg CreateFeeParams) (Fee, error) {
+	row := q.db.QueryRow(ctx, createFee,
+		arg.Kind,
+		arg.AccountID,
+		arg.Amount,
+		arg.TransferID,
+		arg.ChargedTransferID,
+		arg.Period,
+	)
+	var i Fee
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.AccountID,
+		&i.Amount,
+		&i.TransferID,
+		&i.ChargedTransferID,
+		&i.Period,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createFeeRun = `-- name: CreateFeeRun :execrows
+INSERT INTO fee_runs (period)
+VALUES ($1)
+ON CONFLICT DO NOTHING
+`
+
+func (q *Queries) CreateFeeRun(ctx context.Context, period time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, createFeeRun, period)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteFeeSchedule = `-- name: DeleteFeeSchedule :execrows
+DELETE
+FROM fee_schedules
+WHERE kind = $1
+  AND currency = $2
+  AND account_type = $3
+`
+
+type DeleteFeeScheduleParams struct {
+	Kind        string `json:"kind"`
+	Currency    string `json:"currency"`
+	AccountType string `json:"account_type"`
+}
+
+func (q *Queries) DeleteFeeSchedule(ctx context.Context, arg DeleteFeeScheduleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteFeeSchedule, arg.Kind, arg.Currency, arg.AccountType)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteFeeWaiver = `-- name: DeleteFeeWaiver :execrows
+DELETE
+FROM fee_waivers
+WHERE username = $1
+  AND kind = $2
+`
+
+type DeleteFeeWaiverParams struct {
+	Username string `json:"username"`
+	Kind     string `json:"kind"`
+}
+
+func (q *Queries) DeleteFeeWaiver(ctx context.Context, arg DeleteFeeWaiverParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteFeeWaiver, arg.Username, arg.Kind)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getActiveFeeWaiver = `-- name: GetActiveFeeWaiver :one
+SELECT username, kind, reason, expires_at, created_by, created_at
+FROM fee_waivers
+WHERE username = $1
+  AND kind = $2
+  AND (expires_at IS NULL OR expires_at > now())
+LIMIT 1
+`
+
+type GetActiveFeeWaiverParams struct {
+	Username string `json:"username"`
+	Kind     string `json:"kind"`
+}
+
+func (q *Queries) GetActiveFeeWaiver(ctx context.Context, arg GetActiveFeeWaiverParams) (FeeWaiver, error) {
+	row := q.db.QueryRow(ctx, getActiveFeeWaiver, arg.Username, arg.Kind)
+	var i FeeWaiver
+	err := row.Scan(
+		&i.Username,
+		&i.Kind,
+		&i.Reason,
+		&i.ExpiresAt,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getFeeSchedule = `-- name: GetFeeSchedule :one
+SELECT kind, currency, account_type, flat_amount, rate_bps, min_amount, max_amount, updated_at
+FROM fee_schedules
+WHERE kind = $1
+  AND currency = $2
+  AND account_type = $3
+LIMIT 1
+`
+
+type GetFeeScheduleParams struct {
+	Kind        string `json:"kind"`
+	Currency    string `json:"currency"`
+	AccountType string `json:"account_type"`
+}
+
+func (q *Queries) GetFeeSchedule(ctx context.Context, arg GetFeeScheduleParams) (FeeSchedule, error) {
+	row := q.db.QueryRow(ctx, getFeeSchedule, arg.Kind, arg.Currency, arg.AccountType)
+	var i FeeSchedule
+	err := row.Scan(
+		&i.Kind,
+		&i.Currency,
+		&i.AccountType,
+		&i.FlatAmount,
+		&i.RateBps,
+		&i.MinAmount,
+		&i.MaxAmount,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getLatestFeeRun = `-- name: GetLatestFeeRun :one
+SELECT period, created_at
+FROM fee_runs
+ORDER BY period DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestFeeRun(ctx context.Context) (FeeRun, error) {
+	row := q.db.QueryRow(ctx, getLatestFeeRun)
+	var i FeeRun
+	err := row.Scan(&i.Period, &i.CreatedAt)
+	return i, err
+}
+
+const getMaintenanceFee = `-- name: GetMaintenanceFee :one
+SELECT id, kind, account_id, amount, transfer_id, charged_transfer_id, period, created_at
+FROM fees
+WHERE kind = 'maintenance'
+  AND account_id = $1
+  AND period = $2
+LIMIT 1
+`
+
+type GetMaintenanceFeeParams struct {
+	AccountID int64       `json:"account_id"`
+	Period    pgtype.Date `json:"period"`
+}
+
+func (q *Queries) GetMaintenanceFee(ctx context.Context, arg GetMaintenanceFeeParams) (Fee, error) {
+	row := q.db.QueryRow(ctx, getMaintenanceFee, arg.AccountID, arg.Period)
+	var i Fee
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.AccountID,
+		&i.Amount,
+		&i.TransferID,
+		&i.ChargedTransferID,
+		&i.Period,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listFeeSchedules = `-- name: ListFeeSchedules :many
+SELECT kind, currency, account_type, flat_amount, rate_bps, min_amount, max_amount, updated_at
+FROM fee_schedules
+ORDER BY kind, currency, account_type
+`
+
+func (q *Queries) ListFeeSchedules(ctx context.Context) ([]FeeSchedule, error) {
+	rows, err := q.db.Query(ctx, listFeeSchedules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []FeeSchedule{}
+	for rows.Next() {
+		var i FeeSchedule
+		if err := rows.Scan(
+			&i.Kind,
+			&i.Currency,
+			&i.AccountType,
+			&i.FlatAmount,
+			&i.RateBps,
+			&i.MinAmount,
+			&i.MaxAmount,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeeWaivers = `-- name: ListFeeWaivers :many
+SELECT username, kind, reason, expires_at, created_by, created_at
+FROM fee_waivers
+WHERE username = $1
+ORDER BY kind
+`
+
+func (q *Queries) ListFeeWaivers(ctx context.Context, username string) ([]FeeWaiver, error) {
+	rows, err := q.db.Query(ctx, listFeeWaivers, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []FeeWaiver{}
+	for rows.Next() {
+		var i FeeWaiver
+		if err := rows.Scan(
+			&i.Username,
+			&i.Kind,
+			&i.Reason,
+			&i.ExpiresAt,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMaintenanceFeeAccounts = `-- name: ListMaintenanceFeeAccounts :many
+SELECT a.id
+FROM accounts a
+         JOIN fee_schedules s
+              ON s.kind = 'maintenance' AND s.currency = a.currency AND s.account_type = a.type
+WHERE a.created_at < $1
+  AND a.id > $2
+ORDER BY a.id
+LIMIT $3
+`
+
+type ListMaintenanceFeeAccountsParams struct {
+	PeriodEnd time.Time `json:"period_end"`
+	AfterID   int64     `json:"after_id"`
+	PageSize  int32     `json:"page_size"`
+}
+
+// accounts opened before the end of the period whose type and currency have a maintenance fee, after_id pages through them
+func (q *Queries) ListMaintenanceFeeAccounts(ctx context.Context, arg ListMaintenanceFeeAccountsParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listMaintenanceFeeAccounts, arg.PeriodEnd, arg.AfterID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertFeeSchedule = `-- name: UpsertFeeSchedule :one
+INSERT INTO fee_schedules (kind, currency, account_type, flat_amount, rate_bps, min_amount, max_amount)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (kind, currency, account_type) DO UPDATE
+    SET flat_amount = EXCLUDED.flat_amount,
+        rate_bps    = EXCLUDED.rate_bps,
+        min_amount  = EXCLUDED.min_amount,
+        max_amount  = EXCLUDED.max_amount,
+        updated_at  = now()
+RETURNING kind, currency, account_type, flat_amount, rate_bps, min_amount, max_amount, updated_at
+`
+
+type UpsertFeeScheduleParams struct {
+	Kind        string      `json:"kind"`
+	Currency    string      `json:"currency"`
+	AccountType string      `json:"account_type"`
+	FlatAmount  int64       `json:"flat_amount"`
+	RateBps     int64       `json:"rate_bps"`
+	MinAmount   int64       `json:"min_amount"`
+	MaxAmount   pgtype.Int8 `json:"max_amount"`
+}
+
+func (q *Queries) UpsertFeeSchedule(ctx context.Context, arg UpsertFeeScheduleParams) (FeeSchedule, error) {
+	row := q.db.QueryRow(ctx, upsertFeeSchedule,
+		arg.Kind,
+		arg.Currency,
+		arg.AccountType,
+		arg.FlatAmount,
+		arg.RateBps,
+		arg.MinAmount,
+		arg.MaxAmount,
+	)
+	var i FeeSchedule
+	err := row.Scan(
+		&i.Kind,
+		&i.Currency,
+		&i.AccountType,
+		&i.FlatAmount,
+		&i.RateBps,
+		&i.MinAmount,
+		&i.MaxAmount,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertFeeWaiver = `-- name: UpsertFeeWaiver :one
+INSERT INTO fee_waivers (username, kind, reason, expires_at, created_by)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (username, kind) DO UPDATE
+    SET reason     = EXCLUDED.reason,
+        expires_at = EXCLUDED.expires_at,
+        created_by = EXCLUDED.created_by,
+        created_at = now()
+RETURNING username, kind, reason, expires_at, created_by, created_at
+`
+
+type UpsertFeeWaiverParams struct {
+	Username  string             `json:"username"`
+	Kind      string             `json:"kind"`
+	Reason    string             `json:"reason"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	CreatedBy string             `json:"created_by"`
+}
+
+func (q *Queries) UpsertFeeWaiver(ctx context.Context, arg UpsertFeeWaiverParams) (FeeWaiver, error) {
+	row := q.db.QueryRow(ctx, upsertFeeWaiver,
+		arg.Username,
+		arg.Kind,
+		arg.Reason,
+		arg.ExpiresAt,
+		arg.CreatedBy,
+	)
+	var i FeeWaiver
+	err := row.Scan(
+		&i.Username,
+		&i.Kind,
+		&i.Reason,
+		&i.ExpiresAt,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,252 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// feeCurrency is only used by the fee tests, so the schedules they set do not charge the accounts of other tests.
+const feeCurrency = "CHF"
+
+func setFeeSchedule(t *testing.T, arg UpsertFeeScheduleParams) FeeSchedule {
+	var _, err = testQueries.UpsertCurrency(context.Background(), UpsertCurrencyParams{Code: feeCurrency, Enabled: true})
+	require.NoError(t, err)
+
+	schedule, err := testQueries.UpsertFeeSchedule(context.Background(), arg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		var _, err = testQueries.DeleteFeeSchedule(context.Background(), DeleteFeeScheduleParams{
+			Kind:        arg.Kind,
+			Currency:    arg.Currency,
+			AccountType: arg.AccountType,
+		})
+		require.NoError(t, err)
+	})
+	return schedule
+}
+
+func createFeeAccount(t *testing.T, accountType string, balance int64) Account {
+	var user = createRandomUser(t)
+	var account, err = testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  balance,
+		Currency: feeCurrency,
+		Type:     accountType,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func TestFeeScheduleCompute(t *testing.T) {
+	var schedule = FeeSchedule{
+		FlatAmount: 10,
+		RateBps:    100,
+		MinAmount:  20,
+		MaxAmount:  pgtype.Int8{Int64: 500, Valid: true},
+	}
+	testCases := []struct {
+		amount int64
+		fee    int64
+	}{
+		{amount: 0, fee: 20},
+		{amount: 1_000, fee: 20},
+		{amount: 5_000, fee: 60},
+		{amount: 100_000, fee: 500},
+	}
+	for _, tc := range testCases {
+		var fee, err = schedule.Compute(tc.amount)
+		require.NoError(t, err)
+		require.Equal(t, tc.fee, fee, tc.amount)
+	}
+
+	schedule.MaxAmount = pgtype.Int8{}
+	fee, err := schedule.Compute(100_000)
+	require.NoError(t, err)
+	require.Equal(t, int64(1_010), fee)
+}
+
+func TestTransferTxFee(t *testing.T) {
+	var store = NewStore(testDB)
+	setFeeSchedule(t, UpsertFeeScheduleParams{
+		Kind:        FeeKindTransfer,
+		Currency:    feeCurrency,
+		AccountType: AccountTypeChecking,
+		FlatAmount:  10,
+		RateBps:     100,
+		MinAmount:   20,
+		MaxAmount:   pgtype.Int8{Int64: 500, Valid: true},
+	})
+	var from = createFeeAccount(t, AccountTypeChecking, 10_000)
+	var to = createFeeAccount(t, AccountTypeChecking, 0)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        5_000,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Fees, 1)
+
+	var charge = result.Fees[0]
+	require.Equal(t, FeeKindTransfer, charge.Fee.Kind)
+	require.Equal(t, from.ID, charge.Fee.AccountID)
+	require.Equal(t, int64(60), charge.Fee.Amount)
+	require.Equal(t, result.Transfer.ID, charge.Fee.ChargedTransferID.Int64)
+	require.Equal(t, charge.Transfer.ID, charge.Fee.TransferID)
+	require.Equal(t, int64(-60), charge.Entry.Amount)
+	require.Equal(t, from.ID, charge.Entry.AccountID)
+
+	// the fee is a separate transfer to the fee income account of the bank
+	income, err := testQueries.GetBankAccount(context.Background(), GetBankAccountParams{
+		Purpose:  BankPurposeFeeIncome,
+		Currency: feeCurrency,
+	})
+	require.NoError(t, err)
+	require.Equal(t, income.AccountID, charge.Transfer.ToAccountID)
+	require.Equal(t, int64(10_000-5_000-60), result.FromAccount.Balance)
+	require.Equal(t, int64(5_000), result.ToAccount.Balance)
+
+	// the receiver pays nothing
+	result, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: to.ID,
+		ToAccountID:   from.ID,
+		Amount:        100,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Fees, 1)
+	require.Equal(t, to.ID, result.Fees[0].Fee.AccountID)
+	require.Equal(t, int64(20), result.Fees[0].Fee.Amount)
+}
+
+func TestTransferTxFeeWaived(t *testing.T) {
+	var store = NewStore(testDB)
+	setFeeSchedule(t, UpsertFeeScheduleParams{
+		Kind:        FeeKindTransfer,
+		Currency:    feeCurrency,
+		AccountType: AccountTypeChecking,
+		FlatAmount:  10,
+	})
+	var from = createFeeAccount(t, AccountTypeChecking, 10_000)
+	var to = createFeeAccount(t, AccountTypeChecking, 0)
+
+	var _, err = testQueries.UpsertFeeWaiver(context.Background(), UpsertFeeWaiverParams{
+		Username:  from.Owner,
+		Kind:      FeeKindTransfer,
+		Reason:    "test",
+		CreatedBy: from.Owner,
+	})
+	require.NoError(t, err)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 100})
+	require.NoError(t, err)
+	require.Empty(t, result.Fees)
+	require.Equal(t, int64(9_900), result.FromAccount.Balance)
+
+	// an expired waiver no longer applies
+	_, err = testQueries.UpsertFeeWaiver(context.Background(), UpsertFeeWaiverParams{
+		Username:  from.Owner,
+		Kind:      FeeKindTransfer,
+		Reason:    "test",
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true},
+		CreatedBy: from.Owner,
+	})
+	require.NoError(t, err)
+
+	result, err = store.TransferTx(context.Background(), TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 100})
+	require.NoError(t, err)
+	require.Len(t, result.Fees, 1)
+}
+
+func TestTransferTxFeeOverdrawsSavings(t *testing.T) {
+	var store = NewStore(testDB)
+	setFeeSchedule(t, UpsertFeeScheduleParams{
+		Kind:        FeeKindTransfer,
+		Currency:    feeCurrency,
+		AccountType: AccountTypeSavings,
+		FlatAmount:  10,
+	})
+	var from = createFeeAccount(t, AccountTypeSavings, 100)
+	var to = createFeeAccount(t, AccountTypeChecking, 0)
+
+	var _, err = store.TransferTx(context.Background(), TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 100})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	account, err := store.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), account.Balance)
+}
+
+func TestChargeMaintenanceFeeTx(t *testing.T) {
+	var store = NewStore(testDB)
+	setFeeSchedule(t, UpsertFeeScheduleParams{
+		Kind:        FeeKindMaintenance,
+		Currency:    feeCurrency,
+		AccountType: AccountTypeSavings,
+		FlatAmount:  300,
+	})
+	var rich = createFeeAccount(t, AccountTypeSavings, 1_000)
+	var poor = createFeeAccount(t, AccountTypeSavings, 200)
+	var checking = createFeeAccount(t, AccountTypeChecking, 1_000)
+	var period = time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)
+
+	ids, err := store.ListMaintenanceFeeAccounts(context.Background(), ListMaintenanceFeeAccountsParams{
+		PeriodEnd: time.Now().Add(time.Minute),
+		AfterID:   rich.ID - 1,
+		PageSize:  1000,
+	})
+	require.NoError(t, err)
+	require.Contains(t, ids, rich.ID)
+	require.Contains(t, ids, poor.ID)
+	require.NotContains(t, ids, checking.ID)
+
+	charge, err := store.ChargeMaintenanceFeeTx(context.Background(), ChargeMaintenanceFeeTxParams{AccountID: rich.ID, Period: period})
+	require.NoError(t, err)
+	require.Equal(t, int64(300), charge.Fee.Amount)
+	require.Equal(t, period, charge.Fee.Period.Time)
+
+	// the month is only charged once
+	again, err := store.ChargeMaintenanceFeeTx(context.Background(), ChargeMaintenanceFeeTxParams{AccountID: rich.ID, Period: period})
+	require.NoError(t, err)
+	require.Equal(t, charge.Fee, again.Fee)
+	account, err := store.GetAccount(context.Background(), rich.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(700), account.Balance)
+
+	// a savings account is charged at most its balance
+	charge, err = store.ChargeMaintenanceFeeTx(context.Background(), ChargeMaintenanceFeeTxParams{AccountID: poor.ID, Period: period})
+	require.NoError(t, err)
+	require.Equal(t, int64(200), charge.Fee.Amount)
+
+	// accounts without a schedule are not charged
+	charge, err = store.ChargeMaintenanceFeeTx(context.Background(), ChargeMaintenanceFeeTxParams{AccountID: checking.ID, Period: period})
+	require.NoError(t, err)
+	require.Zero(t, charge.Fee.ID)
+}
+
+func TestCreateFeeRunTx(t *testing.T) {
+	var store = NewStore(testDB)
+	// a month long past, so the test does not move the latest run of the scheduler
+	var period = time.Date(1000, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, int(util.RandomInt(0, 9_000)), 0)
+
+	var calls int
+	var arg = CreateFeeRunTxParams{
+		Period: period,
+		AfterCreate: func(q Querier) error {
+			calls++
+			return nil
+		},
+	}
+	created, err := store.CreateFeeRunTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	created, err = store.CreateFeeRunTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, 1, calls)
+}
@@ -0,0 +1,312 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: interest.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createBankAccount = `-- name: CreateBankAccount :one
+INSERT INTO bank_accounts (purpose, currency, account_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (purpose, currency) DO NOTHING
+RETURNING purpose, currency, account_id
+`
+
+type CreateBankAccountParams struct {
+	Purpose   string `json:"purpose"`
+	Currency  string `json:"currency"`
+	AccountID int64  `json:"account_id"`
+}
+
+// finds no row when another transaction opened the account of the purpose and currency first
+func (q *Queries) CreateBankAccount(ctx context.Context, arg CreateBankAccountParams) (BankAccount, error) {
+	row := q.db.QueryRow(ctx, createBankAccount, arg.Purpose, arg.Currency, arg.AccountID)
+	var i BankAccount
+	err := row.Scan(&i.Purpose, &i.Currency, &i.AccountID)
+	return i, err
+}
+
+const createInterestAccrual = `-- name: CreateInterestAccrual :execrows
+INSERT INTO interest_accruals (account_id, accrual_date, balance, rate_bps, amount_micros)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT DO NOTHING
+`
+
+type CreateInterestAccrualParams struct {
+	AccountID    int64     `json:"account_id"`
+	AccrualDate  time.Time `json:"accrual_date"`
+	Balance      int64     `json:"balance"`
+	RateBps      int64     `json:"rate_bps"`
+	AmountMicros int64     `json:"amount_micros"`
+}
+
+// does nothing when the account already accrued interest on that day, so a rerun of the accrual is harmless
+func (q *Queries) CreateInterestAccrual(ctx context.Context, arg CreateInterestAccrualParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createInterestAccrual,
+		arg.AccountID,
+		arg.AccrualDate,
+		arg.Balance,
+		arg.RateBps,
+		arg.AmountMicros,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const createInterestPosting = `-- name: CreateInterestPosting :one
+INSERT INTO interest_postings (account_id, period, accrued_micros, amount, carry_micros, transfer_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING account_id, period, accrued_micros, amount, carry_micros, transfer_id, created_at
+`
+
+type CreateInterestPostingParams struct {
+	AccountID     int64       `json:"account_id"`
+	Period        time.Time   `json:"period"`
+	AccruedMicros int64       `json:"accrued_micros"`
+	Amount        int64       `json:"amount"`
+	CarryMicros   int64       `json:"carry_micros"`
+	TransferID    pgtype.Int8 `json:"transfer_id"`
+}
+
+func (q *Queries) CreateInterestPosting(ctx context.Context, arg CreateInterestPostingParams) (InterestPosting, error) {
+	row := q.db.QueryRow(ctx, createInterestPosting,
+		arg.AccountID,
+		arg.Period,
+		arg.AccruedMicros,
+		arg.Amount,
+		arg.CarryMicros,
+		arg.TransferID,
+	)
+	var i InterestPosting
+	err := row.Scan(
+		&i.AccountID,
+		&i.Period,
+		&i.AccruedMicros,
+		&i.Amount,
+		&i.CarryMicros,
+		&i.TransferID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createInterestRun = `-- name: CreateInterestRun :execrows
+INSERT INTO interest_runs (accrual_date)
+VALUES ($1)
+ON CONFLICT DO NOTHING
+`
+
+func (q *Queries) CreateInterestRun(ctx context.Context, accrualDate time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, createInterestRun, accrualDate)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getBankAccount = `-- name: GetBankAccount :one
+SELECT purpose, currency, account_id
+FROM bank_accounts
+WHERE purpose = $1
+  AND currency = $2
+LIMIT 1
+`
+
+type GetBankAccountParams struct {
+	Purpose  string `json:"purpose"`
+	Currency string `json:"currency"`
+}
+
+func (q *Queries) GetBankAccount(ctx context.Context, arg GetBankAccountParams) (BankAccount, error) {
+	row := q.db.QueryRow(ctx, getBankAccount, arg.Purpose, arg.Currency)
+	var i BankAccount
+	err := row.Scan(&i.Purpose, &i.Currency, &i.AccountID)
+	return i, err
+}
+
+const getInterestPosting = `-- name: GetInterestPosting :one
+SELECT account_id, period, accrued_micros, amount, carry_micros, transfer_id, created_at
+FROM interest_postings
+WHERE account_id = $1
+  AND period = $2
+LIMIT 1
+`
+
+type GetInterestPostingParams struct {
+	AccountID int64     `json:"account_id"`
+	Period    time.Time `json:"period"`
+}
+
+func (q *Queries) GetInterestPosting(ctx context.Context, arg GetInterestPostingParams) (InterestPosting, error) {
+	row := q.db.QueryRow(ctx, getInterestPosting, arg.AccountID, arg.Period)
+	var i InterestPosting
+	err := row.Scan(
+		&i.AccountID,
+		&i.Period,
+		&i.AccruedMicros,
+		&i.Amount,
+		&i.CarryMicros,
+		&i.TransferID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLatestInterestRun = `-- name: GetLatestInterestRun :one
+SELECT accrual_date, created_at
+FROM interest_runs
+ORDER BY accrual_date DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestInterestRun(ctx context.Context) (InterestRun, error) {
+	row := q.db.QueryRow(ctx, getLatestInterestRun)
+	var i InterestRun
+	err := row.Scan(&i.AccrualDate, &i.CreatedAt)
+	return i, err
+}
+
+const getPreviousInterestPosting = `-- name: GetPreviousInterestPosting :one
+SELECT account_id, period, accrued_micros, amount, carry_micros, transfer_id, created_at
+FROM interest_postings
+WHERE account_id = $1
+  AND period < $2
+ORDER BY period DESC
+LIMIT 1
+`
+
+type GetPreviousInterestPostingParams struct {
+	AccountID int64     `json:"account_id"`
+	Period    time.Time `json:"period"`
+}
+
+func (q *Queries) GetPreviousInterestPosting(ctx context.Context, arg GetPreviousInterestPostingParams) (InterestPosting, error) {
+	row := q.db.QueryRow(ctx, getPreviousInterestPosting, arg.AccountID, arg.Period)
+	var i InterestPosting
+	err := row.Scan(
+		&i.AccountID,
+		&i.Period,
+		&i.AccruedMicros,
+		&i.Amount,
+		&i.CarryMicros,
+		&i.TransferID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listInterestAccruals = `-- name: ListInterestAccruals :many
+SELECT account_id, accrual_date, balance, rate_bps, amount_micros, created_at, posted_period
+FROM interest_accruals
+WHERE account_id = $1
+  AND accrual_date >= $2
+  AND accrual_date < $3
+ORDER BY accrual_date
+`
+
+type ListInterestAccrualsParams struct {
+	AccountID int64     `json:"account_id"`
+	FromDate  time.Time `json:"from_date"`
+	ToDate    time.Time `json:"to_date"`
+}
+
+func (q *Queries) ListInterestAccruals(ctx context.Context, arg ListInterestAccrualsParams) ([]InterestAccrual, error) {
+	rows, err := q.db.Query(ctx, listInterestAccruals, arg.AccountID, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []InterestAccrual{}
+	for rows.Next() {
+		var i InterestAccrual
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.AccrualDate,
+			&i.Balance,
+			&i.RateBps,
+			&i.AmountMicros,
+			&i.CreatedAt,
+			&i.PostedPeriod,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnpostedInterestAccounts = `-- name: ListUnpostedInterestAccounts :many
+SELECT DISTINCT account_id
+FROM interest_accruals
+WHERE posted_period IS NULL
+  AND accrual_date < $1
+  AND account_id > $2
+ORDER BY account_id
+LIMIT $3
+`
+
+type ListUnpostedInterestAccountsParams struct {
+	ToDate   time.Time `json:"to_date"`
+	AfterID  int64     `json:"after_id"`
+	PageSize int32     `json:"page_size"`
+}
+
+// accounts with accruals before to_date not posted yet, after_id pages through them
+func (q *Queries) ListUnpostedInterestAccounts(ctx context.Context, arg ListUnpostedInterestAccountsParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listUnpostedInterestAccounts, arg.ToDate, arg.AfterID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var account_id int64
+		if err := rows.Scan(&account_id); err != nil {
+			return nil, err
+		}
+		items = append(items, account_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const postInterestAccruals = `-- name: PostInterestAccruals :one
+WITH posted AS (
+    UPDATE interest_accruals
+        SET posted_period = $1::date
+        WHERE account_id = $2
+            AND posted_period IS NULL
+            AND accrual_date < $3
+        RETURNING amount_micros)
+SELECT COALESCE(SUM(amount_micros), 0)::bigint AS amount_micros
+FROM posted
+`
+
+type PostInterestAccrualsParams struct {
+	Period    time.Time `json:"period"`
+	AccountID int64     `json:"account_id"`
+	ToDate    time.Time `json:"to_date"`
+}
+
+// marks the accruals of the account before to_date not posted yet as posted in the period and sums them,
+// so an accrual recorded after its month was posted is credited by the next posting
+func (q *Queries) PostInterestAccruals(ctx context.Context, arg PostInterestAccrualsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, postInterestAccruals, arg.Period, arg.AccountID, arg.ToDate)
+	var amount_micros int64
+	err := row.Scan(&amount_micros)
+	return amount_micros, err
+}
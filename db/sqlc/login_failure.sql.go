@@ -0,0 +1,141 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: login_failure.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const blockLogin = `-- name: BlockLogin :exec
+UPDATE login_failures
+SET blocked_until = $1
+WHERE scope = $2
+  AND subject = $3
+`
+
+type BlockLoginParams struct {
+	BlockedUntil pgtype.Timestamptz `json:"blocked_until"`
+	Scope        string             `json:"scope"`
+	Subject      string             `json:"subject"`
+}
+
+func (q *Queries) BlockLogin(ctx context.Context, arg BlockLoginParams) error {
+	_, err := q.db.Exec(ctx, blockLogin, arg.BlockedUntil, arg.Scope, arg.Subject)
+	return err
+}
+
+const deleteLoginFailures = `-- name: DeleteLoginFailures :execrows
+DELETE
+FROM login_failures
+WHERE scope = $1
+  AND subject = $2
+`
+
+type DeleteLoginFailuresParams struct {
+	Scope   string `json:"scope"`
+	Subject string `json:"subject"`
+}
+
+func (q *Queries) DeleteLoginFailures(ctx context.Context, arg DeleteLoginFailuresParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteLoginFailures, arg.Scope, arg.Subject)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getLoginBlock = `-- name: GetLoginBlock :one
+SELECT scope, subject, failures, last_failed_at, blocked_until
+FROM login_failures
+WHERE ((scope = 'username' AND subject = $1) OR (scope = 'ip' AND subject = $2))
+  AND blocked_until > now()
+ORDER BY blocked_until DESC
+LIMIT 1
+`
+
+type GetLoginBlockParams struct {
+	Username string `json:"username"`
+	Ip       string `json:"ip"`
+}
+
+func (q *Queries) GetLoginBlock(ctx context.Context, arg GetLoginBlockParams) (LoginFailure, error) {
+	row := q.db.QueryRow(ctx, getLoginBlock, arg.Username, arg.Ip)
+	var i LoginFailure
+	err := row.Scan(
+		&i.Scope,
+		&i.Subject,
+		&i.Failures,
+		&i.LastFailedAt,
+		&i.BlockedUntil,
+	)
+	return i, err
+}
+
+const recordLoginAttempt = `-- name: RecordLoginAttempt :one
+INSERT INTO login_failures (scope, subject, failures, last_failed_at, blocked_until)
+VALUES ($1, $2, 1, now(), $3)
+ON CONFLICT (scope, subject) DO UPDATE
+    SET failures       = CASE
+                             WHEN login_failures.last_failed_at < $4::timestamptz THEN 1
+                             ELSE login_failures.failures + 1
+        END,
+        last_failed_at = now(),
+        blocked_until  = $3
+WHERE login_failures.blocked_until IS NULL
+   OR login_failures.blocked_until <= now()
+RETURNING scope, subject, failures, last_failed_at, blocked_until
+`
+
+type RecordLoginAttemptParams struct {
+	Scope       string             `json:"scope"`
+	Subject     string             `json:"subject"`
+	HoldUntil   pgtype.Timestamptz `json:"hold_until"`
+	ResetBefore time.Time          `json:"reset_before"`
+}
+
+// RecordLoginAttempt counts an attempt as a failure before the credentials are checked,
+// so concurrent attempts cannot get past the limits. No row is returned while the subject is blocked.
+// hold_until blocks the subject until the attempt is failed or released.
+func (q *Queries) RecordLoginAttempt(ctx context.Context, arg RecordLoginAttemptParams) (LoginFailure, error) {
+	row := q.db.QueryRow(ctx, recordLoginAttempt,
+		arg.Scope,
+		arg.Subject,
+		arg.HoldUntil,
+		arg.ResetBefore,
+	)
+	var i LoginFailure
+	err := row.Scan(
+		&i.Scope,
+		&i.Subject,
+		&i.Failures,
+		&i.LastFailedAt,
+		&i.BlockedUntil,
+	)
+	return i, err
+}
+
+const releaseLoginAttempt = `-- name: ReleaseLoginAttempt :exec
+UPDATE login_failures
+SET failures      = greatest(failures - 1, 0),
+    blocked_until = CASE WHEN $1::bool THEN NULL ELSE blocked_until END
+WHERE scope = $2
+  AND subject = $3
+`
+
+type ReleaseLoginAttemptParams struct {
+	ReleaseHold bool   `json:"release_hold"`
+	Scope       string `json:"scope"`
+	Subject     string `json:"subject"`
+}
+
+// ReleaseLoginAttempt takes back a recorded attempt whose credentials were valid.
+func (q *Queries) ReleaseLoginAttempt(ctx context.Context, arg ReleaseLoginAttemptParams) error {
+	_, err := q.db.Exec(ctx, releaseLoginAttempt, arg.ReleaseHold, arg.Scope, arg.Subject)
+	return err
+}
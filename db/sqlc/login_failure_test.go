@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordLoginAttempt(t *testing.T) {
+	var arg = RecordLoginAttemptParams{
+		Scope:       "username",
+		Subject:     util.RandomOwner(),
+		ResetBefore: time.Now().Add(-time.Hour),
+	}
+
+	for i := int32(1); i <= 3; i++ {
+		var failure, err = testQueries.RecordLoginAttempt(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, i, failure.Failures)
+		require.WithinDuration(t, time.Now(), failure.LastFailedAt, time.Second)
+		require.False(t, failure.BlockedUntil.Valid)
+	}
+
+	// failures older than reset_before no longer count
+	arg.ResetBefore = time.Now().Add(time.Minute)
+	var failure, err = testQueries.RecordLoginAttempt(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), failure.Failures)
+
+	// a held subject refuses the next attempt until the hold is released
+	arg.ResetBefore = time.Now().Add(-time.Hour)
+	arg.HoldUntil = pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true}
+	failure, err = testQueries.RecordLoginAttempt(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), failure.Failures)
+	_, err = testQueries.RecordLoginAttempt(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	err = testQueries.ReleaseLoginAttempt(context.Background(), ReleaseLoginAttemptParams{
+		Scope:       arg.Scope,
+		Subject:     arg.Subject,
+		ReleaseHold: true,
+	})
+	require.NoError(t, err)
+	failure, err = testQueries.RecordLoginAttempt(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), failure.Failures)
+}
+
+func TestGetLoginBlock(t *testing.T) {
+	var username = util.RandomOwner()
+	var ip = "198.51.100." + util.RandomString(3)
+	var arg = GetLoginBlockParams{Username: username, Ip: ip}
+
+	var _, err = testQueries.GetLoginBlock(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	for _, failure := range []RecordLoginAttemptParams{
+		{Scope: "username", Subject: username, ResetBefore: time.Now().Add(-time.Hour)},
+		{Scope: "ip", Subject: ip, ResetBefore: time.Now().Add(-time.Hour)},
+	} {
+		_, err = testQueries.RecordLoginAttempt(context.Background(), failure)
+		require.NoError(t, err)
+	}
+
+	// an expired block does not count
+	err = testQueries.BlockLogin(context.Background(), BlockLoginParams{
+		Scope:        "username",
+		Subject:      username,
+		BlockedUntil: pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+	_, err = testQueries.GetLoginBlock(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	var blockedUntil = time.Now().Add(time.Minute)
+	err = testQueries.BlockLogin(context.Background(), BlockLoginParams{
+		Scope:        "ip",
+		Subject:      ip,
+		BlockedUntil: pgtype.Timestamptz{Time: blockedUntil, Valid: true},
+	})
+	require.NoError(t, err)
+
+	block, err := testQueries.GetLoginBlock(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, "ip", block.Scope)
+	require.WithinDuration(t, blockedUntil, block.BlockedUntil.Time, time.Second)
+}
+
+func TestDeleteLoginFailures(t *testing.T) {
+	var arg = RecordLoginAttemptParams{
+		Scope:       "username",
+		Subject:     util.RandomOwner(),
+		ResetBefore: time.Now().Add(-time.Hour),
+	}
+	var _, err = testQueries.RecordLoginAttempt(context.Background(), arg)
+	require.NoError(t, err)
+
+	var deleteArg = DeleteLoginFailuresParams{Scope: arg.Scope, Subject: arg.Subject}
+	rows, err := testQueries.DeleteLoginFailures(context.Background(), deleteArg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+
+	rows, err = testQueries.DeleteLoginFailures(context.Background(), deleteArg)
+	require.NoError(t, err)
+	require.Zero(t, rows)
+}
@@ -1,17 +1,17 @@
 package db
 
 import (
-	"database/sql"
+	"context"
 	"log"
 	"os"
 	"testing"
 
 	"github.com/Ma-hiru/simplebank/util"
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var testQueries *Queries
-var testDB *sql.DB
+var testDB *pgxpool.Pool
 
 func TestMain(m *testing.M) {
 	var config, err = util.LoadConfig("../..", "app")
@@ -20,13 +20,13 @@ func TestMain(m *testing.M) {
 	}
 
 	{
-		var conn, err = sql.Open(config.DBDriver, config.DBSource)
-		testDB = conn
+		var connPool, err = NewPool(context.Background(), config)
+		testDB = connPool
 
 		if err != nil {
 			log.Fatal("cannot connect to db:", err)
 		}
-		testQueries = New(conn)
+		testQueries = New(connPool)
 	}
 
 	os.Exit(m.Run())
@@ -0,0 +1,39 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: mfa_token.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const useMFAToken = `-- name: UseMFAToken :execrows
+WITH expired AS (
+    DELETE FROM used_mfa_tokens
+    WHERE expired_at < now()
+)
+INSERT
+INTO used_mfa_tokens (id, expired_at)
+VALUES ($1, $2)
+ON CONFLICT (id) DO NOTHING
+`
+
+type UseMFATokenParams struct {
+	ID        uuid.UUID `json:"id"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// UseMFAToken marks an mfa pending token as exchanged and reports 0 rows if it already was.
+// The tokens that expired are dropped on the way, they are refused anyway.
+func (q *Queries) UseMFAToken(ctx context.Context, arg UseMFATokenParams) (int64, error) {
+	result, err := q.db.Exec(ctx, useMFAToken, arg.ID, arg.ExpiredAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
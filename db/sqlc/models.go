@@ -5,7 +5,11 @@
 package db
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Account struct {
@@ -14,6 +18,38 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// checking, savings, or internal for the ledger accounts of the bank
+	Type string `json:"type"`
+}
+
+type ApiKey struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	Name     string    `json:"name"`
+	// public part of the key used to look it up
+	Prefix string `json:"prefix"`
+	// sha256 of the whole key, the key itself is only shown once
+	KeyHash    string             `json:"key_hash"`
+	Scopes     []string           `json:"scopes"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+// internal accounts the bank books its own income and expenses on
+type BankAccount struct {
+	Purpose   string `json:"purpose"`
+	Currency  string `json:"currency"`
+	AccountID int64  `json:"account_id"`
+}
+
+type Currency struct {
+	// ISO 4217 alphabetic code
+	Code string `json:"code"`
+	// new accounts are only opened in enabled currencies, the accounts already open keep transferring
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type Entry struct {
@@ -22,6 +58,186 @@ type Entry struct {
 	// can be nagative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// set on the entries of a multi-leg transfer
+	TransferGroupID pgtype.Int8 `json:"transfer_group_id"`
+	// set on the two entries of a transfer
+	TransferID pgtype.Int8 `json:"transfer_id"`
+}
+
+type Fee struct {
+	ID        int64  `json:"id"`
+	Kind      string `json:"kind"`
+	AccountID int64  `json:"account_id"`
+	Amount    int64  `json:"amount"`
+	// transfer moving the fee to the fee income account of the bank
+	TransferID int64 `json:"transfer_id"`
+	// transfer a transfer fee was charged on
+	ChargedTransferID pgtype.Int8 `json:"charged_transfer_id"`
+	// first day of the month a maintenance fee was charged for
+	Period    pgtype.Date `json:"period"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// months the maintenance fees have been scheduled for
+type FeeRun struct {
+	Period    time.Time `json:"period"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FeeSchedule struct {
+	// transfer, charged to the sender of a transfer, or maintenance, charged monthly
+	Kind        string `json:"kind"`
+	Currency    string `json:"currency"`
+	AccountType string `json:"account_type"`
+	FlatAmount  int64  `json:"flat_amount"`
+	// percentage of the transferred amount in basis points
+	RateBps   int64 `json:"rate_bps"`
+	MinAmount int64 `json:"min_amount"`
+	// no cap when null
+	MaxAmount pgtype.Int8 `json:"max_amount"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+type FeeWaiver struct {
+	Username string `json:"username"`
+	Kind     string `json:"kind"`
+	Reason   string `json:"reason"`
+	// the waiver never expires when null
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	CreatedBy string             `json:"created_by"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type InterestAccrual struct {
+	AccountID   int64     `json:"account_id"`
+	AccrualDate time.Time `json:"accrual_date"`
+	// end-of-day balance the interest is computed on
+	Balance int64 `json:"balance"`
+	// annual interest rate in basis points
+	RateBps int64 `json:"rate_bps"`
+	// interest in millionths of the minor unit
+	AmountMicros int64     `json:"amount_micros"`
+	CreatedAt    time.Time `json:"created_at"`
+	// period of the posting that credited the accrual, null until it is posted
+	PostedPeriod pgtype.Date `json:"posted_period"`
+}
+
+type InterestPosting struct {
+	AccountID int64 `json:"account_id"`
+	// first day of the month the interest was accrued in
+	Period time.Time `json:"period"`
+	// interest of the month plus the carry of the previous posting
+	AccruedMicros int64 `json:"accrued_micros"`
+	Amount        int64 `json:"amount"`
+	// rounding remainder carried over to the next posting
+	CarryMicros int64       `json:"carry_micros"`
+	TransferID  pgtype.Int8 `json:"transfer_id"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// days the interest accrual has been scheduled for
+type InterestRun struct {
+	AccrualDate time.Time `json:"accrual_date"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type LoginFailure struct {
+	// username or ip
+	Scope        string    `json:"scope"`
+	Subject      string    `json:"subject"`
+	Failures     int32     `json:"failures"`
+	LastFailedAt time.Time `json:"last_failed_at"`
+	// no login is accepted for the subject before this time
+	BlockedUntil pgtype.Timestamptz `json:"blocked_until"`
+}
+
+// domain events written in the transaction of the change, published by the outbox relay
+type OutboxEvent struct {
+	ID int64 `json:"id"`
+	// the events of an account are published in id order
+	AccountID int64           `json:"account_id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+	// null until the relay published the event
+	PublishedAt pgtype.Timestamptz `json:"published_at"`
+	// failed attempts to publish the event
+	Attempts  int32  `json:"attempts"`
+	LastError string `json:"last_error"`
+	// the event and the later events of its account wait until then after a failed attempt
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// set when the attempts are exhausted, the event is then kept but no longer published
+	DeadAt pgtype.Timestamptz `json:"dead_at"`
+}
+
+type PasswordReset struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha256 of the token sent by email, the token itself is never stored
+	TokenHash string    `json:"token_hash"`
+	IsUsed    bool      `json:"is_used"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+type PayrollBatch struct {
+	ID            int64  `json:"id"`
+	FromAccountID int64  `json:"from_account_id"`
+	CreatedBy     string `json:"created_by"`
+	// all rows are transferred in one transaction, or none is
+	Atomic bool `json:"atomic"`
+	// pending, completed, partially_completed or failed
+	Status      string             `json:"status"`
+	CreatedAt   time.Time          `json:"created_at"`
+	CompletedAt pgtype.Timestamptz `json:"completed_at"`
+}
+
+type PayrollRow struct {
+	BatchID int64 `json:"batch_id"`
+	// line of the row in the uploaded file
+	RowNumber   int32  `json:"row_number"`
+	ToAccountID int64  `json:"to_account_id"`
+	Amount      int64  `json:"amount"`
+	Currency    string `json:"currency"`
+	Reference   string `json:"reference"`
+	// pending, completed or failed
+	Status     string      `json:"status"`
+	Error      string      `json:"error"`
+	TransferID pgtype.Int8 `json:"transfer_id"`
+}
+
+type RateLimitBucket struct {
+	Key string `json:"key"`
+	// tokens left in the bucket at updated_at
+	Tokens float64 `json:"tokens"`
+	// whether the last request took a token
+	Allowed   bool      `json:"allowed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RecoveryCode struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha256 of the recovery code, the code itself is only shown once
+	CodeHash  string             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type Task struct {
+	ID      int64           `json:"id"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+	// pending, running, completed or failed
+	Status      string    `json:"status"`
+	Attempts    int32     `json:"attempts"`
+	MaxAttempts int32     `json:"max_attempts"`
+	LastError   string    `json:"last_error"`
+	RunAt       time.Time `json:"run_at"`
+	// set while a processor is running the task
+	LockedAt  pgtype.Timestamptz `json:"locked_at"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
 }
 
 type Transfer struct {
@@ -33,6 +249,19 @@ type Transfer struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type TransferGroup struct {
+	ID          int64     `json:"id"`
+	Currency    string    `json:"currency"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// mfa pending tokens already exchanged for an access token, kept until they expire
+type UsedMfaToken struct {
+	ID        uuid.UUID `json:"id"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
 type User struct {
 	Username          string    `json:"username"`
 	HashPassword      string    `json:"hash_password"`
@@ -40,4 +269,68 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	// depositor or admin
+	Role string `json:"role"`
+	// AES-GCM encrypted TOTP secret, set on enrolment and enabled once confirmed with a code
+	TotpSecret  pgtype.Text `json:"totp_secret"`
+	TotpEnabled bool        `json:"totp_enabled"`
+	// time step of the last accepted TOTP code, a code of this step or an earlier one is refused
+	TotpLastStep int64 `json:"totp_last_step"`
+}
+
+type VerifyEmail struct {
+	ID         int64     `json:"id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	SecretCode string    `json:"secret_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
+}
+
+type WebhookAttempt struct {
+	ID         int64 `json:"id"`
+	DeliveryID int64 `json:"delivery_id"`
+	Attempt    int32 `json:"attempt"`
+	// 0 when no response was received
+	ResponseCode int32     `json:"response_code"`
+	Error        string    `json:"error"`
+	DurationMs   int64     `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64     `json:"id"`
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	EventID        int64     `json:"event_id"`
+	// pending, sending, succeeded or dead once the attempts are exhausted
+	Status        string             `json:"status"`
+	Attempts      int32              `json:"attempts"`
+	LastError     string             `json:"last_error"`
+	NextAttemptAt time.Time          `json:"next_attempt_at"`
+	LockedAt      pgtype.Timestamptz `json:"locked_at"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+// only recorded when the user has a subscription for the event type
+type WebhookEvent struct {
+	ID        int64           `json:"id"`
+	Username  string          `json:"username"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type WebhookSubscription struct {
+	ID         uuid.UUID `json:"id"`
+	Username   string    `json:"username"`
+	Url        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	// signing secret sealed with the webhook encryption key, it is only shown once
+	Secret string `json:"secret"`
+	// balance.low is sent when a debit takes a balance below it
+	LowBalanceThreshold int64     `json:"low_balance_threshold"`
+	CreatedAt           time.Time `json:"created_at"`
 }
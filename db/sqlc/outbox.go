@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Types of the outbox events.
+const (
+	// OutboxAccountCreated carries the new Account.
+	OutboxAccountCreated = "account.created"
+	// OutboxEntryCreated carries an EntryEvent for every entry booked on an account.
+	OutboxEntryCreated = "entry.created"
+)
+
+// EntryEvent is the payload of an entry.created outbox event.
+type EntryEvent struct {
+	Entry Entry `json:"entry"`
+	// Account is the account at the end of the transaction that booked the entry.
+	Account Account `json:"account"`
+}
+
+// recordOutbox writes a domain event of an account to the outbox, in the transaction of the change.
+// The account row must be locked by the transaction, which keeps the ids of its events in commit order.
+func recordOutbox(ctx context.Context, queries *Queries, accountID int64, eventType string, payload any) error {
+	var data, err = json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s outbox event: %w", eventType, err)
+	}
+	return queries.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		AccountID: accountID,
+		Type:      eventType,
+		Payload:   data,
+	})
+}
+
+// recordEntry writes the entry.created event of an entry.
+func recordEntry(ctx context.Context, queries *Queries, entry Entry, account Account) error {
+	return recordOutbox(ctx, queries, account.ID, OutboxEntryCreated, EntryEvent{Entry: entry, Account: account})
+}
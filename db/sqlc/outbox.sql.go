@@ -0,0 +1,142 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: outbox.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const createOutboxEvent = `-- name: CreateOutboxEvent :exec
+INSERT INTO outbox_events (account_id, type, payload)
+VALUES ($1, $2, $3)
+`
+
+type CreateOutboxEventParams struct {
+	AccountID int64           `json:"account_id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+func (q *Queries) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) error {
+	_, err := q.db.Exec(ctx, createOutboxEvent, arg.AccountID, arg.Type, arg.Payload)
+	return err
+}
+
+const deletePublishedOutboxEvents = `-- name: DeletePublishedOutboxEvents :execrows
+DELETE
+FROM outbox_events
+WHERE published_at < $1::timestamptz
+`
+
+func (q *Queries) DeletePublishedOutboxEvents(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePublishedOutboxEvents, publishedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const failOutboxEvent = `-- name: FailOutboxEvent :exec
+UPDATE outbox_events
+SET attempts        = attempts + 1,
+    last_error      = $1,
+    next_attempt_at = $2,
+    dead_at         = CASE WHEN $3::bool THEN now() END
+WHERE id = $4
+`
+
+type FailOutboxEventParams struct {
+	LastError     string    `json:"last_error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	Dead          bool      `json:"dead"`
+	ID            int64     `json:"id"`
+}
+
+// counts a failed attempt, the event is tried again at next_attempt_at unless it is dead-lettered
+func (q *Queries) FailOutboxEvent(ctx context.Context, arg FailOutboxEventParams) error {
+	_, err := q.db.Exec(ctx, failOutboxEvent,
+		arg.LastError,
+		arg.NextAttemptAt,
+		arg.Dead,
+		arg.ID,
+	)
+	return err
+}
+
+const listUnpublishedOutboxEvents = `-- name: ListUnpublishedOutboxEvents :many
+SELECT e.id, e.account_id, e.type, e.payload, e.created_at, e.published_at, e.attempts, e.last_error, e.next_attempt_at, e.dead_at
+FROM outbox_events e
+WHERE e.published_at IS NULL
+  AND e.dead_at IS NULL
+  AND NOT EXISTS (SELECT 1
+                  FROM outbox_events b
+                  WHERE b.account_id = e.account_id
+                    AND b.id <= e.id
+                    AND b.published_at IS NULL
+                    AND b.dead_at IS NULL
+                    AND b.next_attempt_at > now())
+ORDER BY e.id
+LIMIT $1
+`
+
+// the events to publish in id order. The events of an account wait behind its first pending event until that one
+// is due again, so an account that keeps failing does not fill the batches and hold back the other accounts.
+func (q *Queries) ListUnpublishedOutboxEvents(ctx context.Context, limit int32) ([]OutboxEvent, error) {
+	rows, err := q.db.Query(ctx, listUnpublishedOutboxEvents, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OutboxEvent{}
+	for rows.Next() {
+		var i OutboxEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Type,
+			&i.Payload,
+			&i.CreatedAt,
+			&i.PublishedAt,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.DeadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markOutboxEventsPublished = `-- name: MarkOutboxEventsPublished :exec
+UPDATE outbox_events
+SET published_at = now()
+WHERE id = ANY ($1::bigint[])
+`
+
+func (q *Queries) MarkOutboxEventsPublished(ctx context.Context, ids []int64) error {
+	_, err := q.db.Exec(ctx, markOutboxEventsPublished, ids)
+	return err
+}
+
+const tryLockOutboxRelay = `-- name: TryLockOutboxRelay :one
+SELECT pg_try_advisory_xact_lock(hashtext('outbox_relay'))::bool AS locked
+`
+
+// TryLockOutboxRelay takes the lock of the outbox relay until the end of the transaction,
+// it returns false when another relay holds it.
+func (q *Queries) TryLockOutboxRelay(ctx context.Context) (bool, error) {
+	row := q.db.QueryRow(ctx, tryLockOutboxRelay)
+	var locked bool
+	err := row.Scan(&locked)
+	return locked, err
+}
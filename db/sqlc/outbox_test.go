@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// relayAll publishes every unpublished outbox event and returns those of the accounts.
+func relayAll(t *testing.T, store Store, accountIDs ...int64) []OutboxEvent {
+	var wanted = make(map[int64]bool, len(accountIDs))
+	for _, id := range accountIDs {
+		wanted[id] = true
+	}
+
+	var relayed []OutboxEvent
+	for {
+		var listed, err = store.RelayOutboxTx(context.Background(), RelayOutboxTxParams{
+			BatchSize: 100,
+			Publish: func(events []OutboxEvent) OutboxPublishResult {
+				var ids = make([]int64, len(events))
+				for i, event := range events {
+					ids[i] = event.ID
+					if wanted[event.AccountID] {
+						relayed = append(relayed, event)
+					}
+				}
+				return OutboxPublishResult{Published: ids}
+			},
+		})
+		require.NoError(t, err)
+		if listed < 100 {
+			return relayed
+		}
+	}
+}
+
+func TestCreateAccountTxOutbox(t *testing.T) {
+	var store = NewStore(testDB)
+	var account, err = store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Currency: util.RandomCurrency(),
+		Type:     AccountTypeChecking,
+	})
+	require.NoError(t, err)
+
+	var events = relayAll(t, store, account.ID)
+	require.Len(t, events, 1)
+	require.Equal(t, OutboxAccountCreated, events[0].Type)
+	var payload Account
+	require.NoError(t, json.Unmarshal(events[0].Payload, &payload))
+	require.Equal(t, account.ID, payload.ID)
+}
+
+func TestTransferTxOutbox(t *testing.T) {
+	var store = NewStore(testDB)
+	var account1 = createRandomAccount(t)
+	var account2 = createRandomAccount(t)
+
+	var results []TransferTxResult
+	for range 3 {
+		var result, err = store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+		})
+		require.NoError(t, err)
+		results = append(results, result)
+	}
+
+	var events = relayAll(t, store, account1.ID, account2.ID)
+	require.Len(t, events, 6)
+
+	// the events of each account are in the order of its entries
+	var entries = make(map[int64][]EntryEvent)
+	for i, event := range events {
+		require.Equal(t, OutboxEntryCreated, event.Type)
+		if i > 0 {
+			require.Greater(t, event.ID, events[i-1].ID)
+		}
+		var payload EntryEvent
+		require.NoError(t, json.Unmarshal(event.Payload, &payload))
+		require.Equal(t, event.AccountID, payload.Account.ID)
+		entries[event.AccountID] = append(entries[event.AccountID], payload)
+	}
+	for i, result := range results {
+		require.Equal(t, result.FromEntry.ID, entries[account1.ID][i].Entry.ID)
+		require.Equal(t, result.FromAccount.Balance, entries[account1.ID][i].Account.Balance)
+		require.Equal(t, result.ToEntry.ID, entries[account2.ID][i].Entry.ID)
+		require.Equal(t, result.ToAccount.Balance, entries[account2.ID][i].Account.Balance)
+	}
+
+	// published events are not relayed again
+	require.Empty(t, relayAll(t, store, account1.ID, account2.ID))
+
+	deleted, err := testQueries.DeletePublishedOutboxEvents(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(6))
+}
+
+func TestRelayOutboxTxUnpublished(t *testing.T) {
+	var store = NewStore(testDB)
+	var account, err = store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Currency: util.RandomCurrency(),
+		Type:     AccountTypeSavings,
+	})
+	require.NoError(t, err)
+
+	// the events a publisher does not return stay in the outbox
+	_, err = store.RelayOutboxTx(context.Background(), RelayOutboxTxParams{
+		BatchSize: 1000,
+		Publish: func(events []OutboxEvent) OutboxPublishResult {
+			var ids []int64
+			for _, event := range events {
+				if event.AccountID != account.ID {
+					ids = append(ids, event.ID)
+				}
+			}
+			return OutboxPublishResult{Published: ids}
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, relayAll(t, store, account.ID), 1)
+}
+
+func TestRelayOutboxTxFailingAccount(t *testing.T) {
+	var store = NewStore(testDB)
+	var failing = createRandomAccount(t)
+	var other = createRandomAccount(t)
+
+	// more events of the failing account than fit in a batch, then one of another account
+	for range 150 {
+		require.NoError(t, testQueries.CreateOutboxEvent(context.Background(), CreateOutboxEventParams{
+			AccountID: failing.ID,
+			Type:      OutboxEntryCreated,
+			Payload:   json.RawMessage(`{}`),
+		}))
+	}
+	require.NoError(t, testQueries.CreateOutboxEvent(context.Background(), CreateOutboxEventParams{
+		AccountID: other.ID,
+		Type:      OutboxEntryCreated,
+		Payload:   json.RawMessage(`{}`),
+	}))
+
+	var attempted []int64
+	var relayed []OutboxEvent
+	for {
+		var listed, err = store.RelayOutboxTx(context.Background(), RelayOutboxTxParams{
+			BatchSize: 100,
+			Publish: func(events []OutboxEvent) OutboxPublishResult {
+				var result OutboxPublishResult
+				var failed bool
+				for _, event := range events {
+					if event.AccountID != failing.ID {
+						result.Published = append(result.Published, event.ID)
+						if event.AccountID == other.ID {
+							relayed = append(relayed, event)
+						}
+						continue
+					}
+					if failed {
+						continue
+					}
+					failed = true
+					attempted = append(attempted, event.ID)
+					result.Failed = append(result.Failed, FailOutboxEventParams{
+						ID:            event.ID,
+						LastError:     "publisher unavailable",
+						NextAttemptAt: time.Now().Add(time.Hour),
+					})
+				}
+				return result
+			},
+		})
+		require.NoError(t, err)
+		if listed < 100 {
+			break
+		}
+	}
+
+	// the failing account waits behind its first event without holding back the other one
+	require.Len(t, attempted, 1)
+	require.Len(t, relayed, 1)
+	require.Empty(t, relayAll(t, store, failing.ID))
+
+	// once dead-lettered, the next events of the account go on
+	require.NoError(t, testQueries.FailOutboxEvent(context.Background(), FailOutboxEventParams{
+		ID:            attempted[0],
+		LastError:     "publisher unavailable",
+		NextAttemptAt: time.Now(),
+		Dead:          true,
+	}))
+	var events = relayAll(t, store, failing.ID)
+	require.Len(t, events, 149)
+	require.Greater(t, events[0].ID, attempted[0])
+}
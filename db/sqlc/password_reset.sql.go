@@ -0,0 +1,106 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: password_reset.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createPasswordReset = `-- name: CreatePasswordReset :one
+INSERT INTO password_resets (username, token_hash, expired_at)
+VALUES ($1, $2, $3)
+RETURNING id, username, token_hash, is_used, created_at, expired_at
+`
+
+type CreatePasswordResetParams struct {
+	Username  string    `json:"username"`
+	TokenHash string    `json:"token_hash"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+func (q *Queries) CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error) {
+	row := q.db.QueryRow(ctx, createPasswordReset, arg.Username, arg.TokenHash, arg.ExpiredAt)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.TokenHash,
+		&i.IsUsed,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+	)
+	return i, err
+}
+
+const getPasswordResetUser = `-- name: GetPasswordResetUser :one
+SELECT users.username, users.hash_password, users.full_name, users.email, users.password_changed_at, users.created_at, users.is_email_verified, users.role, users.totp_secret, users.totp_enabled, users.totp_last_step
+FROM password_resets
+         JOIN users ON users.username = password_resets.username
+WHERE password_resets.token_hash = $1
+  AND password_resets.is_used = FALSE
+  AND password_resets.expired_at > now()
+`
+
+func (q *Queries) GetPasswordResetUser(ctx context.Context, tokenHash string) (User, error) {
+	row := q.db.QueryRow(ctx, getPasswordResetUser, tokenHash)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const invalidatePasswordResets = `-- name: InvalidatePasswordResets :exec
+UPDATE password_resets
+SET is_used = TRUE
+WHERE username = $1
+  AND is_used = FALSE
+  AND created_at <= $2
+`
+
+type InvalidatePasswordResetsParams struct {
+	Username     string    `json:"username"`
+	IssuedBefore time.Time `json:"issued_before"`
+}
+
+func (q *Queries) InvalidatePasswordResets(ctx context.Context, arg InvalidatePasswordResetsParams) error {
+	_, err := q.db.Exec(ctx, invalidatePasswordResets, arg.Username, arg.IssuedBefore)
+	return err
+}
+
+const usePasswordReset = `-- name: UsePasswordReset :one
+UPDATE password_resets
+SET is_used = TRUE
+WHERE token_hash = $1
+  AND is_used = FALSE
+  AND expired_at > now()
+RETURNING id, username, token_hash, is_used, created_at, expired_at
+`
+
+func (q *Queries) UsePasswordReset(ctx context.Context, tokenHash string) (PasswordReset, error) {
+	row := q.db.QueryRow(ctx, usePasswordReset, tokenHash)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.TokenHash,
+		&i.IsUsed,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+	)
+	return i, err
+}
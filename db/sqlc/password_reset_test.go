@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPasswordReset(t *testing.T, user User, duration time.Duration) (PasswordReset, string) {
+	var token, err = util.RandomSecret(32)
+	require.NoError(t, err)
+
+	var arg = CreatePasswordResetParams{
+		Username:  user.Username,
+		TokenHash: util.HashSecret(token),
+		ExpiredAt: time.Now().Add(duration),
+	}
+	passwordReset, err := testQueries.CreatePasswordReset(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.NotZero(t, passwordReset.ID)
+	require.Equal(t, arg.Username, passwordReset.Username)
+	require.Equal(t, arg.TokenHash, passwordReset.TokenHash)
+	require.False(t, passwordReset.IsUsed)
+	require.WithinDuration(t, arg.ExpiredAt, passwordReset.ExpiredAt, time.Second)
+	return passwordReset, token
+}
+
+func TestUsePasswordReset(t *testing.T) {
+	var passwordReset, token = createRandomPasswordReset(t, createRandomUser(t), time.Minute)
+
+	var used, err = testQueries.UsePasswordReset(context.Background(), util.HashSecret(token))
+	require.NoError(t, err)
+	require.Equal(t, passwordReset.ID, used.ID)
+	require.True(t, used.IsUsed)
+
+	// single use
+	_, err = testQueries.UsePasswordReset(context.Background(), util.HashSecret(token))
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUseExpiredPasswordReset(t *testing.T) {
+	var _, token = createRandomPasswordReset(t, createRandomUser(t), -time.Minute)
+
+	var _, err = testQueries.UsePasswordReset(context.Background(), util.HashSecret(token))
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	var store = NewStore(testDB)
+	var user = createRandomUser(t)
+	var _, token = createRandomPasswordReset(t, user, time.Minute)
+	var _, otherToken = createRandomPasswordReset(t, user, time.Minute)
+
+	var hashedPassword, err = util.HashPassword(util.RandomString(8))
+	require.NoError(t, err)
+
+	result, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:    util.HashSecret(token),
+		HashPassword: hashedPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, result.User.Username)
+	require.Equal(t, hashedPassword, result.User.HashPassword)
+	require.True(t, result.User.PasswordChangedAt.After(user.PasswordChangedAt))
+
+	// every token issued before the change is invalidated
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:    util.HashSecret(otherToken),
+		HashPassword: hashedPassword,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,245 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: payroll.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const completePayrollBatch = `-- name: CompletePayrollBatch :one
+UPDATE payroll_batches
+SET status       = $2,
+    completed_at = now()
+WHERE id = $1
+RETURNING id, from_account_id, created_by, atomic, status, created_at, completed_at
+`
+
+type CompletePayrollBatchParams struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+func (q *Queries) CompletePayrollBatch(ctx context.Context, arg CompletePayrollBatchParams) (PayrollBatch, error) {
+	row := q.db.QueryRow(ctx, completePayrollBatch, arg.ID, arg.Status)
+	var i PayrollBatch
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.CreatedBy,
+		&i.Atomic,
+		&i.Status,
+		&i.CreatedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const completePayrollRow = `-- name: CompletePayrollRow :one
+UPDATE payroll_rows
+SET status      = 'completed',
+    error       = '',
+    transfer_id = $1::bigint
+WHERE batch_id = $2
+  AND row_number = $3
+RETURNING batch_id, row_number, to_account_id, amount, currency, reference, status, error, transfer_id
+`
+
+type CompletePayrollRowParams struct {
+	TransferID int64 `json:"transfer_id"`
+	BatchID    int64 `json:"batch_id"`
+	RowNumber  int32 `json:"row_number"`
+}
+
+func (q *Queries) CompletePayrollRow(ctx context.Context, arg CompletePayrollRowParams) (PayrollRow, error) {
+	row := q.db.QueryRow(ctx, completePayrollRow, arg.TransferID, arg.BatchID, arg.RowNumber)
+	var i PayrollRow
+	err := row.Scan(
+		&i.BatchID,
+		&i.RowNumber,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Reference,
+		&i.Status,
+		&i.Error,
+		&i.TransferID,
+	)
+	return i, err
+}
+
+const createPayrollBatch = `-- name: CreatePayrollBatch :one
+INSERT INTO payroll_batches (from_account_id, created_by, atomic)
+VALUES ($1, $2, $3)
+RETURNING id, from_account_id, created_by, atomic, status, created_at, completed_at
+`
+
+type CreatePayrollBatchParams struct {
+	FromAccountID int64  `json:"from_account_id"`
+	CreatedBy     string `json:"created_by"`
+	Atomic        bool   `json:"atomic"`
+}
+
+func (q *Queries) CreatePayrollBatch(ctx context.Context, arg CreatePayrollBatchParams) (PayrollBatch, error) {
+	row := q.db.QueryRow(ctx, createPayrollBatch, arg.FromAccountID, arg.CreatedBy, arg.Atomic)
+	var i PayrollBatch
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.CreatedBy,
+		&i.Atomic,
+		&i.Status,
+		&i.CreatedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const createPayrollRow = `-- name: CreatePayrollRow :one
+INSERT INTO payroll_rows (batch_id, row_number, to_account_id, amount, currency, reference)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING batch_id, row_number, to_account_id, amount, currency, reference, status, error, transfer_id
+`
+
+type CreatePayrollRowParams struct {
+	BatchID     int64  `json:"batch_id"`
+	RowNumber   int32  `json:"row_number"`
+	ToAccountID int64  `json:"to_account_id"`
+	Amount      int64  `json:"amount"`
+	Currency    string `json:"currency"`
+	Reference   string `json:"reference"`
+}
+
+func (q *Queries) CreatePayrollRow(ctx context.Context, arg CreatePayrollRowParams) (PayrollRow, error) {
+	row := q.db.QueryRow(ctx, createPayrollRow,
+		arg.BatchID,
+		arg.RowNumber,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Currency,
+		arg.Reference,
+	)
+	var i PayrollRow
+	err := row.Scan(
+		&i.BatchID,
+		&i.RowNumber,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Reference,
+		&i.Status,
+		&i.Error,
+		&i.TransferID,
+	)
+	return i, err
+}
+
+const failPayrollRows = `-- name: FailPayrollRows :many
+UPDATE payroll_rows
+SET status = 'failed',
+    error  = $1
+WHERE batch_id = $2
+  AND status = 'pending'
+  AND ($3::int IS NULL OR row_number = $3)
+RETURNING batch_id, row_number, to_account_id, amount, currency, reference, status, error, transfer_id
+`
+
+type FailPayrollRowsParams struct {
+	Error     string      `json:"error"`
+	BatchID   int64       `json:"batch_id"`
+	RowNumber pgtype.Int4 `json:"row_number"`
+}
+
+// marks the rows still pending as failed; a null row_number fails every pending row of the batch
+func (q *Queries) FailPayrollRows(ctx context.Context, arg FailPayrollRowsParams) ([]PayrollRow, error) {
+	rows, err := q.db.Query(ctx, failPayrollRows, arg.Error, arg.BatchID, arg.RowNumber)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PayrollRow{}
+	for rows.Next() {
+		var i PayrollRow
+		if err := rows.Scan(
+			&i.BatchID,
+			&i.RowNumber,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Currency,
+			&i.Reference,
+			&i.Status,
+			&i.Error,
+			&i.TransferID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPayrollBatch = `-- name: GetPayrollBatch :one
+SELECT id, from_account_id, created_by, atomic, status, created_at, completed_at
+FROM payroll_batches
+WHERE id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetPayrollBatch(ctx context.Context, id int64) (PayrollBatch, error) {
+	row := q.db.QueryRow(ctx, getPayrollBatch, id)
+	var i PayrollBatch
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.CreatedBy,
+		&i.Atomic,
+		&i.Status,
+		&i.CreatedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const listPayrollRows = `-- name: ListPayrollRows :many
+SELECT batch_id, row_number, to_account_id, amount, currency, reference, status, error, transfer_id
+FROM payroll_rows
+WHERE batch_id = $1
+ORDER BY row_number
+`
+
+func (q *Queries) ListPayrollRows(ctx context.Context, batchID int64) ([]PayrollRow, error) {
+	rows, err := q.db.Query(ctx, listPayrollRows, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PayrollRow{}
+	for rows.Next() {
+		var i PayrollRow
+		if err := rows.Scan(
+			&i.BatchID,
+			&i.RowNumber,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Currency,
+			&i.Reference,
+			&i.Status,
+			&i.Error,
+			&i.TransferID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Supported values of util.Config.DBQueryExecMode, named after pgx.QueryExecMode.
+var queryExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
+// NewPoolConfig parses DBSource and applies the pool settings of the config.
+// Zero settings keep the pgxpool defaults.
+func NewPoolConfig(config util.Config) (*pgxpool.Config, error) {
+	var poolConfig, err = pgxpool.ParseConfig(config.DBSource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse db source: %w", err)
+	}
+
+	if config.DBMaxConns > 0 {
+		poolConfig.MaxConns = config.DBMaxConns
+	}
+	if config.DBMinConns > 0 {
+		poolConfig.MinConns = config.DBMinConns
+	}
+	if poolConfig.MinConns > poolConfig.MaxConns {
+		return nil, fmt.Errorf("min conns %d is greater than max conns %d", poolConfig.MinConns, poolConfig.MaxConns)
+	}
+	if config.DBMaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = config.DBMaxConnLifetime
+	}
+	if config.DBMaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = config.DBMaxConnIdleTime
+	}
+	if config.DBHealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = config.DBHealthCheckPeriod
+	}
+
+	var connConfig = poolConfig.ConnConfig
+	if config.DBStatementCacheCapacity > 0 {
+		connConfig.StatementCacheCapacity = config.DBStatementCacheCapacity
+		connConfig.DescriptionCacheCapacity = config.DBStatementCacheCapacity
+	}
+	if config.DBQueryExecMode != "" {
+		var mode, ok = queryExecModes[config.DBQueryExecMode]
+		if !ok {
+			return nil, fmt.Errorf("unsupported query exec mode: %q", config.DBQueryExecMode)
+		}
+		connConfig.DefaultQueryExecMode = mode
+	}
+
+	return poolConfig, nil
+}
+
+// NewPool creates a connection pool configured by NewPoolConfig.
+// Connections are opened lazily, so it does not fail when the database is down.
+func NewPool(ctx context.Context, config util.Config) (*pgxpool.Pool, error) {
+	var poolConfig, err = NewPoolConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return pgxpool.NewWithConfig(ctx, poolConfig)
+}
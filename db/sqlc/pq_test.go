@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// pqDBTX runs the generated queries on a database/sql transaction of the lib/pq driver,
+// the driver of the store before the move to pgx, so both can be benchmarked with the same queries.
+type pqDBTX struct {
+	tx *sql.Tx
+}
+
+func (db pqDBTX) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	var result, err = db.tx.ExecContext(ctx, query, pqArgs(args)...)
+	if err != nil {
+		return pgconn.CommandTag{}, pqError(err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", rows)), nil
+}
+
+func (db pqDBTX) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	var rows, err = db.tx.QueryContext(ctx, query, pqArgs(args)...)
+	if err != nil {
+		return nil, pqError(err)
+	}
+	return &pqRows{rows: rows}, nil
+}
+
+func (db pqDBTX) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	return pqRow{row: db.tx.QueryRowContext(ctx, query, pqArgs(args)...)}
+}
+
+// pqArgs converts the arguments lib/pq does not encode like pgx: arrays and json.
+func pqArgs(args []any) []any {
+	var converted = make([]any, len(args))
+	for i, arg := range args {
+		switch value := arg.(type) {
+		case json.RawMessage:
+			converted[i] = string(value)
+		case []byte:
+			converted[i] = value
+		default:
+			if reflect.ValueOf(arg).Kind() == reflect.Slice {
+				converted[i] = pq.Array(arg)
+			} else {
+				converted[i] = arg
+			}
+		}
+	}
+	return converted
+}
+
+// pqError maps the errors of lib/pq to those of pgx, which the store checks.
+func pqError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return pgx.ErrNoRows
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return &pgconn.PgError{Code: string(pqErr.Code), Message: pqErr.Message}
+	}
+	return err
+}
+
+type pqRow struct {
+	row *sql.Row
+}
+
+func (row pqRow) Scan(dest ...any) error {
+	return pqError(row.row.Scan(dest...))
+}
+
+type pqRows struct {
+	rows *sql.Rows
+	err  error
+}
+
+func (rows *pqRows) Close()                                       { rows.err = rows.rows.Close() }
+func (rows *pqRows) Err() error                                   { return pqError(errors.Join(rows.rows.Err(), rows.err)) }
+func (rows *pqRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (rows *pqRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (rows *pqRows) Next() bool                                   { return rows.rows.Next() }
+func (rows *pqRows) Scan(dest ...any) error                       { return pqError(rows.rows.Scan(dest...)) }
+func (rows *pqRows) RawValues() [][]byte                          { return nil }
+func (rows *pqRows) Conn() *pgx.Conn                              { return nil }
+
+func (rows *pqRows) Values() ([]any, error) {
+	return nil, errors.New("values are not supported on lib/pq rows")
+}
+
+// pqTransferTx runs the transaction of TransferTx on a lib/pq connection pool,
+// again after a deadlock as execTx does.
+func pqTransferTx(ctx context.Context, db *sql.DB, arg TransferTxParams) (TransferTxResult, error) {
+	for {
+		var result, err = pqRunTransferTx(ctx, db, arg)
+		if err == nil || !isRetryable(err) {
+			return result, err
+		}
+	}
+}
+
+func pqRunTransferTx(ctx context.Context, db *sql.DB, arg TransferTxParams) (TransferTxResult, error) {
+	var tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		return TransferTxResult{}, err
+	}
+
+	result, err := transferWithFee(ctx, New(pqDBTX{tx: tx}), arg)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return result, fmt.Errorf("tx err: %w, rb err: %v", err, rbErr)
+		}
+		return result, err
+	}
+	return result, pqError(tx.Commit())
+}
+
+func TestPQTransferTx(t *testing.T) {
+	var config, err = util.LoadConfig("../..", "app")
+	require.NoError(t, err)
+	sqlDB, err := sql.Open("postgres", config.DBSource)
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	// the lib/pq variant of the benchmark runs the same transaction as TransferTx
+	var account1 = createRandomAccount(t)
+	var account2 = createRandomAccount(t)
+	result, err := pqTransferTx(context.Background(), sqlDB, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account1.ID, result.FromAccount.ID)
+	require.Equal(t, account2.Balance+10, result.ToAccount.Balance)
+	require.Equal(t, result.Transfer.ID, result.ToEntry.TransferID.Int64)
+
+	updated, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.ToAccount.Balance, updated.Balance)
+}
@@ -6,24 +6,152 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	BlockLogin(ctx context.Context, arg BlockLoginParams) error
+	ClaimTasks(ctx context.Context, arg ClaimTasksParams) ([]Task, error)
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	CompletePayrollBatch(ctx context.Context, arg CompletePayrollBatchParams) (PayrollBatch, error)
+	CompletePayrollRow(ctx context.Context, arg CompletePayrollRowParams) (PayrollRow, error)
+	CompleteTask(ctx context.Context, id int64) error
+	CompleteWebhookDelivery(ctx context.Context, id int64) error
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	// finds no row when another transaction opened the account of the purpose and currency first
+	CreateBankAccount(ctx context.Context, arg CreateBankAccountParams) (BankAccount, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateFee(ctx context.Context, arg CreateFeeParams) (Fee, error)
+	CreateFeeRun(ctx context.Context, period time.Time) (int64, error)
+	CreateGroupEntry(ctx context.Context, arg CreateGroupEntryParams) (Entry, error)
+	// does nothing when the account already accrued interest on that day, so a rerun of the accrual is harmless
+	CreateInterestAccrual(ctx context.Context, arg CreateInterestAccrualParams) (int64, error)
+	CreateInterestPosting(ctx context.Context, arg CreateInterestPostingParams) (InterestPosting, error)
+	CreateInterestRun(ctx context.Context, accrualDate time.Time) (int64, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) error
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
+	CreatePayrollBatch(ctx context.Context, arg CreatePayrollBatchParams) (PayrollBatch, error)
+	CreatePayrollRow(ctx context.Context, arg CreatePayrollRowParams) (PayrollRow, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
+	CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
+	CreateTransferEntry(ctx context.Context, arg CreateTransferEntryParams) (Entry, error)
+	CreateTransferGroup(ctx context.Context, arg CreateTransferGroupParams) (TransferGroup, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
+	CreateWebhookAttempt(ctx context.Context, arg CreateWebhookAttemptParams) error
+	// CreateWebhookEvent records the event and a delivery to every subscription of the user to its type.
+	// Nothing is written when there is none. The balances are only given for balance.low,
+	// which only goes to the subscriptions whose threshold the balance fell below.
+	CreateWebhookEvent(ctx context.Context, arg CreateWebhookEventParams) (int64, error)
+	CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error)
+	DeadLetterWebhookDelivery(ctx context.Context, arg DeadLetterWebhookDeliveryParams) error
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteFeeSchedule(ctx context.Context, arg DeleteFeeScheduleParams) (int64, error)
+	DeleteFeeWaiver(ctx context.Context, arg DeleteFeeWaiverParams) (int64, error)
+	DeleteLoginFailures(ctx context.Context, arg DeleteLoginFailuresParams) (int64, error)
+	DeletePublishedOutboxEvents(ctx context.Context, publishedBefore time.Time) (int64, error)
+	DeleteRateLimitBuckets(ctx context.Context, updatedBefore time.Time) (int64, error)
+	DeleteRecoveryCodes(ctx context.Context, username string) error
+	DeleteWebhookSubscription(ctx context.Context, arg DeleteWebhookSubscriptionParams) (int64, error)
+	EnableUserTOTP(ctx context.Context, username string) (User, error)
+	// counts a failed attempt, the event is tried again at next_attempt_at unless it is dead-lettered
+	FailOutboxEvent(ctx context.Context, arg FailOutboxEventParams) error
+	// marks the rows still pending as failed; a null row_number fails every pending row of the batch
+	FailPayrollRows(ctx context.Context, arg FailPayrollRowsParams) ([]PayrollRow, error)
+	FailTask(ctx context.Context, arg FailTaskParams) error
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (GetAPIKeyByPrefixRow, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetActiveFeeWaiver(ctx context.Context, arg GetActiveFeeWaiverParams) (FeeWaiver, error)
+	GetBankAccount(ctx context.Context, arg GetBankAccountParams) (BankAccount, error)
+	GetCurrency(ctx context.Context, code string) (Currency, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetFeeSchedule(ctx context.Context, arg GetFeeScheduleParams) (FeeSchedule, error)
+	GetInterestPosting(ctx context.Context, arg GetInterestPostingParams) (InterestPosting, error)
+	GetLatestFeeRun(ctx context.Context) (FeeRun, error)
+	GetLatestInterestRun(ctx context.Context) (InterestRun, error)
+	GetLoginBlock(ctx context.Context, arg GetLoginBlockParams) (LoginFailure, error)
+	GetMaintenanceFee(ctx context.Context, arg GetMaintenanceFeeParams) (Fee, error)
+	GetPasswordResetUser(ctx context.Context, tokenHash string) (User, error)
+	GetPayrollBatch(ctx context.Context, id int64) (PayrollBatch, error)
+	GetPreviousInterestPosting(ctx context.Context, arg GetPreviousInterestPostingParams) (InterestPosting, error)
+	GetStatementBalances(ctx context.Context, arg GetStatementBalancesParams) (GetStatementBalancesRow, error)
+	GetTask(ctx context.Context, id int64) (Task, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferGroup(ctx context.Context, id int64) (TransferGroup, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	GetWebhookEvent(ctx context.Context, id int64) (WebhookEvent, error)
+	GetWebhookSubscription(ctx context.Context, id uuid.UUID) (WebhookSubscription, error)
+	InvalidatePasswordResets(ctx context.Context, arg InvalidatePasswordResetsParams) error
+	ListAPIKeys(ctx context.Context, username string) ([]ApiKey, error)
+	// balances of the accounts of a type at a point in time, from their current balance minus the later entries
+	ListAccountBalancesAt(ctx context.Context, arg ListAccountBalancesAtParams) ([]ListAccountBalancesAtRow, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListAccountsByID(ctx context.Context, ids []int64) ([]Account, error)
+	// locks the accounts in ascending id order, so concurrent transactions cannot deadlock on them
+	ListAccountsForUpdate(ctx context.Context, ids []int64) ([]Account, error)
+	ListCurrencies(ctx context.Context) ([]Currency, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListFeeSchedules(ctx context.Context) ([]FeeSchedule, error)
+	ListFeeWaivers(ctx context.Context, username string) ([]FeeWaiver, error)
+	ListGroupEntries(ctx context.Context, transferGroupID int64) ([]Entry, error)
+	ListInterestAccruals(ctx context.Context, arg ListInterestAccrualsParams) ([]InterestAccrual, error)
+	// accounts opened before the end of the period whose type and currency have a maintenance fee, after_id pages through them
+	ListMaintenanceFeeAccounts(ctx context.Context, arg ListMaintenanceFeeAccountsParams) ([]int64, error)
+	ListPayrollRows(ctx context.Context, batchID int64) ([]PayrollRow, error)
+	ListStatementLines(ctx context.Context, arg ListStatementLinesParams) ([]ListStatementLinesRow, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	// accounts with accruals before to_date not posted yet, after_id pages through them
+	ListUnpostedInterestAccounts(ctx context.Context, arg ListUnpostedInterestAccountsParams) ([]int64, error)
+	// the events to publish in id order. The events of an account wait behind its first pending event until that one
+	// is due again, so an account that keeps failing does not fill the batches and hold back the other accounts.
+	ListUnpublishedOutboxEvents(ctx context.Context, limit int32) ([]OutboxEvent, error)
+	ListWebhookAttempts(ctx context.Context, deliveryID int64) ([]WebhookAttempt, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]ListWebhookDeliveriesRow, error)
+	ListWebhookSubscriptions(ctx context.Context, username string) ([]WebhookSubscription, error)
+	MarkOutboxEventsPublished(ctx context.Context, ids []int64) error
+	// marks the accruals of the account before to_date not posted yet as posted in the period and sums them,
+	// so an accrual recorded after its month was posted is credited by the next posting
+	PostInterestAccruals(ctx context.Context, arg PostInterestAccrualsParams) (int64, error)
+	// RecordLoginAttempt counts an attempt as a failure before the credentials are checked,
+	// so concurrent attempts cannot get past the limits. No row is returned while the subject is blocked.
+	// hold_until blocks the subject until the attempt is failed or released.
+	RecordLoginAttempt(ctx context.Context, arg RecordLoginAttemptParams) (LoginFailure, error)
+	// ReleaseLoginAttempt takes back a recorded attempt whose credentials were valid.
+	ReleaseLoginAttempt(ctx context.Context, arg ReleaseLoginAttemptParams) error
+	ReplayWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	RetryTask(ctx context.Context, arg RetryTaskParams) error
+	RetryWebhookDelivery(ctx context.Context, arg RetryWebhookDeliveryParams) error
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error)
+	SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) (User, error)
+	// refills the bucket for the time since its last update, then takes a token if one is left
+	TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (TakeRateLimitTokenRow, error)
+	TouchAPIKey(ctx context.Context, id uuid.UUID) error
+	// TryLockOutboxRelay takes the lock of the outbox relay until the end of the transaction,
+	// it returns false when another relay holds it.
+	TryLockOutboxRelay(ctx context.Context) (bool, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateVerifyEmail(ctx context.Context, arg UpdateVerifyEmailParams) (VerifyEmail, error)
+	UpsertCurrency(ctx context.Context, arg UpsertCurrencyParams) (Currency, error)
+	UpsertFeeSchedule(ctx context.Context, arg UpsertFeeScheduleParams) (FeeSchedule, error)
+	UpsertFeeWaiver(ctx context.Context, arg UpsertFeeWaiverParams) (FeeWaiver, error)
+	// UseMFAToken marks an mfa pending token as exchanged and reports 0 rows if it already was.
+	// The tokens that expired are dropped on the way, they are refused anyway.
+	UseMFAToken(ctx context.Context, arg UseMFATokenParams) (int64, error)
+	UsePasswordReset(ctx context.Context, tokenHash string) (PasswordReset, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	// UseTOTPStep accepts a TOTP code of the step only once, and never after a code of a later step.
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error)
+	VerifyUserEmail(ctx context.Context, username string) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,62 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: rate_limit.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const deleteRateLimitBuckets = `-- name: DeleteRateLimitBuckets :execrows
+DELETE
+FROM rate_limit_buckets
+WHERE updated_at < $1
+`
+
+func (q *Queries) DeleteRateLimitBuckets(ctx context.Context, updatedBefore time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRateLimitBuckets, updatedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const takeRateLimitToken = `-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+VALUES ($1, $2::float8 - 1, TRUE, now())
+ON CONFLICT (key) DO UPDATE
+    SET tokens     = CASE
+                         WHEN least($2::float8,
+                                    b.tokens + extract(epoch FROM now() - b.updated_at) * $3::float8) >= 1
+                             THEN least($2::float8,
+                                        b.tokens + extract(epoch FROM now() - b.updated_at) * $3::float8) - 1
+                         ELSE least($2::float8,
+                                    b.tokens + extract(epoch FROM now() - b.updated_at) * $3::float8)
+        END,
+        allowed    = least($2::float8,
+                           b.tokens + extract(epoch FROM now() - b.updated_at) * $3::float8) >= 1,
+        updated_at = now()
+RETURNING tokens, allowed
+`
+
+type TakeRateLimitTokenParams struct {
+	Key   string  `json:"key"`
+	Burst float64 `json:"burst"`
+	Rate  float64 `json:"rate"`
+}
+
+type TakeRateLimitTokenRow struct {
+	Tokens  float64 `json:"tokens"`
+	Allowed bool    `json:"allowed"`
+}
+
+// refills the bucket for the time since its last update, then takes a token if one is left
+func (q *Queries) TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (TakeRateLimitTokenRow, error) {
+	row := q.db.QueryRow(ctx, takeRateLimitToken, arg.Key, arg.Burst, arg.Rate)
+	var i TakeRateLimitTokenRow
+	err := row.Scan(&i.Tokens, &i.Allowed)
+	return i, err
+}
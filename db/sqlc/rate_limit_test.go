@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeRateLimitToken(t *testing.T) {
+	var arg = TakeRateLimitTokenParams{
+		Key:   "test:" + util.RandomString(12),
+		Burst: 2,
+		// slow enough that no token comes back during the test
+		Rate: 0.001,
+	}
+
+	var row, err = testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, row.Allowed)
+	require.InDelta(t, 1, row.Tokens, 0.01)
+
+	row, err = testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, row.Allowed)
+	require.InDelta(t, 0, row.Tokens, 0.01)
+
+	row, err = testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, row.Allowed)
+	require.Less(t, row.Tokens, 1.0)
+
+	// a fast rate refills the bucket up to the burst only
+	arg.Rate = 1e6
+	row, err = testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, row.Allowed)
+	require.InDelta(t, 1, row.Tokens, 0.01)
+}
+
+func TestDeleteRateLimitBuckets(t *testing.T) {
+	var key = "test:" + util.RandomString(12)
+	var _, err = testQueries.TakeRateLimitToken(context.Background(), TakeRateLimitTokenParams{Key: key, Burst: 1, Rate: 1})
+	require.NoError(t, err)
+
+	deleted, err := testQueries.DeleteRateLimitBuckets(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+
+	// the bucket starts full again
+	row, err := testQueries.TakeRateLimitToken(context.Background(), TakeRateLimitTokenParams{Key: key, Burst: 1, Rate: 0.001})
+	require.NoError(t, err)
+	require.True(t, row.Allowed)
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetUserTOTPSecret(t *testing.T) {
+	var user = createRandomUser(t)
+	require.False(t, user.TotpSecret.Valid)
+	require.False(t, user.TotpEnabled)
+
+	// enabling without a pending secret matches no user
+	var _, err = testQueries.EnableUserTOTP(context.Background(), user.Username)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	var secret = pgtype.Text{String: util.RandomString(32), Valid: true}
+	updated, err := testQueries.SetUserTOTPSecret(context.Background(), SetUserTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: secret,
+	})
+	require.NoError(t, err)
+	require.Equal(t, secret, updated.TotpSecret)
+	require.False(t, updated.TotpEnabled)
+
+	updated, err = testQueries.EnableUserTOTP(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.True(t, updated.TotpEnabled)
+}
+
+func TestEnableTOTPTx(t *testing.T) {
+	var store = NewStore(testDB)
+	var user = createRandomUser(t)
+
+	var _, err = testQueries.SetUserTOTPSecret(context.Background(), SetUserTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: pgtype.Text{String: util.RandomString(32), Valid: true},
+	})
+	require.NoError(t, err)
+
+	var arg = EnableTOTPTxParams{
+		Username:           user.Username,
+		RecoveryCodeHashes: []string{util.RandomString(64), util.RandomString(64)},
+	}
+	result, err := store.EnableTOTPTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.User.TotpEnabled)
+	require.Len(t, result.RecoveryCodes, 2)
+
+	// enabling again replaces the old recovery codes
+	var staleCode = arg.RecoveryCodeHashes[0]
+	arg.RecoveryCodeHashes = []string{util.RandomString(64)}
+	_, err = store.EnableTOTPTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: staleCode,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	var user = createRandomUser(t)
+	var arg = CreateRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: util.RandomString(64),
+	}
+	var code, err = testQueries.CreateRecoveryCode(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, code.UsedAt.Valid)
+
+	used, err := testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams(arg))
+	require.NoError(t, err)
+	require.Equal(t, code.ID, used.ID)
+	require.True(t, used.UsedAt.Valid)
+	require.WithinDuration(t, time.Now(), used.UsedAt.Time, time.Second)
+
+	// a recovery code only works once
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams(arg))
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	var user = createRandomUser(t)
+	var step = time.Now().Unix() / 30
+
+	var rows, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: step})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+
+	// the same step or an earlier one is a replay
+	for _, replayed := range []int64{step, step - 1} {
+		rows, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: replayed})
+		require.NoError(t, err)
+		require.Zero(t, rows)
+	}
+
+	rows, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: step + 1})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+}
+
+func TestUseMFAToken(t *testing.T) {
+	var arg = UseMFATokenParams{
+		ID:        uuid.New(),
+		ExpiredAt: time.Now().Add(time.Minute),
+	}
+
+	var rows, err = testQueries.UseMFAToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+
+	// a token is exchanged once
+	rows, err = testQueries.UseMFAToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, rows)
+}
+
+func TestLoginMFATx(t *testing.T) {
+	var store = NewStore(testDB)
+	var user = createRandomUser(t)
+	var codes = make([]CreateRecoveryCodeParams, 2)
+	for i := range codes {
+		codes[i] = CreateRecoveryCodeParams{Username: user.Username, CodeHash: util.RandomString(64)}
+		var _, err = testQueries.CreateRecoveryCode(context.Background(), codes[i])
+		require.NoError(t, err)
+	}
+
+	var arg = LoginMFATxParams{
+		MFAToken: UseMFATokenParams{ID: uuid.New(), ExpiredAt: time.Now().Add(time.Minute)},
+		Username: user.Username,
+	}
+
+	// a wrong code leaves the token to try again
+	arg.RecoveryCodeHash = util.RandomString(64)
+	require.ErrorIs(t, store.LoginMFATx(context.Background(), arg), ErrRecordNotFound)
+
+	arg.RecoveryCodeHash = codes[0].CodeHash
+	require.NoError(t, store.LoginMFATx(context.Background(), arg))
+
+	// a replayed token is refused before it consumes another code
+	arg.RecoveryCodeHash = codes[1].CodeHash
+	require.ErrorIs(t, store.LoginMFATx(context.Background(), arg), ErrMFATokenUsed)
+	arg.TOTPStep, arg.RecoveryCodeHash = time.Now().Unix()/30, ""
+	require.ErrorIs(t, store.LoginMFATx(context.Background(), arg), ErrMFATokenUsed)
+
+	var _, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams(codes[1]))
+	require.NoError(t, err)
+	rows, err := testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: arg.TOTPStep})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+}
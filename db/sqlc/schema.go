@@ -0,0 +1,31 @@
+package db
+
+import "context"
+
+// SchemaVersion is the migration version the queries in this package are generated against.
+// Bump it together with every new migration in db/migration.
+const SchemaVersion int64 = 24
+
+const getSchemaMigration = `SELECT version, dirty
+FROM schema_migrations
+LIMIT 1
+`
+
+// SchemaMigration is the migration state golang-migrate keeps in the schema_migrations table.
+type SchemaMigration struct {
+	Version int64 `json:"version"`
+	Dirty   bool  `json:"dirty"`
+}
+
+// Ping verifies the connection to the database is still alive.
+func (store *SQLStore) Ping(ctx context.Context) error {
+	return store.connPool.Ping(ctx)
+}
+
+// GetSchemaMigration returns the migration version currently applied to the database.
+func (store *SQLStore) GetSchemaMigration(ctx context.Context) (SchemaMigration, error) {
+	var row = store.connPool.QueryRow(ctx, getSchemaMigration)
+	var migration SchemaMigration
+	var err = row.Scan(&migration.Version, &migration.Dirty)
+	return migration, err
+}
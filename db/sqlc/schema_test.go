@@ -0,0 +1,22 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPing(t *testing.T) {
+	var store = NewStore(testDB)
+	require.NoError(t, store.Ping(context.Background()))
+}
+
+func TestGetSchemaMigration(t *testing.T) {
+	var store = NewStore(testDB)
+
+	var migration, err = store.GetSchemaMigration(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, SchemaVersion, migration.Version)
+	require.False(t, migration.Dirty)
+}
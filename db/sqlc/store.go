@@ -2,32 +2,85 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Store provides all functions to execute db queries and transactions
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error)
+	AdjustBalanceTx(ctx context.Context, arg AdjustBalanceTxParams) (AdjustBalanceTxResult, error)
+	MultiTransferTx(ctx context.Context, arg MultiTransferTxParams) (MultiTransferTxResult, error)
+	PayrollTx(ctx context.Context, arg PayrollTxParams) (PayrollTxResult, error)
+	RelayOutboxTx(ctx context.Context, arg RelayOutboxTxParams) (int, error)
+	StatementTx(ctx context.Context, arg StatementTxParams) error
+	CreateInterestRunTx(ctx context.Context, arg CreateInterestRunTxParams) (bool, error)
+	PostInterestTx(ctx context.Context, arg PostInterestTxParams) (PostInterestTxResult, error)
+	CreateFeeRunTx(ctx context.Context, arg CreateFeeRunTxParams) (bool, error)
+	ChargeMaintenanceFeeTx(ctx context.Context, arg ChargeMaintenanceFeeTxParams) (FeeCharge, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error)
+	EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (EnableTOTPTxResult, error)
+	LoginMFATx(ctx context.Context, arg LoginMFATxParams) error
+	Ping(ctx context.Context) error
+	GetSchemaMigration(ctx context.Context) (SchemaMigration, error)
+	TxStats() TxStats
 }
 
 // SQLStore provides all functions to execute SQL queries and transactions
 type SQLStore struct {
 	*Queries
-	db *sql.DB
+	connPool *pgxpool.Pool
+	retry    TxRetryPolicy
+	stats    txCounters
+}
+
+// NewStore creates a Store running its queries on the connection pool.
+// Transactions are retried with DefaultTxRetryPolicy.
+func NewStore(connPool *pgxpool.Pool) Store {
+	return NewStoreWithRetryPolicy(connPool, DefaultTxRetryPolicy)
 }
 
-func NewStore(db *sql.DB) Store {
+// NewStoreWithRetryPolicy creates a Store retrying its transactions with the given policy.
+func NewStoreWithRetryPolicy(connPool *pgxpool.Pool, retry TxRetryPolicy) Store {
 	return &SQLStore{
-		Queries: New(db),
-		db:      db,
+		Queries:  New(connPool),
+		connPool: connPool,
+		retry:    retry,
+	}
+}
+
+// execTx executes a function within a database transaction.
+// The whole transaction, fn included, is run again when it fails with a serialization failure or a deadlock,
+// so fn must not have side effects outside of the transaction.
+func (store *SQLStore) execTx(ctx context.Context, txOptions pgx.TxOptions, fn func(*Queries) error) error {
+	for attempt := 1; ; attempt++ {
+		store.stats.attempts.Add(1)
+		var err = store.runTx(ctx, txOptions, fn)
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+		if attempt >= store.retry.MaxAttempts {
+			store.stats.exhausted.Add(1)
+			return err
+		}
+
+		store.stats.retries.Add(1)
+		if err := sleep(ctx, store.retry.delay(attempt)); err != nil {
+			return err
+		}
 	}
 }
 
-// execTx executes a function within a database transaction
-func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	var tx, err = store.db.BeginTx(ctx, nil)
+func (store *SQLStore) runTx(ctx context.Context, txOptions pgx.TxOptions, fn func(*Queries) error) error {
+	var tx, err = store.connPool.BeginTx(ctx, txOptions)
 	if err != nil {
 		return err
 	}
@@ -36,12 +89,12 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 	err = fn(q)
 
 	if err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("tx err: %w, rb err: %v", err, rbErr)
 		}
 		return err
 	}
-	return tx.Commit()
+	return tx.Commit(ctx)
 }
 
 // TransferTxParams contains the input parameters of the transfer transaction
@@ -58,52 +111,93 @@ type TransferTxResult struct {
 	ToEntry     Entry    `json:"to_entry"`
 	FromAccount Account  `json:"from_account"`
 	ToAccount   Account  `json:"to_account"`
+	// Fees are charged to the sender on top of the amount.
+	Fees []FeeCharge `json:"fees"`
 }
 
 // TransferTx performs a money transfer from one account to the other.
-// It creates a transfer record, add account entries, and update accounts' balance within a single db transaction
+// It creates a transfer record, add account entries, and update accounts' balance within a single db transaction.
+// The transfer fee of the sender is charged in the same transaction, as a separate transfer to the bank.
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	var err = store.execTx(ctx, func(queries *Queries) error {
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
 		var err error
+		result, err = transferWithFee(ctx, queries, arg)
+		return err
+	})
 
-		result.Transfer, err = queries.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+	return result, err
+}
 
-		result.FromEntry, err = queries.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+// transferWithFee runs transfer and charges the transfer fee of the sender within the transaction of queries.
+func transferWithFee(ctx context.Context, queries *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	var result, err = transfer(ctx, queries, arg)
+	if err != nil {
+		return result, err
+	}
 
-		result.ToEntry, err = queries.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+	fee, err := feeFor(ctx, queries, FeeKindTransfer, result.FromAccount, arg.Amount)
+	if err != nil || fee == 0 {
+		return result, err
+	}
+	var charge FeeCharge
+	var chargedTransferID = pgtype.Int8{Int64: result.Transfer.ID, Valid: true}
+	charge, result.FromAccount, err = chargeFee(ctx, queries, result.FromAccount, FeeKindTransfer, fee, chargedTransferID, pgtype.Date{})
+	result.Fees = []FeeCharge{charge}
+	return result, err
+}
 
-		// to avoid deadlock, we always update the account with smaller ID first
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, queries, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, queries, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
-		}
+// transfer moves the money between the two accounts of arg within the transaction of queries,
+// and records the outbox events of the entries and the webhook events of the owners.
+func transfer(ctx context.Context, queries *Queries, arg TransferTxParams) (result TransferTxResult, err error) {
+	result.Transfer, err = queries.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+	})
+	if err != nil {
+		return
+	}
 
-		return err
+	result.FromEntry, err = queries.CreateTransferEntry(ctx, CreateTransferEntryParams{
+		AccountID:  arg.FromAccountID,
+		Amount:     -arg.Amount,
+		TransferID: result.Transfer.ID,
 	})
+	if err != nil {
+		return
+	}
 
-	return result, err
+	result.ToEntry, err = queries.CreateTransferEntry(ctx, CreateTransferEntryParams{
+		AccountID:  arg.ToAccountID,
+		Amount:     arg.Amount,
+		TransferID: result.Transfer.ID,
+	})
+	if err != nil {
+		return
+	}
+
+	// to avoid deadlock, we always update the account with smaller ID first
+	if arg.FromAccountID < arg.ToAccountID {
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, queries, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
+	} else {
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, queries, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+	}
+	if err != nil {
+		return
+	}
+	if err = checkBalance(result.FromAccount); err != nil {
+		return
+	}
+	if err = recordEntry(ctx, queries, result.FromEntry, result.FromAccount); err != nil {
+		return
+	}
+	if err = recordEntry(ctx, queries, result.ToEntry, result.ToAccount); err != nil {
+		return
+	}
+	err = recordTransfer(ctx, queries, result)
+	return
 }
 
 func addMoney(
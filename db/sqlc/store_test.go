@@ -2,9 +2,12 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"math/rand/v2"
 	"testing"
 
+	"github.com/Ma-hiru/simplebank/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -61,6 +64,7 @@ func TestTransferTx(t *testing.T) {
 		require.NotEmpty(t, fromEntry)
 		require.Equal(t, account1.ID, fromEntry.AccountID)
 		require.Equal(t, -amount, fromEntry.Amount)
+		require.Equal(t, transfer.ID, fromEntry.TransferID.Int64)
 		require.NotZero(t, fromEntry.ID)
 		require.NotZero(t, fromEntry.CreatedAt)
 
@@ -71,6 +75,7 @@ func TestTransferTx(t *testing.T) {
 		require.NotEmpty(t, toEntry)
 		require.Equal(t, account2.ID, toEntry.AccountID)
 		require.Equal(t, amount, toEntry.Amount)
+		require.Equal(t, transfer.ID, toEntry.TransferID.Int64)
 		require.NotZero(t, toEntry.ID)
 		require.NotZero(t, toEntry.CreatedAt)
 
@@ -159,3 +164,74 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, account1.Balance, updatedAccount1.Balance)
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 }
+
+// BenchmarkTransferTx measures TransferTx throughput between random accounts, once per query exec mode of pgx
+// and once on lib/pq, the driver before the move to pgx, running the same queries through database/sql.
+// The exec and simple_protocol modes pay the round-trips of an unprepared statement on every query,
+// like lib/pq does, while cache_statement prepares each query once per connection.
+//
+//	go test ./db/sqlc -run '^$' -bench TransferTx -benchtime 2000x
+func BenchmarkTransferTx(b *testing.B) {
+	var config, err = util.LoadConfig("../..", "app")
+	require.NoError(b, err)
+
+	var accounts = make([]Account, 20)
+	for i := range accounts {
+		accounts[i] = createRandomAccount(b)
+	}
+
+	b.Run("lib_pq", func(b *testing.B) {
+		var sqlDB, err = sql.Open("postgres", config.DBSource)
+		require.NoError(b, err)
+		defer sqlDB.Close()
+		sqlDB.SetMaxOpenConns(int(config.DBMaxConns))
+
+		benchmarkTransfers(b, accounts, func(ctx context.Context, arg TransferTxParams) error {
+			var _, err = pqTransferTx(ctx, sqlDB, arg)
+			return err
+		})
+	})
+
+	for _, mode := range []string{"exec", "simple_protocol", "cache_describe", "cache_statement"} {
+		b.Run(mode, func(b *testing.B) {
+			config.DBQueryExecMode = mode
+			var connPool, err = NewPool(context.Background(), config)
+			require.NoError(b, err)
+			defer connPool.Close()
+			var store = NewStore(connPool)
+
+			benchmarkTransfers(b, accounts, func(ctx context.Context, arg TransferTxParams) error {
+				var _, err = store.TransferTx(ctx, arg)
+				return err
+			})
+		})
+	}
+}
+
+// benchmarkTransfers runs transfer b.N times in parallel between random accounts.
+func benchmarkTransfers(b *testing.B, accounts []Account, transfer func(ctx context.Context, arg TransferTxParams) error) {
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var from, to = randomAccountPair(accounts)
+			var err = transfer(context.Background(), TransferTxParams{
+				FromAccountID: from.ID,
+				ToAccountID:   to.ID,
+				Amount:        1,
+			})
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func randomAccountPair(accounts []Account) (Account, Account) {
+	var i = rand.IntN(len(accounts))
+	var j = rand.IntN(len(accounts) - 1)
+	if j >= i {
+		j++
+	}
+	return accounts[i], accounts[j]
+}
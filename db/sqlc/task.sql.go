@@ -0,0 +1,181 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: task.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const claimTasks = `-- name: ClaimTasks :many
+UPDATE tasks
+SET status     = 'running',
+    attempts   = attempts + 1,
+    locked_at  = now(),
+    updated_at = now()
+WHERE id IN (SELECT t.id
+             FROM tasks t
+             WHERE (t.status = 'pending' AND t.run_at <= now())
+                OR (t.status = 'running' AND t.locked_at < $1::timestamptz)
+             ORDER BY t.run_at
+             LIMIT $2 FOR UPDATE SKIP LOCKED)
+RETURNING id, type, payload, status, attempts, max_attempts, last_error, run_at, locked_at, created_at, updated_at
+`
+
+type ClaimTasksParams struct {
+	StaleBefore time.Time `json:"stale_before"`
+	MaxTasks    int32     `json:"max_tasks"`
+}
+
+func (q *Queries) ClaimTasks(ctx context.Context, arg ClaimTasksParams) ([]Task, error) {
+	rows, err := q.db.Query(ctx, claimTasks, arg.StaleBefore, arg.MaxTasks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Task{}
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.MaxAttempts,
+			&i.LastError,
+			&i.RunAt,
+			&i.LockedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const completeTask = `-- name: CompleteTask :exec
+UPDATE tasks
+SET status     = 'completed',
+    locked_at  = NULL,
+    last_error = '',
+    updated_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) CompleteTask(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, completeTask, id)
+	return err
+}
+
+const createTask = `-- name: CreateTask :one
+INSERT INTO tasks (type, payload, max_attempts, run_at)
+VALUES ($1, $2, $3, $4)
+RETURNING id, type, payload, status, attempts, max_attempts, last_error, run_at, locked_at, created_at, updated_at
+`
+
+type CreateTaskParams struct {
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	MaxAttempts int32           `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+}
+
+func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error) {
+	row := q.db.QueryRow(ctx, createTask,
+		arg.Type,
+		arg.Payload,
+		arg.MaxAttempts,
+		arg.RunAt,
+	)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.Type,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.LastError,
+		&i.RunAt,
+		&i.LockedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const failTask = `-- name: FailTask :exec
+UPDATE tasks
+SET status     = 'failed',
+    locked_at  = NULL,
+    last_error = $1,
+    updated_at = now()
+WHERE id = $2
+`
+
+type FailTaskParams struct {
+	LastError string `json:"last_error"`
+	ID        int64  `json:"id"`
+}
+
+func (q *Queries) FailTask(ctx context.Context, arg FailTaskParams) error {
+	_, err := q.db.Exec(ctx, failTask, arg.LastError, arg.ID)
+	return err
+}
+
+const getTask = `-- name: GetTask :one
+SELECT id, type, payload, status, attempts, max_attempts, last_error, run_at, locked_at, created_at, updated_at
+FROM tasks
+WHERE id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetTask(ctx context.Context, id int64) (Task, error) {
+	row := q.db.QueryRow(ctx, getTask, id)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.Type,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.LastError,
+		&i.RunAt,
+		&i.LockedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const retryTask = `-- name: RetryTask :exec
+UPDATE tasks
+SET status     = 'pending',
+    locked_at  = NULL,
+    last_error = $1,
+    run_at     = $2,
+    updated_at = now()
+WHERE id = $3
+`
+
+type RetryTaskParams struct {
+	LastError string    `json:"last_error"`
+	RunAt     time.Time `json:"run_at"`
+	ID        int64     `json:"id"`
+}
+
+func (q *Queries) RetryTask(ctx context.Context, arg RetryTaskParams) error {
+	_, err := q.db.Exec(ctx, retryTask, arg.LastError, arg.RunAt, arg.ID)
+	return err
+}
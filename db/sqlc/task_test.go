@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomTask(t *testing.T) Task {
+	var arg = CreateTaskParams{
+		Type:        "task:" + util.RandomOwner(),
+		Payload:     json.RawMessage(`{"username":"` + util.RandomOwner() + `"}`),
+		MaxAttempts: 3,
+		RunAt:       time.Now(),
+	}
+
+	var task, err = testQueries.CreateTask(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.NotZero(t, task.ID)
+	require.Equal(t, arg.Type, task.Type)
+	require.JSONEq(t, string(arg.Payload), string(task.Payload))
+	require.Equal(t, "pending", task.Status)
+	require.Zero(t, task.Attempts)
+	require.Equal(t, arg.MaxAttempts, task.MaxAttempts)
+	require.False(t, task.LockedAt.Valid)
+	return task
+}
+
+func TestCreateTask(t *testing.T) {
+	createRandomTask(t)
+}
+
+func TestClaimTasks(t *testing.T) {
+	var task = createRandomTask(t)
+
+	// tasks are claimed by other tests in parallel, so look for ours among everything claimed
+	var claimed Task
+	for claimed.ID != task.ID {
+		var tasks, err = testQueries.ClaimTasks(context.Background(), ClaimTasksParams{
+			StaleBefore: time.Now().Add(-time.Hour),
+			MaxTasks:    100,
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, tasks)
+
+		for _, other := range tasks {
+			if other.ID == task.ID {
+				claimed = other
+			}
+		}
+	}
+
+	require.Equal(t, "running", claimed.Status)
+	require.Equal(t, int32(1), claimed.Attempts)
+	require.True(t, claimed.LockedAt.Valid)
+
+	// a running task is not claimed again until it becomes stale
+	var tasks, err = testQueries.ClaimTasks(context.Background(), ClaimTasksParams{
+		StaleBefore: time.Now().Add(-time.Hour),
+		MaxTasks:    100,
+	})
+	require.NoError(t, err)
+	for _, other := range tasks {
+		require.NotEqual(t, task.ID, other.ID)
+	}
+}
+
+func TestCompleteTask(t *testing.T) {
+	var task = createRandomTask(t)
+
+	require.NoError(t, testQueries.CompleteTask(context.Background(), task.ID))
+
+	var got, err = testQueries.GetTask(context.Background(), task.ID)
+	require.NoError(t, err)
+	require.Equal(t, "completed", got.Status)
+	require.False(t, got.LockedAt.Valid)
+}
+
+func TestRetryTask(t *testing.T) {
+	var task = createRandomTask(t)
+	var runAt = time.Now().Add(time.Minute)
+
+	var err = testQueries.RetryTask(context.Background(), RetryTaskParams{
+		ID:        task.ID,
+		LastError: "boom",
+		RunAt:     runAt,
+	})
+	require.NoError(t, err)
+
+	got, err := testQueries.GetTask(context.Background(), task.ID)
+	require.NoError(t, err)
+	require.Equal(t, "pending", got.Status)
+	require.Equal(t, "boom", got.LastError)
+	require.WithinDuration(t, runAt, got.RunAt, time.Second)
+}
+
+func TestFailTask(t *testing.T) {
+	var task = createRandomTask(t)
+
+	var err = testQueries.FailTask(context.Background(), FailTaskParams{
+		ID:        task.ID,
+		LastError: "boom",
+	})
+	require.NoError(t, err)
+
+	got, err := testQueries.GetTask(context.Background(), task.ID)
+	require.NoError(t, err)
+	require.Equal(t, "failed", got.Status)
+	require.Equal(t, "boom", got.LastError)
+}
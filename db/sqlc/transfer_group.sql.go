@@ -0,0 +1,112 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: transfer_group.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createGroupEntry = `-- name: CreateGroupEntry :one
+INSERT INTO entries (account_id, amount, transfer_group_id)
+VALUES ($1, $2, $3::bigint)
+RETURNING id, account_id, amount, created_at, transfer_group_id, transfer_id
+`
+
+type CreateGroupEntryParams struct {
+	AccountID       int64 `json:"account_id"`
+	Amount          int64 `json:"amount"`
+	TransferGroupID int64 `json:"transfer_group_id"`
+}
+
+func (q *Queries) CreateGroupEntry(ctx context.Context, arg CreateGroupEntryParams) (Entry, error) {
+	row := q.db.QueryRow(ctx, createGroupEntry, arg.AccountID, arg.Amount, arg.TransferGroupID)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.TransferGroupID,
+		&i.TransferID,
+	)
+	return i, err
+}
+
+const createTransferGroup = `-- name: CreateTransferGroup :one
+INSERT INTO transfer_groups (currency, description)
+VALUES ($1, $2)
+RETURNING id, currency, description, created_at
+`
+
+type CreateTransferGroupParams struct {
+	Currency    string `json:"currency"`
+	Description string `json:"description"`
+}
+
+func (q *Queries) CreateTransferGroup(ctx context.Context, arg CreateTransferGroupParams) (TransferGroup, error) {
+	row := q.db.QueryRow(ctx, createTransferGroup, arg.Currency, arg.Description)
+	var i TransferGroup
+	err := row.Scan(
+		&i.ID,
+		&i.Currency,
+		&i.Description,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getTransferGroup = `-- name: GetTransferGroup :one
+SELECT id, currency, description, created_at
+FROM transfer_groups
+WHERE id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetTransferGroup(ctx context.Context, id int64) (TransferGroup, error) {
+	row := q.db.QueryRow(ctx, getTransferGroup, id)
+	var i TransferGroup
+	err := row.Scan(
+		&i.ID,
+		&i.Currency,
+		&i.Description,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listGroupEntries = `-- name: ListGroupEntries :many
+SELECT id, account_id, amount, created_at, transfer_group_id, transfer_id
+FROM entries
+WHERE transfer_group_id = $1::bigint
+ORDER BY id
+`
+
+func (q *Queries) ListGroupEntries(ctx context.Context, transferGroupID int64) ([]Entry, error) {
+	rows, err := q.db.Query(ctx, listGroupEntries, transferGroupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransferGroupID,
+			&i.TransferID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AdjustBalanceTxParams contains the input parameters of the adjust balance transaction
+type AdjustBalanceTxParams struct {
+	AccountID int64 `json:"account_id"`
+	// Balance is the balance the account must have after the adjustment.
+	Balance int64 `json:"balance"`
+}
+
+// AdjustBalanceTxResult is the result of the adjust balance transaction
+type AdjustBalanceTxResult struct {
+	Account Account `json:"account"`
+	// Entry is empty when the account already had the balance.
+	Entry Entry `json:"entry"`
+}
+
+// AdjustBalanceTx sets the balance of an account, booking the difference as an entry so that statements and
+// past balances, which are derived from the entries, stay consistent with it.
+func (store *SQLStore) AdjustBalanceTx(ctx context.Context, arg AdjustBalanceTxParams) (AdjustBalanceTxResult, error) {
+	var result AdjustBalanceTxResult
+
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		var account, err = queries.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+		result.Account = account
+		var amount = arg.Balance - account.Balance
+		if amount == 0 {
+			return nil
+		}
+
+		result.Entry, err = queries.CreateEntry(ctx, CreateEntryParams{AccountID: account.ID, Amount: amount})
+		if err != nil {
+			return err
+		}
+		result.Account, err = queries.AddAccountBalance(ctx, AddAccountBalanceParams{ID: account.ID, Amount: amount})
+		if err != nil {
+			return err
+		}
+		return recordEntry(ctx, queries, result.Entry, result.Account)
+	})
+
+	return result, err
+}
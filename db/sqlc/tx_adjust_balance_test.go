@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjustBalanceTx(t *testing.T) {
+	var store = NewStore(testDB)
+	var account = createRandomAccountIn(t, util.USD)
+	var from = time.Now().Add(-time.Second)
+
+	var result, err = store.AdjustBalanceTx(context.Background(), AdjustBalanceTxParams{
+		AccountID: account.ID,
+		Balance:   account.Balance + 150,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+150, result.Account.Balance)
+	require.Equal(t, account.ID, result.Entry.AccountID)
+	require.Equal(t, int64(150), result.Entry.Amount)
+
+	// the statement balances are derived from the entries, so they see the adjustment
+	var balances, lines = readStatement(t, StatementTxParams{
+		AccountID: account.ID,
+		From:      from,
+		To:        time.Now().Add(time.Second),
+		PageSize:  10,
+	})
+	require.Equal(t, account.Balance, balances.OpeningBalance)
+	require.Equal(t, account.Balance+150, balances.ClosingBalance)
+	require.Len(t, lines, 1)
+	require.Equal(t, result.Entry.ID, lines[0].ID)
+
+	// an unchanged balance books nothing
+	result, err = store.AdjustBalanceTx(context.Background(), AdjustBalanceTxParams{
+		AccountID: account.ID,
+		Balance:   account.Balance + 150,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+150, result.Account.Balance)
+	require.Zero(t, result.Entry.ID)
+
+	_, err = store.AdjustBalanceTx(context.Background(), AdjustBalanceTxParams{AccountID: -1, Balance: 1})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateAccountTx creates an account and records its account.created outbox and webhook events within a single db transaction
+func (store *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	var account Account
+
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		var err error
+		account, err = queries.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+		if err = recordOutbox(ctx, queries, account.ID, OutboxAccountCreated, account); err != nil {
+			return err
+		}
+		return recordEvent(ctx, queries, account.Owner, EventAccountCreated, AccountEvent{Account: account})
+	})
+
+	return account, err
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateUserTxParams contains the input parameters of the create user transaction
+type CreateUserTxParams struct {
+	CreateUserParams
+	// AfterCreate runs inside the same transaction once the user is inserted.
+	// Anything written through q is committed or rolled back together with the user.
+	AfterCreate func(q Querier, user User) error
+}
+
+// CreateUserTxResult is the result of the create user transaction
+type CreateUserTxResult struct {
+	User User
+}
+
+// CreateUserTx creates a new user and runs the AfterCreate callback within a single db transaction
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		var err error
+
+		result.User, err = queries.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		if arg.AfterCreate == nil {
+			return nil
+		}
+		return arg.AfterCreate(queries, result.User)
+	})
+
+	return result, err
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// EnableTOTPTxParams contains the input parameters of the enable TOTP transaction
+type EnableTOTPTxParams struct {
+	Username           string
+	RecoveryCodeHashes []string
+}
+
+// EnableTOTPTxResult is the result of the enable TOTP transaction
+type EnableTOTPTxResult struct {
+	User          User
+	RecoveryCodes []RecoveryCode
+}
+
+// EnableTOTPTx turns on two-factor authentication for a user with a pending TOTP secret
+// and replaces their recovery codes, within a single db transaction.
+// It returns ErrRecordNotFound when the user has no TOTP secret.
+func (store *SQLStore) EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (EnableTOTPTxResult, error) {
+	var result EnableTOTPTxResult
+
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		var err error
+
+		result.User, err = queries.EnableUserTOTP(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		err = queries.DeleteRecoveryCodes(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.RecoveryCodes = make([]RecoveryCode, 0, len(arg.RecoveryCodeHashes))
+		for _, codeHash := range arg.RecoveryCodeHashes {
+			var recoveryCode, err = queries.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				Username: arg.Username,
+				CodeHash: codeHash,
+			})
+			if err != nil {
+				return err
+			}
+			result.RecoveryCodes = append(result.RecoveryCodes, recoveryCode)
+		}
+		return nil
+	})
+
+	return result, err
+}
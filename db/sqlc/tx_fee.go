@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// CreateFeeRunTxParams contains the input parameters of the create fee run transaction
+type CreateFeeRunTxParams struct {
+	// Period is the first day of the month the maintenance fees are charged for.
+	Period time.Time
+	// AfterCreate runs inside the same transaction once the run is recorded, it is skipped when the run already existed.
+	AfterCreate func(q Querier) error
+}
+
+// CreateFeeRunTx records that the maintenance fees of a month are scheduled and runs the AfterCreate callback
+// within a single db transaction. It reports whether the run was new.
+func (store *SQLStore) CreateFeeRunTx(ctx context.Context, arg CreateFeeRunTxParams) (bool, error) {
+	var created bool
+
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		var rows, err = queries.CreateFeeRun(ctx, arg.Period)
+		if err != nil {
+			return err
+		}
+		created = rows > 0
+
+		if !created || arg.AfterCreate == nil {
+			return nil
+		}
+		return arg.AfterCreate(queries)
+	})
+
+	return created, err
+}
+
+// ChargeMaintenanceFeeTxParams contains the input parameters of the charge maintenance fee transaction
+type ChargeMaintenanceFeeTxParams struct {
+	AccountID int64 `json:"account_id"`
+	// Period is the first day of the month the fee is charged for.
+	Period time.Time `json:"period"`
+}
+
+// ChargeMaintenanceFeeTx charges the monthly maintenance fee of an account, unless its owner has a waiver.
+// Accounts that may not be overdrawn are charged at most their balance.
+// A month is only charged once: charging it again returns the existing fee.
+// The returned charge is empty when nothing was charged.
+func (store *SQLStore) ChargeMaintenanceFeeTx(ctx context.Context, arg ChargeMaintenanceFeeTxParams) (FeeCharge, error) {
+	var result FeeCharge
+
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		var period = pgtype.Date{Time: arg.Period, Valid: true}
+		var err error
+		result.Fee, err = queries.GetMaintenanceFee(ctx, GetMaintenanceFeeParams{AccountID: arg.AccountID, Period: period})
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrRecordNotFound) {
+			return err
+		}
+
+		account, err := queries.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+		fee, err := feeFor(ctx, queries, FeeKindMaintenance, account, 0)
+		if err != nil {
+			return err
+		}
+		if !RulesOf(account.Type).Overdraft {
+			fee = min(fee, max(account.Balance, 0))
+		}
+		if fee == 0 {
+			return nil
+		}
+
+		result, _, err = chargeFee(ctx, queries, account, FeeKindMaintenance, fee, pgtype.Int8{}, period)
+		return err
+	})
+
+	return result, err
+}
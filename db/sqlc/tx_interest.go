@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// BankPurposeInterestExpense is the internal account the interest paid on savings is booked on.
+const BankPurposeInterestExpense = "interest_expense"
+
+// CreateInterestRunTxParams contains the input parameters of the create interest run transaction
+type CreateInterestRunTxParams struct {
+	AccrualDate time.Time
+	// AfterCreate runs inside the same transaction once the run is recorded, it is skipped when the run already existed.
+	AfterCreate func(q Querier) error
+}
+
+// CreateInterestRunTx records that the interest accrual of a day is scheduled and runs the AfterCreate callback
+// within a single db transaction. It reports whether the run was new.
+func (store *SQLStore) CreateInterestRunTx(ctx context.Context, arg CreateInterestRunTxParams) (bool, error) {
+	var created bool
+
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		var rows, err = queries.CreateInterestRun(ctx, arg.AccrualDate)
+		if err != nil {
+			return err
+		}
+		created = rows > 0
+
+		if !created || arg.AfterCreate == nil {
+			return nil
+		}
+		return arg.AfterCreate(queries)
+	})
+
+	return created, err
+}
+
+// PostInterestTxParams contains the input parameters of the post interest transaction
+type PostInterestTxParams struct {
+	AccountID int64 `json:"account_id"`
+	// Period is the first day of the month the interest was accrued in.
+	Period time.Time `json:"period"`
+}
+
+// PostInterestTxResult is the result of the post interest transaction
+type PostInterestTxResult struct {
+	Posting InterestPosting `json:"posting"`
+	// Transfer is empty when the interest rounded to nothing.
+	Transfer TransferTxResult `json:"transfer"`
+}
+
+// PostInterestTx credits the interest an account accrued up to the end of a month, transferring it from the interest
+// expense account of the bank in the account currency. The accruals not posted yet, which include those of earlier
+// months recorded after their month was posted, and the carry of the previous posting are rounded to minor units
+// and the remainder is carried over to the next posting.
+// A month is only posted once: posting it again returns the existing posting.
+func (store *SQLStore) PostInterestTx(ctx context.Context, arg PostInterestTxParams) (PostInterestTxResult, error) {
+	var result PostInterestTxResult
+
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		var period = GetInterestPostingParams{AccountID: arg.AccountID, Period: arg.Period}
+		var err error
+		result.Posting, err = queries.GetInterestPosting(ctx, period)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrRecordNotFound) {
+			return err
+		}
+
+		accrued, err := queries.PostInterestAccruals(ctx, PostInterestAccrualsParams{
+			Period:    arg.Period,
+			AccountID: arg.AccountID,
+			ToDate:    arg.Period.AddDate(0, 1, 0),
+		})
+		if err != nil {
+			return err
+		}
+		previous, err := queries.GetPreviousInterestPosting(ctx, GetPreviousInterestPostingParams(period))
+		if err != nil && !errors.Is(err, ErrRecordNotFound) {
+			return err
+		}
+		accrued += previous.CarryMicros
+
+		var amount, carry = util.RoundMicros(accrued)
+		if amount < 0 {
+			amount, carry = 0, accrued
+		}
+
+		var transferID pgtype.Int8
+		if amount > 0 {
+			account, err := queries.GetAccount(ctx, arg.AccountID)
+			if err != nil {
+				return err
+			}
+			expense, err := bankAccount(ctx, queries, BankPurposeInterestExpense, account.Currency)
+			if err != nil {
+				return err
+			}
+			result.Transfer, err = transfer(ctx, queries, TransferTxParams{
+				FromAccountID: expense.ID,
+				ToAccountID:   account.ID,
+				Amount:        amount,
+			})
+			if err != nil {
+				return err
+			}
+			transferID = pgtype.Int8{Int64: result.Transfer.Transfer.ID, Valid: true}
+		}
+
+		result.Posting, err = queries.CreateInterestPosting(ctx, CreateInterestPostingParams{
+			AccountID:     arg.AccountID,
+			Period:        arg.Period,
+			AccruedMicros: accrued,
+			Amount:        amount,
+			CarryMicros:   carry,
+			TransferID:    transferID,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// bankAccount returns the internal account of the bank for a purpose and a currency, opening it on first use.
+// When two transactions open it at once, the second waits for the first and uses its account.
+func bankAccount(ctx context.Context, queries *Queries, purpose string, currency string) (Account, error) {
+	var arg = GetBankAccountParams{Purpose: purpose, Currency: currency}
+	var bank, err = queries.GetBankAccount(ctx, arg)
+	if err == nil {
+		return queries.GetAccount(ctx, bank.AccountID)
+	}
+	if !errors.Is(err, ErrRecordNotFound) {
+		return Account{}, err
+	}
+
+	account, err := queries.CreateAccount(ctx, CreateAccountParams{
+		Owner:    BankUsername,
+		Balance:  0,
+		Currency: currency,
+		Type:     AccountTypeInternal,
+	})
+	if err != nil {
+		return Account{}, fmt.Errorf("failed to open %s account in %s: %w", purpose, currency, err)
+	}
+	_, err = queries.CreateBankAccount(ctx, CreateBankAccountParams{
+		Purpose:   purpose,
+		Currency:  currency,
+		AccountID: account.ID,
+	})
+	if !errors.Is(err, ErrRecordNotFound) {
+		return account, err
+	}
+
+	// another transaction opened it first, its row is visible once the insert gave way
+	if err = queries.DeleteAccount(ctx, account.ID); err != nil {
+		return Account{}, err
+	}
+	bank, err = queries.GetBankAccount(ctx, arg)
+	if err != nil {
+		return Account{}, err
+	}
+	return queries.GetAccount(ctx, bank.AccountID)
+}
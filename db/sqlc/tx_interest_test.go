@@ -0,0 +1,283 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSavingsAccount(t *testing.T, balance int64) Account {
+	var user = createRandomUser(t)
+	var account, err = testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  balance,
+		Currency: util.USD,
+		Type:     AccountTypeSavings,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountTypeSavings, account.Type)
+	return account
+}
+
+func TestSavingsAccountCannotOverdraw(t *testing.T) {
+	var store = NewStore(testDB)
+	var savings = createRandomSavingsAccount(t, 100)
+	var checking = createRandomAccountIn(t, util.USD)
+
+	var _, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: savings.ID,
+		ToAccountID:   checking.ID,
+		Amount:        101,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.MultiTransferTx(context.Background(), MultiTransferTxParams{
+		Currency: util.USD,
+		Legs: []TransferLeg{
+			{AccountID: savings.ID, Amount: -101},
+			{AccountID: checking.ID, Amount: 101},
+		},
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	account, err := store.GetAccount(context.Background(), savings.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), account.Balance)
+
+	// checking accounts keep allowing overdrafts
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: checking.ID,
+		ToAccountID:   savings.ID,
+		Amount:        checking.Balance + 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), result.FromAccount.Balance)
+}
+
+func TestOneAccountPerType(t *testing.T) {
+	var checking = createRandomAccountIn(t, util.USD)
+	var _, err = testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    checking.Owner,
+		Currency: util.USD,
+		Type:     AccountTypeSavings,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    checking.Owner,
+		Currency: util.USD,
+		Type:     AccountTypeSavings,
+	})
+	require.Equal(t, UniqueViolation, ErrorCode(err))
+}
+
+func TestListAccountBalancesAt(t *testing.T) {
+	var store = NewStore(testDB)
+	var savings = createRandomSavingsAccount(t, 1000)
+	var checking = createRandomAccountIn(t, util.USD)
+
+	var at = time.Now()
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: savings.ID,
+		ToAccountID:   checking.ID,
+		Amount:        300,
+	})
+	require.NoError(t, err)
+
+	balances, err := testQueries.ListAccountBalancesAt(context.Background(), ListAccountBalancesAtParams{
+		At:       at,
+		Type:     AccountTypeSavings,
+		AfterID:  savings.ID - 1,
+		PageSize: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, balances, 1)
+	require.Equal(t, savings.ID, balances[0].ID)
+	require.Equal(t, int64(1000), balances[0].Balance)
+
+	balances, err = testQueries.ListAccountBalancesAt(context.Background(), ListAccountBalancesAtParams{
+		At:       time.Now(),
+		Type:     AccountTypeSavings,
+		AfterID:  savings.ID - 1,
+		PageSize: 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(700), balances[0].Balance)
+}
+
+func TestCreateInterestRunTx(t *testing.T) {
+	var store = NewStore(testDB)
+	// a day long past, so the test does not move the latest run of the scheduler
+	var day = time.Date(1000, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(util.RandomInt(0, 300_000)))
+
+	var calls int
+	var arg = CreateInterestRunTxParams{
+		AccrualDate: day,
+		AfterCreate: func(q Querier) error {
+			calls++
+			return nil
+		},
+	}
+	created, err := store.CreateInterestRunTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	created, err = store.CreateInterestRunTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, 1, calls)
+}
+
+func accrueInterest(t *testing.T, account Account, day time.Time, micros int64) {
+	var rows, err = testQueries.CreateInterestAccrual(context.Background(), CreateInterestAccrualParams{
+		AccountID:    account.ID,
+		AccrualDate:  day,
+		Balance:      account.Balance,
+		RateBps:      250,
+		AmountMicros: micros,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+}
+
+func TestPostInterestTx(t *testing.T) {
+	var store = NewStore(testDB)
+	var account = createRandomSavingsAccount(t, 100_000)
+
+	var march = time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)
+	for day := range 3 {
+		accrueInterest(t, account, march.AddDate(0, 0, day), 1_400_000)
+	}
+	// accruing a day twice is ignored
+	rows, err := testQueries.CreateInterestAccrual(context.Background(), CreateInterestAccrualParams{
+		AccountID:    account.ID,
+		AccrualDate:  march,
+		AmountMicros: 1,
+	})
+	require.NoError(t, err)
+	require.Zero(t, rows)
+	// the next month is not part of the posting
+	accrueInterest(t, account, march.AddDate(0, 1, 0), 800_000)
+
+	result, err := store.PostInterestTx(context.Background(), PostInterestTxParams{AccountID: account.ID, Period: march})
+	require.NoError(t, err)
+	require.Equal(t, int64(4_200_000), result.Posting.AccruedMicros)
+	require.Equal(t, int64(4), result.Posting.Amount)
+	require.Equal(t, int64(200_000), result.Posting.CarryMicros)
+	require.Equal(t, result.Transfer.Transfer.ID, result.Posting.TransferID.Int64)
+	require.Equal(t, account.ID, result.Transfer.ToAccount.ID)
+	require.Equal(t, account.Balance+4, result.Transfer.ToAccount.Balance)
+	require.Equal(t, AccountTypeInternal, result.Transfer.FromAccount.Type)
+	require.Equal(t, BankUsername, result.Transfer.FromAccount.Owner)
+
+	bank, err := testQueries.GetBankAccount(context.Background(), GetBankAccountParams{
+		Purpose:  BankPurposeInterestExpense,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	require.Equal(t, bank.AccountID, result.Transfer.FromAccount.ID)
+
+	// posting the month again returns the first posting
+	again, err := store.PostInterestTx(context.Background(), PostInterestTxParams{AccountID: account.ID, Period: march})
+	require.NoError(t, err)
+	require.Equal(t, result.Posting, again.Posting)
+	require.Zero(t, again.Transfer.Transfer.ID)
+
+	// the carry of March is added to April
+	april, err := store.PostInterestTx(context.Background(), PostInterestTxParams{AccountID: account.ID, Period: march.AddDate(0, 1, 0)})
+	require.NoError(t, err)
+	require.Equal(t, int64(1_000_000), april.Posting.AccruedMicros)
+	require.Equal(t, int64(1), april.Posting.Amount)
+	require.Zero(t, april.Posting.CarryMicros)
+	require.Equal(t, account.Balance+5, april.Transfer.ToAccount.Balance)
+}
+
+func TestPostInterestTxLateAccrual(t *testing.T) {
+	var store = NewStore(testDB)
+	var account = createRandomSavingsAccount(t, 100_000)
+
+	var june = time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+	accrueInterest(t, account, june, 1_000_000)
+	june30, err := store.PostInterestTx(context.Background(), PostInterestTxParams{AccountID: account.ID, Period: june})
+	require.NoError(t, err)
+	require.Equal(t, int64(1_000_000), june30.Posting.AccruedMicros)
+
+	// the last day of June is accrued after June was posted, so July credits it
+	accrueInterest(t, account, june.AddDate(0, 1, -1), 2_000_000)
+	var july = june.AddDate(0, 1, 0)
+	accrueInterest(t, account, july, 500_000)
+
+	var to = july.AddDate(0, 1, 0)
+	accountIDs, err := testQueries.ListUnpostedInterestAccounts(context.Background(), ListUnpostedInterestAccountsParams{
+		ToDate:   to,
+		AfterID:  account.ID - 1,
+		PageSize: 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{account.ID}, accountIDs)
+
+	result, err := store.PostInterestTx(context.Background(), PostInterestTxParams{AccountID: account.ID, Period: july})
+	require.NoError(t, err)
+	require.Equal(t, int64(2_500_000), result.Posting.AccruedMicros)
+	require.Equal(t, int64(2), result.Posting.Amount)
+	require.Equal(t, int64(500_000), result.Posting.CarryMicros)
+
+	accountIDs, err = testQueries.ListUnpostedInterestAccounts(context.Background(), ListUnpostedInterestAccountsParams{
+		ToDate:   to,
+		AfterID:  account.ID - 1,
+		PageSize: 1,
+	})
+	require.NoError(t, err)
+	require.NotContains(t, accountIDs, account.ID)
+}
+
+func TestPostInterestTxRoundsToNothing(t *testing.T) {
+	var store = NewStore(testDB)
+	var account = createRandomSavingsAccount(t, 10)
+
+	var may = time.Date(2023, time.May, 1, 0, 0, 0, 0, time.UTC)
+	accrueInterest(t, account, may, 300_000)
+
+	var result, err = store.PostInterestTx(context.Background(), PostInterestTxParams{AccountID: account.ID, Period: may})
+	require.NoError(t, err)
+	require.Zero(t, result.Posting.Amount)
+	require.Equal(t, int64(300_000), result.Posting.CarryMicros)
+	require.False(t, result.Posting.TransferID.Valid)
+}
+
+func TestBankAccountOpenedConcurrently(t *testing.T) {
+	var store = NewStore(testDB).(*SQLStore)
+	var purpose = "test_" + util.RandomString(8)
+
+	// the transactions all miss the account, one opens it and the others use it
+	const n = 5
+	var ids = make(chan int64, n)
+	var errs = make(chan error, n)
+	for range n {
+		go func() {
+			errs <- store.execTx(context.Background(), pgx.TxOptions{}, func(queries *Queries) error {
+				var account, err = bankAccount(context.Background(), queries, purpose, util.EUR)
+				ids <- account.ID
+				return err
+			})
+		}()
+	}
+
+	var opened int64
+	for range n {
+		require.NoError(t, <-errs)
+		var id = <-ids
+		if opened == 0 {
+			opened = id
+		}
+		require.Equal(t, opened, id)
+	}
+
+	bank, err := testQueries.GetBankAccount(context.Background(), GetBankAccountParams{Purpose: purpose, Currency: util.EUR})
+	require.NoError(t, err)
+	require.Equal(t, opened, bank.AccountID)
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrMFATokenUsed is returned by LoginMFATx when the mfa pending token was already exchanged.
+var ErrMFATokenUsed = errors.New("mfa token has already been used")
+
+// LoginMFATxParams contains the input parameters of the mfa login transaction
+type LoginMFATxParams struct {
+	MFAToken UseMFATokenParams
+	Username string
+	// TOTPStep is the time step of a valid TOTP code. When zero, the recovery code is used instead.
+	TOTPStep         int64
+	RecoveryCodeHash string
+}
+
+// LoginMFATx claims an mfa pending token and consumes the second factor it is exchanged with,
+// the TOTP step or the recovery code, within a single db transaction.
+// A token is claimed before the factor, so a replayed token consumes nothing and concurrent
+// exchanges of one token wait for each other: a token uses at most one factor.
+// It returns ErrMFATokenUsed when the token was already exchanged, and ErrRecordNotFound when the factor
+// is not valid. The token is left unclaimed then, so the user can try another code.
+func (store *SQLStore) LoginMFATx(ctx context.Context, arg LoginMFATxParams) error {
+	return store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		var rows, err = queries.UseMFAToken(ctx, arg.MFAToken)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrMFATokenUsed
+		}
+
+		if arg.TOTPStep != 0 {
+			rows, err = queries.UseTOTPStep(ctx, UseTOTPStepParams{
+				Username: arg.Username,
+				Step:     arg.TOTPStep,
+			})
+			if err == nil && rows == 0 {
+				err = ErrRecordNotFound
+			}
+			return err
+		}
+
+		_, err = queries.UseRecoveryCode(ctx, UseRecoveryCodeParams{
+			Username: arg.Username,
+			CodeHash: arg.RecoveryCodeHash,
+		})
+		return err
+	})
+}
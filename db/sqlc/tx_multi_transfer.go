@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrTransferUnbalanced       = errors.New("transfer legs do not net to zero")
+	ErrTransferCurrencyMismatch = errors.New("account currency does not match the transfer currency")
+)
+
+// TransferLeg moves Amount into the account, or out of it when Amount is negative.
+type TransferLeg struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"`
+}
+
+// MultiTransferTxParams contains the input parameters of the multi-leg transfer transaction
+type MultiTransferTxParams struct {
+	Currency    string        `json:"currency"`
+	Description string        `json:"description"`
+	Legs        []TransferLeg `json:"legs"`
+}
+
+// MultiTransferTxResult is the result of the multi-leg transfer transaction
+type MultiTransferTxResult struct {
+	Group TransferGroup `json:"group"`
+	// Entries are in the order of the legs.
+	Entries []Entry `json:"entries"`
+	// Accounts are the updated accounts in ascending id order.
+	Accounts []Account `json:"accounts"`
+}
+
+// Validate checks that the legs move money and net to zero.
+func (arg MultiTransferTxParams) Validate() error {
+	if len(arg.Legs) < 2 {
+		return fmt.Errorf("%w: a transfer needs at least two legs", ErrTransferUnbalanced)
+	}
+	var sum int64
+	for _, leg := range arg.Legs {
+		if leg.Amount == 0 {
+			return fmt.Errorf("%w: leg of account %d has no amount", ErrTransferUnbalanced, leg.AccountID)
+		}
+		if (leg.Amount > 0 && sum > math.MaxInt64-leg.Amount) || (leg.Amount < 0 && sum < math.MinInt64-leg.Amount) {
+			return fmt.Errorf("%w: amounts overflow", ErrTransferUnbalanced)
+		}
+		sum += leg.Amount
+	}
+	if sum != 0 {
+		return fmt.Errorf("%w: legs sum to %d", ErrTransferUnbalanced, sum)
+	}
+	return nil
+}
+
+// MultiTransferTx moves money between any number of accounts of the same currency within a single db transaction.
+// All accounts are locked in ascending id order before any balance changes, which keeps concurrent
+// transfers over overlapping accounts from deadlocking. It creates a transfer group with one entry per leg.
+func (store *SQLStore) MultiTransferTx(ctx context.Context, arg MultiTransferTxParams) (MultiTransferTxResult, error) {
+	if err := arg.Validate(); err != nil {
+		return MultiTransferTxResult{}, err
+	}
+
+	// the net change of every account, several legs may touch the same one
+	var net = make(map[int64]int64, len(arg.Legs))
+	for _, leg := range arg.Legs {
+		net[leg.AccountID] += leg.Amount
+	}
+	var ids = make([]int64, 0, len(net))
+	for id := range net {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	var result MultiTransferTxResult
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		var locked, err = queries.ListAccountsForUpdate(ctx, ids)
+		if err != nil {
+			return err
+		}
+		if len(locked) != len(ids) {
+			for i, id := range ids {
+				if i >= len(locked) || locked[i].ID != id {
+					return fmt.Errorf("account %d: %w", id, ErrRecordNotFound)
+				}
+			}
+		}
+		for _, account := range locked {
+			if account.Currency != arg.Currency {
+				return fmt.Errorf("%w: account %d is in %s, not %s", ErrTransferCurrencyMismatch, account.ID, account.Currency, arg.Currency)
+			}
+		}
+
+		result.Group, err = queries.CreateTransferGroup(ctx, CreateTransferGroupParams{
+			Currency:    arg.Currency,
+			Description: arg.Description,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Entries = make([]Entry, len(arg.Legs))
+		for i, leg := range arg.Legs {
+			result.Entries[i], err = queries.CreateGroupEntry(ctx, CreateGroupEntryParams{
+				AccountID:       leg.AccountID,
+				Amount:          leg.Amount,
+				TransferGroupID: result.Group.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		result.Accounts = make([]Account, len(ids))
+		for i, id := range ids {
+			result.Accounts[i], err = queries.AddAccountBalance(ctx, AddAccountBalanceParams{
+				ID:     id,
+				Amount: net[id],
+			})
+			if err != nil {
+				return err
+			}
+			if err = checkBalance(result.Accounts[i]); err != nil {
+				return err
+			}
+			if err = recordLowBalance(ctx, queries, result.Accounts[i], -net[id]); err != nil {
+				return err
+			}
+		}
+
+		var accounts = make(map[int64]Account, len(result.Accounts))
+		for _, account := range result.Accounts {
+			accounts[account.ID] = account
+		}
+		for _, entry := range result.Entries {
+			if err = recordEntry(ctx, queries, entry, accounts[entry.AccountID]); err != nil {
+				return err
+			}
+		}
+
+		// each owner only learns about the legs of their own accounts
+		var owners []string
+		var entries = make(map[string][]Entry, len(result.Accounts))
+		for _, entry := range result.Entries {
+			var owner = accounts[entry.AccountID].Owner
+			if _, ok := entries[owner]; !ok {
+				owners = append(owners, owner)
+			}
+			entries[owner] = append(entries[owner], entry)
+		}
+		for _, owner := range owners {
+			var data = TransferGroupEvent{Group: result.Group, Entries: entries[owner]}
+			if err = recordEvent(ctx, queries, owner, EventTransferGroupCreated, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return result, err
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// createRandomAccountIn creates an account in the currency with a balance large enough for the tests.
+func createRandomAccountIn(t *testing.T, currency string) Account {
+	var user = createRandomUser(t)
+	var account, err = testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  10000,
+		Currency: currency,
+		Type:     AccountTypeChecking,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func TestMultiTransferTx(t *testing.T) {
+	var store = NewStore(testDB)
+	var payer = createRandomAccountIn(t, util.USD)
+	var payee1 = createRandomAccountIn(t, util.USD)
+	var payee2 = createRandomAccountIn(t, util.USD)
+
+	var arg = MultiTransferTxParams{
+		Currency:    util.USD,
+		Description: "split",
+		Legs: []TransferLeg{
+			{AccountID: payee2.ID, Amount: 20},
+			{AccountID: payer.ID, Amount: -30},
+			{AccountID: payee1.ID, Amount: 10},
+		},
+	}
+	var result, err = store.MultiTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.NotZero(t, result.Group.ID)
+	require.Equal(t, util.USD, result.Group.Currency)
+	require.Equal(t, "split", result.Group.Description)
+
+	require.Len(t, result.Entries, 3)
+	for i, entry := range result.Entries {
+		require.Equal(t, arg.Legs[i].AccountID, entry.AccountID)
+		require.Equal(t, arg.Legs[i].Amount, entry.Amount)
+		require.Equal(t, result.Group.ID, entry.TransferGroupID.Int64)
+	}
+	entries, err := store.ListGroupEntries(context.Background(), result.Group.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.Entries, entries)
+
+	require.Len(t, result.Accounts, 3)
+	require.Less(t, result.Accounts[0].ID, result.Accounts[1].ID)
+	require.Less(t, result.Accounts[1].ID, result.Accounts[2].ID)
+	var balances = make(map[int64]int64)
+	for _, account := range result.Accounts {
+		balances[account.ID] = account.Balance
+	}
+	require.Equal(t, payer.Balance-30, balances[payer.ID])
+	require.Equal(t, payee1.Balance+10, balances[payee1.ID])
+	require.Equal(t, payee2.Balance+20, balances[payee2.ID])
+}
+
+func TestMultiTransferTxRejected(t *testing.T) {
+	var store = NewStore(testDB)
+	var usd1 = createRandomAccountIn(t, util.USD)
+	var usd2 = createRandomAccountIn(t, util.USD)
+	var eur = createRandomAccountIn(t, util.EUR)
+
+	var _, err = store.MultiTransferTx(context.Background(), MultiTransferTxParams{
+		Currency: util.USD,
+		Legs:     []TransferLeg{{AccountID: usd1.ID, Amount: -10}, {AccountID: usd2.ID, Amount: 5}},
+	})
+	require.ErrorIs(t, err, ErrTransferUnbalanced)
+
+	_, err = store.MultiTransferTx(context.Background(), MultiTransferTxParams{
+		Currency: util.USD,
+		Legs:     []TransferLeg{{AccountID: usd1.ID, Amount: -10}, {AccountID: eur.ID, Amount: 10}},
+	})
+	require.ErrorIs(t, err, ErrTransferCurrencyMismatch)
+
+	_, err = store.MultiTransferTx(context.Background(), MultiTransferTxParams{
+		Currency: util.USD,
+		Legs:     []TransferLeg{{AccountID: usd1.ID, Amount: -10}, {AccountID: usd2.ID + 1000000, Amount: 10}},
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// nothing moved
+	account, err := store.GetAccount(context.Background(), usd1.ID)
+	require.NoError(t, err)
+	require.Equal(t, usd1.Balance, account.Balance)
+}
+
+func TestMultiTransferTxDeadlock(t *testing.T) {
+	var store = NewStore(testDB)
+	var accounts = []Account{
+		createRandomAccountIn(t, util.USD),
+		createRandomAccountIn(t, util.USD),
+		createRandomAccountIn(t, util.USD),
+	}
+
+	// rotate the legs so every transaction touches the same accounts in a different order
+	const n = 30
+	var errs = make(chan error)
+	for i := range n {
+		var legs = make([]TransferLeg, len(accounts))
+		for j := range accounts {
+			legs[j] = TransferLeg{AccountID: accounts[(i+j)%len(accounts)].ID, Amount: 10}
+		}
+		legs[0].Amount = -20
+		go func() {
+			var _, err = store.MultiTransferTx(context.Background(), MultiTransferTxParams{Currency: util.USD, Legs: legs})
+			errs <- err
+		}()
+	}
+	for range n {
+		require.NoError(t, <-errs)
+	}
+
+	// every account was the payer in a third of the transfers, so the balances are back where they started
+	for _, account := range accounts {
+		var updated, err = store.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance, updated.Balance)
+	}
+	require.Zero(t, store.TxStats().Retries)
+}
+
+func TestMultiTransferTxParamsValidate(t *testing.T) {
+	var testCases = []struct {
+		name string
+		legs []TransferLeg
+		ok   bool
+	}{
+		{"Balanced", []TransferLeg{{1, -10}, {2, 4}, {3, 6}}, true},
+		{"OneLeg", []TransferLeg{{1, 0}}, false},
+		{"Unbalanced", []TransferLeg{{1, -10}, {2, 4}}, false},
+		{"ZeroLeg", []TransferLeg{{1, -10}, {2, 10}, {3, 0}}, false},
+		{"Overflow", []TransferLeg{{1, 1 << 62}, {2, 1 << 62}, {3, -(1 << 62)}, {4, -(1 << 62)}}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err = MultiTransferTxParams{Currency: util.USD, Legs: tc.legs}.Validate()
+			if tc.ok {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrTransferUnbalanced)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RelayOutboxTxParams contains the input parameters of the outbox relay transaction
+type RelayOutboxTxParams struct {
+	BatchSize int32
+	// Publish is given the events due in id order and returns what became of them.
+	// It runs again for the same events when the transaction fails, so they are published at least once.
+	Publish func(events []OutboxEvent) OutboxPublishResult
+}
+
+// OutboxPublishResult is what the Publish func of RelayOutboxTxParams did with a batch of events.
+// The events in neither list are left as they are.
+type OutboxPublishResult struct {
+	// Published are the ids of the events published.
+	Published []int64
+	// Failed are the events that could not be published, to try again or dead-letter.
+	Failed []FailOutboxEventParams
+}
+
+// RelayOutboxTx publishes a batch of outbox events and records the outcome within a single db transaction.
+// A transaction-level advisory lock lets only one relay run at a time across all replicas,
+// so the events of an account are published in the order they were written.
+// It returns how many events were listed, zero when another relay holds the lock.
+func (store *SQLStore) RelayOutboxTx(ctx context.Context, arg RelayOutboxTxParams) (int, error) {
+	var listed int
+
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		listed = 0
+		var locked, err = queries.TryLockOutboxRelay(ctx)
+		if err != nil || !locked {
+			return err
+		}
+
+		events, err := queries.ListUnpublishedOutboxEvents(ctx, arg.BatchSize)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		listed = len(events)
+
+		var result = arg.Publish(events)
+		for _, failed := range result.Failed {
+			if err = queries.FailOutboxEvent(ctx, failed); err != nil {
+				return err
+			}
+		}
+		if len(result.Published) == 0 {
+			return nil
+		}
+		return queries.MarkOutboxEventsPublished(ctx, result.Published)
+	})
+
+	return listed, err
+}
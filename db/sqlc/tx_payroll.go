@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Statuses of a payroll batch and of its rows.
+const (
+	PayrollStatusPending            = "pending"
+	PayrollStatusCompleted          = "completed"
+	PayrollStatusPartiallyCompleted = "partially_completed"
+	PayrollStatusFailed             = "failed"
+)
+
+// PayrollRowParams is a payment of the batch.
+type PayrollRowParams struct {
+	RowNumber   int32  `json:"row_number"`
+	ToAccountID int64  `json:"to_account_id"`
+	Amount      int64  `json:"amount"`
+	Currency    string `json:"currency"`
+	Reference   string `json:"reference"`
+}
+
+// PayrollTxParams contains the input parameters of the payroll transaction
+type PayrollTxParams struct {
+	FromAccountID int64  `json:"from_account_id"`
+	CreatedBy     string `json:"created_by"`
+	// Atomic runs every row in one transaction, so either all of them are paid or none is.
+	// Otherwise each row is paid in its own transaction and the failed rows are skipped.
+	Atomic bool               `json:"atomic"`
+	Rows   []PayrollRowParams `json:"rows"`
+}
+
+// PayrollTxResult is the result of the payroll transaction
+type PayrollTxResult struct {
+	Batch PayrollBatch `json:"batch"`
+	Rows  []PayrollRow `json:"rows"`
+}
+
+// PayrollTx records a payroll batch, then pays its rows from the source account.
+// The batch is recorded first so that its outcome can be looked up even when the payments fail:
+// row failures are reported in the row and batch statuses, and only database errors are returned.
+func (store *SQLStore) PayrollTx(ctx context.Context, arg PayrollTxParams) (PayrollTxResult, error) {
+	var result PayrollTxResult
+
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		var err error
+		result.Batch, err = queries.CreatePayrollBatch(ctx, CreatePayrollBatchParams{
+			FromAccountID: arg.FromAccountID,
+			CreatedBy:     arg.CreatedBy,
+			Atomic:        arg.Atomic,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, row := range arg.Rows {
+			_, err = queries.CreatePayrollRow(ctx, CreatePayrollRowParams{
+				BatchID:     result.Batch.ID,
+				RowNumber:   row.RowNumber,
+				ToAccountID: row.ToAccountID,
+				Amount:      row.Amount,
+				Currency:    row.Currency,
+				Reference:   row.Reference,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	var status string
+	if arg.Atomic {
+		status, err = store.payAtomic(ctx, result.Batch, arg.Rows)
+	} else {
+		status, err = store.payBestEffort(ctx, result.Batch, arg.Rows)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	result.Batch, err = store.CompletePayrollBatch(ctx, CompletePayrollBatchParams{
+		ID:     result.Batch.ID,
+		Status: status,
+	})
+	if err != nil {
+		return result, err
+	}
+	result.Rows, err = store.ListPayrollRows(ctx, result.Batch.ID)
+	return result, err
+}
+
+// payAtomic pays every row in a single transaction, locking all the accounts in ascending id order first.
+func (store *SQLStore) payAtomic(ctx context.Context, batch PayrollBatch, rows []PayrollRowParams) (string, error) {
+	var ids = []int64{batch.FromAccountID}
+	for _, row := range rows {
+		ids = append(ids, row.ToAccountID)
+	}
+
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		var _, err = queries.ListAccountsForUpdate(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err = payRow(ctx, queries, batch, row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		return PayrollStatusCompleted, nil
+	}
+
+	_, err = store.FailPayrollRows(ctx, FailPayrollRowsParams{BatchID: batch.ID, Error: err.Error()})
+	return PayrollStatusFailed, err
+}
+
+// payBestEffort pays every row in its own transaction and marks the rows that could not be paid as failed.
+func (store *SQLStore) payBestEffort(ctx context.Context, batch PayrollBatch, rows []PayrollRowParams) (string, error) {
+	var paid = 0
+	for _, row := range rows {
+		var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+			return payRow(ctx, queries, batch, row)
+		})
+		if err == nil {
+			paid++
+			continue
+		}
+
+		_, err = store.FailPayrollRows(ctx, FailPayrollRowsParams{
+			BatchID:   batch.ID,
+			RowNumber: pgtype.Int4{Int32: row.RowNumber, Valid: true},
+			Error:     err.Error(),
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch paid {
+	case len(rows):
+		return PayrollStatusCompleted, nil
+	case 0:
+		return PayrollStatusFailed, nil
+	}
+	return PayrollStatusPartiallyCompleted, nil
+}
+
+// payRow transfers the amount of the row and marks the row as completed.
+func payRow(ctx context.Context, queries *Queries, batch PayrollBatch, row PayrollRowParams) error {
+	var result, err = transfer(ctx, queries, TransferTxParams{
+		FromAccountID: batch.FromAccountID,
+		ToAccountID:   row.ToAccountID,
+		Amount:        row.Amount,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = queries.CompletePayrollRow(ctx, CompletePayrollRowParams{
+		BatchID:    batch.ID,
+		RowNumber:  row.RowNumber,
+		TransferID: result.Transfer.ID,
+	})
+	return err
+}
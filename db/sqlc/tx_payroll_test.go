@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// createPayrollTx pays the amounts from payer to the payees, one row each.
+func createPayrollTx(t *testing.T, payer Account, atomic bool, payees []Account, amounts []int64) PayrollTxResult {
+	var arg = PayrollTxParams{
+		FromAccountID: payer.ID,
+		CreatedBy:     payer.Owner,
+		Atomic:        atomic,
+	}
+	for i, payee := range payees {
+		arg.Rows = append(arg.Rows, PayrollRowParams{
+			RowNumber:   int32(i + 2),
+			ToAccountID: payee.ID,
+			Amount:      amounts[i],
+			Currency:    payee.Currency,
+			Reference:   util.RandomString(10),
+		})
+	}
+
+	var result, err = NewStore(testDB).PayrollTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, result.Batch.ID)
+	require.Equal(t, atomic, result.Batch.Atomic)
+	require.True(t, result.Batch.CompletedAt.Valid)
+	require.Len(t, result.Rows, len(payees))
+	for i, row := range result.Rows {
+		require.Equal(t, arg.Rows[i].RowNumber, row.RowNumber)
+		require.Equal(t, arg.Rows[i].Reference, row.Reference)
+	}
+	return result
+}
+
+func requireBalance(t *testing.T, account Account, balance int64) {
+	var got, err = testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, balance, got.Balance)
+}
+
+func TestPayrollTxAtomic(t *testing.T) {
+	var payer = createRandomAccountIn(t, util.USD)
+	var payee1 = createRandomAccountIn(t, util.USD)
+	var payee2 = createRandomAccountIn(t, util.USD)
+
+	var result = createPayrollTx(t, payer, true, []Account{payee1, payee2}, []int64{10, 20})
+	require.Equal(t, PayrollStatusCompleted, result.Batch.Status)
+	for _, row := range result.Rows {
+		require.Equal(t, PayrollStatusCompleted, row.Status)
+		require.True(t, row.TransferID.Valid)
+		require.Empty(t, row.Error)
+	}
+
+	requireBalance(t, payer, payer.Balance-30)
+	requireBalance(t, payee1, payee1.Balance+10)
+	requireBalance(t, payee2, payee2.Balance+20)
+}
+
+func TestPayrollTxAtomicFailure(t *testing.T) {
+	var payer = createRandomAccountIn(t, util.USD)
+	var payee1 = createRandomAccountIn(t, util.USD)
+	var payee2 = createRandomAccountIn(t, util.USD)
+
+	// the second row overflows the balance of its payee, so the first one is rolled back as well
+	var result = createPayrollTx(t, payer, true, []Account{payee1, payee2}, []int64{10, math.MaxInt64})
+	require.Equal(t, PayrollStatusFailed, result.Batch.Status)
+	for _, row := range result.Rows {
+		require.Equal(t, PayrollStatusFailed, row.Status)
+		require.False(t, row.TransferID.Valid)
+		require.NotEmpty(t, row.Error)
+	}
+
+	requireBalance(t, payer, payer.Balance)
+	requireBalance(t, payee1, payee1.Balance)
+	requireBalance(t, payee2, payee2.Balance)
+}
+
+func TestPayrollTxBestEffort(t *testing.T) {
+	var payer = createRandomAccountIn(t, util.USD)
+	var payee1 = createRandomAccountIn(t, util.USD)
+	var payee2 = createRandomAccountIn(t, util.USD)
+
+	var result = createPayrollTx(t, payer, false, []Account{payee1, payee2}, []int64{10, math.MaxInt64})
+	require.Equal(t, PayrollStatusPartiallyCompleted, result.Batch.Status)
+
+	require.Equal(t, PayrollStatusCompleted, result.Rows[0].Status)
+	require.True(t, result.Rows[0].TransferID.Valid)
+	require.Equal(t, PayrollStatusFailed, result.Rows[1].Status)
+	require.False(t, result.Rows[1].TransferID.Valid)
+	require.NotEmpty(t, result.Rows[1].Error)
+
+	requireBalance(t, payer, payer.Balance-10)
+	requireBalance(t, payee1, payee1.Balance+10)
+	requireBalance(t, payee2, payee2.Balance)
+
+	var batch, err = testQueries.GetPayrollBatch(context.Background(), result.Batch.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.Batch, batch)
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ResetPasswordTxParams contains the input parameters of the reset password transaction
+type ResetPasswordTxParams struct {
+	TokenHash    string
+	HashPassword string
+}
+
+// ResetPasswordTxResult is the result of the reset password transaction
+type ResetPasswordTxResult struct {
+	User          User
+	PasswordReset PasswordReset
+}
+
+// ResetPasswordTx consumes a password reset token, stores the new password
+// and invalidates every other reset token issued to the user before the change, within a single db transaction.
+// It returns ErrRecordNotFound when the token does not exist, was already used or has expired.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error) {
+	var result ResetPasswordTxResult
+
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		var err error
+
+		result.PasswordReset, err = queries.UsePasswordReset(ctx, arg.TokenHash)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = queries.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			Username:     result.PasswordReset.Username,
+			HashPassword: arg.HashPassword,
+		})
+		if err != nil {
+			return err
+		}
+
+		return queries.InvalidatePasswordResets(ctx, InvalidatePasswordResetsParams{
+			Username:     result.User.Username,
+			IssuedBefore: result.User.PasswordChangedAt,
+		})
+	})
+
+	return result, err
+}
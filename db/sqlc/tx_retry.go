@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+)
+
+// TxRetryPolicy controls how execTx retries transactions aborted by a serialization failure or a deadlock.
+type TxRetryPolicy struct {
+	// MaxAttempts counts the first run, so 1 disables retries.
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry. It doubles on every retry up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultTxRetryPolicy is used when the config leaves the retry settings empty.
+var DefaultTxRetryPolicy = TxRetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    200 * time.Millisecond,
+}
+
+// NewTxRetryPolicy reads the retry policy from the config. Zero settings keep the defaults.
+func NewTxRetryPolicy(config util.Config) TxRetryPolicy {
+	var policy = DefaultTxRetryPolicy
+	if config.DBTxMaxAttempts > 0 {
+		policy.MaxAttempts = config.DBTxMaxAttempts
+	}
+	if config.DBTxRetryBaseDelay > 0 {
+		policy.BaseDelay = config.DBTxRetryBaseDelay
+	}
+	if config.DBTxRetryMaxDelay > 0 {
+		policy.MaxDelay = config.DBTxRetryMaxDelay
+	}
+	return policy
+}
+
+// delay returns the backoff after the given attempt, picked at random in the upper half
+// of the exponential delay so that conflicting transactions do not retry in lockstep.
+func (policy TxRetryPolicy) delay(attempt int) time.Duration {
+	var d = policy.BaseDelay
+	for i := 1; i < attempt && d < policy.MaxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, policy.MaxDelay)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// TxStats counts the transactions run by a Store since it was created.
+type TxStats struct {
+	Attempts int64 `json:"attempts"`
+	Retries  int64 `json:"retries"`
+	// Exhausted counts the transactions that still failed after the last attempt.
+	Exhausted int64 `json:"exhausted"`
+}
+
+type txCounters struct {
+	attempts  atomic.Int64
+	retries   atomic.Int64
+	exhausted atomic.Int64
+}
+
+// TxStats returns the transaction counters of the store.
+func (store *SQLStore) TxStats() TxStats {
+	return TxStats{
+		Attempts:  store.stats.attempts.Load(),
+		Retries:   store.stats.retries.Load(),
+		Exhausted: store.stats.exhausted.Load(),
+	}
+}
+
+// isRetryable reports whether the transaction failed only because of concurrent transactions.
+func isRetryable(err error) bool {
+	switch ErrorCode(err) {
+	case SerializationFailure, DeadlockDetected:
+		return true
+	}
+	return false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	var timer = time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTxRetryPolicy(t *testing.T) {
+	require.Equal(t, DefaultTxRetryPolicy, NewTxRetryPolicy(util.Config{}))
+
+	var policy = NewTxRetryPolicy(util.Config{
+		DBTxMaxAttempts:    5,
+		DBTxRetryBaseDelay: time.Millisecond,
+		DBTxRetryMaxDelay:  time.Second,
+	})
+	require.Equal(t, TxRetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond, MaxDelay: time.Second}, policy)
+}
+
+func TestTxRetryPolicyDelay(t *testing.T) {
+	var policy = TxRetryPolicy{MaxAttempts: 10, BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	var testCases = []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 10 * time.Millisecond},
+		{2, 20 * time.Millisecond},
+		{3, 40 * time.Millisecond},
+		{4, 50 * time.Millisecond},
+		{9, 50 * time.Millisecond},
+	}
+	for _, tc := range testCases {
+		for range 100 {
+			var delay = policy.delay(tc.attempt)
+			require.GreaterOrEqual(t, delay, tc.max/2)
+			require.LessOrEqual(t, delay, tc.max)
+		}
+	}
+
+	require.Zero(t, TxRetryPolicy{MaxAttempts: 3}.delay(1))
+}
+
+func TestIsRetryable(t *testing.T) {
+	require.True(t, isRetryable(&pgconn.PgError{Code: SerializationFailure}))
+	require.True(t, isRetryable(&pgconn.PgError{Code: DeadlockDetected}))
+	require.False(t, isRetryable(&pgconn.PgError{Code: UniqueViolation}))
+	require.False(t, isRetryable(ErrRecordNotFound))
+}
+
+// conflictingTx runs two serializable transactions that both read the balance of the account
+// before adding to it, so one of them fails with a serialization failure on its first attempt.
+func conflictingTx(t *testing.T, store *SQLStore, account Account) []error {
+	var read sync.WaitGroup
+	read.Add(2)
+
+	var errs = make([]error, 2)
+	var done sync.WaitGroup
+	for i := range errs {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			var attempt = 0
+			errs[i] = store.execTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.Serializable}, func(q *Queries) error {
+				attempt++
+				var current, err = q.GetAccount(context.Background(), account.ID)
+				if err != nil {
+					return err
+				}
+				if attempt == 1 {
+					read.Done()
+					read.Wait()
+				}
+				_, err = q.UpdateAccount(context.Background(), UpdateAccountParams{
+					ID:      account.ID,
+					Balance: current.Balance + 10,
+				})
+				return err
+			})
+		}()
+	}
+	done.Wait()
+	return errs
+}
+
+func TestExecTxRetriesSerializationFailure(t *testing.T) {
+	var store = NewStore(testDB).(*SQLStore)
+	var account = createRandomAccount(t)
+
+	for _, err := range conflictingTx(t, store, account) {
+		require.NoError(t, err)
+	}
+
+	var updated, err = store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+20, updated.Balance)
+
+	var stats = store.TxStats()
+	require.Equal(t, int64(3), stats.Attempts)
+	require.Equal(t, int64(1), stats.Retries)
+	require.Zero(t, stats.Exhausted)
+}
+
+func TestExecTxExhaustsRetries(t *testing.T) {
+	var store = NewStoreWithRetryPolicy(testDB, TxRetryPolicy{MaxAttempts: 1}).(*SQLStore)
+	var account = createRandomAccount(t)
+
+	var failed = 0
+	for _, err := range conflictingTx(t, store, account) {
+		if err != nil {
+			require.Equal(t, SerializationFailure, ErrorCode(err))
+			failed++
+		}
+	}
+	require.Equal(t, 1, failed)
+
+	var stats = store.TxStats()
+	require.Equal(t, int64(2), stats.Attempts)
+	require.Zero(t, stats.Retries)
+	require.Equal(t, int64(1), stats.Exhausted)
+}
+
+func TestExecTxReadOnly(t *testing.T) {
+	var store = NewStore(testDB).(*SQLStore)
+	var account = createRandomAccount(t)
+
+	var err = store.execTx(context.Background(), pgx.TxOptions{AccessMode: pgx.ReadOnly}, func(q *Queries) error {
+		var _, err = q.UpdateAccount(context.Background(), UpdateAccountParams{ID: account.ID, Balance: 0})
+		return err
+	})
+	// read_only_sql_transaction is not retried
+	require.Equal(t, "25006", ErrorCode(err))
+	require.Equal(t, int64(1), store.TxStats().Attempts)
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// defaultStatementPageSize is the number of lines read from the database at a time.
+const defaultStatementPageSize = 500
+
+// StatementTxParams contains the input parameters of the statement transaction
+type StatementTxParams struct {
+	AccountID int64
+	// From is inclusive and To is exclusive.
+	From     time.Time
+	To       time.Time
+	PageSize int32
+	// WriteHeader runs once with the balances at From and To, before any line.
+	WriteHeader func(balances GetStatementBalancesRow) error
+	// WriteLine runs for every entry of the period in booking order.
+	WriteLine func(line ListStatementLinesRow) error
+}
+
+// StatementTx reads the balances and the entries of an account over a period from a single snapshot,
+// so that the lines always add up from the opening to the closing balance.
+// The lines are read one page at a time and handed to WriteLine, so the period is never held in memory.
+// Unlike execTx the transaction is not retried, since the lines may already have been sent;
+// a read-only repeatable read transaction never fails with a serialization error anyway.
+func (store *SQLStore) StatementTx(ctx context.Context, arg StatementTxParams) error {
+	var pageSize = arg.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultStatementPageSize
+	}
+
+	var txOptions = pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	return store.runTx(ctx, txOptions, func(queries *Queries) error {
+		var balances, err = queries.GetStatementBalances(ctx, GetStatementBalancesParams{
+			AccountID: arg.AccountID,
+			FromTime:  arg.From,
+			ToTime:    arg.To,
+		})
+		if err != nil {
+			return err
+		}
+		if err = arg.WriteHeader(balances); err != nil {
+			return err
+		}
+
+		var page = ListStatementLinesParams{
+			AccountID: arg.AccountID,
+			FromTime:  arg.From,
+			ToTime:    arg.To,
+			AfterTime: arg.From,
+			PageSize:  pageSize,
+		}
+		for {
+			var lines, err = queries.ListStatementLines(ctx, page)
+			if err != nil {
+				return err
+			}
+			for _, line := range lines {
+				if err = arg.WriteLine(line); err != nil {
+					return err
+				}
+			}
+			if len(lines) < int(pageSize) {
+				return nil
+			}
+
+			var last = lines[len(lines)-1]
+			page.AfterTime, page.AfterID = last.CreatedAt, last.ID
+		}
+	})
+}
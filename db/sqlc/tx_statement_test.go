@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// readStatement collects the balances and the lines of a statement.
+func readStatement(t *testing.T, arg StatementTxParams) (GetStatementBalancesRow, []ListStatementLinesRow) {
+	var balances GetStatementBalancesRow
+	var lines []ListStatementLinesRow
+	arg.WriteHeader = func(b GetStatementBalancesRow) error {
+		balances = b
+		return nil
+	}
+	arg.WriteLine = func(line ListStatementLinesRow) error {
+		lines = append(lines, line)
+		return nil
+	}
+
+	var err = NewStore(testDB).StatementTx(context.Background(), arg)
+	require.NoError(t, err)
+	return balances, lines
+}
+
+func TestStatementTx(t *testing.T) {
+	var store = NewStore(testDB)
+	var account = createRandomAccountIn(t, util.USD)
+	var other = createRandomAccountIn(t, util.USD)
+
+	var from = time.Now().Add(-time.Second)
+	var amounts = []int64{-10, 20, -30, 40, -50}
+	var transfers []Transfer
+	for _, amount := range amounts {
+		var arg = TransferTxParams{FromAccountID: account.ID, ToAccountID: other.ID, Amount: -amount}
+		if amount > 0 {
+			arg = TransferTxParams{FromAccountID: other.ID, ToAccountID: account.ID, Amount: amount}
+		}
+		var result, err = store.TransferTx(context.Background(), arg)
+		require.NoError(t, err)
+		transfers = append(transfers, result.Transfer)
+	}
+	var to = time.Now().Add(time.Second)
+
+	var balances, lines = readStatement(t, StatementTxParams{AccountID: account.ID, From: from, To: to, PageSize: 2})
+	require.Equal(t, account.Balance, balances.OpeningBalance)
+	require.Equal(t, account.Balance-30, balances.ClosingBalance)
+
+	require.Len(t, lines, len(amounts))
+	for i, line := range lines {
+		require.Equal(t, amounts[i], line.Amount)
+		require.Equal(t, transfers[i].ID, line.TransferID.Int64)
+		require.Equal(t, transfers[i].FromAccountID, line.FromAccountID.Int64)
+		require.Equal(t, transfers[i].ToAccountID, line.ToAccountID.Int64)
+		require.False(t, line.TransferGroupID.Valid)
+	}
+
+	// a period after the transfers has no lines and both balances are the current one
+	balances, lines = readStatement(t, StatementTxParams{AccountID: account.ID, From: to, To: to.Add(time.Hour)})
+	require.Equal(t, account.Balance-30, balances.OpeningBalance)
+	require.Equal(t, account.Balance-30, balances.ClosingBalance)
+	require.Empty(t, lines)
+}
+
+func TestStatementTxAccountNotFound(t *testing.T) {
+	var err = NewStore(testDB).StatementTx(context.Background(), StatementTxParams{
+		AccountID:   -1,
+		From:        time.Now().Add(-time.Hour),
+		To:          time.Now(),
+		WriteHeader: func(GetStatementBalancesRow) error { return nil },
+		WriteLine:   func(ListStatementLinesRow) error { return nil },
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
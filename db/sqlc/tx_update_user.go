@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// UpdateUserTxParams contains the input parameters of the update user transaction
+type UpdateUserTxParams struct {
+	UpdateUserParams
+	// AfterUpdate runs inside the same transaction once the user is updated.
+	AfterUpdate func(q Querier, user User) error
+}
+
+// UpdateUserTxResult is the result of the update user transaction
+type UpdateUserTxResult struct {
+	User User
+}
+
+// UpdateUserTx updates a user and runs the AfterUpdate callback within a single db transaction
+func (store *SQLStore) UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error) {
+	var result UpdateUserTxResult
+
+	var err = store.execTx(ctx, pgx.TxOptions{}, func(queries *Queries) error {
+		var err error
+
+		result.User, err = queries.UpdateUser(ctx, arg.UpdateUserParams)
+		if err != nil {
+			return err
+		}
+
+		if arg.AfterUpdate == nil {
+			return nil
+		}
+		return arg.AfterUpdate(queries, result.User)
+	})
+
+	return result, err
+}
@@ -7,12 +7,14 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (username, hash_password, full_name, email)
 VALUES ($1, $2, $3, $4)
-RETURNING username, hash_password, full_name, email, password_changed_at, created_at
+RETURNING username, hash_password, full_name, email, password_changed_at, created_at, is_email_verified, role, totp_secret, totp_enabled, totp_last_step
 `
 
 type CreateUserParams struct {
@@ -23,7 +25,7 @@ type CreateUserParams struct {
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser,
+	row := q.db.QueryRow(ctx, createUser,
 		arg.Username,
 		arg.HashPassword,
 		arg.FullName,
@@ -37,19 +39,234 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const enableUserTOTP = `-- name: EnableUserTOTP :one
+UPDATE users
+SET totp_enabled = TRUE
+WHERE username = $1
+  AND totp_secret IS NOT NULL
+RETURNING username, hash_password, full_name, email, password_changed_at, created_at, is_email_verified, role, totp_secret, totp_enabled, totp_last_step
+`
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRow(ctx, enableUserTOTP, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, hash_password, full_name, email, password_changed_at, created_at
+SELECT username, hash_password, full_name, email, password_changed_at, created_at, is_email_verified, role, totp_secret, totp_enabled, totp_last_step
 FROM users
 WHERE username = $1
 LIMIT 1
 `
 
 func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUser, username)
+	row := q.db.QueryRow(ctx, getUser, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT username, hash_password, full_name, email, password_changed_at, created_at, is_email_verified, role, totp_secret, totp_enabled, totp_last_step
+FROM users
+WHERE email = $1
+LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const setUserTOTPSecret = `-- name: SetUserTOTPSecret :one
+UPDATE users
+SET totp_secret  = $2,
+    totp_enabled = FALSE
+WHERE username = $1
+RETURNING username, hash_password, full_name, email, password_changed_at, created_at, is_email_verified, role, totp_secret, totp_enabled, totp_last_step
+`
+
+type SetUserTOTPSecretParams struct {
+	Username   string      `json:"username"`
+	TotpSecret pgtype.Text `json:"totp_secret"`
+}
+
+func (q *Queries) SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) (User, error) {
+	row := q.db.QueryRow(ctx, setUserTOTPSecret, arg.Username, arg.TotpSecret)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+SET hash_password       = COALESCE($1, hash_password),
+    password_changed_at = COALESCE($2, password_changed_at),
+    full_name           = COALESCE($3, full_name),
+    email               = COALESCE($4, email),
+    is_email_verified   = COALESCE($5, is_email_verified)
+WHERE username = $6
+RETURNING username, hash_password, full_name, email, password_changed_at, created_at, is_email_verified, role, totp_secret, totp_enabled, totp_last_step
+`
+
+type UpdateUserParams struct {
+	HashPassword      pgtype.Text        `json:"hash_password"`
+	PasswordChangedAt pgtype.Timestamptz `json:"password_changed_at"`
+	FullName          pgtype.Text        `json:"full_name"`
+	Email             pgtype.Text        `json:"email"`
+	IsEmailVerified   pgtype.Bool        `json:"is_email_verified"`
+	Username          string             `json:"username"`
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUser,
+		arg.HashPassword,
+		arg.PasswordChangedAt,
+		arg.FullName,
+		arg.Email,
+		arg.IsEmailVerified,
+		arg.Username,
+	)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET hash_password       = $2,
+    password_changed_at = now()
+WHERE username = $1
+RETURNING username, hash_password, full_name, email, password_changed_at, created_at, is_email_verified, role, totp_secret, totp_enabled, totp_last_step
+`
+
+type UpdateUserPasswordParams struct {
+	Username     string `json:"username"`
+	HashPassword string `json:"hash_password"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserPassword, arg.Username, arg.HashPassword)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const useTOTPStep = `-- name: UseTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $1
+WHERE username = $2
+  AND totp_last_step < $1
+`
+
+type UseTOTPStepParams struct {
+	Step     int64  `json:"step"`
+	Username string `json:"username"`
+}
+
+// UseTOTPStep accepts a TOTP code of the step only once, and never after a code of a later step.
+func (q *Queries) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error) {
+	result, err := q.db.Exec(ctx, useTOTPStep, arg.Step, arg.Username)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET is_email_verified = TRUE
+WHERE username = $1
+RETURNING username, hash_password, full_name, email, password_changed_at, created_at, is_email_verified, role, totp_secret, totp_enabled, totp_last_step
+`
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRow(ctx, verifyUserEmail, username)
 	var i User
 	err := row.Scan(
 		&i.Username,
@@ -58,6 +275,11 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
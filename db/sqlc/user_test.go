@@ -2,14 +2,16 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 	"time"
 
 	"github.com/Ma-hiru/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
-func createRandomUser(t *testing.T) User {
+func createRandomUser(t testing.TB) User {
 	var hashedPassword, err = util.HashPassword(util.RandomString(6))
 	require.NoError(t, err)
 	var arg = CreateUserParams{
@@ -50,3 +52,112 @@ func TestGetUser(t *testing.T) {
 	require.WithinDuration(t, user1.PasswordChangedAt, user2.PasswordChangedAt, time.Second)
 	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
 }
+
+func TestCreateUserTx(t *testing.T) {
+	var store = NewStore(testDB)
+	var hashedPassword, err = util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	var arg = CreateUserParams{
+		Username:     util.RandomOwner(),
+		FullName:     util.RandomOwner(),
+		HashPassword: hashedPassword,
+		Email:        util.RandomEmail(),
+	}
+
+	// a failing callback rolls the user back
+	_, err = store.CreateUserTx(context.Background(), CreateUserTxParams{
+		CreateUserParams: arg,
+		AfterCreate: func(q Querier, user User) error {
+			return sql.ErrConnDone
+		},
+	})
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	_, err = testQueries.GetUser(context.Background(), arg.Username)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	var called bool
+	result, err := store.CreateUserTx(context.Background(), CreateUserTxParams{
+		CreateUserParams: arg,
+		AfterCreate: func(q Querier, user User) error {
+			called = true
+			require.Equal(t, arg.Username, user.Username)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, called)
+	require.Equal(t, arg.Username, result.User.Username)
+	require.False(t, result.User.IsEmailVerified)
+}
+
+func TestGetUserByEmail(t *testing.T) {
+	var user1 = createRandomUser(t)
+	var user2, err = testQueries.GetUserByEmail(context.Background(), user1.Email)
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+}
+
+func TestUpdateUserOnlyFullName(t *testing.T) {
+	var oldUser = createRandomUser(t)
+	var newFullName = util.RandomOwner()
+
+	var updatedUser, err = testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username: oldUser.Username,
+		FullName: pgtype.Text{String: newFullName, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newFullName, updatedUser.FullName)
+	require.Equal(t, oldUser.Email, updatedUser.Email)
+	require.Equal(t, oldUser.HashPassword, updatedUser.HashPassword)
+	require.WithinDuration(t, oldUser.PasswordChangedAt, updatedUser.PasswordChangedAt, time.Second)
+}
+
+func TestUpdateUserAllFields(t *testing.T) {
+	var oldUser = createRandomUser(t)
+	var newFullName = util.RandomOwner()
+	var newEmail = util.RandomEmail()
+	var newHashedPassword, err = util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+	var changedAt = time.Now()
+
+	updatedUser, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username:          oldUser.Username,
+		FullName:          pgtype.Text{String: newFullName, Valid: true},
+		Email:             pgtype.Text{String: newEmail, Valid: true},
+		HashPassword:      pgtype.Text{String: newHashedPassword, Valid: true},
+		PasswordChangedAt: pgtype.Timestamptz{Time: changedAt, Valid: true},
+		IsEmailVerified:   pgtype.Bool{Bool: false, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newFullName, updatedUser.FullName)
+	require.Equal(t, newEmail, updatedUser.Email)
+	require.Equal(t, newHashedPassword, updatedUser.HashPassword)
+	require.WithinDuration(t, changedAt, updatedUser.PasswordChangedAt, time.Second)
+	require.False(t, updatedUser.IsEmailVerified)
+}
+
+func TestUpdateUserTx(t *testing.T) {
+	var store = NewStore(testDB)
+	var oldUser = createRandomUser(t)
+	var arg = UpdateUserParams{
+		Username: oldUser.Username,
+		Email:    pgtype.Text{String: util.RandomEmail(), Valid: true},
+	}
+
+	// a failing callback rolls the update back
+	var _, err = store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		UpdateUserParams: arg,
+		AfterUpdate: func(q Querier, user User) error {
+			return sql.ErrConnDone
+		},
+	})
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	user, err := testQueries.GetUser(context.Background(), oldUser.Username)
+	require.NoError(t, err)
+	require.Equal(t, oldUser.Email, user.Email)
+
+	result, err := store.UpdateUserTx(context.Background(), UpdateUserTxParams{UpdateUserParams: arg})
+	require.NoError(t, err)
+	require.Equal(t, arg.Email.String, result.User.Email)
+}
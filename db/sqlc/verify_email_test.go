@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomVerifyEmail(t *testing.T, user User) VerifyEmail {
+	var arg = CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+	}
+
+	var verifyEmail, err = testQueries.CreateVerifyEmail(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.NotZero(t, verifyEmail.ID)
+	require.Equal(t, arg.Username, verifyEmail.Username)
+	require.Equal(t, arg.Email, verifyEmail.Email)
+	require.Equal(t, arg.SecretCode, verifyEmail.SecretCode)
+	require.False(t, verifyEmail.IsUsed)
+	require.True(t, verifyEmail.ExpiredAt.After(verifyEmail.CreatedAt))
+	return verifyEmail
+}
+
+func TestCreateVerifyEmail(t *testing.T) {
+	createRandomVerifyEmail(t, createRandomUser(t))
+}
+
+func TestUpdateVerifyEmail(t *testing.T) {
+	var verifyEmail = createRandomVerifyEmail(t, createRandomUser(t))
+
+	// wrong secret code
+	var _, err = testQueries.UpdateVerifyEmail(context.Background(), UpdateVerifyEmailParams{
+		ID:         verifyEmail.ID,
+		SecretCode: util.RandomString(32),
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	var arg = UpdateVerifyEmailParams{
+		ID:         verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	}
+	updated, err := testQueries.UpdateVerifyEmail(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, updated.IsUsed)
+
+	// a code can only be used once
+	_, err = testQueries.UpdateVerifyEmail(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	var store = NewStore(testDB)
+	var user = createRandomUser(t)
+	require.False(t, user.IsEmailVerified)
+	var verifyEmail = createRandomVerifyEmail(t, user)
+
+	var result, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.NoError(t, err)
+	require.True(t, result.VerifyEmail.IsUsed)
+	require.Equal(t, user.Username, result.User.Username)
+	require.True(t, result.User.IsEmailVerified)
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Types of the webhook events.
+const (
+	EventAccountCreated       = "account.created"
+	EventTransferCreated      = "transfer.created"
+	EventTransferGroupCreated = "transfer_group.created"
+	// EventBalanceLow is sent when a debit takes a balance below the threshold of the subscription.
+	EventBalanceLow = "balance.low"
+)
+
+// Statuses of a webhook delivery.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySending   = "sending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryDead      = "dead"
+)
+
+// AccountEvent is the data of the account.created and balance.low events.
+type AccountEvent struct {
+	Account Account `json:"account"`
+}
+
+// TransferEvent is the data of the transfer.created event, sent to the owners of both accounts.
+type TransferEvent struct {
+	Transfer Transfer `json:"transfer"`
+	Currency string   `json:"currency"`
+}
+
+// TransferGroupEvent is the data of the transfer_group.created event, sent to the owners of all accounts.
+type TransferGroupEvent struct {
+	Group TransferGroup `json:"group"`
+	// Entries are those of the accounts of the receiving owner, the other legs are not disclosed.
+	Entries []Entry `json:"entries"`
+}
+
+// recordEvent records a webhook event for the user, if they subscribed to its type.
+// The deliveries are committed or rolled back together with the change they report.
+func recordEvent(ctx context.Context, queries *Queries, username string, eventType string, data any) error {
+	if username == BankUsername {
+		return nil
+	}
+	return insertWebhookEvent(ctx, queries, CreateWebhookEventParams{
+		Username: username,
+		Type:     eventType,
+	}, data)
+}
+
+// recordLowBalance records balance.low for the subscriptions whose threshold a debit took the account below.
+// The account is the one after the debit.
+func recordLowBalance(ctx context.Context, queries *Queries, account Account, debit int64) error {
+	if account.Owner == BankUsername || debit <= 0 {
+		return nil
+	}
+	return insertWebhookEvent(ctx, queries, CreateWebhookEventParams{
+		Username:      account.Owner,
+		Type:          EventBalanceLow,
+		BalanceBefore: pgtype.Int8{Int64: account.Balance + debit, Valid: true},
+		BalanceAfter:  pgtype.Int8{Int64: account.Balance, Valid: true},
+	}, AccountEvent{Account: account})
+}
+
+// recordTransfer records the events of a transfer between two accounts.
+func recordTransfer(ctx context.Context, queries *Queries, result TransferTxResult) error {
+	var data = TransferEvent{
+		Transfer: result.Transfer,
+		Currency: result.FromAccount.Currency,
+	}
+	if err := recordEvent(ctx, queries, result.FromAccount.Owner, EventTransferCreated, data); err != nil {
+		return err
+	}
+	if result.ToAccount.Owner != result.FromAccount.Owner {
+		if err := recordEvent(ctx, queries, result.ToAccount.Owner, EventTransferCreated, data); err != nil {
+			return err
+		}
+	}
+	return recordLowBalance(ctx, queries, result.FromAccount, result.Transfer.Amount)
+}
+
+func insertWebhookEvent(ctx context.Context, queries *Queries, arg CreateWebhookEventParams, data any) error {
+	var err error
+	arg.Data, err = json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", arg.Type, err)
+	}
+	_, err = queries.CreateWebhookEvent(ctx, arg)
+	return err
+}
@@ -0,0 +1,471 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: webhook.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const claimWebhookDeliveries = `-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET status     = 'sending',
+    attempts   = attempts + 1,
+    locked_at  = now(),
+    updated_at = now()
+WHERE id IN (SELECT d.id
+             FROM webhook_deliveries d
+             WHERE (d.status = 'pending' AND d.next_attempt_at <= now())
+                OR (d.status = 'sending' AND d.locked_at < $1::timestamptz)
+             ORDER BY d.next_attempt_at
+             LIMIT $2 FOR UPDATE SKIP LOCKED)
+RETURNING id, subscription_id, event_id, status, attempts, last_error, next_attempt_at, locked_at, created_at, updated_at
+`
+
+type ClaimWebhookDeliveriesParams struct {
+	StaleBefore   time.Time `json:"stale_before"`
+	MaxDeliveries int32     `json:"max_deliveries"`
+}
+
+func (q *Queries) ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, claimWebhookDeliveries, arg.StaleBefore, arg.MaxDeliveries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookDelivery{}
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.EventID,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.LockedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const completeWebhookDelivery = `-- name: CompleteWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status     = 'succeeded',
+    locked_at  = NULL,
+    last_error = '',
+    updated_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) CompleteWebhookDelivery(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, completeWebhookDelivery, id)
+	return err
+}
+
+const createWebhookAttempt = `-- name: CreateWebhookAttempt :exec
+INSERT INTO webhook_attempts (delivery_id, attempt, response_code, error, duration_ms)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateWebhookAttemptParams struct {
+	DeliveryID   int64  `json:"delivery_id"`
+	Attempt      int32  `json:"attempt"`
+	ResponseCode int32  `json:"response_code"`
+	Error        string `json:"error"`
+	DurationMs   int64  `json:"duration_ms"`
+}
+
+func (q *Queries) CreateWebhookAttempt(ctx context.Context, arg CreateWebhookAttemptParams) error {
+	_, err := q.db.Exec(ctx, createWebhookAttempt,
+		arg.DeliveryID,
+		arg.Attempt,
+		arg.ResponseCode,
+		arg.Error,
+		arg.DurationMs,
+	)
+	return err
+}
+
+const createWebhookEvent = `-- name: CreateWebhookEvent :execrows
+WITH subscriptions AS (SELECT s.id
+                       FROM webhook_subscriptions s
+                       WHERE s.username = $1
+                         AND $2::varchar = ANY (s.event_types)
+                         AND ($3::bigint IS NULL
+                           OR (s.low_balance_threshold <= $3::bigint
+                               AND s.low_balance_threshold > $4::bigint))),
+     event AS (
+         INSERT INTO webhook_events (username, type, data)
+             SELECT $1, $2, $5::jsonb
+             WHERE EXISTS (SELECT 1 FROM subscriptions)
+             RETURNING id)
+INSERT
+INTO webhook_deliveries (subscription_id, event_id)
+SELECT subscriptions.id, event.id
+FROM subscriptions,
+     event
+`
+
+type CreateWebhookEventParams struct {
+	Username      string          `json:"username"`
+	Type          string          `json:"type"`
+	BalanceBefore pgtype.Int8     `json:"balance_before"`
+	BalanceAfter  pgtype.Int8     `json:"balance_after"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// CreateWebhookEvent records the event and a delivery to every subscription of the user to its type.
+// Nothing is written when there is none. The balances are only given for balance.low,
+// which only goes to the subscriptions whose threshold the balance fell below.
+func (q *Queries) CreateWebhookEvent(ctx context.Context, arg CreateWebhookEventParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createWebhookEvent,
+		arg.Username,
+		arg.Type,
+		arg.BalanceBefore,
+		arg.BalanceAfter,
+		arg.Data,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const createWebhookSubscription = `-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (id, username, url, event_types, secret, low_balance_threshold)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, username, url, event_types, secret, low_balance_threshold, created_at
+`
+
+type CreateWebhookSubscriptionParams struct {
+	ID                  uuid.UUID `json:"id"`
+	Username            string    `json:"username"`
+	Url                 string    `json:"url"`
+	EventTypes          []string  `json:"event_types"`
+	Secret              string    `json:"secret"`
+	LowBalanceThreshold int64     `json:"low_balance_threshold"`
+}
+
+func (q *Queries) CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error) {
+	row := q.db.QueryRow(ctx, createWebhookSubscription,
+		arg.ID,
+		arg.Username,
+		arg.Url,
+		arg.EventTypes,
+		arg.Secret,
+		arg.LowBalanceThreshold,
+	)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Url,
+		&i.EventTypes,
+		&i.Secret,
+		&i.LowBalanceThreshold,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deadLetterWebhookDelivery = `-- name: DeadLetterWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status     = 'dead',
+    locked_at  = NULL,
+    last_error = $1,
+    updated_at = now()
+WHERE id = $2
+`
+
+type DeadLetterWebhookDeliveryParams struct {
+	LastError string `json:"last_error"`
+	ID        int64  `json:"id"`
+}
+
+func (q *Queries) DeadLetterWebhookDelivery(ctx context.Context, arg DeadLetterWebhookDeliveryParams) error {
+	_, err := q.db.Exec(ctx, deadLetterWebhookDelivery, arg.LastError, arg.ID)
+	return err
+}
+
+const deleteWebhookSubscription = `-- name: DeleteWebhookSubscription :execrows
+DELETE
+FROM webhook_subscriptions
+WHERE id = $1
+  AND username = $2
+`
+
+type DeleteWebhookSubscriptionParams struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+}
+
+func (q *Queries) DeleteWebhookSubscription(ctx context.Context, arg DeleteWebhookSubscriptionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebhookSubscription, arg.ID, arg.Username)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getWebhookDelivery = `-- name: GetWebhookDelivery :one
+SELECT id, subscription_id, event_id, status, attempts, last_error, next_attempt_at, locked_at, created_at, updated_at
+FROM webhook_deliveries
+WHERE id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, getWebhookDelivery, id)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.EventID,
+		&i.Status,
+		&i.Attempts,
+		&i.LastError,
+		&i.NextAttemptAt,
+		&i.LockedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getWebhookEvent = `-- name: GetWebhookEvent :one
+SELECT id, username, type, data, created_at
+FROM webhook_events
+WHERE id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetWebhookEvent(ctx context.Context, id int64) (WebhookEvent, error) {
+	row := q.db.QueryRow(ctx, getWebhookEvent, id)
+	var i WebhookEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Type,
+		&i.Data,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getWebhookSubscription = `-- name: GetWebhookSubscription :one
+SELECT id, username, url, event_types, secret, low_balance_threshold, created_at
+FROM webhook_subscriptions
+WHERE id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetWebhookSubscription(ctx context.Context, id uuid.UUID) (WebhookSubscription, error) {
+	row := q.db.QueryRow(ctx, getWebhookSubscription, id)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Url,
+		&i.EventTypes,
+		&i.Secret,
+		&i.LowBalanceThreshold,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listWebhookAttempts = `-- name: ListWebhookAttempts :many
+SELECT id, delivery_id, attempt, response_code, error, duration_ms, created_at
+FROM webhook_attempts
+WHERE delivery_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListWebhookAttempts(ctx context.Context, deliveryID int64) ([]WebhookAttempt, error) {
+	rows, err := q.db.Query(ctx, listWebhookAttempts, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookAttempt{}
+	for rows.Next() {
+		var i WebhookAttempt
+		if err := rows.Scan(
+			&i.ID,
+			&i.DeliveryID,
+			&i.Attempt,
+			&i.ResponseCode,
+			&i.Error,
+			&i.DurationMs,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookDeliveries = `-- name: ListWebhookDeliveries :many
+SELECT webhook_deliveries.id, webhook_deliveries.subscription_id, webhook_deliveries.event_id, webhook_deliveries.status, webhook_deliveries.attempts, webhook_deliveries.last_error, webhook_deliveries.next_attempt_at, webhook_deliveries.locked_at, webhook_deliveries.created_at, webhook_deliveries.updated_at, webhook_events.type AS event_type
+FROM webhook_deliveries
+         JOIN webhook_events ON webhook_events.id = webhook_deliveries.event_id
+WHERE webhook_deliveries.subscription_id = $1
+  AND ($2::varchar IS NULL OR webhook_deliveries.status = $2)
+ORDER BY webhook_deliveries.id DESC
+LIMIT $4 OFFSET $3
+`
+
+type ListWebhookDeliveriesParams struct {
+	SubscriptionID uuid.UUID   `json:"subscription_id"`
+	Status         pgtype.Text `json:"status"`
+	PageOffset     int32       `json:"page_offset"`
+	PageSize       int32       `json:"page_size"`
+}
+
+type ListWebhookDeliveriesRow struct {
+	WebhookDelivery WebhookDelivery `json:"webhook_delivery"`
+	EventType       string          `json:"event_type"`
+}
+
+func (q *Queries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]ListWebhookDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, listWebhookDeliveries,
+		arg.SubscriptionID,
+		arg.Status,
+		arg.PageOffset,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListWebhookDeliveriesRow{}
+	for rows.Next() {
+		var i ListWebhookDeliveriesRow
+		if err := rows.Scan(
+			&i.WebhookDelivery.ID,
+			&i.WebhookDelivery.SubscriptionID,
+			&i.WebhookDelivery.EventID,
+			&i.WebhookDelivery.Status,
+			&i.WebhookDelivery.Attempts,
+			&i.WebhookDelivery.LastError,
+			&i.WebhookDelivery.NextAttemptAt,
+			&i.WebhookDelivery.LockedAt,
+			&i.WebhookDelivery.CreatedAt,
+			&i.WebhookDelivery.UpdatedAt,
+			&i.EventType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookSubscriptions = `-- name: ListWebhookSubscriptions :many
+SELECT id, username, url, event_types, secret, low_balance_threshold, created_at
+FROM webhook_subscriptions
+WHERE username = $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListWebhookSubscriptions(ctx context.Context, username string) ([]WebhookSubscription, error) {
+	rows, err := q.db.Query(ctx, listWebhookSubscriptions, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookSubscription{}
+	for rows.Next() {
+		var i WebhookSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Url,
+			&i.EventTypes,
+			&i.Secret,
+			&i.LowBalanceThreshold,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const replayWebhookDelivery = `-- name: ReplayWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status          = 'pending',
+    attempts        = 0,
+    next_attempt_at = now(),
+    updated_at      = now()
+WHERE id = $1
+  AND status IN ('succeeded', 'dead')
+RETURNING id, subscription_id, event_id, status, attempts, last_error, next_attempt_at, locked_at, created_at, updated_at
+`
+
+func (q *Queries) ReplayWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, replayWebhookDelivery, id)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.EventID,
+		&i.Status,
+		&i.Attempts,
+		&i.LastError,
+		&i.NextAttemptAt,
+		&i.LockedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const retryWebhookDelivery = `-- name: RetryWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status          = 'pending',
+    locked_at       = NULL,
+    last_error      = $1,
+    next_attempt_at = $2,
+    updated_at      = now()
+WHERE id = $3
+`
+
+type RetryWebhookDeliveryParams struct {
+	LastError     string    `json:"last_error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	ID            int64     `json:"id"`
+}
+
+func (q *Queries) RetryWebhookDelivery(ctx context.Context, arg RetryWebhookDeliveryParams) error {
+	_, err := q.db.Exec(ctx, retryWebhookDelivery, arg.LastError, arg.NextAttemptAt, arg.ID)
+	return err
+}
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomWebhook(t *testing.T, username string, threshold int64, eventTypes ...string) WebhookSubscription {
+	var subscription, err = testQueries.CreateWebhookSubscription(context.Background(), CreateWebhookSubscriptionParams{
+		ID:                  uuid.New(),
+		Username:            username,
+		Url:                 "https://example.com/" + util.RandomString(8),
+		EventTypes:          eventTypes,
+		Secret:              util.RandomString(32),
+		LowBalanceThreshold: threshold,
+	})
+	require.NoError(t, err)
+	return subscription
+}
+
+func deliveriesOf(t *testing.T, subscription WebhookSubscription) []ListWebhookDeliveriesRow {
+	var rows, err = testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		PageSize:       10,
+	})
+	require.NoError(t, err)
+	return rows
+}
+
+func TestCreateAccountTxEvent(t *testing.T) {
+	var store = NewStore(testDB)
+	var user = createRandomUser(t)
+	var subscription = createRandomWebhook(t, user.Username, 0, EventAccountCreated)
+
+	var account, err = store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: util.RandomCurrency(),
+		Type:     AccountTypeChecking,
+	})
+	require.NoError(t, err)
+
+	var rows = deliveriesOf(t, subscription)
+	require.Len(t, rows, 1)
+	require.Equal(t, EventAccountCreated, rows[0].EventType)
+	require.Equal(t, WebhookDeliveryPending, rows[0].WebhookDelivery.Status)
+
+	event, err := testQueries.GetWebhookEvent(context.Background(), rows[0].WebhookDelivery.EventID)
+	require.NoError(t, err)
+	var data AccountEvent
+	require.NoError(t, json.Unmarshal(event.Data, &data))
+	require.Equal(t, account.ID, data.Account.ID)
+}
+
+func TestTransferTxEvents(t *testing.T) {
+	var store = NewStore(testDB)
+	var account1 = createRandomAccount(t)
+	var account2, err = testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Balance:  0,
+		Currency: account1.Currency,
+		Type:     AccountTypeChecking,
+	})
+	require.NoError(t, err)
+
+	var sender = createRandomWebhook(t, account1.Owner, account1.Balance-5, EventTransferCreated, EventBalanceLow)
+	var receiver = createRandomWebhook(t, account2.Owner, 0, EventBalanceLow)
+	var unrelated = createRandomWebhook(t, account2.Owner, 0, EventAccountCreated)
+	var recipient = createRandomWebhook(t, account2.Owner, 0, EventTransferCreated)
+
+	// the first transfer takes the balance below the threshold, the second one stays below it
+	for range 2 {
+		_, err = store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+		})
+		require.NoError(t, err)
+	}
+
+	var types = make(map[string]int)
+	for _, row := range deliveriesOf(t, sender) {
+		types[row.EventType]++
+	}
+	require.Equal(t, map[string]int{EventTransferCreated: 2, EventBalanceLow: 1}, types)
+	require.Len(t, deliveriesOf(t, recipient), 2)
+	require.Empty(t, deliveriesOf(t, receiver))
+	require.Empty(t, deliveriesOf(t, unrelated))
+}
+
+func TestMultiTransferTxEvents(t *testing.T) {
+	var store = NewStore(testDB)
+	var account1 = createRandomAccount(t)
+	var account2, err = testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    account1.Owner,
+		Balance:  0,
+		Currency: account1.Currency,
+		Type:     AccountTypeSavings,
+	})
+	require.NoError(t, err)
+	var subscription = createRandomWebhook(t, account1.Owner, account1.Balance, EventTransferGroupCreated, EventBalanceLow)
+
+	result, err := store.MultiTransferTx(context.Background(), MultiTransferTxParams{
+		Currency: account1.Currency,
+		Legs: []TransferLeg{
+			{AccountID: account1.ID, Amount: -10},
+			{AccountID: account2.ID, Amount: 10},
+		},
+	})
+	require.NoError(t, err)
+
+	var rows = deliveriesOf(t, subscription)
+	require.Len(t, rows, 2)
+	var types = []string{rows[0].EventType, rows[1].EventType}
+	require.ElementsMatch(t, []string{EventTransferGroupCreated, EventBalanceLow}, types)
+
+	for _, row := range rows {
+		if row.EventType != EventTransferGroupCreated {
+			continue
+		}
+		event, err := testQueries.GetWebhookEvent(context.Background(), row.WebhookDelivery.EventID)
+		require.NoError(t, err)
+		var data TransferGroupEvent
+		require.NoError(t, json.Unmarshal(event.Data, &data))
+		require.Equal(t, result.Group.ID, data.Group.ID)
+		require.Len(t, data.Entries, 2)
+	}
+}
+
+func TestMultiTransferTxEventsPerOwner(t *testing.T) {
+	var store = NewStore(testDB)
+	var payer = createRandomAccount(t)
+	var payee1 = createRandomAccount(t)
+	payee2, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Balance:  0,
+		Currency: payer.Currency,
+		Type:     AccountTypeChecking,
+	})
+	require.NoError(t, err)
+	var payeeSubscription = createRandomWebhook(t, payee2.Owner, 0, EventTransferGroupCreated)
+	var payerSubscription = createRandomWebhook(t, payer.Owner, 0, EventTransferGroupCreated)
+
+	result, err := store.MultiTransferTx(context.Background(), MultiTransferTxParams{
+		Currency: payer.Currency,
+		Legs: []TransferLeg{
+			{AccountID: payer.ID, Amount: -30},
+			{AccountID: payee1.ID, Amount: 10},
+			{AccountID: payee2.ID, Amount: 20},
+		},
+	})
+	require.NoError(t, err)
+
+	// each owner sees the legs of their own accounts only
+	for _, tc := range []struct {
+		subscription WebhookSubscription
+		entry        Entry
+	}{
+		{payeeSubscription, result.Entries[2]},
+		{payerSubscription, result.Entries[0]},
+	} {
+		var rows = deliveriesOf(t, tc.subscription)
+		require.Len(t, rows, 1)
+		event, err := testQueries.GetWebhookEvent(context.Background(), rows[0].WebhookDelivery.EventID)
+		require.NoError(t, err)
+		var data TransferGroupEvent
+		require.NoError(t, json.Unmarshal(event.Data, &data))
+		require.Equal(t, result.Group.ID, data.Group.ID)
+		require.Len(t, data.Entries, 1)
+		require.Equal(t, tc.entry.ID, data.Entries[0].ID)
+		require.Equal(t, tc.entry.AccountID, data.Entries[0].AccountID)
+	}
+}
+
+func TestWebhookDeliveryReplay(t *testing.T) {
+	var store = NewStore(testDB)
+	var user = createRandomUser(t)
+	var subscription = createRandomWebhook(t, user.Username, 0, EventAccountCreated)
+	var _, err = store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: util.RandomCurrency(),
+		Type:     AccountTypeChecking,
+	})
+	require.NoError(t, err)
+	var delivery = deliveriesOf(t, subscription)[0].WebhookDelivery
+
+	// a pending delivery cannot be replayed
+	_, err = testQueries.ReplayWebhookDelivery(context.Background(), delivery.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	err = testQueries.RetryWebhookDelivery(context.Background(), RetryWebhookDeliveryParams{
+		ID:            delivery.ID,
+		LastError:     "unexpected status 500",
+		NextAttemptAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+	err = testQueries.CreateWebhookAttempt(context.Background(), CreateWebhookAttemptParams{
+		DeliveryID:   delivery.ID,
+		Attempt:      1,
+		ResponseCode: 500,
+		Error:        "unexpected status 500",
+	})
+	require.NoError(t, err)
+	err = testQueries.DeadLetterWebhookDelivery(context.Background(), DeadLetterWebhookDeliveryParams{
+		ID:        delivery.ID,
+		LastError: "unexpected status 500",
+	})
+	require.NoError(t, err)
+
+	dead, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		Status:         pgtype.Text{String: WebhookDeliveryDead, Valid: true},
+		PageSize:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+
+	replayed, err := testQueries.ReplayWebhookDelivery(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	require.Equal(t, WebhookDeliveryPending, replayed.Status)
+	require.Zero(t, replayed.Attempts)
+
+	attempts, err := testQueries.ListWebhookAttempts(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+
+	// deleting the subscription drops its deliveries
+	rows, err := testQueries.DeleteWebhookSubscription(context.Background(), DeleteWebhookSubscriptionParams{
+		ID:       subscription.ID,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+	_, err = testQueries.GetWebhookDelivery(context.Background(), delivery.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
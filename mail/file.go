@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer writes every message as an .eml file into a directory instead of delivering it.
+type FileMailer struct {
+	from mail.Address
+	dir  string
+	seq  atomic.Int64
+}
+
+// NewFileMailer creates a new FileMailer, creating dir if it does not exist.
+func NewFileMailer(from mail.Address, dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create mail dir: %w", err)
+	}
+	return &FileMailer{from: from, dir: dir}, nil
+}
+
+// Send writes the message to a new file in the mail directory.
+func (mailer *FileMailer) Send(_ context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	var now = time.Now()
+	var name = fmt.Sprintf("%s-%d.eml", now.Format("20060102T150405.000000000"), mailer.seq.Add(1))
+	return os.WriteFile(filepath.Join(mailer.dir, name), msg.encode(mailer.from, now), 0o644)
+}
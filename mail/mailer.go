@@ -0,0 +1,70 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+)
+
+// Supported values of util.Config.Mailer.
+const (
+	MailerSMTP   = "smtp"
+	MailerFile   = "file"
+	MailerMemory = "memory"
+)
+
+// Message is an email to be delivered by a Mailer.
+type Message struct {
+	To      []string
+	Subject string
+	// Body is the HTML content of the email.
+	Body string
+}
+
+// Mailer is an interface for delivering emails.
+type Mailer interface {
+	// Send delivers the message to all of its recipients.
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer creates the Mailer selected by config.Mailer.
+func NewMailer(config util.Config) (Mailer, error) {
+	var from = mail.Address{Name: config.EmailSenderName, Address: config.EmailSenderAddress}
+
+	switch config.Mailer {
+	case MailerSMTP:
+		return NewSMTPMailer(from, config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword), nil
+	case MailerFile:
+		return NewFileMailer(from, config.MailDir)
+	case MailerMemory:
+		return NewMemoryMailer(), nil
+	}
+	return nil, fmt.Errorf("unsupported mailer: %q", config.Mailer)
+}
+
+// encode renders the message in RFC 5322 format.
+func (msg Message) encode(from mail.Address, date time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/html; charset=\"UTF-8\"\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+	return buf.Bytes()
+}
+
+func (msg Message) validate() error {
+	if len(msg.To) == 0 {
+		return fmt.Errorf("message has no recipient")
+	}
+	return nil
+}
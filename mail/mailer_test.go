@@ -0,0 +1,73 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomMessage() Message {
+	return Message{
+		To:      []string{util.RandomEmail()},
+		Subject: "Hello " + util.RandomOwner(),
+		Body:    "<h1>" + util.RandomString(20) + "</h1>",
+	}
+}
+
+func TestMemoryMailer(t *testing.T) {
+	var mailer = NewMemoryMailer()
+	var msg = randomMessage()
+
+	require.NoError(t, mailer.Send(context.Background(), msg))
+	require.Equal(t, []Message{msg}, mailer.Messages())
+
+	require.Error(t, mailer.Send(context.Background(), Message{Subject: "no recipient"}))
+	require.Len(t, mailer.Messages(), 1)
+}
+
+func TestFileMailer(t *testing.T) {
+	var dir = t.TempDir()
+	var from = mail.Address{Name: "Simple Bank", Address: "no-reply@simplebank.local"}
+	var mailer, err = NewFileMailer(from, dir)
+	require.NoError(t, err)
+
+	var msg = randomMessage()
+	require.NoError(t, mailer.Send(context.Background(), msg))
+	require.NoError(t, mailer.Send(context.Background(), randomMessage()))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, msg.To[0], parsed.Header.Get("To"))
+	require.Equal(t, msg.Subject, parsed.Header.Get("Subject"))
+	require.Contains(t, parsed.Header.Get("From"), from.Address)
+}
+
+func TestNewMailer(t *testing.T) {
+	var mailer, err = NewMailer(util.Config{Mailer: MailerMemory})
+	require.NoError(t, err)
+	require.IsType(t, &MemoryMailer{}, mailer)
+
+	mailer, err = NewMailer(util.Config{Mailer: MailerFile, MailDir: t.TempDir()})
+	require.NoError(t, err)
+	require.IsType(t, &FileMailer{}, mailer)
+
+	mailer, err = NewMailer(util.Config{Mailer: MailerSMTP})
+	require.NoError(t, err)
+	require.IsType(t, &SMTPMailer{}, mailer)
+
+	_, err = NewMailer(util.Config{Mailer: "pigeon"})
+	require.Error(t, err)
+}
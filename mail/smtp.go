@@ -0,0 +1,87 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPMailer delivers emails through an SMTP server.
+type SMTPMailer struct {
+	from     mail.Address
+	host     string
+	port     int
+	username string
+	password string
+}
+
+// NewSMTPMailer creates a new SMTPMailer
+func NewSMTPMailer(from mail.Address, host string, port int, username, password string) *SMTPMailer {
+	return &SMTPMailer{
+		from:     from,
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+	}
+}
+
+// Send delivers the message, upgrading the connection with STARTTLS when the server supports it.
+func (mailer *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	var conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(mailer.host, strconv.Itoa(mailer.port)))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, mailer.host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: mailer.host}); err != nil {
+			return err
+		}
+	}
+	if mailer.username != "" {
+		if err = client.Auth(smtp.PlainAuth("", mailer.username, mailer.password, mailer.host)); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(mailer.from.Address); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		if err = client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(msg.encode(mailer.from, time.Now())); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/Ma-hiru/simplebank/api"
 	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/mail"
+	"github.com/Ma-hiru/simplebank/outbox"
 	"github.com/Ma-hiru/simplebank/util"
-	_ "github.com/lib/pq"
+	"github.com/Ma-hiru/simplebank/worker"
 )
 
 func main() {
@@ -17,12 +22,58 @@ func main() {
 	}
 
 	{
-		var conn, err = sql.Open(config.DBDriver, config.DBSource)
+		var connPool, err = db.NewPool(context.Background(), config)
 		if err != nil {
 			log.Fatal("cannot connect to db:", err)
 		}
-		var store = db.NewStore(conn)
-		var server = api.NewServer(store)
+		defer connPool.Close()
+		var store = db.NewStoreWithRetryPolicy(connPool, db.NewTxRetryPolicy(config))
+
+		mailer, err := mail.NewMailer(config)
+		if err != nil {
+			log.Fatal("cannot create mailer:", err)
+		}
+		var taskProcessor = worker.NewPGTaskProcessor(config, store, mailer)
+		if err = taskProcessor.Start(); err != nil {
+			log.Fatal("cannot start task processor:", err)
+		}
+		defer taskProcessor.Shutdown()
+
+		var outboxRelay *worker.OutboxRelay
+		if config.OutboxPublisher != "" {
+			publisher, err := outbox.NewPublisher(config)
+			if err != nil {
+				log.Fatal("cannot create outbox publisher:", err)
+			}
+			outboxRelay = worker.NewOutboxRelay(config, store, publisher)
+			if err = outboxRelay.Start(); err != nil {
+				log.Fatal("cannot start outbox relay:", err)
+			}
+			defer outboxRelay.Shutdown()
+		}
+
+		server, err := api.NewServer(config, store, worker.NewPGTaskDistributor())
+		if err != nil {
+			log.Fatal("cannot create server:", err)
+		}
+		server.RegisterWorker("task_processor", taskProcessor.Check)
+		if outboxRelay != nil {
+			server.RegisterWorker("outbox_relay", outboxRelay.Check)
+		}
+
+		if config.TokenKeyringFile != "" {
+			var reload = make(chan os.Signal, 1)
+			signal.Notify(reload, syscall.SIGHUP)
+			go func() {
+				for range reload {
+					if err := server.ReloadTokenKeys(); err != nil {
+						log.Println("cannot reload token keys:", err)
+						continue
+					}
+					log.Println("token keys reloaded")
+				}
+			}()
+		}
 
 		err = server.Start(config.ServerAddress)
 		if err != nil {
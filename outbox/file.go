@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FilePublisher appends every event as a line of JSON to a file.
+type FilePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFilePublisher creates a new FilePublisher, creating the file and its directory if they do not exist.
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	if path == "" {
+		return nil, fmt.Errorf("outbox file is not set")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create outbox dir: %w", err)
+	}
+	var file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open outbox file: %w", err)
+	}
+	return &FilePublisher{file: file}, nil
+}
+
+// Publish appends the event and syncs the file, so a published event survives a crash.
+func (publisher *FilePublisher) Publish(_ context.Context, event Event) error {
+	var line, err = json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	if _, err = publisher.file.Write(line); err != nil {
+		return err
+	}
+	return publisher.file.Sync()
+}
+
+func (publisher *FilePublisher) Close() error {
+	return publisher.file.Close()
+}
@@ -0,0 +1,65 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const httpTimeout = 10 * time.Second
+
+// HTTPPublisher posts every event as JSON to a URL.
+// The event ID is sent as the Idempotency-Key header, so the receiver can drop the redelivered events.
+type HTTPPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPPublisher creates a new HTTPPublisher
+func NewHTTPPublisher(rawURL string) (*HTTPPublisher, error) {
+	var target, err = url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("invalid outbox http url: %q", rawURL)
+	}
+	return &HTTPPublisher{
+		url:    rawURL,
+		client: &http.Client{Timeout: httpTimeout},
+	}, nil
+}
+
+// Publish posts the event and expects a 2xx response.
+func (publisher *HTTPPublisher) Publish(ctx context.Context, event Event) error {
+	var body, err = json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, publisher.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", strconv.FormatInt(event.ID, 10))
+
+	rsp, err := publisher.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(rsp.Body, 64<<10))
+
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", rsp.StatusCode)
+	}
+	return nil
+}
+
+func (publisher *HTTPPublisher) Close() error {
+	publisher.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,26 @@
+package outbox
+
+import (
+	"context"
+	"log"
+)
+
+// LogPublisher writes every event to a logger instead of publishing it.
+type LogPublisher struct {
+	logger *log.Logger
+}
+
+// NewLogPublisher creates a new LogPublisher
+func NewLogPublisher(logger *log.Logger) *LogPublisher {
+	return &LogPublisher{logger: logger}
+}
+
+// Publish logs the event.
+func (publisher *LogPublisher) Publish(_ context.Context, event Event) error {
+	publisher.logger.Printf("outbox event %d %s of account %d: %s", event.ID, event.Type, event.AccountID, event.Payload)
+	return nil
+}
+
+func (publisher *LogPublisher) Close() error {
+	return nil
+}
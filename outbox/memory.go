@@ -0,0 +1,37 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryPublisher keeps every event in memory instead of publishing it.
+// It is meant for tests and local development.
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewMemoryPublisher creates a new MemoryPublisher
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish records the event.
+func (publisher *MemoryPublisher) Publish(_ context.Context, event Event) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	publisher.events = append(publisher.events, event)
+	return nil
+}
+
+func (publisher *MemoryPublisher) Close() error {
+	return nil
+}
+
+// Events returns a copy of all events published so far.
+func (publisher *MemoryPublisher) Events() []Event {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	return append([]Event(nil), publisher.events...)
+}
@@ -0,0 +1,193 @@
+package outbox
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	natsDefaultPort = "4222"
+	natsTimeout     = 10 * time.Second
+)
+
+// NATSPublisher publishes every event on <subject>.<event type> of a NATS server.
+// It speaks just enough of the core NATS text protocol to publish, without the client library:
+// every PUB is followed by a PING, and the event only counts as published once the server answered PONG.
+type NATSPublisher struct {
+	address  string
+	subject  string
+	username string
+	password string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewNATSPublisher creates a new NATSPublisher from a nats://[user:password@]host[:port] URL.
+// It connects on the first event.
+func NewNATSPublisher(rawURL string, subject string) (*NATSPublisher, error) {
+	var target, err = url.Parse(rawURL)
+	if err != nil || target.Scheme != "nats" || target.Hostname() == "" {
+		return nil, fmt.Errorf("invalid outbox nats url: %q", rawURL)
+	}
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n*>") {
+		return nil, fmt.Errorf("invalid outbox nats subject: %q", subject)
+	}
+
+	var port = target.Port()
+	if port == "" {
+		port = natsDefaultPort
+	}
+	var publisher = &NATSPublisher{
+		address: net.JoinHostPort(target.Hostname(), port),
+		subject: subject,
+	}
+	if target.User != nil {
+		publisher.username = target.User.Username()
+		publisher.password, _ = target.User.Password()
+	}
+	return publisher, nil
+}
+
+// Publish sends the event and waits for the server to acknowledge it.
+// The connection is dropped on any error and made again for the next event.
+func (publisher *NATSPublisher) Publish(ctx context.Context, event Event) error {
+	var data, err = json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	if publisher.conn == nil {
+		if err = publisher.connect(ctx); err != nil {
+			return fmt.Errorf("cannot connect to nats: %w", err)
+		}
+	}
+	publisher.setDeadline(ctx)
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "PUB %s.%s %d\r\n", publisher.subject, event.Type, len(data))
+	msg.Write(data)
+	msg.WriteString("\r\nPING\r\n")
+	if _, err = publisher.conn.Write(msg.Bytes()); err == nil {
+		err = publisher.waitPong()
+	}
+	if err != nil {
+		publisher.closeConn()
+	}
+	return err
+}
+
+func (publisher *NATSPublisher) Close() error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	return publisher.closeConn()
+}
+
+type natsConnectOptions struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+}
+
+// connect dials the server, reads its INFO and sends CONNECT.
+func (publisher *NATSPublisher) connect(ctx context.Context) error {
+	var dialer net.Dialer
+	var conn, err = dialer.DialContext(ctx, "tcp", publisher.address)
+	if err != nil {
+		return err
+	}
+	publisher.conn = conn
+	publisher.reader = bufio.NewReader(conn)
+	publisher.setDeadline(ctx)
+
+	line, err := publisher.readLine()
+	if err == nil && !strings.HasPrefix(line, "INFO ") {
+		err = fmt.Errorf("unexpected greeting %q", line)
+	}
+	if err != nil {
+		publisher.closeConn()
+		return err
+	}
+
+	options, err := json.Marshal(natsConnectOptions{
+		Name:    "simplebank-outbox",
+		Lang:    "go",
+		Version: "1.0.0",
+		User:    publisher.username,
+		Pass:    publisher.password,
+	})
+	if err != nil {
+		publisher.closeConn()
+		return err
+	}
+	if _, err = fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", options); err == nil {
+		err = publisher.waitPong()
+	}
+	if err != nil {
+		publisher.closeConn()
+	}
+	return err
+}
+
+// waitPong reads until the PONG answering our PING, answering the PINGs of the server on the way.
+func (publisher *NATSPublisher) waitPong() error {
+	for {
+		var line, err = publisher.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err = publisher.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (publisher *NATSPublisher) readLine() (string, error) {
+	var line, err = publisher.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (publisher *NATSPublisher) setDeadline(ctx context.Context) {
+	var deadline, ok = ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(natsTimeout)
+	}
+	_ = publisher.conn.SetDeadline(deadline)
+}
+
+func (publisher *NATSPublisher) closeConn() error {
+	if publisher.conn == nil {
+		return nil
+	}
+	var err = publisher.conn.Close()
+	publisher.conn = nil
+	publisher.reader = nil
+	return err
+}
@@ -0,0 +1,55 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+)
+
+// Supported values of util.Config.OutboxPublisher.
+const (
+	PublisherLog    = "log"
+	PublisherFile   = "file"
+	PublisherHTTP   = "http"
+	PublisherNATS   = "nats"
+	PublisherMemory = "memory"
+)
+
+// Event is a domain event read from the outbox table.
+// Events are published at least once, consumers drop the duplicates by ID.
+type Event struct {
+	ID        int64           `json:"id"`
+	AccountID int64           `json:"account_id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Publisher is an interface for publishing the outbox events to other systems.
+type Publisher interface {
+	// Publish returns once the event is accepted by the destination.
+	Publish(ctx context.Context, event Event) error
+	// Close releases the connections or files held by the publisher.
+	Close() error
+}
+
+// NewPublisher creates the Publisher selected by config.OutboxPublisher.
+func NewPublisher(config util.Config) (Publisher, error) {
+	switch config.OutboxPublisher {
+	case PublisherLog:
+		return NewLogPublisher(log.Default()), nil
+	case PublisherFile:
+		return NewFilePublisher(config.OutboxFile)
+	case PublisherHTTP:
+		return NewHTTPPublisher(config.OutboxHTTPURL)
+	case PublisherNATS:
+		return NewNATSPublisher(config.OutboxNATSURL, config.OutboxNATSSubject)
+	case PublisherMemory:
+		return NewMemoryPublisher(), nil
+	}
+	return nil, fmt.Errorf("unsupported outbox publisher: %q", config.OutboxPublisher)
+}
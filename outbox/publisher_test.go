@@ -0,0 +1,203 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomEvent() Event {
+	return Event{
+		ID:        util.RandomInt(1, 1000),
+		AccountID: util.RandomInt(1, 1000),
+		Type:      "entry.created",
+		Payload:   json.RawMessage(`{"entry":{"amount":` + strconv.FormatInt(util.RandomMoney(), 10) + `}}`),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestNewPublisher(t *testing.T) {
+	var publisher, err = NewPublisher(util.Config{OutboxPublisher: PublisherLog})
+	require.NoError(t, err)
+	require.IsType(t, &LogPublisher{}, publisher)
+
+	publisher, err = NewPublisher(util.Config{OutboxPublisher: PublisherMemory})
+	require.NoError(t, err)
+	require.IsType(t, &MemoryPublisher{}, publisher)
+
+	publisher, err = NewPublisher(util.Config{OutboxPublisher: PublisherNATS, OutboxNATSURL: "nats://localhost", OutboxNATSSubject: "bank"})
+	require.NoError(t, err)
+	require.Equal(t, "localhost:4222", publisher.(*NATSPublisher).address)
+
+	_, err = NewPublisher(util.Config{OutboxPublisher: PublisherHTTP, OutboxHTTPURL: "ftp://example.com"})
+	require.Error(t, err)
+	_, err = NewPublisher(util.Config{OutboxPublisher: PublisherNATS, OutboxNATSURL: "nats://localhost", OutboxNATSSubject: "bank.>"})
+	require.Error(t, err)
+	_, err = NewPublisher(util.Config{OutboxPublisher: PublisherFile})
+	require.Error(t, err)
+	_, err = NewPublisher(util.Config{OutboxPublisher: "kafka"})
+	require.Error(t, err)
+}
+
+func TestMemoryPublisher(t *testing.T) {
+	var publisher = NewMemoryPublisher()
+	var event = randomEvent()
+
+	require.NoError(t, publisher.Publish(context.Background(), event))
+	require.Equal(t, []Event{event}, publisher.Events())
+	require.NoError(t, publisher.Close())
+}
+
+func TestFilePublisher(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "outbox", "events.jsonl")
+	var publisher, err = NewFilePublisher(path)
+	require.NoError(t, err)
+
+	var events = []Event{randomEvent(), randomEvent()}
+	for _, event := range events {
+		require.NoError(t, publisher.Publish(context.Background(), event))
+	}
+	require.NoError(t, publisher.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	for i, line := range lines {
+		var event Event
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		require.Equal(t, events[i].ID, event.ID)
+		require.JSONEq(t, string(events[i].Payload), string(event.Payload))
+	}
+}
+
+func TestHTTPPublisher(t *testing.T) {
+	var event = randomEvent()
+	var status = http.StatusAccepted
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.Equal(t, strconv.FormatInt(event.ID, 10), r.Header.Get("Idempotency-Key"))
+
+		var got Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		require.Equal(t, event.AccountID, got.AccountID)
+		require.Equal(t, event.Type, got.Type)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	var publisher, err = NewHTTPPublisher(server.URL)
+	require.NoError(t, err)
+	defer publisher.Close()
+	require.NoError(t, publisher.Publish(context.Background(), event))
+
+	status = http.StatusServiceUnavailable
+	require.EqualError(t, publisher.Publish(context.Background(), event), "unexpected status 503")
+}
+
+// fakeNATSServer accepts one connection at a time and answers like a NATS server.
+// The subjects and payloads of the PUB messages are sent to msgs,
+// and a PUB on a subject ending in .fail is answered with -ERR.
+func fakeNATSServer(t *testing.T, msgs chan<- [2]string) string {
+	var listener, err = net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			var conn, err = listener.Accept()
+			if err != nil {
+				return
+			}
+			serveNATS(conn, msgs)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveNATS(conn net.Conn, msgs chan<- [2]string) {
+	defer conn.Close()
+	var reader = bufio.NewReader(conn)
+	_, _ = io.WriteString(conn, "INFO {\"server_id\":\"fake\"}\r\n")
+
+	for {
+		var line, err = reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		var fields = strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "PING":
+			// a server PING before the PONG must be answered by the client
+			_, _ = io.WriteString(conn, "PING\r\n")
+			if line, err = reader.ReadString('\n'); err != nil || line != "PONG\r\n" {
+				return
+			}
+			_, _ = io.WriteString(conn, "PONG\r\n")
+		case fields[0] == "PUB" && len(fields) == 3:
+			var size, _ = strconv.Atoi(fields[2])
+			var payload = make([]byte, size+2)
+			if _, err = io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			if strings.HasSuffix(fields[1], ".fail") {
+				_, _ = io.WriteString(conn, "-ERR 'Permissions Violation'\r\n")
+				return
+			}
+			msgs <- [2]string{fields[1], string(payload[:size])}
+		}
+	}
+}
+
+func TestNATSPublisher(t *testing.T) {
+	var msgs = make(chan [2]string, 10)
+	var address = fakeNATSServer(t, msgs)
+
+	var publisher, err = NewNATSPublisher("nats://user:secret@"+address, "simplebank")
+	require.NoError(t, err)
+	defer publisher.Close()
+	require.Equal(t, "user", publisher.username)
+
+	var event = randomEvent()
+	require.NoError(t, publisher.Publish(context.Background(), event))
+	var msg = <-msgs
+	require.Equal(t, "simplebank.entry.created", msg[0])
+	var got Event
+	require.NoError(t, json.Unmarshal([]byte(msg[1]), &got))
+	require.Equal(t, event.ID, got.ID)
+
+	// the connection is dropped on an error and made again for the next event
+	var failing = randomEvent()
+	failing.Type = "entry.fail"
+	require.ErrorContains(t, publisher.Publish(context.Background(), failing), "Permissions Violation")
+	require.Nil(t, publisher.conn)
+
+	require.NoError(t, publisher.Publish(context.Background(), event))
+	require.Equal(t, "simplebank.entry.created", (<-msgs)[0])
+}
+
+func TestNATSPublisherUnreachable(t *testing.T) {
+	var listener, err = net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	var address = listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	publisher, err := NewNATSPublisher("nats://"+address, "simplebank")
+	require.NoError(t, err)
+	require.ErrorContains(t, publisher.Publish(context.Background(), randomEvent()), "cannot connect to nats")
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+)
+
+// Supported values of util.Config.RateLimitBackend.
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+// Limit is a token bucket holding up to Burst tokens and refilling all of them every Period.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseLimit parses a limit written as "<burst>/<period>", such as "10/1m".
+// An empty string is the zero Limit, which disables rate limiting.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" {
+		return Limit{}, nil
+	}
+
+	var burst, period, ok = strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: want <burst>/<period>", s)
+	}
+	var limit Limit
+	var err error
+	limit.Burst, err = strconv.Atoi(burst)
+	if err != nil || limit.Burst <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", s)
+	}
+	limit.Period, err = time.ParseDuration(period)
+	if err != nil || limit.Period <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", s)
+	}
+	return limit, nil
+}
+
+// Enabled reports whether the limit restricts anything.
+func (limit Limit) Enabled() bool {
+	return limit.Burst > 0 && limit.Period > 0
+}
+
+// rate is the number of tokens added to the bucket per second.
+func (limit Limit) rate() float64 {
+	return float64(limit.Burst) / limit.Period.Seconds()
+}
+
+// Result describes the state of a bucket after a request has been counted.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long to wait before the next request can be allowed, zero when Allowed.
+	RetryAfter time.Duration
+	// Reset is how long it takes to refill the bucket completely.
+	Reset time.Duration
+}
+
+// newResult builds the Result of a bucket holding tokens after the request has been counted.
+func newResult(limit Limit, tokens float64, allowed bool) Result {
+	var rate = limit.rate()
+	var result = Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: max(int(tokens), 0),
+		Reset:     durationOf((float64(limit.Burst) - tokens) / rate),
+	}
+	if !allowed {
+		result.RetryAfter = durationOf((1 - tokens) / rate)
+	}
+	return result
+}
+
+func durationOf(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Limiter counts requests against token buckets identified by a key.
+type Limiter interface {
+	// Allow takes a token from the bucket of the key, creating a full bucket the first time the key is seen.
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// NewLimiter creates the Limiter selected by config.RateLimitBackend.
+// The memory backend is used when none is configured.
+func NewLimiter(config util.Config, store db.Store) (Limiter, error) {
+	switch config.RateLimitBackend {
+	case "", BackendMemory:
+		return NewMemoryLimiter(), nil
+	case BackendPostgres:
+		return NewPostgresLimiter(store), nil
+	}
+	return nil, fmt.Errorf("unsupported rate limit backend: %q", config.RateLimitBackend)
+}
@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestParseLimit(t *testing.T) {
+	var testCases = []struct {
+		input string
+		limit Limit
+		ok    bool
+	}{
+		{"", Limit{}, true},
+		{"10/1m", Limit{Burst: 10, Period: time.Minute}, true},
+		{"1/500ms", Limit{Burst: 1, Period: 500 * time.Millisecond}, true},
+		{"10", Limit{}, false},
+		{"0/1m", Limit{}, false},
+		{"-1/1m", Limit{}, false},
+		{"ten/1m", Limit{}, false},
+		{"10/0s", Limit{}, false},
+		{"10/minute", Limit{}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			var limit, err = ParseLimit(tc.input)
+			if !tc.ok {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.limit, limit)
+			require.Equal(t, tc.input != "", limit.Enabled())
+		})
+	}
+}
+
+func TestNewLimiter(t *testing.T) {
+	var limiter, err = NewLimiter(util.Config{}, nil)
+	require.NoError(t, err)
+	require.IsType(t, &MemoryLimiter{}, limiter)
+
+	limiter, err = NewLimiter(util.Config{RateLimitBackend: BackendPostgres}, nil)
+	require.NoError(t, err)
+	require.IsType(t, &PostgresLimiter{}, limiter)
+
+	_, err = NewLimiter(util.Config{RateLimitBackend: "redis"}, nil)
+	require.Error(t, err)
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	var now = time.Now()
+	var limiter = NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	var limit = Limit{Burst: 3, Period: 3 * time.Second}
+	var ctx = context.Background()
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		var result, err = limiter.Allow(ctx, "a", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, 3, result.Limit)
+		require.Equal(t, remaining, result.Remaining)
+		require.Zero(t, result.RetryAfter)
+	}
+
+	var result, err = limiter.Allow(ctx, "a", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, time.Second, result.RetryAfter)
+	require.Equal(t, 3*time.Second, result.Reset)
+
+	// other keys have their own bucket
+	result, err = limiter.Allow(ctx, "b", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// one token is back after a third of the period
+	now = now.Add(time.Second)
+	result, err = limiter.Allow(ctx, "a", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+
+	// the bucket never holds more than the burst
+	now = now.Add(time.Hour)
+	result, err = limiter.Allow(ctx, "a", limit)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Remaining)
+}
+
+func TestMemoryLimiterPrune(t *testing.T) {
+	var now = time.Now()
+	var limiter = NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	var limit = Limit{Burst: 1, Period: time.Minute}
+
+	for i := range maxIdleBuckets {
+		var _, err = limiter.Allow(context.Background(), strconv.Itoa(i), limit)
+		require.NoError(t, err)
+	}
+	require.Len(t, limiter.buckets, maxIdleBuckets)
+
+	now = now.Add(time.Minute)
+	var _, err = limiter.Allow(context.Background(), "new", limit)
+	require.NoError(t, err)
+	require.Len(t, limiter.buckets, 1)
+}
+
+func TestPostgresLimiter(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	var store = mockdb.NewMockStore(ctrl)
+	var limit = Limit{Burst: 10, Period: 10 * time.Second}
+
+	store.EXPECT().
+		TakeRateLimitToken(gomock.Any(), gomock.Eq(db.TakeRateLimitTokenParams{Key: "a", Burst: 10, Rate: 1})).
+		Times(1).
+		Return(db.TakeRateLimitTokenRow{Tokens: 0.5, Allowed: false}, nil)
+	store.EXPECT().
+		TakeRateLimitToken(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.TakeRateLimitTokenRow{}, sql.ErrConnDone)
+	store.EXPECT().
+		DeleteRateLimitBuckets(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(int64(1), nil)
+
+	var limiter = NewPostgresLimiter(store)
+	limiter.lastPruned = time.Now().Add(-pruneInterval)
+
+	var result, err = limiter.Allow(context.Background(), "a", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	require.Equal(t, 500*time.Millisecond, result.RetryAfter)
+	require.Equal(t, 9500*time.Millisecond, result.Reset)
+
+	_, err = limiter.Allow(context.Background(), "a", limit)
+	require.ErrorIs(t, err, sql.ErrConnDone)
+}
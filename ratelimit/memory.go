@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxIdleBuckets is the number of buckets kept before full ones are dropped.
+const maxIdleBuckets = 10000
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryLimiter keeps the buckets in process memory.
+// Each replica counts on its own, so it fits single instance deployments.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// NewMemoryLimiter creates an empty MemoryLimiter.
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the key.
+func (limiter *MemoryLimiter) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	var now = limiter.now()
+	var b, ok = limiter.buckets[key]
+	if !ok {
+		if len(limiter.buckets) >= maxIdleBuckets {
+			limiter.prune(now, limit)
+		}
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		limiter.buckets[key] = b
+	}
+
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.updatedAt).Seconds()*limit.rate())
+	b.updatedAt = now
+
+	var allowed = b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return newResult(limit, b.tokens, allowed), nil
+}
+
+// prune drops the buckets that have been refilled completely, since a new bucket would be the same.
+func (limiter *MemoryLimiter) prune(now time.Time, limit Limit) {
+	for key, b := range limiter.buckets {
+		if now.Sub(b.updatedAt) >= limit.Period {
+			delete(limiter.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+)
+
+// pruneInterval is how often the stale buckets are deleted from the database.
+const pruneInterval = 10 * time.Minute
+
+// PostgresLimiter keeps the buckets in the rate_limit_buckets table, so every replica shares them.
+type PostgresLimiter struct {
+	store db.Store
+
+	mu         sync.Mutex
+	lastPruned time.Time
+	// maxPeriod is the longest period seen, after which any idle bucket is full again.
+	maxPeriod time.Duration
+}
+
+// NewPostgresLimiter creates a PostgresLimiter on top of the store.
+func NewPostgresLimiter(store db.Store) *PostgresLimiter {
+	return &PostgresLimiter{store: store, lastPruned: time.Now()}
+}
+
+// Allow takes a token from the bucket of the key in a single statement.
+func (limiter *PostgresLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	var row, err = limiter.store.TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(limit.Burst),
+		Rate:  limit.rate(),
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	limiter.prune(ctx, limit)
+	return newResult(limit, row.Tokens, row.Allowed), nil
+}
+
+// prune deletes the buckets that have been idle long enough to be full again.
+// Errors are ignored: the rows are deleted on the next run.
+func (limiter *PostgresLimiter) prune(ctx context.Context, limit Limit) {
+	limiter.mu.Lock()
+	var now = time.Now()
+	limiter.maxPeriod = max(limiter.maxPeriod, limit.Period)
+	if now.Sub(limiter.lastPruned) < pruneInterval {
+		limiter.mu.Unlock()
+		return
+	}
+	limiter.lastPruned = now
+	var idle = max(pruneInterval, limiter.maxPeriod)
+	limiter.mu.Unlock()
+
+	_, _ = limiter.store.DeleteRateLimitBuckets(ctx, now.Add(-idle))
+}
@@ -0,0 +1,151 @@
+package statement
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+)
+
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtDateTime struct {
+	DtTm string `xml:"DtTm"`
+}
+
+type camtBalance struct {
+	Code      string       `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount   `xml:"Amt"`
+	CdtDbtInd string       `xml:"CdtDbtInd"`
+	Date      camtDateTime `xml:"Dt"`
+}
+
+type camtEntry struct {
+	NtryRef       string       `xml:"NtryRef"`
+	Amount        camtAmount   `xml:"Amt"`
+	CdtDbtInd     string       `xml:"CdtDbtInd"`
+	Status        string       `xml:"Sts"`
+	BookingDate   camtDateTime `xml:"BookgDt"`
+	ValueDate     camtDateTime `xml:"ValDt"`
+	AcctSvcrRef   string       `xml:"AcctSvcrRef"`
+	Domain        string       `xml:"BkTxCd>Domn>Cd"`
+	Family        string       `xml:"BkTxCd>Domn>Fmly>Cd"`
+	SubFamily     string       `xml:"BkTxCd>Domn>Fmly>SubFmlyCd"`
+	TransactionID string       `xml:"NtryDtls>TxDtls>Refs>TxId,omitempty"`
+	// the counterparty is the debtor of a credit and the creditor of a debit
+	DebtorAccount   string `xml:"NtryDtls>TxDtls>RltdPties>DbtrAcct>Id>Othr>Id,omitempty"`
+	CreditorAccount string `xml:"NtryDtls>TxDtls>RltdPties>CdtrAcct>Id>Othr>Id,omitempty"`
+	Remittance      string `xml:"NtryDtls>TxDtls>RmtInf>Ustrd,omitempty"`
+}
+
+// camt053Encoder writes an ISO 20022 camt.053 bank to customer statement.
+type camt053Encoder struct {
+	xml    *xmlWriter
+	header Header
+}
+
+func newCAMT053Encoder(w io.Writer) *camt053Encoder {
+	return &camt053Encoder{xml: newXMLWriter(w)}
+}
+
+func (encoder *camt053Encoder) WriteHeader(header Header) error {
+	encoder.header = header
+	var id = fmt.Sprintf("%d-%s", header.AccountID, header.GeneratedAt.UTC().Format("20060102150405"))
+
+	var w = encoder.xml
+	w.declaration()
+	w.start("Document", xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: camt053Namespace})
+	w.start("BkToCstmrStmt")
+	w.start("GrpHdr")
+	w.element("MsgId", id)
+	w.element("CreDtTm", formatCAMTTime(header.GeneratedAt))
+	w.end()
+
+	w.start("Stmt")
+	w.element("Id", id)
+	w.element("CreDtTm", formatCAMTTime(header.GeneratedAt))
+	w.start("FrToDt")
+	w.element("FrDtTm", formatCAMTTime(header.From))
+	w.element("ToDtTm", formatCAMTTime(header.To))
+	w.end()
+	w.start("Acct")
+	w.element("Id", struct {
+		ID string `xml:"Othr>Id"`
+	}{ID: strconv.FormatInt(header.AccountID, 10)})
+	w.element("Ccy", header.Currency)
+	w.element("Ownr", struct {
+		Name string `xml:"Nm"`
+	}{Name: header.Owner})
+	w.element("Svcr", struct {
+		ID string `xml:"FinInstnId>Othr>Id"`
+	}{ID: bankID})
+	w.end()
+	w.element("Bal", encoder.balance("OPBD", header.OpeningBalance, header.From))
+	w.element("Bal", encoder.balance("CLBD", header.ClosingBalance, header.To))
+	return w.flush()
+}
+
+func (encoder *camt053Encoder) WriteLine(line Line) error {
+	var entry = camtEntry{
+		NtryRef:     strconv.FormatInt(line.EntryID, 10),
+		Amount:      camtAmount{Currency: encoder.header.Currency, Value: util.FormatMinorUnits(abs(line.Amount), encoder.header.Currency)},
+		CdtDbtInd:   creditDebit(line.Amount),
+		Status:      "BOOK",
+		BookingDate: camtDateTime{DtTm: formatCAMTTime(line.BookedAt)},
+		ValueDate:   camtDateTime{DtTm: formatCAMTTime(line.BookedAt)},
+		AcctSvcrRef: strconv.FormatInt(line.EntryID, 10),
+		Domain:      "PMNT",
+		Family:      "ICDT",
+		SubFamily:   "BOOK",
+		Remittance:  line.Description,
+	}
+	if line.Amount >= 0 {
+		entry.Family = "RCDT"
+	}
+	if line.TransferID != 0 {
+		entry.TransactionID = strconv.FormatInt(line.TransferID, 10)
+	}
+	if line.CounterpartyAccountID != 0 {
+		var counterparty = strconv.FormatInt(line.CounterpartyAccountID, 10)
+		if line.Amount >= 0 {
+			entry.DebtorAccount = counterparty
+		} else {
+			entry.CreditorAccount = counterparty
+		}
+	}
+
+	encoder.xml.element("Ntry", entry)
+	return encoder.xml.err
+}
+
+func (encoder *camt053Encoder) Close() error {
+	return encoder.xml.close()
+}
+
+func (encoder *camt053Encoder) balance(code string, amount int64, at time.Time) camtBalance {
+	return camtBalance{
+		Code:      code,
+		Amount:    camtAmount{Currency: encoder.header.Currency, Value: util.FormatMinorUnits(abs(amount), encoder.header.Currency)},
+		CdtDbtInd: creditDebit(amount),
+		Date:      camtDateTime{DtTm: formatCAMTTime(at)},
+	}
+}
+
+func creditDebit(amount int64) string {
+	if amount < 0 {
+		return "DBIT"
+	}
+	return "CRDT"
+}
+
+func formatCAMTTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
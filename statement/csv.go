@@ -0,0 +1,57 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+)
+
+var csvColumns = []string{
+	"booked_at", "entry_id", "transfer_id", "counterparty_account_id", "description", "amount", "currency", "balance",
+}
+
+// csvEncoder writes one row per line with the running balance of the account.
+type csvEncoder struct {
+	writer   *csv.Writer
+	currency string
+	balance  int64
+}
+
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{writer: csv.NewWriter(w)}
+}
+
+func (encoder *csvEncoder) WriteHeader(header Header) error {
+	encoder.currency = header.Currency
+	encoder.balance = header.OpeningBalance
+	return encoder.writer.Write(csvColumns)
+}
+
+func (encoder *csvEncoder) WriteLine(line Line) error {
+	encoder.balance += line.Amount
+	return encoder.writer.Write([]string{
+		line.BookedAt.UTC().Format(time.RFC3339),
+		strconv.FormatInt(line.EntryID, 10),
+		optionalID(line.TransferID),
+		optionalID(line.CounterpartyAccountID),
+		line.Description,
+		util.FormatMinorUnits(line.Amount, encoder.currency),
+		encoder.currency,
+		util.FormatMinorUnits(encoder.balance, encoder.currency),
+	})
+}
+
+func (encoder *csvEncoder) Close() error {
+	encoder.writer.Flush()
+	return encoder.writer.Error()
+}
+
+func optionalID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
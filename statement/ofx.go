@@ -0,0 +1,125 @@
+package statement
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+)
+
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="211" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+`
+
+// ofxTimeLayout is the OFX datetime format with an explicit UTC offset.
+const ofxTimeLayout = "20060102150405.000[+0:UTC]"
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxSignOn struct {
+	Status   ofxStatus `xml:"STATUS"`
+	DTServer string    `xml:"DTSERVER"`
+	Language string    `xml:"LANGUAGE"`
+}
+
+type ofxAccount struct {
+	BankID   string `xml:"BANKID"`
+	AcctID   string `xml:"ACCTID"`
+	AcctType string `xml:"ACCTTYPE"`
+}
+
+type ofxTransaction struct {
+	TrnType  string `xml:"TRNTYPE"`
+	DTPosted string `xml:"DTPOSTED"`
+	TrnAmt   string `xml:"TRNAMT"`
+	FITID    string `xml:"FITID"`
+	Name     string `xml:"NAME,omitempty"`
+	Memo     string `xml:"MEMO,omitempty"`
+}
+
+type ofxBalance struct {
+	BalAmt string `xml:"BALAMT"`
+	DTAsOf string `xml:"DTASOF"`
+}
+
+// ofxEncoder writes an OFX 2.1.1 bank statement response.
+type ofxEncoder struct {
+	w      io.Writer
+	xml    *xmlWriter
+	header Header
+}
+
+func newOFXEncoder(w io.Writer) *ofxEncoder {
+	return &ofxEncoder{w: w, xml: newXMLWriter(w)}
+}
+
+func (encoder *ofxEncoder) WriteHeader(header Header) error {
+	encoder.header = header
+	if _, err := io.WriteString(encoder.w, ofxHeader); err != nil {
+		return err
+	}
+
+	var w = encoder.xml
+	w.start("OFX")
+	w.start("SIGNONMSGSRSV1")
+	w.element("SONRS", ofxSignOn{
+		Status:   ofxStatus{Code: 0, Severity: "INFO"},
+		DTServer: formatOFXTime(header.GeneratedAt),
+		Language: "ENG",
+	})
+	w.end()
+
+	w.start("BANKMSGSRSV1")
+	w.start("STMTTRNRS")
+	w.element("TRNUID", "0")
+	w.element("STATUS", ofxStatus{Code: 0, Severity: "INFO"})
+	w.start("STMTRS")
+	w.element("CURDEF", header.Currency)
+	w.element("BANKACCTFROM", ofxAccount{
+		BankID:   bankID,
+		AcctID:   strconv.FormatInt(header.AccountID, 10),
+		AcctType: "CHECKING",
+	})
+	w.start("BANKTRANLIST")
+	w.element("DTSTART", formatOFXTime(header.From))
+	w.element("DTEND", formatOFXTime(header.To))
+	return w.flush()
+}
+
+func (encoder *ofxEncoder) WriteLine(line Line) error {
+	var transaction = ofxTransaction{
+		TrnType:  "CREDIT",
+		DTPosted: formatOFXTime(line.BookedAt),
+		TrnAmt:   util.FormatMinorUnits(line.Amount, encoder.header.Currency),
+		FITID:    strconv.FormatInt(line.EntryID, 10),
+		Memo:     line.Description,
+	}
+	if line.Amount < 0 {
+		transaction.TrnType = "DEBIT"
+	}
+	if line.CounterpartyAccountID != 0 {
+		transaction.Name = "Account " + strconv.FormatInt(line.CounterpartyAccountID, 10)
+	}
+
+	encoder.xml.element("STMTTRN", transaction)
+	return encoder.xml.err
+}
+
+func (encoder *ofxEncoder) Close() error {
+	var w = encoder.xml
+	// BANKTRANLIST
+	w.end()
+	w.element("LEDGERBAL", ofxBalance{
+		BalAmt: util.FormatMinorUnits(encoder.header.ClosingBalance, encoder.header.Currency),
+		DTAsOf: formatOFXTime(encoder.header.To),
+	})
+	return w.close()
+}
+
+func formatOFXTime(t time.Time) string {
+	return t.UTC().Format(ofxTimeLayout)
+}
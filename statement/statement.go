@@ -0,0 +1,94 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Supported statement formats.
+const (
+	FormatCSV     = "csv"
+	FormatOFX     = "ofx"
+	FormatCAMT053 = "camt053"
+)
+
+// bankID identifies the bank in the OFX and camt.053 documents.
+const bankID = "SIMPLEBANK"
+
+// Header describes the account and the period covered by a statement.
+type Header struct {
+	AccountID      int64
+	Owner          string
+	Currency       string
+	From           time.Time
+	To             time.Time
+	OpeningBalance int64
+	ClosingBalance int64
+	GeneratedAt    time.Time
+}
+
+// Line is a booked entry of the statement.
+type Line struct {
+	EntryID  int64
+	BookedAt time.Time
+	Amount   int64
+	// TransferID and CounterpartyAccountID are zero when the entry does not belong to a transfer.
+	TransferID            int64
+	CounterpartyAccountID int64
+	Description           string
+}
+
+// Encoder writes a statement to an io.Writer as it is read.
+// WriteHeader is called once, then WriteLine for every line in booking order, then Close.
+// The document is only complete once Close returns, so a statement interrupted by an error
+// is never mistaken for a complete one by the XML formats.
+type Encoder interface {
+	WriteHeader(header Header) error
+	WriteLine(line Line) error
+	Close() error
+}
+
+// NewEncoder creates the encoder of the format.
+func NewEncoder(format string, w io.Writer) (Encoder, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVEncoder(w), nil
+	case FormatOFX:
+		return newOFXEncoder(w), nil
+	case FormatCAMT053:
+		return newCAMT053Encoder(w), nil
+	default:
+		return nil, fmt.Errorf("unknown statement format %q", format)
+	}
+}
+
+// ContentType is the MIME type of the format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatOFX:
+		return "application/x-ofx"
+	default:
+		return "application/xml"
+	}
+}
+
+// FileName is the suggested name of the statement file.
+func FileName(format string, header Header) string {
+	var ext = format
+	if format == FormatCAMT053 {
+		ext = "xml"
+	}
+	return fmt.Sprintf("statement-%d-%s-%s.%s",
+		header.AccountID, header.From.Format(time.DateOnly), header.To.Format(time.DateOnly), ext)
+}
+
+// abs returns the absolute value of an amount, which the XML formats pair with a credit or debit indicator.
+func abs(amount int64) int64 {
+	if amount < 0 {
+		return -amount
+	}
+	return amount
+}
@@ -0,0 +1,155 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testHeader = Header{
+	AccountID:      7,
+	Owner:          "alice",
+	Currency:       "USD",
+	From:           time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+	To:             time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+	OpeningBalance: 10000,
+	ClosingBalance: 8000,
+	GeneratedAt:    time.Date(2026, 6, 2, 8, 30, 0, 0, time.UTC),
+}
+
+var testLines = []Line{
+	{EntryID: 11, BookedAt: time.Date(2026, 5, 3, 9, 0, 0, 0, time.UTC), Amount: -5025, TransferID: 4, CounterpartyAccountID: 9},
+	{EntryID: 12, BookedAt: time.Date(2026, 5, 20, 17, 15, 0, 0, time.UTC), Amount: 3025, Description: "split, dinner & drinks"},
+}
+
+func encodeStatement(t *testing.T, format string, lines []Line, close bool) []byte {
+	var buf bytes.Buffer
+	var encoder, err = NewEncoder(format, &buf)
+	require.NoError(t, err)
+
+	require.NoError(t, encoder.WriteHeader(testHeader))
+	for _, line := range lines {
+		require.NoError(t, encoder.WriteLine(line))
+	}
+	if close {
+		require.NoError(t, encoder.Close())
+	}
+	return buf.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	var data = encodeStatement(t, FormatCSV, testLines, true)
+
+	var records, err = csv.NewReader(bytes.NewReader(data)).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		csvColumns,
+		{"2026-05-03T09:00:00Z", "11", "4", "9", "", "-50.25", "USD", "49.75"},
+		{"2026-05-20T17:15:00Z", "12", "", "", "split, dinner & drinks", "30.25", "USD", "80.00"},
+	}, records)
+}
+
+func TestOFX(t *testing.T) {
+	var data = string(encodeStatement(t, FormatOFX, testLines, true))
+
+	require.True(t, strings.HasPrefix(data, ofxHeader))
+	requireWellFormed(t, data)
+	require.Contains(t, data, "<CURDEF>USD</CURDEF>")
+	require.Contains(t, data, "<ACCTID>7</ACCTID>")
+	require.Contains(t, data, "<DTSTART>20260501000000.000[+0:UTC]</DTSTART>")
+	require.Contains(t, data, "<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20260503090000.000[+0:UTC]</DTPOSTED>"+
+		"<TRNAMT>-50.25</TRNAMT><FITID>11</FITID><NAME>Account 9</NAME></STMTTRN>")
+	require.Contains(t, data, "<TRNTYPE>CREDIT</TRNTYPE>")
+	require.Contains(t, data, "<MEMO>split, dinner &amp; drinks</MEMO>")
+	require.Contains(t, data, "</BANKTRANLIST><LEDGERBAL><BALAMT>80.00</BALAMT>")
+	require.True(t, strings.HasSuffix(data, "</OFX>"))
+}
+
+func TestCAMT053(t *testing.T) {
+	var data = encodeStatement(t, FormatCAMT053, testLines, true)
+
+	var document struct {
+		XMLName xml.Name `xml:"urn:iso:std:iso:20022:tech:xsd:camt.053.001.02 Document"`
+		Stmt    struct {
+			Account  string `xml:"Acct>Id>Othr>Id"`
+			Currency string `xml:"Acct>Ccy"`
+			Balances []struct {
+				Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+				Amount    camtAmount `xml:"Amt"`
+				CdtDbtInd string     `xml:"CdtDbtInd"`
+			} `xml:"Bal"`
+			Entries []camtEntry `xml:"Ntry"`
+		} `xml:"BkToCstmrStmt>Stmt"`
+	}
+	require.NoError(t, xml.Unmarshal(data, &document))
+
+	var stmt = document.Stmt
+	require.Equal(t, "7", stmt.Account)
+	require.Equal(t, "USD", stmt.Currency)
+	require.Len(t, stmt.Balances, 2)
+	require.Equal(t, "OPBD", stmt.Balances[0].Code)
+	require.Equal(t, camtAmount{Currency: "USD", Value: "100.00"}, stmt.Balances[0].Amount)
+	require.Equal(t, "CLBD", stmt.Balances[1].Code)
+	require.Equal(t, camtAmount{Currency: "USD", Value: "80.00"}, stmt.Balances[1].Amount)
+
+	require.Len(t, stmt.Entries, 2)
+	require.Equal(t, camtAmount{Currency: "USD", Value: "50.25"}, stmt.Entries[0].Amount)
+	require.Equal(t, "DBIT", stmt.Entries[0].CdtDbtInd)
+	require.Equal(t, "4", stmt.Entries[0].TransactionID)
+	require.Equal(t, "9", stmt.Entries[0].CreditorAccount)
+	require.Equal(t, "2026-05-03T09:00:00Z", stmt.Entries[0].BookingDate.DtTm)
+	require.Equal(t, "CRDT", stmt.Entries[1].CdtDbtInd)
+	require.Equal(t, "split, dinner & drinks", stmt.Entries[1].Remittance)
+	require.Empty(t, stmt.Entries[1].DebtorAccount)
+}
+
+func TestNegativeBalance(t *testing.T) {
+	var header = testHeader
+	header.ClosingBalance = -2000
+
+	var buf bytes.Buffer
+	var encoder = newCAMT053Encoder(&buf)
+	require.NoError(t, encoder.WriteHeader(header))
+	require.NoError(t, encoder.Close())
+	require.Contains(t, buf.String(), `<Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp><Amt Ccy="USD">20.00</Amt><CdtDbtInd>DBIT</CdtDbtInd>`)
+}
+
+func TestUnclosedXMLIsIncomplete(t *testing.T) {
+	for _, format := range []string{FormatOFX, FormatCAMT053} {
+		var data = string(encodeStatement(t, format, testLines[:1], false))
+		var decoder = xml.NewDecoder(strings.NewReader(strings.TrimPrefix(data, ofxHeader)))
+		var err error
+		for err == nil {
+			_, err = decoder.Token()
+		}
+		require.False(t, errors.Is(err, io.EOF), format)
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	var _, err = NewEncoder("pdf", io.Discard)
+	require.Error(t, err)
+}
+
+func TestFileName(t *testing.T) {
+	require.Equal(t, "statement-7-2026-05-01-2026-06-01.csv", FileName(FormatCSV, testHeader))
+	require.Equal(t, "statement-7-2026-05-01-2026-06-01.xml", FileName(FormatCAMT053, testHeader))
+}
+
+func requireWellFormed(t *testing.T, data string) {
+	var decoder = xml.NewDecoder(strings.NewReader(data))
+	for {
+		var _, err = decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		require.NoError(t, err)
+	}
+}
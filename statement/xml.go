@@ -0,0 +1,73 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// xmlWriter streams an XML document element by element.
+// The first error is kept and returned by close, so that callers can write a run of elements before checking it.
+type xmlWriter struct {
+	encoder *xml.Encoder
+	open    []xml.Name
+	err     error
+}
+
+func newXMLWriter(w io.Writer) *xmlWriter {
+	return &xmlWriter{encoder: xml.NewEncoder(w)}
+}
+
+// declaration writes the XML declaration, which must come first in the document.
+func (w *xmlWriter) declaration() {
+	if w.err != nil {
+		return
+	}
+	w.err = w.encoder.EncodeToken(xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0" encoding="UTF-8"`)})
+}
+
+// start opens an element that is closed by end.
+func (w *xmlWriter) start(name string, attrs ...xml.Attr) {
+	if w.err != nil {
+		return
+	}
+	var element = xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs}
+	w.open = append(w.open, element.Name)
+	w.err = w.encoder.EncodeToken(element)
+}
+
+// end closes the innermost open element.
+func (w *xmlWriter) end() {
+	if w.err != nil {
+		return
+	}
+	var name = w.open[len(w.open)-1]
+	w.open = w.open[:len(w.open)-1]
+	w.err = w.encoder.EncodeToken(xml.EndElement{Name: name})
+}
+
+// element writes a complete element named name holding v.
+func (w *xmlWriter) element(name string, v any) {
+	if w.err != nil {
+		return
+	}
+	w.err = w.encoder.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: name}})
+}
+
+// flush writes out the buffered elements.
+func (w *xmlWriter) flush() error {
+	if w.err == nil {
+		w.err = w.encoder.Flush()
+	}
+	return w.err
+}
+
+// close closes every open element and flushes the document.
+func (w *xmlWriter) close() error {
+	for len(w.open) > 0 && w.err == nil {
+		w.end()
+	}
+	if w.err == nil {
+		w.err = w.encoder.Close()
+	}
+	return w.err
+}
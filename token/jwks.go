@@ -0,0 +1,102 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrVerifyOnly is returned when a verifier is asked to create a token.
+var ErrVerifyOnly = errors.New("token verifier cannot create tokens")
+
+// KeyPublisher is implemented by makers that sign tokens with an asymmetric key,
+// so the public part can be published for other services.
+type KeyPublisher interface {
+	JWKS() JWKS
+}
+
+// JWK is an Ed25519 public key in JSON Web Key format (RFC 8037).
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+// JWKS is a JSON Web Key Set as served on /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewEd25519JWK describes an Ed25519 public key as a JWK, with its thumbprint as kid.
+func NewEd25519JWK(publicKey ed25519.PublicKey) JWK {
+	return JWK{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		X:   base64.RawURLEncoding.EncodeToString(publicKey),
+		Kid: KeyID(publicKey),
+		Alg: "EdDSA",
+		Use: "sig",
+	}
+}
+
+// Ed25519PublicKey decodes the public key of the JWK.
+func (jwk JWK) Ed25519PublicKey() (ed25519.PublicKey, error) {
+	if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" {
+		return nil, fmt.Errorf("unsupported jwk type %s/%s", jwk.Kty, jwk.Crv)
+	}
+	var publicKey, err = base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwk: %w", err)
+	}
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid jwk: public key must be %d bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(publicKey), nil
+}
+
+// KeyID returns the RFC 7638 thumbprint of an Ed25519 public key, used as its kid.
+func KeyID(publicKey ed25519.PublicKey) string {
+	var x = base64.RawURLEncoding.EncodeToString(publicKey)
+	var sum = sha256.Sum256([]byte(`{"crv":"Ed25519","kty":"OKP","x":"` + x + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ParseEd25519Seed decodes an Ed25519 private key from its hex encoded seed.
+func ParseEd25519Seed(seed string) (ed25519.PrivateKey, error) {
+	var bytes, err = hex.DecodeString(seed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Ed25519 seed: %w", err)
+	}
+	if len(bytes) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid Ed25519 seed: must be %d bytes", ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(bytes), nil
+}
+
+// ed25519KeySet is the key set of a maker created from one private key, named by its thumbprint.
+func ed25519KeySet(privateKey ed25519.PrivateKey) (KeySet, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return KeySet{}, fmt.Errorf("invalid key size: must be %d bytes", ed25519.PrivateKeySize)
+	}
+	var kid = KeyID(privateKey.Public().(ed25519.PublicKey))
+	return singleKeySet(kid, hex.EncodeToString(privateKey.Seed())), nil
+}
+
+// keyringJWKS publishes the public keys of a keyring, including keys that no longer sign
+// but still verify tokens.
+func keyringJWKS(keyring *Keyring[ed25519.PrivateKey]) JWKS {
+	var ids, keys = keyring.All()
+	var jwks = JWKS{Keys: make([]JWK, 0, len(keys))}
+	for i, privateKey := range keys {
+		var jwk = NewEd25519JWK(privateKey.Public().(ed25519.PublicKey))
+		jwk.Kid = ids[i]
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
@@ -12,32 +12,59 @@ const minSecretKeySize = 32
 
 // JWTMaker is a JSON Web Token maker
 type JWTMaker struct {
-	secretKey string
+	keyring *Keyring[[]byte]
 }
 
 // NewJWTMaker creates a new JWTMaker
 func NewJWTMaker(secretKey string) (*JWTMaker, error) {
-	if len(secretKey) < minSecretKeySize {
+	return NewJWTMakerWithKeys(singleKeySet(defaultKeyID, secretKey))
+}
+
+// NewJWTMakerWithKeys creates a JWTMaker signing with the active key of the set.
+func NewJWTMakerWithKeys(set KeySet) (*JWTMaker, error) {
+	var keyring, err = NewKeyring(set, parseJWTSecret)
+	if err != nil {
+		return nil, err
+	}
+	return &JWTMaker{keyring: keyring}, nil
+}
+
+func parseJWTSecret(secret string) ([]byte, error) {
+	if len(secret) < minSecretKeySize {
 		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minSecretKeySize)
 	}
-	return &JWTMaker{secretKey}, nil
+	return []byte(secret), nil
 }
 
-// CreateToken creates a new token for a specific username and duration.
-func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	var payload, err = NewPayload(username, duration)
+// CreateToken creates a new token for a specific username, role and duration.
+func (maker *JWTMaker) CreateToken(username string, role string, duration time.Duration, opts ...Option) (string, *Payload, error) {
+	var payload, err = NewPayload(username, role, duration, opts...)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
+	var kid, secretKey = maker.keyring.Active()
 	var jwtToken = jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	jwtToken.Header["kid"] = kid
 
-	return jwtToken.SignedString([]byte(maker.secretKey))
+	token, err := jwtToken.SignedString(secretKey)
+	return token, payload, err
 }
 
 // VerifyToken checks if the token is valid or not.
-func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
-	var jwtToken, err = jwt.ParseWithClaims(token, &Payload{}, maker.keyFunc)
+func (maker *JWTMaker) VerifyToken(token string, opts ...VerifyOption) (*Payload, error) {
+	var payload, err = verifyJWT(token, maker.keyFunc)
+	return checkPayload(payload, err, opts)
+}
+
+// SetKeys replaces the keys of the maker.
+func (maker *JWTMaker) SetKeys(set KeySet) error {
+	return maker.keyring.Set(set)
+}
+
+// verifyJWT parses a JWT with the key returned by keyFunc and returns its payload.
+func verifyJWT(token string, keyFunc jwt.Keyfunc) (*Payload, error) {
+	var jwtToken, err = jwt.ParseWithClaims(token, &Payload{}, keyFunc)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, jwt.ErrTokenExpired
@@ -45,6 +72,12 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		if errors.Is(err, jwt.ErrTokenSignatureInvalid) {
 			return nil, jwt.ErrTokenSignatureInvalid
 		}
+		if errors.Is(err, ErrUnknownKeyID) {
+			return nil, ErrUnknownKeyID
+		}
+		if errors.Is(err, ErrKeyRetired) {
+			return nil, ErrKeyRetired
+		}
 		return nil, err
 	}
 
@@ -52,6 +85,10 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 	if !ok {
 		return nil, jwt.ErrTokenInvalidClaims
 	}
+	// tokens issued before token types existed are access tokens
+	if payload.Type == "" {
+		payload.Type = TokenTypeAccess
+	}
 
 	return payload, nil
 }
@@ -71,5 +108,25 @@ func (maker *JWTMaker) keyFunc(token *jwt.Token) (any, error) {
 		return nil, jwt.ErrTokenExpired
 	}
 
-	return []byte(maker.secretKey), nil
+	return keyringKeyFunc(maker.keyring, token, func(secretKey []byte) any { return secretKey })
+}
+
+// keyringKeyFunc returns the key named by the kid header. Tokens signed before key ids
+// existed are checked against every key that is not retired.
+func keyringKeyFunc[K any](keyring *Keyring[K], token *jwt.Token, verificationKey func(K) any) (any, error) {
+	var kid, ok = token.Header["kid"].(string)
+	if !ok {
+		var _, keys = keyring.All()
+		var set = jwt.VerificationKeySet{Keys: make([]jwt.VerificationKey, 0, len(keys))}
+		for _, key := range keys {
+			set.Keys = append(set.Keys, verificationKey(key))
+		}
+		return set, nil
+	}
+
+	var key, err = keyring.Lookup(kid)
+	if err != nil {
+		return nil, err
+	}
+	return verificationKey(key), nil
 }
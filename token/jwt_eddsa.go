@@ -0,0 +1,70 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTEdDSAMaker is a JSON Web Token maker signing with an Ed25519 key,
+// so other services only need the public key to verify its tokens.
+type JWTEdDSAMaker struct {
+	keyring *Keyring[ed25519.PrivateKey]
+}
+
+// NewJWTEdDSAMaker creates a new JWTEdDSAMaker
+func NewJWTEdDSAMaker(privateKey ed25519.PrivateKey) (*JWTEdDSAMaker, error) {
+	var set, err = ed25519KeySet(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewJWTEdDSAMakerWithKeys(set)
+}
+
+// NewJWTEdDSAMakerWithKeys creates a JWTEdDSAMaker signing with the active key of the set.
+func NewJWTEdDSAMakerWithKeys(set KeySet) (*JWTEdDSAMaker, error) {
+	var keyring, err = NewKeyring(set, ParseEd25519Seed)
+	if err != nil {
+		return nil, err
+	}
+	return &JWTEdDSAMaker{keyring: keyring}, nil
+}
+
+// CreateToken creates a new token for a specific username, role and duration.
+func (maker *JWTEdDSAMaker) CreateToken(username string, role string, duration time.Duration, opts ...Option) (string, *Payload, error) {
+	var payload, err = NewPayload(username, role, duration, opts...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var kid, privateKey = maker.keyring.Active()
+	var jwtToken = jwt.NewWithClaims(jwt.SigningMethodEdDSA, payload)
+	jwtToken.Header["kid"] = kid
+
+	token, err := jwtToken.SignedString(privateKey)
+	return token, payload, err
+}
+
+// VerifyToken checks if the token is valid or not.
+func (maker *JWTEdDSAMaker) VerifyToken(token string, opts ...VerifyOption) (*Payload, error) {
+	var payload, err = verifyJWT(token, func(jwtToken *jwt.Token) (any, error) {
+		if _, ok := jwtToken.Method.(*jwt.SigningMethodEd25519); !ok {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return keyringKeyFunc(maker.keyring, jwtToken, func(privateKey ed25519.PrivateKey) any {
+			return privateKey.Public()
+		})
+	})
+	return checkPayload(payload, err, opts)
+}
+
+// SetKeys replaces the keys of the maker.
+func (maker *JWTEdDSAMaker) SetKeys(set KeySet) error {
+	return maker.keyring.Set(set)
+}
+
+// JWKS returns the public keys that verify the tokens of this maker.
+func (maker *JWTEdDSAMaker) JWKS() JWKS {
+	return keyringJWKS(maker.keyring)
+}
@@ -0,0 +1,69 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEd25519Key(t *testing.T) ed25519.PrivateKey {
+	var _, privateKey, err = ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	return privateKey
+}
+
+func TestJWTEdDSAMaker(t *testing.T) {
+	var maker, err = NewJWTEdDSAMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+
+	var username = util.RandomOwner()
+	var token, createdPayload, err1 = maker.CreateToken(username, util.DepositorRole, time.Minute)
+	require.NoError(t, err1)
+
+	var payload, err2 = maker.VerifyToken(token)
+	require.NoError(t, err2)
+	require.Equal(t, createdPayload.ID, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, util.DepositorRole, payload.Role)
+	require.Equal(t, TokenTypeAccess, payload.Type)
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Payload{})
+	require.NoError(t, err)
+	require.Equal(t, "EdDSA", parsed.Header["alg"])
+	require.Equal(t, maker.JWKS().Keys[0].Kid, parsed.Header["kid"])
+}
+
+func TestExpiredJWTEdDSAToken(t *testing.T) {
+	var maker, err = NewJWTEdDSAMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+
+	var token, _, err1 = maker.CreateToken(util.RandomOwner(), util.DepositorRole, -time.Minute)
+	require.NoError(t, err1)
+
+	var payload, err2 = maker.VerifyToken(token)
+	require.ErrorIs(t, err2, jwt.ErrTokenExpired)
+	require.Nil(t, payload)
+}
+
+func TestJWTEdDSAMakerRejectsOtherKeys(t *testing.T) {
+	var maker, err = NewJWTEdDSAMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+	otherMaker, err := NewJWTEdDSAMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+	hmacMaker, err := NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	var token, _, err1 = otherMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err1)
+	_, err = maker.VerifyToken(token)
+	require.ErrorIs(t, err, ErrUnknownKeyID)
+
+	token, _, err = hmacMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(token)
+	require.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
@@ -14,13 +14,15 @@ func TestJWTMaker(t *testing.T) {
 	require.NoError(t, err)
 
 	var username = util.RandomOwner()
+	var role = util.DepositorRole
 	var duration = time.Minute
 	var issuedAt = time.Now()
 	var expiredAt = issuedAt.Add(duration)
 
-	var token, err1 = maker.CreateToken(username, duration)
+	var token, createdPayload, err1 = maker.CreateToken(username, role, duration)
 	require.NoError(t, err1)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, createdPayload)
 
 	var payload, err2 = maker.VerifyToken(token)
 	require.NoError(t, err2)
@@ -28,6 +30,8 @@ func TestJWTMaker(t *testing.T) {
 
 	require.NotZero(t, payload.ID)
 	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.Equal(t, createdPayload.ID, payload.ID)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
 }
@@ -36,7 +40,7 @@ func TestExpiredJWTToken(t *testing.T) {
 	var maker, err = NewJWTMaker(util.RandomString(32))
 	require.NoError(t, err)
 
-	var token, err1 = maker.CreateToken(util.RandomOwner(), -time.Minute)
+	var token, _, err1 = maker.CreateToken(util.RandomOwner(), util.DepositorRole, -time.Minute)
 	require.NoError(t, err1)
 	require.NotEmpty(t, token)
 
@@ -47,7 +51,7 @@ func TestExpiredJWTToken(t *testing.T) {
 }
 
 func TestInvalidJWTTokenAlg(t *testing.T) {
-	var payload, err = NewPayload(util.RandomOwner(), time.Minute)
+	var payload, err = NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
 	require.NoError(t, err)
 
 	var jwtToken = jwt.NewWithClaims(jwt.SigningMethodNone, payload)
@@ -62,3 +66,51 @@ func TestInvalidJWTTokenAlg(t *testing.T) {
 	require.EqualError(t, err, jwt.ErrTokenSignatureInvalid.Error())
 	require.Nil(t, payload)
 }
+
+func TestJWTTokenIssuedBefore(t *testing.T) {
+	var maker, err = NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	var token, createdPayload, err1 = maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err1)
+
+	var payload, err2 = maker.VerifyToken(token)
+	require.NoError(t, err2)
+
+	// iat loses its sub-second part, which must not revoke the token
+	require.False(t, payload.IssuedBefore(createdPayload.IssuedAt))
+	require.False(t, payload.IssuedBefore(createdPayload.IssuedAt.Add(-time.Minute)))
+	require.True(t, payload.IssuedBefore(createdPayload.IssuedAt.Add(time.Second)))
+}
+
+func TestJWTTokenType(t *testing.T) {
+	var secretKey = util.RandomString(32)
+	var maker, err = NewJWTMaker(secretKey)
+	require.NoError(t, err)
+
+	var accessToken, _, err1 = maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err1)
+	payload, err := maker.VerifyToken(accessToken)
+	require.NoError(t, err)
+	require.Equal(t, TokenTypeAccess, payload.Type)
+
+	mfaToken, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute, WithType(TokenTypeMFAPending))
+	require.NoError(t, err)
+	payload, err = maker.VerifyToken(mfaToken)
+	require.NoError(t, err)
+	require.Equal(t, TokenTypeMFAPending, payload.Type)
+
+	// tokens issued before token types existed have no type claim
+	var legacyToken, err2 = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":         payload.ID.String(),
+		"username":   payload.Username,
+		"role":       payload.Role,
+		"issued_at":  payload.IssuedAt,
+		"expired_at": payload.ExpiredAt,
+		"exp":        payload.ExpiredAt.Unix(),
+	}).SignedString([]byte(secretKey))
+	require.NoError(t, err2)
+	payload, err = maker.VerifyToken(legacyToken)
+	require.NoError(t, err)
+	require.Equal(t, TokenTypeAccess, payload.Type)
+}
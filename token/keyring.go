@@ -0,0 +1,157 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ErrKeyRetired is returned when a token is signed by a key that has been retired.
+var ErrKeyRetired = errors.New("token is signed by a retired key")
+
+// defaultKeyID names the only key of makers created from a single secret.
+const defaultKeyID = "default"
+
+// Key is a token signing key, identified by the kid of the tokens it signs.
+// Secret is the symmetric key for JWTMaker and PasetoMaker, and the hex encoded
+// Ed25519 seed for JWTEdDSAMaker and PasetoPublicMaker.
+type Key struct {
+	ID      string `json:"id"`
+	Secret  string `json:"secret"`
+	Retired bool   `json:"retired"`
+}
+
+// KeySet lists the keys of a maker and names the one signing new tokens.
+// Keep the previous key in the set until the tokens it signed have expired,
+// then retire it.
+type KeySet struct {
+	Active string `json:"active"`
+	Keys   []Key  `json:"keys"`
+}
+
+// LoadKeySet reads a key set from a JSON file.
+func LoadKeySet(path string) (KeySet, error) {
+	var set KeySet
+	var data, err = os.ReadFile(path)
+	if err != nil {
+		return set, fmt.Errorf("cannot read key set: %w", err)
+	}
+	if err = json.Unmarshal(data, &set); err != nil {
+		return set, fmt.Errorf("cannot parse key set: %w", err)
+	}
+	return set, nil
+}
+
+// KeyRotator is implemented by makers whose keys can be replaced while running.
+type KeyRotator interface {
+	SetKeys(set KeySet) error
+}
+
+// Keyring holds the parsed keys of a maker. It is safe for concurrent use,
+// and Set swaps all keys at once, so a reload never leaves a half updated keyring.
+type Keyring[K any] struct {
+	parse func(secret string) (K, error)
+
+	mu       sync.RWMutex
+	activeID string
+	keys     map[string]K
+	retired  map[string]bool
+	// order keeps the active key first, for tokens without a kid
+	order []string
+}
+
+// NewKeyring creates a keyring from a key set, parsing each secret with parse.
+func NewKeyring[K any](set KeySet, parse func(secret string) (K, error)) (*Keyring[K], error) {
+	var keyring = &Keyring[K]{parse: parse}
+	if err := keyring.Set(set); err != nil {
+		return nil, err
+	}
+	return keyring, nil
+}
+
+// Set replaces the keys of the keyring. The keyring is left unchanged when the set is invalid.
+func (keyring *Keyring[K]) Set(set KeySet) error {
+	var keys = make(map[string]K, len(set.Keys))
+	var retired = make(map[string]bool)
+	var order = []string{set.Active}
+	var activeFound bool
+
+	for _, key := range set.Keys {
+		if key.ID == "" {
+			return errors.New("key id is required")
+		}
+		if _, ok := keys[key.ID]; ok || retired[key.ID] {
+			return fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		if key.Retired {
+			if key.ID == set.Active {
+				return fmt.Errorf("active key %q is retired", key.ID)
+			}
+			retired[key.ID] = true
+			continue
+		}
+
+		var parsed, err = keyring.parse(key.Secret)
+		if err != nil {
+			return fmt.Errorf("invalid key %q: %w", key.ID, err)
+		}
+		keys[key.ID] = parsed
+		if key.ID == set.Active {
+			activeFound = true
+		} else {
+			order = append(order, key.ID)
+		}
+	}
+	if !activeFound {
+		return fmt.Errorf("active key %q is not in the key set", set.Active)
+	}
+
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+	keyring.activeID = set.Active
+	keyring.keys = keys
+	keyring.retired = retired
+	keyring.order = order
+	return nil
+}
+
+// Active returns the key signing new tokens and its id.
+func (keyring *Keyring[K]) Active() (string, K) {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+	return keyring.activeID, keyring.keys[keyring.activeID]
+}
+
+// Lookup returns the key named by kid.
+func (keyring *Keyring[K]) Lookup(kid string) (K, error) {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	var key, ok = keyring.keys[kid]
+	if !ok {
+		if keyring.retired[kid] {
+			return key, ErrKeyRetired
+		}
+		return key, ErrUnknownKeyID
+	}
+	return key, nil
+}
+
+// All returns the ids and keys that still verify tokens, the active one first.
+func (keyring *Keyring[K]) All() ([]string, []K) {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	var keys = make([]K, 0, len(keyring.order))
+	for _, id := range keyring.order {
+		keys = append(keys, keyring.keys[id])
+	}
+	return append([]string(nil), keyring.order...), keys
+}
+
+// singleKeySet is the key set of a maker created from one secret.
+func singleKeySet(id string, secret string) KeySet {
+	return KeySet{Active: id, Keys: []Key{{ID: id, Secret: secret}}}
+}
@@ -0,0 +1,148 @@
+package token
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+type rotatingMaker interface {
+	Maker
+	KeyRotator
+}
+
+var rotatingMakers = map[string]struct {
+	newMaker  func(set KeySet) (rotatingMaker, error)
+	newSecret func(t *testing.T) string
+}{
+	"JWT": {
+		newMaker:  func(set KeySet) (rotatingMaker, error) { return NewJWTMakerWithKeys(set) },
+		newSecret: func(t *testing.T) string { return util.RandomString(32) },
+	},
+	"Paseto": {
+		newMaker:  func(set KeySet) (rotatingMaker, error) { return NewPasetoMakerWithKeys(set) },
+		newSecret: func(t *testing.T) string { return util.RandomString(32) },
+	},
+	"JWTEdDSA": {
+		newMaker:  func(set KeySet) (rotatingMaker, error) { return NewJWTEdDSAMakerWithKeys(set) },
+		newSecret: func(t *testing.T) string { return hex.EncodeToString(newTestEd25519Key(t).Seed()) },
+	},
+	"PasetoPublic": {
+		newMaker:  func(set KeySet) (rotatingMaker, error) { return NewPasetoPublicMakerWithKeys(set) },
+		newSecret: func(t *testing.T) string { return hex.EncodeToString(newTestEd25519Key(t).Seed()) },
+	},
+}
+
+func TestKeyRotation(t *testing.T) {
+	for name, tc := range rotatingMakers {
+		t.Run(name, func(t *testing.T) {
+			var oldKey = Key{ID: "2026-09", Secret: tc.newSecret(t)}
+			var newKey = Key{ID: "2026-10", Secret: tc.newSecret(t)}
+
+			var maker, err = tc.newMaker(KeySet{Active: oldKey.ID, Keys: []Key{oldKey}})
+			require.NoError(t, err)
+			oldToken, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+			require.NoError(t, err)
+
+			// the new key signs, the old one still verifies
+			require.NoError(t, maker.SetKeys(KeySet{Active: newKey.ID, Keys: []Key{oldKey, newKey}}))
+			newToken, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+			require.NoError(t, err)
+			_, err = maker.VerifyToken(oldToken)
+			require.NoError(t, err)
+			_, err = maker.VerifyToken(newToken)
+			require.NoError(t, err)
+
+			// a maker that only knows the old key cannot verify tokens of the new one
+			oldMaker, err := tc.newMaker(KeySet{Active: oldKey.ID, Keys: []Key{oldKey}})
+			require.NoError(t, err)
+			_, err = oldMaker.VerifyToken(newToken)
+			require.ErrorIs(t, err, ErrUnknownKeyID)
+
+			oldKey.Retired = true
+			require.NoError(t, maker.SetKeys(KeySet{Active: newKey.ID, Keys: []Key{oldKey, newKey}}))
+			_, err = maker.VerifyToken(oldToken)
+			require.ErrorIs(t, err, ErrKeyRetired)
+			_, err = maker.VerifyToken(newToken)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestKeyringRejectsInvalidKeySets(t *testing.T) {
+	var secret = util.RandomString(32)
+	var maker, err = NewJWTMakerWithKeys(KeySet{Active: "a", Keys: []Key{{ID: "a", Secret: secret}}})
+	require.NoError(t, err)
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	for name, set := range map[string]KeySet{
+		"MissingActive": {Active: "b", Keys: []Key{{ID: "a", Secret: secret}}},
+		"RetiredActive": {Active: "a", Keys: []Key{{ID: "a", Secret: secret, Retired: true}}},
+		"DuplicateID":   {Active: "a", Keys: []Key{{ID: "a", Secret: secret}, {ID: "a", Secret: secret}}},
+		"EmptyID":       {Active: "a", Keys: []Key{{ID: "a", Secret: secret}, {Secret: secret}}},
+		"ShortSecret":   {Active: "a", Keys: []Key{{ID: "a", Secret: "short"}}},
+	} {
+		require.Error(t, maker.SetKeys(set), name)
+	}
+
+	// a rejected reload keeps the previous keys
+	_, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+}
+
+func TestTokensWithoutKeyID(t *testing.T) {
+	var oldSecret = util.RandomString(32)
+	var set = KeySet{Active: "new", Keys: []Key{
+		{ID: "old", Secret: oldSecret},
+		{ID: "new", Secret: util.RandomString(32)},
+	}}
+	var payload, err = NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	// tokens signed before key ids existed are checked against every key
+	jwtMaker, err := NewJWTMakerWithKeys(set)
+	require.NoError(t, err)
+	jwtToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, payload).SignedString([]byte(oldSecret))
+	require.NoError(t, err)
+	verified, err := jwtMaker.VerifyToken(jwtToken)
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, verified.ID)
+
+	pasetoMaker, err := NewPasetoMakerWithKeys(set)
+	require.NoError(t, err)
+	symmetricKey, err := paseto.V4SymmetricKeyFromBytes([]byte(oldSecret))
+	require.NoError(t, err)
+	verified, err = pasetoMaker.VerifyToken(payload.ToPasetoToken().V4Encrypt(symmetricKey, nil))
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, verified.ID)
+
+	// unless the key has been retired
+	set.Keys[0].Retired = true
+	require.NoError(t, jwtMaker.SetKeys(set))
+	_, err = jwtMaker.VerifyToken(jwtToken)
+	require.Error(t, err)
+}
+
+func TestLoadKeySet(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "keys.json")
+	var data = `{"active":"b","keys":[{"id":"a","secret":"s1","retired":true},{"id":"b","secret":"s2"}]}`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	var set, err = LoadKeySet(path)
+	require.NoError(t, err)
+	require.Equal(t, KeySet{Active: "b", Keys: []Key{
+		{ID: "a", Secret: "s1", Retired: true},
+		{ID: "b", Secret: "s2"},
+	}}, set)
+
+	_, err = LoadKeySet(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
@@ -1,38 +1,118 @@
 package token
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"aidanwoods.dev/go-paseto"
 )
 
+// pasetoFooter is the unencrypted footer of our PASETO tokens.
+type pasetoFooter struct {
+	Kid string `json:"kid"`
+}
+
 // PasetoMaker is a PASETO token maker
 type PasetoMaker struct {
-	secretKey paseto.V4SymmetricKey
+	keyring *Keyring[paseto.V4SymmetricKey]
 }
 
 // NewPasetoMaker creates a new PasetoMaker
 func NewPasetoMaker(symmetricKey paseto.V4SymmetricKey) (*PasetoMaker, error) {
-	return &PasetoMaker{secretKey: symmetricKey}, nil
+	return NewPasetoMakerWithKeys(singleKeySet(defaultKeyID, string(symmetricKey.ExportBytes())))
 }
 
-// CreateToken creates a new token for a specific username and duration.
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	var payload, err = NewPayload(username, duration)
+// NewPasetoMakerWithKeys creates a PasetoMaker encrypting with the active key of the set.
+func NewPasetoMakerWithKeys(set KeySet) (*PasetoMaker, error) {
+	var keyring, err = NewKeyring(set, func(secret string) (paseto.V4SymmetricKey, error) {
+		return paseto.V4SymmetricKeyFromBytes([]byte(secret))
+	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return &PasetoMaker{keyring: keyring}, nil
+}
 
-	return payload.ToPasetoToken().V4Encrypt(maker.secretKey, nil), nil
+// CreateToken creates a new token for a specific username, role and duration.
+func (maker *PasetoMaker) CreateToken(username string, role string, duration time.Duration, opts ...Option) (string, *Payload, error) {
+	var payload, err = NewPayload(username, role, duration, opts...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var kid, symmetricKey = maker.keyring.Active()
+	footer, err := json.Marshal(pasetoFooter{Kid: kid})
+	if err != nil {
+		return "", nil, err
+	}
+
+	var token = payload.ToPasetoToken()
+	token.SetFooter(footer)
+	return token.V4Encrypt(symmetricKey, nil), payload, nil
 }
 
 // VerifyToken checks if the token is valid or not.
-func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
-	var parser = paseto.NewParser()
-	var parseToken, err = parser.ParseV4Local(maker.secretKey, token, nil)
+func (maker *PasetoMaker) VerifyToken(token string, opts ...VerifyOption) (*Payload, error) {
+	var payload, err = verifyPaseto(maker.keyring, paseto.V4Local, token, func(symmetricKey paseto.V4SymmetricKey) (*paseto.Token, error) {
+		return paseto.NewParser().ParseV4Local(symmetricKey, token, nil)
+	})
+	return checkPayload(payload, err, opts)
+}
+
+// SetKeys replaces the keys of the maker.
+func (maker *PasetoMaker) SetKeys(set KeySet) error {
+	return maker.keyring.Set(set)
+}
+
+// verifyPaseto parses a token with the key named in its footer. Tokens issued before
+// key ids existed have no footer and are checked against every key that is not retired.
+func verifyPaseto[K any](keyring *Keyring[K], protocol paseto.Protocol, token string, parse func(key K) (*paseto.Token, error)) (*Payload, error) {
+	var kid, err = pasetoKeyID(protocol, token)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewPayloadFromPasetoToken(parseToken)
+	if kid != "" {
+		var key, err = keyring.Lookup(kid)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := parse(key)
+		if err != nil {
+			return nil, err
+		}
+		return NewPayloadFromPasetoToken(parsed)
+	}
+
+	var _, keys = keyring.All()
+	var firstErr error
+	for _, key := range keys {
+		var parsed, err = parse(key)
+		if err == nil {
+			return NewPayloadFromPasetoToken(parsed)
+		}
+		// the active key comes first, report why it failed
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// pasetoKeyID reads the kid from the footer of a token without verifying it.
+func pasetoKeyID(protocol paseto.Protocol, token string) (string, error) {
+	var rawFooter, err = paseto.NewParser().UnsafeParseFooter(protocol, token)
+	if err != nil {
+		return "", err
+	}
+	if len(rawFooter) == 0 {
+		return "", nil
+	}
+
+	var footer pasetoFooter
+	if err := json.Unmarshal(rawFooter, &footer); err != nil {
+		return "", fmt.Errorf("invalid token footer: %w", err)
+	}
+	return footer.Kid, nil
 }
@@ -0,0 +1,81 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+)
+
+// PasetoPublicMaker is a PASETO v4.public token maker signing with an Ed25519 key,
+// so other services only need the public key to verify its tokens.
+type PasetoPublicMaker struct {
+	keyring *Keyring[ed25519.PrivateKey]
+}
+
+// NewPasetoPublicMaker creates a new PasetoPublicMaker
+func NewPasetoPublicMaker(privateKey ed25519.PrivateKey) (*PasetoPublicMaker, error) {
+	var set, err = ed25519KeySet(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewPasetoPublicMakerWithKeys(set)
+}
+
+// NewPasetoPublicMakerWithKeys creates a PasetoPublicMaker signing with the active key of the set.
+func NewPasetoPublicMakerWithKeys(set KeySet) (*PasetoPublicMaker, error) {
+	var keyring, err = NewKeyring(set, ParseEd25519Seed)
+	if err != nil {
+		return nil, err
+	}
+	return &PasetoPublicMaker{keyring: keyring}, nil
+}
+
+// CreateToken creates a new token for a specific username, role and duration.
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, duration time.Duration, opts ...Option) (string, *Payload, error) {
+	var payload, err = NewPayload(username, role, duration, opts...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var kid, privateKey = maker.keyring.Active()
+	secretKey, err := paseto.NewV4AsymmetricSecretKeyFromEd25519(privateKey)
+	if err != nil {
+		return "", nil, err
+	}
+	footer, err := json.Marshal(pasetoFooter{Kid: kid})
+	if err != nil {
+		return "", nil, err
+	}
+
+	var token = payload.ToPasetoToken()
+	token.SetFooter(footer)
+	return token.V4Sign(secretKey, nil), payload, nil
+}
+
+// VerifyToken checks if the token is valid or not.
+func (maker *PasetoPublicMaker) VerifyToken(token string, opts ...VerifyOption) (*Payload, error) {
+	var payload, err = verifyPaseto(maker.keyring, paseto.V4Public, token, func(privateKey ed25519.PrivateKey) (*paseto.Token, error) {
+		return parsePasetoPublic(privateKey.Public().(ed25519.PublicKey), token)
+	})
+	return checkPayload(payload, err, opts)
+}
+
+// SetKeys replaces the keys of the maker.
+func (maker *PasetoPublicMaker) SetKeys(set KeySet) error {
+	return maker.keyring.Set(set)
+}
+
+// JWKS returns the public keys that verify the tokens of this maker.
+func (maker *PasetoPublicMaker) JWKS() JWKS {
+	return keyringJWKS(maker.keyring)
+}
+
+func parsePasetoPublic(publicKey ed25519.PublicKey, token string) (*paseto.Token, error) {
+	var key, err = paseto.NewV4AsymmetricPublicKeyFromEd25519(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return paseto.NewParser().ParseV4Public(key, token, nil)
+}
@@ -0,0 +1,50 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasetoPublicMaker(t *testing.T) {
+	var maker, err = NewPasetoPublicMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+
+	var username = util.RandomOwner()
+	var token, createdPayload, err1 = maker.CreateToken(username, util.DepositorRole, time.Minute, WithType(TokenTypeMFAPending))
+	require.NoError(t, err1)
+	require.Contains(t, token, "v4.public.")
+
+	var payload, err2 = maker.VerifyToken(token)
+	require.NoError(t, err2)
+	require.Equal(t, createdPayload.ID, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, TokenTypeMFAPending, payload.Type)
+	require.WithinDuration(t, createdPayload.ExpiredAt, payload.ExpiredAt, time.Second)
+}
+
+func TestExpiredPasetoPublicToken(t *testing.T) {
+	var maker, err = NewPasetoPublicMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+
+	var token, _, err1 = maker.CreateToken(util.RandomOwner(), util.DepositorRole, -time.Minute)
+	require.NoError(t, err1)
+
+	var payload, err2 = maker.VerifyToken(token)
+	require.EqualError(t, err2, "this token has expired")
+	require.Nil(t, payload)
+}
+
+func TestPasetoPublicMakerRejectsOtherKeys(t *testing.T) {
+	var maker, err = NewPasetoPublicMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+	otherMaker, err := NewPasetoPublicMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+
+	var token, _, err1 = otherMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err1)
+	_, err = maker.VerifyToken(token)
+	require.Error(t, err)
+}
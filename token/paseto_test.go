@@ -14,13 +14,15 @@ func TestPasetoMaker(t *testing.T) {
 	require.NoError(t, err)
 
 	var username = util.RandomOwner()
+	var role = util.DepositorRole
 	var duration = time.Minute
 	var issuedAt = time.Now()
 	var expiredAt = issuedAt.Add(duration)
 
-	var token, err1 = maker.CreateToken(username, duration)
+	var token, createdPayload, err1 = maker.CreateToken(username, role, duration)
 	require.NoError(t, err1)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, createdPayload)
 
 	var payload, err2 = maker.VerifyToken(token)
 	require.NoError(t, err2)
@@ -28,6 +30,8 @@ func TestPasetoMaker(t *testing.T) {
 
 	require.NotZero(t, payload.ID)
 	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.Equal(t, createdPayload.ID, payload.ID)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
 }
@@ -36,7 +40,7 @@ func TestExpiredPasetoToken(t *testing.T) {
 	var maker, err = NewPasetoMaker(paseto.NewV4SymmetricKey())
 	require.NoError(t, err)
 
-	var token, err1 = maker.CreateToken(util.RandomOwner(), -time.Minute)
+	var token, _, err1 = maker.CreateToken(util.RandomOwner(), util.DepositorRole, -time.Minute)
 	require.NoError(t, err1)
 	require.NotEmpty(t, token)
 
@@ -45,3 +49,50 @@ func TestExpiredPasetoToken(t *testing.T) {
 	require.EqualError(t, err2, "this token has expired")
 	require.Nil(t, payload)
 }
+
+func TestPasetoTokenIssuedBefore(t *testing.T) {
+	var maker, err = NewPasetoMaker(paseto.NewV4SymmetricKey())
+	require.NoError(t, err)
+
+	var token, createdPayload, err1 = maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err1)
+
+	var payload, err2 = maker.VerifyToken(token)
+	require.NoError(t, err2)
+
+	// iat loses its sub-second part, which must not revoke the token
+	require.False(t, payload.IssuedBefore(createdPayload.IssuedAt))
+	require.False(t, payload.IssuedBefore(createdPayload.IssuedAt.Add(-time.Minute)))
+	require.True(t, payload.IssuedBefore(createdPayload.IssuedAt.Add(time.Second)))
+}
+
+func TestPasetoTokenType(t *testing.T) {
+	var symmetricKey = paseto.NewV4SymmetricKey()
+	var maker, err = NewPasetoMaker(symmetricKey)
+	require.NoError(t, err)
+
+	var accessToken, _, err1 = maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err1)
+	payload, err := maker.VerifyToken(accessToken)
+	require.NoError(t, err)
+	require.Equal(t, TokenTypeAccess, payload.Type)
+
+	mfaToken, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute, WithType(TokenTypeMFAPending))
+	require.NoError(t, err)
+	payload, err = maker.VerifyToken(mfaToken)
+	require.NoError(t, err)
+	require.Equal(t, TokenTypeMFAPending, payload.Type)
+
+	// tokens issued before token types existed have no type claim
+	var legacy = paseto.NewToken()
+	legacy.SetJti(payload.ID.String())
+	legacy.SetSubject(payload.Username)
+	legacy.SetIssuedAt(payload.IssuedAt)
+	legacy.SetNotBefore(payload.IssuedAt)
+	legacy.SetExpiration(payload.ExpiredAt)
+	legacy.SetIssuer(payload.Issuer)
+	legacy.SetString("role", payload.Role)
+	payload, err = maker.VerifyToken(legacy.V4Encrypt(symmetricKey, nil))
+	require.NoError(t, err)
+	require.Equal(t, TokenTypeAccess, payload.Type)
+}
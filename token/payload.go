@@ -1,6 +1,8 @@
 package token
 
 import (
+	"errors"
+	"slices"
 	"time"
 
 	"aidanwoods.dev/go-paseto"
@@ -8,10 +10,96 @@ import (
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrTokenRevoked is returned when a token was issued before the user's last password change.
+	ErrTokenRevoked = errors.New("token has been revoked by a password change")
+	// ErrInvalidAudience is returned when a token is not meant for the expected audience.
+	ErrInvalidAudience = errors.New("token has an invalid audience")
+	// ErrInvalidTokenType is returned when a token is not of the expected type.
+	ErrInvalidTokenType = errors.New("token has an invalid type")
+)
+
+// TokenType tells what a token may be used for.
+type TokenType string
+
+const (
+	// TokenTypeAccess authorizes API requests.
+	TokenTypeAccess TokenType = "access"
+	// TokenTypeMFAPending proves the password step of a login and can only be
+	// exchanged for an access token together with a second factor.
+	TokenTypeMFAPending TokenType = "mfa_pending"
+	// TokenTypeAPIKey marks a payload resolved from an API key rather than a signed token.
+	TokenTypeAPIKey TokenType = "api_key"
+)
+
+// Option customizes the payload of a new token.
+type Option func(payload *Payload)
+
+// WithType sets the type of the token. Tokens are access tokens by default.
+func WithType(tokenType TokenType) Option {
+	return func(payload *Payload) {
+		payload.Type = tokenType
+	}
+}
+
+// WithScopes limits what the token may be used for.
+func WithScopes(scopes ...string) Option {
+	return func(payload *Payload) {
+		payload.Scopes = scopes
+	}
+}
+
+// WithAudience names the services the token is meant for.
+func WithAudience(audience ...string) Option {
+	return func(payload *Payload) {
+		payload.Audience = audience
+	}
+}
+
+// VerifyOption adds a check to the verification of a token.
+type VerifyOption func(payload *Payload) error
+
+// ExpectAudience rejects tokens whose audience does not include audience.
+func ExpectAudience(audience string) VerifyOption {
+	return func(payload *Payload) error {
+		if !slices.Contains(payload.Audience, audience) {
+			return ErrInvalidAudience
+		}
+		return nil
+	}
+}
+
+// ExpectType rejects tokens of another type than tokenType.
+func ExpectType(tokenType TokenType) VerifyOption {
+	return func(payload *Payload) error {
+		if payload.Type != tokenType {
+			return ErrInvalidTokenType
+		}
+		return nil
+	}
+}
+
+// checkPayload runs the verify options on a verified payload.
+func checkPayload(payload *Payload, err error, opts []VerifyOption) (*Payload, error) {
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		if err := opt(payload); err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
+
 // Payload contains the payload data of the token.
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	Type      TokenType `json:"type"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	Audience  []string  `json:"audience,omitempty"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 	Issuer    string    `json:"issuer"`
@@ -44,7 +132,19 @@ func (payload *Payload) GetSubject() (string, error) {
 
 // GetAudience 返回 token 的受众（aud），表示 token 预期的接收方。
 func (payload *Payload) GetAudience() (jwt.ClaimStrings, error) {
-	return jwt.ClaimStrings{}, nil
+	return jwt.ClaimStrings(payload.Audience), nil
+}
+
+// IssuedBefore reports whether the token was issued before t.
+// Both JWT and PASETO only keep whole seconds of iat, so t is truncated the same way
+// to keep a token issued right after t valid.
+func (payload *Payload) IssuedBefore(t time.Time) bool {
+	return payload.IssuedAt.Before(t.Truncate(time.Second))
+}
+
+// HasScope reports whether the token was granted scope.
+func (payload *Payload) HasScope(scope string) bool {
+	return slices.Contains(payload.Scopes, scope)
 }
 
 func (payload *Payload) ToPasetoToken() paseto.Token {
@@ -55,12 +155,20 @@ func (payload *Payload) ToPasetoToken() paseto.Token {
 	token.SetExpiration(payload.ExpiredAt)
 	token.SetNotBefore(payload.IssuedAt)
 	token.SetIssuer(payload.Issuer)
+	token.SetString("role", payload.Role)
+	token.SetString("type", string(payload.Type))
+	if len(payload.Scopes) > 0 {
+		_ = token.Set("scopes", payload.Scopes)
+	}
+	if len(payload.Audience) > 0 {
+		_ = token.Set("audience", payload.Audience)
+	}
 
 	return token
 }
 
-// NewPayload creates a new token payload with a specific username and duration.
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+// NewPayload creates a new token payload with a specific username, role and duration.
+func NewPayload(username string, role string, duration time.Duration, opts ...Option) (*Payload, error) {
 	var tokenID, err = uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -69,10 +177,15 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 	var payload = &Payload{
 		ID:        tokenID,
 		Username:  username,
+		Role:      role,
+		Type:      TokenTypeAccess,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 		Issuer:    "simplebank",
 	}
+	for _, opt := range opts {
+		opt(payload)
+	}
 
 	return payload, nil
 }
@@ -98,6 +211,19 @@ func NewPayloadFromPasetoToken(token *paseto.Token) (*Payload, error) {
 	if err != nil {
 		return nil, err
 	}
+	role, err := token.GetString("role")
+	if err != nil {
+		return nil, err
+	}
+	// tokens issued before token types existed are access tokens
+	var tokenType = TokenTypeAccess
+	if value, err := token.GetString("type"); err == nil {
+		tokenType = TokenType(value)
+	}
+	// scopes and audience are optional
+	var scopes, audience []string
+	_ = token.Get("scopes", &scopes)
+	_ = token.Get("audience", &audience)
 	tokenID, err := uuid.Parse(ID)
 	if err != nil {
 		return nil, err
@@ -106,6 +232,10 @@ func NewPayloadFromPasetoToken(token *paseto.Token) (*Payload, error) {
 	var payload = &Payload{
 		ID:        tokenID,
 		Username:  username,
+		Role:      role,
+		Type:      tokenType,
+		Scopes:    scopes,
+		Audience:  audience,
 		IssuedAt:  issuedAt,
 		ExpiredAt: expiredAt,
 		Issuer:    issuer,
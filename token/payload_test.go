@@ -0,0 +1,60 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopesAndAudience(t *testing.T) {
+	for name, tc := range rotatingMakers {
+		t.Run(name, func(t *testing.T) {
+			var maker, err = tc.newMaker(singleKeySet("a", tc.newSecret(t)))
+			require.NoError(t, err)
+
+			var scopes = []string{"accounts:read", "transfers:write"}
+			token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute,
+				WithScopes(scopes...), WithAudience("simplebank", "ledger"))
+			require.NoError(t, err)
+
+			payload, err := maker.VerifyToken(token, ExpectAudience("ledger"))
+			require.NoError(t, err)
+			require.Equal(t, scopes, payload.Scopes)
+			require.Equal(t, []string{"simplebank", "ledger"}, payload.Audience)
+			require.True(t, payload.HasScope("accounts:read"))
+			require.False(t, payload.HasScope("accounts:write"))
+
+			_, err = maker.VerifyToken(token, ExpectAudience("payments"))
+			require.ErrorIs(t, err, ErrInvalidAudience)
+
+			// tokens without an audience are not meant for anyone in particular
+			token, _, err = maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+			require.NoError(t, err)
+			payload, err = maker.VerifyToken(token)
+			require.NoError(t, err)
+			require.Empty(t, payload.Scopes)
+			require.Empty(t, payload.Audience)
+			_, err = maker.VerifyToken(token, ExpectAudience("ledger"))
+			require.ErrorIs(t, err, ErrInvalidAudience)
+		})
+	}
+}
+
+func TestVerifierExpectAudience(t *testing.T) {
+	var privateKey = newTestEd25519Key(t)
+	var maker, err = NewPasetoPublicMaker(privateKey)
+	require.NoError(t, err)
+	verifier, err := NewVerifier(privateKey.Public().(ed25519.PublicKey))
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute, WithAudience("ledger"))
+	require.NoError(t, err)
+
+	_, err = verifier.VerifyToken(token, ExpectAudience("ledger"))
+	require.NoError(t, err)
+	_, err = verifier.VerifyToken(token, ExpectAudience("payments"))
+	require.ErrorIs(t, err, ErrInvalidAudience)
+}
@@ -0,0 +1,116 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrUnknownKeyID is returned when a token names a key the verifier does not have.
+var ErrUnknownKeyID = errors.New("token is signed by an unknown key")
+
+// Verifier is a Maker that only verifies tokens signed by JWTEdDSAMaker or PasetoPublicMaker.
+// Other services can use it with our published public keys without holding any secret.
+type Verifier struct {
+	keys map[string]ed25519.PublicKey
+}
+
+// NewVerifier creates a Verifier trusting the given public keys.
+func NewVerifier(publicKeys ...ed25519.PublicKey) (*Verifier, error) {
+	if len(publicKeys) == 0 {
+		return nil, errors.New("at least one public key is required")
+	}
+
+	var keys = make(map[string]ed25519.PublicKey, len(publicKeys))
+	for _, publicKey := range publicKeys {
+		if len(publicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid key size: must be %d bytes", ed25519.PublicKeySize)
+		}
+		keys[KeyID(publicKey)] = publicKey
+	}
+	return &Verifier{keys: keys}, nil
+}
+
+// NewVerifierFromJWKS creates a Verifier trusting the keys of a JWKS, as served on /.well-known/jwks.json.
+// Keys are looked up by their kid.
+func NewVerifierFromJWKS(jwks JWKS) (*Verifier, error) {
+	if len(jwks.Keys) == 0 {
+		return nil, errors.New("at least one public key is required")
+	}
+
+	var keys = make(map[string]ed25519.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		var publicKey, err = jwk.Ed25519PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		var kid = jwk.Kid
+		if kid == "" {
+			kid = KeyID(publicKey)
+		}
+		keys[kid] = publicKey
+	}
+	return &Verifier{keys: keys}, nil
+}
+
+// CreateToken always fails, a Verifier holds no private key.
+func (verifier *Verifier) CreateToken(username string, role string, duration time.Duration, opts ...Option) (string, *Payload, error) {
+	return "", nil, ErrVerifyOnly
+}
+
+// VerifyToken checks if a JWT or PASETO v4.public token is valid or not.
+// Only access tokens are accepted, a token only proving the password step of a login is refused.
+// Pass ExpectAudience to only accept tokens meant for the calling service.
+func (verifier *Verifier) VerifyToken(token string, opts ...VerifyOption) (*Payload, error) {
+	opts = append([]VerifyOption{ExpectType(TokenTypeAccess)}, opts...)
+	if strings.HasPrefix(token, paseto.V4Public.Header()) {
+		var payload, err = verifier.verifyPaseto(token)
+		return checkPayload(payload, err, opts)
+	}
+
+	var payload, err = verifyJWT(token, func(jwtToken *jwt.Token) (any, error) {
+		if _, ok := jwtToken.Method.(*jwt.SigningMethodEd25519); !ok {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		var kid, _ = jwtToken.Header["kid"].(string)
+		return verifier.key(kid)
+	})
+	return checkPayload(payload, err, opts)
+}
+
+func (verifier *Verifier) verifyPaseto(token string) (*Payload, error) {
+	var kid, err = pasetoKeyID(paseto.V4Public, token)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := verifier.key(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := parsePasetoPublic(publicKey, token)
+	if err != nil {
+		return nil, err
+	}
+	return NewPayloadFromPasetoToken(parsed)
+}
+
+// key returns the public key named by kid. Tokens without a kid are accepted
+// when the verifier trusts a single key.
+func (verifier *Verifier) key(kid string) (ed25519.PublicKey, error) {
+	if kid == "" && len(verifier.keys) == 1 {
+		for _, publicKey := range verifier.keys {
+			return publicKey, nil
+		}
+	}
+	var publicKey, ok = verifier.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+	return publicKey, nil
+}
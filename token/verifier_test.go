@@ -0,0 +1,122 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier(t *testing.T) {
+	var jwtMaker, err = NewJWTEdDSAMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+	pasetoMaker, err := NewPasetoPublicMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+
+	// a verifier built from the published key sets accepts tokens of both makers
+	var jwks = JWKS{Keys: append(jwtMaker.JWKS().Keys, pasetoMaker.JWKS().Keys...)}
+	data, err := json.Marshal(jwks)
+	require.NoError(t, err)
+	var published JWKS
+	require.NoError(t, json.Unmarshal(data, &published))
+
+	verifier, err := NewVerifierFromJWKS(published)
+	require.NoError(t, err)
+
+	for _, maker := range []Maker{jwtMaker, pasetoMaker} {
+		var username = util.RandomOwner()
+		var token, createdPayload, err = maker.CreateToken(username, util.DepositorRole, time.Minute)
+		require.NoError(t, err)
+
+		payload, err := verifier.VerifyToken(token)
+		require.NoError(t, err)
+		require.Equal(t, createdPayload.ID, payload.ID)
+		require.Equal(t, username, payload.Username)
+	}
+
+	var _, _, err1 = verifier.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.ErrorIs(t, err1, ErrVerifyOnly)
+}
+
+func TestVerifierRejectsUnknownKeys(t *testing.T) {
+	var verifier, err = NewVerifier(newTestEd25519Key(t).Public().(ed25519.PublicKey))
+	require.NoError(t, err)
+
+	jwtMaker, err := NewJWTEdDSAMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+	token, _, err := jwtMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	_, err = verifier.VerifyToken(token)
+	require.ErrorIs(t, err, ErrUnknownKeyID)
+
+	pasetoMaker, err := NewPasetoPublicMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+	token, _, err = pasetoMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	_, err = verifier.VerifyToken(token)
+	require.ErrorIs(t, err, ErrUnknownKeyID)
+
+	// symmetric tokens cannot be verified without the shared secret
+	hmacMaker, err := NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+	token, _, err = hmacMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	_, err = verifier.VerifyToken(token)
+	require.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+
+	localMaker, err := NewPasetoMaker(paseto.NewV4SymmetricKey())
+	require.NoError(t, err)
+	token, _, err = localMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	_, err = verifier.VerifyToken(token)
+	require.Error(t, err)
+}
+
+func TestVerifierRejectsMFAPendingTokens(t *testing.T) {
+	var jwtMaker, err = NewJWTEdDSAMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+	pasetoMaker, err := NewPasetoPublicMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+
+	var jwks = JWKS{Keys: append(jwtMaker.JWKS().Keys, pasetoMaker.JWKS().Keys...)}
+	verifier, err := NewVerifierFromJWKS(jwks)
+	require.NoError(t, err)
+
+	for _, maker := range []Maker{jwtMaker, pasetoMaker} {
+		var token, _, err = maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute,
+			WithType(TokenTypeMFAPending), WithAudience("ledger"))
+		require.NoError(t, err)
+
+		// the makers still verify it, for the login to exchange it for an access token
+		payload, err := maker.VerifyToken(token)
+		require.NoError(t, err)
+		require.Equal(t, TokenTypeMFAPending, payload.Type)
+
+		// other services only accept access tokens, even for their audience
+		_, err = verifier.VerifyToken(token)
+		require.ErrorIs(t, err, ErrInvalidTokenType)
+		_, err = verifier.VerifyToken(token, ExpectAudience("ledger"))
+		require.ErrorIs(t, err, ErrInvalidTokenType)
+	}
+}
+
+func TestJWK(t *testing.T) {
+	var publicKey = newTestEd25519Key(t).Public().(ed25519.PublicKey)
+	var jwk = NewEd25519JWK(publicKey)
+	require.Equal(t, "OKP", jwk.Kty)
+	require.Equal(t, "Ed25519", jwk.Crv)
+	require.Equal(t, KeyID(publicKey), jwk.Kid)
+
+	var decoded, err = jwk.Ed25519PublicKey()
+	require.NoError(t, err)
+	require.Equal(t, publicKey, decoded)
+
+	jwk.Crv = "P-256"
+	_, err = jwk.Ed25519PublicKey()
+	require.Error(t, err)
+}
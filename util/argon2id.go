@@ -0,0 +1,120 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2idParams are the cost parameters of argon2id. Memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follows the OWASP minimum recommendation.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Argon2idHasher hashes passwords with argon2id and encodes them in the PHC string format:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+// NewArgon2idHasher creates an argon2id hasher with the given parameters.
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{params: params}
+}
+
+// Hash returns the PHC encoded argon2id hash of the password.
+func (hasher *Argon2idHasher) Hash(password string) (string, error) {
+	var salt = make([]byte, hasher.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	var key = argon2.IDKey([]byte(password), salt, hasher.params.Iterations, hasher.params.Memory, hasher.params.Parallelism, hasher.params.KeyLength)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		hasher.params.Memory,
+		hasher.params.Iterations,
+		hasher.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// NeedsRehash reports whether the hash is not an argon2id hash with the configured parameters.
+func (hasher *Argon2idHasher) NeedsRehash(hashedPassword string) bool {
+	var params, salt, key, err = decodeArgon2id(hashedPassword)
+	if err != nil {
+		return true
+	}
+	return params.Memory != hasher.params.Memory ||
+		params.Iterations != hasher.params.Iterations ||
+		params.Parallelism != hasher.params.Parallelism ||
+		uint32(len(salt)) != hasher.params.SaltLength ||
+		uint32(len(key)) != hasher.params.KeyLength
+}
+
+func checkArgon2id(password, hashedPassword string) error {
+	var params, salt, key, err = decodeArgon2id(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	var otherKey = argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return ErrMismatchedPassword
+	}
+	return nil
+}
+
+func decodeArgon2id(hashedPassword string) (params Argon2idParams, salt, key []byte, err error) {
+	var fields = strings.Split(hashedPassword, "$")
+	if len(fields) != 6 || fields[1] != Argon2idAlgorithm {
+		err = ErrUnknownPasswordHash
+		return
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(fields[2], "v=%d", &version); err != nil {
+		err = fmt.Errorf("invalid argon2id version: %w", err)
+		return
+	}
+	if version != argon2.Version {
+		err = fmt.Errorf("unsupported argon2id version %d", version)
+		return
+	}
+
+	if _, err = fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		err = fmt.Errorf("invalid argon2id parameters: %w", err)
+		return
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(fields[4]); err != nil {
+		err = fmt.Errorf("invalid argon2id salt: %w", err)
+		return
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(fields[5]); err != nil {
+		err = fmt.Errorf("invalid argon2id key: %w", err)
+		return
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return
+}
@@ -0,0 +1,72 @@
+package util
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// breachedPrefixLength is the length of the hash prefix used to pick a bucket, as in the
+// k-anonymity range API of Have I Been Pwned.
+const breachedPrefixLength = 5
+
+// ErrBreachedPassword is returned for passwords that appear in the breached password list.
+var ErrBreachedPassword = errors.New("has appeared in a data breach, choose a different one")
+
+// BreachedPasswords is an index of SHA-1 hashes of breached passwords, bucketed by hash prefix.
+type BreachedPasswords struct {
+	buckets map[string][]string
+}
+
+// LoadBreachedPasswords reads a breached password list with one upper-case SHA-1 hash per line,
+// optionally followed by ":<count>" as in the Have I Been Pwned downloads.
+// Empty lines and lines starting with "#" are ignored.
+func LoadBreachedPasswords(path string) (*BreachedPasswords, error) {
+	var file, err = os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	var breached = &BreachedPasswords{buckets: make(map[string][]string)}
+	var scanner = bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		var text = strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var hash, _, _ = strings.Cut(text, ":")
+		hash = strings.ToUpper(hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("invalid SHA-1 hash on line %d of breached password list", line)
+		}
+		var prefix, suffix = hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+		breached.buckets[prefix] = append(breached.buckets[prefix], suffix)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read breached password list: %w", err)
+	}
+
+	for _, suffixes := range breached.buckets {
+		slices.Sort(suffixes)
+	}
+	return breached, nil
+}
+
+// Range returns the sorted hash suffixes of breached passwords whose hash starts with prefix.
+func (breached *BreachedPasswords) Range(prefix string) []string {
+	return breached.buckets[strings.ToUpper(prefix)]
+}
+
+// Contains reports whether the password is in the breached password list.
+func (breached *BreachedPasswords) Contains(password string) bool {
+	var sum = sha1.Sum([]byte(password))
+	var hash = strings.ToUpper(hex.EncodeToString(sum[:]))
+	var _, found = slices.BinarySearch(breached.Range(hash[:breachedPrefixLength]), hash[breachedPrefixLength:])
+	return found
+}
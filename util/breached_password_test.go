@@ -0,0 +1,48 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeBreachedPasswords(t *testing.T, content string) string {
+	var path = filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestBreachedPasswords(t *testing.T) {
+	// sha1("password") and sha1("123456")
+	var path = writeBreachedPasswords(t, `# comment
+
+5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493
+7c4a8d09ca3762af61e59520943dc26494f8941b
+`)
+
+	var breached, err = LoadBreachedPasswords(path)
+	require.NoError(t, err)
+
+	require.True(t, breached.Contains("password"))
+	require.True(t, breached.Contains("123456"))
+	require.False(t, breached.Contains("correct horse battery staple"))
+
+	require.Equal(t, []string{"1E4C9B93F3F0682250B6CF8331B7EE68FD8"}, breached.Range("5baa6"))
+	require.Empty(t, breached.Range("00000"))
+}
+
+func TestLoadBreachedPasswordsInvalid(t *testing.T) {
+	var _, err = LoadBreachedPasswords(writeBreachedPasswords(t, "not-a-hash\n"))
+	require.EqualError(t, err, "invalid SHA-1 hash on line 1 of breached password list")
+
+	_, err = LoadBreachedPasswords(filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+}
+
+func TestBundledBreachedPasswords(t *testing.T) {
+	var breached, err = LoadBreachedPasswords("../data/breached_passwords.txt")
+	require.NoError(t, err)
+	require.True(t, breached.Contains("P@ssw0rd"))
+}
@@ -1,13 +1,117 @@
 package util
 
-import "github.com/spf13/viper"
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
 
 // Config stores all configuration of the application.
 // The values are read by viper from a config file or environment variables.
 type Config struct {
-	DBDriver      string `mapstructure:"DB_DRIVER"`
-	DBSource      string `mapstructure:"DB_SOURCE"`
+	DBSource string `mapstructure:"DB_SOURCE"`
+	// The pool settings override the pgxpool defaults when they are not zero.
+	DBMaxConns          int32         `mapstructure:"DB_MAX_CONNS"`
+	DBMinConns          int32         `mapstructure:"DB_MIN_CONNS"`
+	DBMaxConnLifetime   time.Duration `mapstructure:"DB_MAX_CONN_LIFETIME"`
+	DBMaxConnIdleTime   time.Duration `mapstructure:"DB_MAX_CONN_IDLE_TIME"`
+	DBHealthCheckPeriod time.Duration `mapstructure:"DB_HEALTH_CHECK_PERIOD"`
+	// DBStatementCacheCapacity is the number of prepared statements cached per connection.
+	DBStatementCacheCapacity int `mapstructure:"DB_STATEMENT_CACHE_CAPACITY"`
+	// DBQueryExecMode is one of cache_statement, cache_describe, describe_exec, exec or simple_protocol.
+	// Use exec or simple_protocol behind a transaction pooling proxy such as PgBouncer.
+	DBQueryExecMode string `mapstructure:"DB_QUERY_EXEC_MODE"`
+	// Transactions aborted by a serialization failure or a deadlock are retried
+	// up to DBTxMaxAttempts times in total, with a jittered exponential backoff.
+	DBTxMaxAttempts    int           `mapstructure:"DB_TX_MAX_ATTEMPTS"`
+	DBTxRetryBaseDelay time.Duration `mapstructure:"DB_TX_RETRY_BASE_DELAY"`
+	DBTxRetryMaxDelay  time.Duration `mapstructure:"DB_TX_RETRY_MAX_DELAY"`
+
 	ServerAddress string `mapstructure:"SERVER_ADDRESS"`
+	// TrustedProxies is a comma separated list of the IPs or CIDRs of the reverse proxies in front of the server.
+	// Only they may set the client IP with X-Forwarded-For; by default no proxy is trusted.
+	TrustedProxies string `mapstructure:"TRUSTED_PROXIES"`
+
+	// TokenMaker is one of paseto (v4.local), jwt (HS256), paseto_public (v4.public) or jwt_eddsa.
+	TokenMaker        string `mapstructure:"TOKEN_MAKER"`
+	TokenSymmetricKey string `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	// TokenSigningKey is the hex encoded Ed25519 seed used by the paseto_public and jwt_eddsa makers.
+	TokenSigningKey string `mapstructure:"TOKEN_SIGNING_KEY"`
+	// TokenKeyringFile is a JSON key set replacing the two keys above, so keys can be rotated.
+	// It is read again on SIGHUP.
+	TokenKeyringFile string `mapstructure:"TOKEN_KEYRING_FILE"`
+	// TokenAudience is put in the tokens we issue and required from the tokens we accept.
+	TokenAudience       string        `mapstructure:"TOKEN_AUDIENCE"`
+	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	// PasswordChangedCacheTTL bounds how long a password change can take to revoke tokens on other replicas.
+	PasswordChangedCacheTTL time.Duration `mapstructure:"PASSWORD_CHANGED_CACHE_TTL"`
+	// CurrencyCacheTTL bounds how long enabling or disabling a currency can take to reach other replicas.
+	CurrencyCacheTTL time.Duration `mapstructure:"CURRENCY_CACHE_TTL"`
+
+	PasswordHashAlgorithm string `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	Argon2Memory          uint32 `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations      uint32 `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism     uint8  `mapstructure:"ARGON2_PARALLELISM"`
+	BcryptCost            int    `mapstructure:"BCRYPT_COST"`
+
+	PasswordMinLength     int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordRequireUpper  bool   `mapstructure:"PASSWORD_REQUIRE_UPPER"`
+	PasswordRequireLower  bool   `mapstructure:"PASSWORD_REQUIRE_LOWER"`
+	PasswordRequireDigit  bool   `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol bool   `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	BreachedPasswordsFile string `mapstructure:"BREACHED_PASSWORDS_FILE"`
+
+	TOTPEncryptionKey string        `mapstructure:"TOTP_ENCRYPTION_KEY"`
+	TOTPIssuer        string        `mapstructure:"TOTP_ISSUER"`
+	MFATokenDuration  time.Duration `mapstructure:"MFA_TOKEN_DURATION"`
+
+	LoginBackoffBase        time.Duration `mapstructure:"LOGIN_BACKOFF_BASE"`
+	LoginBackoffMax         time.Duration `mapstructure:"LOGIN_BACKOFF_MAX"`
+	LoginMaxFailuresPerUser int32         `mapstructure:"LOGIN_MAX_FAILURES_PER_USER"`
+	LoginMaxFailuresPerIP   int32         `mapstructure:"LOGIN_MAX_FAILURES_PER_IP"`
+	LoginLockoutDuration    time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginFailureWindow      time.Duration `mapstructure:"LOGIN_FAILURE_WINDOW"`
+	SecurityLogFile         string        `mapstructure:"SECURITY_LOG_FILE"`
+
+	// RateLimitBackend is memory (per replica) or postgres (shared by all replicas).
+	RateLimitBackend string `mapstructure:"RATE_LIMIT_BACKEND"`
+	// The rate limits are written as <burst>/<period>, such as 10/1m. Empty disables the limit.
+	RateLimitLogin     string `mapstructure:"RATE_LIMIT_LOGIN"`
+	RateLimitAccounts  string `mapstructure:"RATE_LIMIT_ACCOUNTS"`
+	RateLimitTransfers string `mapstructure:"RATE_LIMIT_TRANSFERS"`
+	RateLimitUsers     string `mapstructure:"RATE_LIMIT_USERS"`
+
+	ReadinessTimeout time.Duration `mapstructure:"READINESS_TIMEOUT"`
+
+	TaskPollInterval time.Duration `mapstructure:"TASK_POLL_INTERVAL"`
+	VerifyEmailURL   string        `mapstructure:"VERIFY_EMAIL_URL"`
+
+	// SavingsInterestRates are the annual rates of savings accounts in basis points per currency,
+	// such as USD=250,EUR=175. Savings accounts in other currencies earn no interest.
+	SavingsInterestRates string `mapstructure:"SAVINGS_INTEREST_RATES"`
+
+	// WebhookEncryptionKey seals the signing secrets of the webhook subscriptions. Webhooks are disabled when it is empty.
+	WebhookEncryptionKey string `mapstructure:"WEBHOOK_ENCRYPTION_KEY"`
+
+	// OutboxPublisher is log, file, http or nats. The outbox relay does not run when it is empty.
+	OutboxPublisher string `mapstructure:"OUTBOX_PUBLISHER"`
+	OutboxFile      string `mapstructure:"OUTBOX_FILE"`
+	OutboxHTTPURL   string `mapstructure:"OUTBOX_HTTP_URL"`
+	// OutboxNATSURL is a nats://host:port address, the events are published on <OutboxNATSSubject>.<event type>.
+	OutboxNATSURL     string `mapstructure:"OUTBOX_NATS_URL"`
+	OutboxNATSSubject string `mapstructure:"OUTBOX_NATS_SUBJECT"`
+
+	ResetPasswordURL           string        `mapstructure:"RESET_PASSWORD_URL"`
+	PasswordResetTokenDuration time.Duration `mapstructure:"PASSWORD_RESET_TOKEN_DURATION"`
+
+	Mailer             string `mapstructure:"MAILER"`
+	MailDir            string `mapstructure:"MAIL_DIR"`
+	EmailSenderName    string `mapstructure:"EMAIL_SENDER_NAME"`
+	EmailSenderAddress string `mapstructure:"EMAIL_SENDER_ADDRESS"`
+	SMTPHost           string `mapstructure:"SMTP_HOST"`
+	SMTPPort           int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername       string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword       string `mapstructure:"SMTP_PASSWORD"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
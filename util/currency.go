@@ -1,6 +1,8 @@
 package util
 
-// List of supported currencies
+import "sort"
+
+// Currencies enabled by the initial migration of the currencies table.
 const (
 	USD = "USD"
 	EUR = "EUR"
@@ -8,11 +10,40 @@ const (
 	CNY = "CNY"
 )
 
-// IsSupportCurrency checks if the given currency is supported
-func IsSupportCurrency(currency string) bool {
-	switch currency {
-	case USD, EUR, CAD, CNY:
-		return true
+// Currency is an ISO 4217 currency.
+type Currency struct {
+	// Code is the alphabetic code, such as "USD".
+	Code string `json:"code"`
+	// Numeric is the three digit numeric code, such as "840".
+	Numeric string `json:"numeric"`
+	// MinorUnits is the number of decimals of the currency, so an amount of 1 is 10^-MinorUnits of a unit.
+	MinorUnits int `json:"minor_units"`
+	// Symbol is the display symbol, which is not unique across currencies.
+	Symbol string `json:"symbol"`
+}
+
+var currencyRegistry = make(map[string]Currency, len(iso4217))
+
+func init() {
+	for _, currency := range iso4217 {
+		currencyRegistry[currency.Code] = currency
+	}
+}
+
+// LookupCurrency returns the ISO 4217 currency with the alphabetic code.
+func LookupCurrency(code string) (Currency, bool) {
+	var currency, ok = currencyRegistry[code]
+	return currency, ok
+}
+
+// Currencies returns every ISO 4217 currency of the registry, sorted by code.
+func Currencies() []Currency {
+	var list = make([]Currency, 0, len(currencyRegistry))
+	for _, currency := range currencyRegistry {
+		list = append(list, currency)
 	}
-	return false
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
 }
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrencyRegistry(t *testing.T) {
+	var usd, ok = LookupCurrency(USD)
+	require.True(t, ok)
+	require.Equal(t, Currency{Code: "USD", Numeric: "840", MinorUnits: 2, Symbol: "$"}, usd)
+
+	var jpy, _ = LookupCurrency("JPY")
+	require.Equal(t, 0, jpy.MinorUnits)
+	var kwd, _ = LookupCurrency("KWD")
+	require.Equal(t, 3, kwd.MinorUnits)
+
+	_, ok = LookupCurrency("XAU")
+	require.False(t, ok)
+	_, ok = LookupCurrency("usd")
+	require.False(t, ok)
+
+	// every supported currency is in the registry
+	for _, code := range currencies {
+		_, ok = LookupCurrency(code)
+		require.True(t, ok, code)
+	}
+
+	var list = Currencies()
+	require.Len(t, list, len(iso4217))
+	var codes = make(map[string]bool)
+	var numerics = make(map[string]bool)
+	for i, currency := range list {
+		if i > 0 {
+			require.Less(t, list[i-1].Code, currency.Code)
+		}
+		require.Len(t, currency.Code, 3)
+		require.Len(t, currency.Numeric, 3)
+		require.NotEmpty(t, currency.Symbol)
+		require.False(t, codes[currency.Code], currency.Code)
+		require.False(t, numerics[currency.Numeric], currency.Numeric)
+		codes[currency.Code] = true
+		numerics[currency.Numeric] = true
+	}
+}
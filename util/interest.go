@@ -0,0 +1,97 @@
+package util
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MicrosPerMinorUnit is the precision interest accrues at: a millionth of a cent for USD.
+// Daily interest is kept at this precision and only rounded to minor units when it is posted.
+const MicrosPerMinorUnit = 1_000_000
+
+// maxInterestRate is 100% a year in basis points.
+const maxInterestRate = 10_000
+
+// ParseInterestRates parses annual interest rates in basis points per currency, such as "USD=250,EUR=175".
+// An empty string is an empty map, which disables interest.
+func ParseInterestRates(s string) (map[string]int64, error) {
+	var rates = make(map[string]int64)
+	if strings.TrimSpace(s) == "" {
+		return rates, nil
+	}
+
+	for _, field := range strings.Split(s, ",") {
+		var code, rate, ok = strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid interest rate %q: want <currency>=<basis points>", field)
+		}
+		if _, ok := LookupCurrency(code); !ok {
+			return nil, fmt.Errorf("invalid interest rate %q: %w", field, ErrUnknownCurrency)
+		}
+		if _, ok := rates[code]; ok {
+			return nil, fmt.Errorf("invalid interest rate %q: %s is set twice", field, code)
+		}
+		var bps, err = strconv.ParseInt(rate, 10, 64)
+		if err != nil || bps < 0 || bps > maxInterestRate {
+			return nil, fmt.Errorf("invalid interest rate %q: want basis points between 0 and %d", field, maxInterestRate)
+		}
+		rates[code] = bps
+	}
+	return rates, nil
+}
+
+// DaysInYear returns 366 in leap years and 365 otherwise.
+func DaysInYear(year int) int {
+	return time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
+
+// DailyInterest returns the interest a balance earns in one day of the year at an annual rate in basis points,
+// in millionths of a minor unit rounded half to even. The rate is split evenly over the days of the year (actual/actual).
+func DailyInterest(balance int64, rateBps int64, year int) (int64, error) {
+	var numerator = new(big.Int).Mul(big.NewInt(balance), big.NewInt(rateBps))
+	numerator.Mul(numerator, big.NewInt(MicrosPerMinorUnit))
+	var denominator = big.NewInt(int64(10_000 * DaysInYear(year)))
+
+	var micros = divRoundHalfEven(numerator, denominator)
+	if !micros.IsInt64() {
+		return 0, fmt.Errorf("%w: interest on %d at %d bps", ErrAmountOverflow, balance, rateBps)
+	}
+	return micros.Int64(), nil
+}
+
+// RoundMicros rounds an amount in millionths of a minor unit to minor units, half to even.
+// The remainder is what was rounded away, to be carried over so no fraction is lost or paid twice.
+func RoundMicros(micros int64) (amount int64, remainder int64) {
+	amount = divRoundHalfEven(big.NewInt(micros), big.NewInt(MicrosPerMinorUnit)).Int64()
+	return amount, micros - amount*MicrosPerMinorUnit
+}
+
+// ApplyBasisPoints returns the part of an amount given in basis points, such as a percentage fee,
+// rounded half to even to the minor unit.
+func ApplyBasisPoints(amount int64, bps int64) (int64, error) {
+	var numerator = new(big.Int).Mul(big.NewInt(amount), big.NewInt(bps))
+	var part = divRoundHalfEven(numerator, big.NewInt(10_000))
+	if !part.IsInt64() {
+		return 0, fmt.Errorf("%w: %d bps of %d", ErrAmountOverflow, bps, amount)
+	}
+	return part.Int64(), nil
+}
+
+// divRoundHalfEven returns x / y rounded to the nearest integer, ties to even. y must be positive.
+func divRoundHalfEven(x, y *big.Int) *big.Int {
+	var quotient, remainder = new(big.Int).DivMod(x, y, new(big.Int))
+	// DivMod is Euclidean division, so the remainder is in [0, y) and quotient rounds toward -inf
+	var twice = new(big.Int).Lsh(remainder, 1)
+	switch twice.Cmp(y) {
+	case 1:
+		quotient.Add(quotient, big.NewInt(1))
+	case 0:
+		if quotient.Bit(0) == 1 {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return quotient
+}
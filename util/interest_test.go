@@ -0,0 +1,122 @@
+package util
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInterestRates(t *testing.T) {
+	var rates, err = ParseInterestRates("USD=250, EUR=175,JPY=0")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"USD": 250, "EUR": 175, "JPY": 0}, rates)
+
+	rates, err = ParseInterestRates("")
+	require.NoError(t, err)
+	require.Empty(t, rates)
+
+	for _, s := range []string{"USD", "USD=", "USD=-1", "USD=10001", "XXX=100", "usd=100", "USD=1,USD=2", "USD=1.5"} {
+		_, err = ParseInterestRates(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestDaysInYear(t *testing.T) {
+	require.Equal(t, 365, DaysInYear(2023))
+	require.Equal(t, 366, DaysInYear(2024))
+	require.Equal(t, 365, DaysInYear(2100))
+	require.Equal(t, 366, DaysInYear(2000))
+}
+
+func TestDailyInterest(t *testing.T) {
+	testCases := []struct {
+		name    string
+		balance int64
+		rateBps int64
+		year    int
+		micros  int64
+	}{
+		// $1000.00 at 2.5%: 100000 * 0.025 / 365 = 6.849315068... cents
+		{name: "CommonYear", balance: 100_000, rateBps: 250, year: 2023, micros: 6_849_315},
+		// 100000 * 0.025 / 366 = 6.830601092... cents
+		{name: "LeapYear", balance: 100_000, rateBps: 250, year: 2024, micros: 6_830_601},
+		{name: "ZeroRate", balance: 100_000, rateBps: 0, year: 2023, micros: 0},
+		{name: "ZeroBalance", balance: 0, rateBps: 250, year: 2023, micros: 0},
+		// 73 * 0.0001 / 365 = 0.00002 cents exactly
+		{name: "Exact", balance: 73, rateBps: 1, year: 2023, micros: 20},
+		// 1 * 0.0001 / 365 * 1e6 = 0.27 micros
+		{name: "RoundDown", balance: 1, rateBps: 1, year: 2023, micros: 0},
+		{name: "Negative", balance: -100_000, rateBps: 250, year: 2023, micros: -6_849_315},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var micros, err = DailyInterest(tc.balance, tc.rateBps, tc.year)
+			require.NoError(t, err)
+			require.Equal(t, tc.micros, micros)
+		})
+	}
+
+	// a year of daily interest adds up to the annual rate, within the rounding of each day
+	var total int64
+	for range DaysInYear(2023) {
+		var micros, err = DailyInterest(100_000, 250, 2023)
+		require.NoError(t, err)
+		total += micros
+	}
+	require.InDelta(t, 2_500*MicrosPerMinorUnit, total, float64(DaysInYear(2023)))
+
+	_, err := DailyInterest(1<<62, 10_000, 2023)
+	require.ErrorIs(t, err, ErrAmountOverflow)
+}
+
+func TestRoundMicros(t *testing.T) {
+	testCases := []struct {
+		micros    int64
+		amount    int64
+		remainder int64
+	}{
+		{micros: 0, amount: 0, remainder: 0},
+		{micros: 2_400_000, amount: 2, remainder: 400_000},
+		{micros: 2_600_000, amount: 3, remainder: -400_000},
+		{micros: 2_500_000, amount: 2, remainder: 500_000},
+		{micros: 3_500_000, amount: 4, remainder: -500_000},
+		{micros: 499_999, amount: 0, remainder: 499_999},
+		{micros: -2_500_000, amount: -2, remainder: -500_000},
+		{micros: -2_600_000, amount: -3, remainder: 400_000},
+	}
+
+	for _, tc := range testCases {
+		var amount, remainder = RoundMicros(tc.micros)
+		require.Equal(t, tc.amount, amount, tc.micros)
+		require.Equal(t, tc.remainder, remainder, tc.micros)
+		require.Equal(t, tc.micros, amount*MicrosPerMinorUnit+remainder)
+	}
+}
+
+func TestApplyBasisPoints(t *testing.T) {
+	testCases := []struct {
+		amount int64
+		bps    int64
+		part   int64
+	}{
+		{amount: 10_000, bps: 150, part: 150},
+		{amount: 1_234, bps: 100, part: 12},
+		// 0.5 cents rounds to the even 0, 1.5 cents to 2
+		{amount: 50, bps: 100, part: 0},
+		{amount: 150, bps: 100, part: 2},
+		{amount: 151, bps: 100, part: 2},
+		{amount: 1_000, bps: 0, part: 0},
+		{amount: 1_000, bps: 10_000, part: 1_000},
+	}
+
+	for _, tc := range testCases {
+		var part, err = ApplyBasisPoints(tc.amount, tc.bps)
+		require.NoError(t, err)
+		require.Equal(t, tc.part, part, "%d bps of %d", tc.bps, tc.amount)
+	}
+
+	_, err := ApplyBasisPoints(math.MaxInt64, 20_000)
+	require.ErrorIs(t, err, ErrAmountOverflow)
+}
@@ -0,0 +1,171 @@
+package util
+
+// iso4217 lists the active ISO 4217 currencies that have minor units.
+// Funds and precious metals without minor units, such as XAU, are left out.
+var iso4217 = []Currency{
+	{Code: "AED", Numeric: "784", MinorUnits: 2, Symbol: "د.إ"},
+	{Code: "AFN", Numeric: "971", MinorUnits: 2, Symbol: "؋"},
+	{Code: "ALL", Numeric: "008", MinorUnits: 2, Symbol: "L"},
+	{Code: "AMD", Numeric: "051", MinorUnits: 2, Symbol: "֏"},
+	{Code: "AOA", Numeric: "973", MinorUnits: 2, Symbol: "Kz"},
+	{Code: "ARS", Numeric: "032", MinorUnits: 2, Symbol: "$"},
+	{Code: "AUD", Numeric: "036", MinorUnits: 2, Symbol: "A$"},
+	{Code: "AWG", Numeric: "533", MinorUnits: 2, Symbol: "ƒ"},
+	{Code: "AZN", Numeric: "944", MinorUnits: 2, Symbol: "₼"},
+	{Code: "BAM", Numeric: "977", MinorUnits: 2, Symbol: "KM"},
+	{Code: "BBD", Numeric: "052", MinorUnits: 2, Symbol: "$"},
+	{Code: "BDT", Numeric: "050", MinorUnits: 2, Symbol: "৳"},
+	{Code: "BGN", Numeric: "975", MinorUnits: 2, Symbol: "лв"},
+	{Code: "BHD", Numeric: "048", MinorUnits: 3, Symbol: ".د.ب"},
+	{Code: "BIF", Numeric: "108", MinorUnits: 0, Symbol: "FBu"},
+	{Code: "BMD", Numeric: "060", MinorUnits: 2, Symbol: "$"},
+	{Code: "BND", Numeric: "096", MinorUnits: 2, Symbol: "$"},
+	{Code: "BOB", Numeric: "068", MinorUnits: 2, Symbol: "Bs"},
+	{Code: "BOV", Numeric: "984", MinorUnits: 2, Symbol: "BOV"},
+	{Code: "BRL", Numeric: "986", MinorUnits: 2, Symbol: "R$"},
+	{Code: "BSD", Numeric: "044", MinorUnits: 2, Symbol: "$"},
+	{Code: "BTN", Numeric: "064", MinorUnits: 2, Symbol: "Nu."},
+	{Code: "BWP", Numeric: "072", MinorUnits: 2, Symbol: "P"},
+	{Code: "BYN", Numeric: "933", MinorUnits: 2, Symbol: "Br"},
+	{Code: "BZD", Numeric: "084", MinorUnits: 2, Symbol: "$"},
+	{Code: "CAD", Numeric: "124", MinorUnits: 2, Symbol: "CA$"},
+	{Code: "CDF", Numeric: "976", MinorUnits: 2, Symbol: "FC"},
+	{Code: "CHE", Numeric: "947", MinorUnits: 2, Symbol: "CHE"},
+	{Code: "CHF", Numeric: "756", MinorUnits: 2, Symbol: "CHF"},
+	{Code: "CHW", Numeric: "948", MinorUnits: 2, Symbol: "CHW"},
+	{Code: "CLF", Numeric: "990", MinorUnits: 4, Symbol: "UF"},
+	{Code: "CLP", Numeric: "152", MinorUnits: 0, Symbol: "$"},
+	{Code: "CNY", Numeric: "156", MinorUnits: 2, Symbol: "¥"},
+	{Code: "COP", Numeric: "170", MinorUnits: 2, Symbol: "$"},
+	{Code: "COU", Numeric: "970", MinorUnits: 2, Symbol: "COU"},
+	{Code: "CRC", Numeric: "188", MinorUnits: 2, Symbol: "₡"},
+	{Code: "CUP", Numeric: "192", MinorUnits: 2, Symbol: "$"},
+	{Code: "CVE", Numeric: "132", MinorUnits: 2, Symbol: "$"},
+	{Code: "CZK", Numeric: "203", MinorUnits: 2, Symbol: "Kč"},
+	{Code: "DJF", Numeric: "262", MinorUnits: 0, Symbol: "Fdj"},
+	{Code: "DKK", Numeric: "208", MinorUnits: 2, Symbol: "kr"},
+	{Code: "DOP", Numeric: "214", MinorUnits: 2, Symbol: "$"},
+	{Code: "DZD", Numeric: "012", MinorUnits: 2, Symbol: "د.ج"},
+	{Code: "EGP", Numeric: "818", MinorUnits: 2, Symbol: "E£"},
+	{Code: "ERN", Numeric: "232", MinorUnits: 2, Symbol: "Nfk"},
+	{Code: "ETB", Numeric: "230", MinorUnits: 2, Symbol: "Br"},
+	{Code: "EUR", Numeric: "978", MinorUnits: 2, Symbol: "€"},
+	{Code: "FJD", Numeric: "242", MinorUnits: 2, Symbol: "$"},
+	{Code: "FKP", Numeric: "238", MinorUnits: 2, Symbol: "£"},
+	{Code: "GBP", Numeric: "826", MinorUnits: 2, Symbol: "£"},
+	{Code: "GEL", Numeric: "981", MinorUnits: 2, Symbol: "₾"},
+	{Code: "GHS", Numeric: "936", MinorUnits: 2, Symbol: "₵"},
+	{Code: "GIP", Numeric: "292", MinorUnits: 2, Symbol: "£"},
+	{Code: "GMD", Numeric: "270", MinorUnits: 2, Symbol: "D"},
+	{Code: "GNF", Numeric: "324", MinorUnits: 0, Symbol: "FG"},
+	{Code: "GTQ", Numeric: "320", MinorUnits: 2, Symbol: "Q"},
+	{Code: "GYD", Numeric: "328", MinorUnits: 2, Symbol: "$"},
+	{Code: "HKD", Numeric: "344", MinorUnits: 2, Symbol: "HK$"},
+	{Code: "HNL", Numeric: "340", MinorUnits: 2, Symbol: "L"},
+	{Code: "HTG", Numeric: "332", MinorUnits: 2, Symbol: "G"},
+	{Code: "HUF", Numeric: "348", MinorUnits: 2, Symbol: "Ft"},
+	{Code: "IDR", Numeric: "360", MinorUnits: 2, Symbol: "Rp"},
+	{Code: "ILS", Numeric: "376", MinorUnits: 2, Symbol: "₪"},
+	{Code: "INR", Numeric: "356", MinorUnits: 2, Symbol: "₹"},
+	{Code: "IQD", Numeric: "368", MinorUnits: 3, Symbol: "ع.د"},
+	{Code: "IRR", Numeric: "364", MinorUnits: 2, Symbol: "﷼"},
+	{Code: "ISK", Numeric: "352", MinorUnits: 0, Symbol: "kr"},
+	{Code: "JMD", Numeric: "388", MinorUnits: 2, Symbol: "$"},
+	{Code: "JOD", Numeric: "400", MinorUnits: 3, Symbol: "د.ا"},
+	{Code: "JPY", Numeric: "392", MinorUnits: 0, Symbol: "¥"},
+	{Code: "KES", Numeric: "404", MinorUnits: 2, Symbol: "KSh"},
+	{Code: "KGS", Numeric: "417", MinorUnits: 2, Symbol: "сом"},
+	{Code: "KHR", Numeric: "116", MinorUnits: 2, Symbol: "៛"},
+	{Code: "KMF", Numeric: "174", MinorUnits: 0, Symbol: "CF"},
+	{Code: "KPW", Numeric: "408", MinorUnits: 2, Symbol: "₩"},
+	{Code: "KRW", Numeric: "410", MinorUnits: 0, Symbol: "₩"},
+	{Code: "KWD", Numeric: "414", MinorUnits: 3, Symbol: "د.ك"},
+	{Code: "KYD", Numeric: "136", MinorUnits: 2, Symbol: "$"},
+	{Code: "KZT", Numeric: "398", MinorUnits: 2, Symbol: "₸"},
+	{Code: "LAK", Numeric: "418", MinorUnits: 2, Symbol: "₭"},
+	{Code: "LBP", Numeric: "422", MinorUnits: 2, Symbol: "ل.ل"},
+	{Code: "LKR", Numeric: "144", MinorUnits: 2, Symbol: "Rs"},
+	{Code: "LRD", Numeric: "430", MinorUnits: 2, Symbol: "$"},
+	{Code: "LSL", Numeric: "426", MinorUnits: 2, Symbol: "L"},
+	{Code: "LYD", Numeric: "434", MinorUnits: 3, Symbol: "ل.د"},
+	{Code: "MAD", Numeric: "504", MinorUnits: 2, Symbol: "د.م."},
+	{Code: "MDL", Numeric: "498", MinorUnits: 2, Symbol: "L"},
+	{Code: "MGA", Numeric: "969", MinorUnits: 2, Symbol: "Ar"},
+	{Code: "MKD", Numeric: "807", MinorUnits: 2, Symbol: "ден"},
+	{Code: "MMK", Numeric: "104", MinorUnits: 2, Symbol: "K"},
+	{Code: "MNT", Numeric: "496", MinorUnits: 2, Symbol: "₮"},
+	{Code: "MOP", Numeric: "446", MinorUnits: 2, Symbol: "MOP$"},
+	{Code: "MRU", Numeric: "929", MinorUnits: 2, Symbol: "UM"},
+	{Code: "MUR", Numeric: "480", MinorUnits: 2, Symbol: "₨"},
+	{Code: "MVR", Numeric: "462", MinorUnits: 2, Symbol: "Rf"},
+	{Code: "MWK", Numeric: "454", MinorUnits: 2, Symbol: "MK"},
+	{Code: "MXN", Numeric: "484", MinorUnits: 2, Symbol: "MX$"},
+	{Code: "MXV", Numeric: "979", MinorUnits: 2, Symbol: "MXV"},
+	{Code: "MYR", Numeric: "458", MinorUnits: 2, Symbol: "RM"},
+	{Code: "MZN", Numeric: "943", MinorUnits: 2, Symbol: "MT"},
+	{Code: "NAD", Numeric: "516", MinorUnits: 2, Symbol: "$"},
+	{Code: "NGN", Numeric: "566", MinorUnits: 2, Symbol: "₦"},
+	{Code: "NIO", Numeric: "558", MinorUnits: 2, Symbol: "C$"},
+	{Code: "NOK", Numeric: "578", MinorUnits: 2, Symbol: "kr"},
+	{Code: "NPR", Numeric: "524", MinorUnits: 2, Symbol: "Rs"},
+	{Code: "NZD", Numeric: "554", MinorUnits: 2, Symbol: "NZ$"},
+	{Code: "OMR", Numeric: "512", MinorUnits: 3, Symbol: "ر.ع."},
+	{Code: "PAB", Numeric: "590", MinorUnits: 2, Symbol: "B/."},
+	{Code: "PEN", Numeric: "604", MinorUnits: 2, Symbol: "S/"},
+	{Code: "PGK", Numeric: "598", MinorUnits: 2, Symbol: "K"},
+	{Code: "PHP", Numeric: "608", MinorUnits: 2, Symbol: "₱"},
+	{Code: "PKR", Numeric: "586", MinorUnits: 2, Symbol: "Rs"},
+	{Code: "PLN", Numeric: "985", MinorUnits: 2, Symbol: "zł"},
+	{Code: "PYG", Numeric: "600", MinorUnits: 0, Symbol: "₲"},
+	{Code: "QAR", Numeric: "634", MinorUnits: 2, Symbol: "ر.ق"},
+	{Code: "RON", Numeric: "946", MinorUnits: 2, Symbol: "lei"},
+	{Code: "RSD", Numeric: "941", MinorUnits: 2, Symbol: "дин."},
+	{Code: "RUB", Numeric: "643", MinorUnits: 2, Symbol: "₽"},
+	{Code: "RWF", Numeric: "646", MinorUnits: 0, Symbol: "FRw"},
+	{Code: "SAR", Numeric: "682", MinorUnits: 2, Symbol: "ر.س"},
+	{Code: "SBD", Numeric: "090", MinorUnits: 2, Symbol: "$"},
+	{Code: "SCR", Numeric: "690", MinorUnits: 2, Symbol: "₨"},
+	{Code: "SDG", Numeric: "938", MinorUnits: 2, Symbol: "ج.س."},
+	{Code: "SEK", Numeric: "752", MinorUnits: 2, Symbol: "kr"},
+	{Code: "SGD", Numeric: "702", MinorUnits: 2, Symbol: "S$"},
+	{Code: "SHP", Numeric: "654", MinorUnits: 2, Symbol: "£"},
+	{Code: "SLE", Numeric: "925", MinorUnits: 2, Symbol: "Le"},
+	{Code: "SOS", Numeric: "706", MinorUnits: 2, Symbol: "Sh"},
+	{Code: "SRD", Numeric: "968", MinorUnits: 2, Symbol: "$"},
+	{Code: "SSP", Numeric: "728", MinorUnits: 2, Symbol: "£"},
+	{Code: "STN", Numeric: "930", MinorUnits: 2, Symbol: "Db"},
+	{Code: "SVC", Numeric: "222", MinorUnits: 2, Symbol: "₡"},
+	{Code: "SYP", Numeric: "760", MinorUnits: 2, Symbol: "£"},
+	{Code: "SZL", Numeric: "748", MinorUnits: 2, Symbol: "L"},
+	{Code: "THB", Numeric: "764", MinorUnits: 2, Symbol: "฿"},
+	{Code: "TJS", Numeric: "972", MinorUnits: 2, Symbol: "SM"},
+	{Code: "TMT", Numeric: "934", MinorUnits: 2, Symbol: "m"},
+	{Code: "TND", Numeric: "788", MinorUnits: 3, Symbol: "د.ت"},
+	{Code: "TOP", Numeric: "776", MinorUnits: 2, Symbol: "T$"},
+	{Code: "TRY", Numeric: "949", MinorUnits: 2, Symbol: "₺"},
+	{Code: "TTD", Numeric: "780", MinorUnits: 2, Symbol: "$"},
+	{Code: "TWD", Numeric: "901", MinorUnits: 2, Symbol: "NT$"},
+	{Code: "TZS", Numeric: "834", MinorUnits: 2, Symbol: "TSh"},
+	{Code: "UAH", Numeric: "980", MinorUnits: 2, Symbol: "₴"},
+	{Code: "UGX", Numeric: "800", MinorUnits: 0, Symbol: "USh"},
+	{Code: "USD", Numeric: "840", MinorUnits: 2, Symbol: "$"},
+	{Code: "USN", Numeric: "997", MinorUnits: 2, Symbol: "$"},
+	{Code: "UYI", Numeric: "940", MinorUnits: 0, Symbol: "UYI"},
+	{Code: "UYU", Numeric: "858", MinorUnits: 2, Symbol: "$U"},
+	{Code: "UYW", Numeric: "927", MinorUnits: 4, Symbol: "UYW"},
+	{Code: "UZS", Numeric: "860", MinorUnits: 2, Symbol: "soʻm"},
+	{Code: "VED", Numeric: "926", MinorUnits: 2, Symbol: "Bs."},
+	{Code: "VES", Numeric: "928", MinorUnits: 2, Symbol: "Bs."},
+	{Code: "VND", Numeric: "704", MinorUnits: 0, Symbol: "₫"},
+	{Code: "VUV", Numeric: "548", MinorUnits: 0, Symbol: "VT"},
+	{Code: "WST", Numeric: "882", MinorUnits: 2, Symbol: "T"},
+	{Code: "XAF", Numeric: "950", MinorUnits: 0, Symbol: "FCFA"},
+	{Code: "XCD", Numeric: "951", MinorUnits: 2, Symbol: "$"},
+	{Code: "XCG", Numeric: "532", MinorUnits: 2, Symbol: "Cg"},
+	{Code: "XOF", Numeric: "952", MinorUnits: 0, Symbol: "CFA"},
+	{Code: "XPF", Numeric: "953", MinorUnits: 0, Symbol: "₣"},
+	{Code: "YER", Numeric: "886", MinorUnits: 2, Symbol: "﷼"},
+	{Code: "ZAR", Numeric: "710", MinorUnits: 2, Symbol: "R"},
+	{Code: "ZMW", Numeric: "967", MinorUnits: 2, Symbol: "ZK"},
+	{Code: "ZWG", Numeric: "924", MinorUnits: 2, Symbol: "ZiG"},
+}
@@ -0,0 +1,201 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrAmountOverflow   = errors.New("amount out of range")
+	ErrInvalidAmount    = errors.New("invalid amount")
+)
+
+// Money is an amount in the minor units of its currency, such as cents for USD.
+// The arithmetic methods fail rather than wrap around on overflow.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+// NewMoney pairs an amount in minor units with the currency of the code.
+func NewMoney(amount int64, code string) (Money, error) {
+	var currency, ok = LookupCurrency(code)
+	if !ok {
+		return Money{}, fmt.Errorf("%w %q", ErrUnknownCurrency, code)
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// ParseMoney parses a decimal amount in units of the currency, such as "-12.30" for USD.
+// More decimals than the currency has minor units are refused rather than rounded.
+func ParseMoney(s string, code string) (Money, error) {
+	var money, err = NewMoney(0, code)
+	if err != nil {
+		return money, err
+	}
+
+	var digits, sign = s, ""
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	var units, fraction, hasPoint = strings.Cut(digits, ".")
+	if !isDigits(units) || (hasPoint && !isDigits(fraction)) {
+		return money, fmt.Errorf("%w %q: want a decimal number", ErrInvalidAmount, s)
+	}
+	if len(fraction) > money.Currency.MinorUnits {
+		return money, fmt.Errorf("%w %q: %s has %d decimals", ErrInvalidAmount, s, code, money.Currency.MinorUnits)
+	}
+
+	fraction += strings.Repeat("0", money.Currency.MinorUnits-len(fraction))
+	money.Amount, err = strconv.ParseInt(sign+units+fraction, 10, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return money, fmt.Errorf("%w: %s", ErrAmountOverflow, s)
+	}
+	return money, err
+}
+
+// MustParseMoney is like ParseMoney but panics on error. It is meant for constants and tests.
+func MustParseMoney(s string, code string) Money {
+	var money, err = ParseMoney(s, code)
+	if err != nil {
+		panic(err)
+	}
+	return money
+}
+
+// FormatMinorUnits formats an amount in minor units as a decimal string in units of the currency, such as "-12.30".
+// Amounts in a currency missing from the registry are left in minor units.
+func FormatMinorUnits(amount int64, code string) string {
+	var money, err = NewMoney(amount, code)
+	if err != nil {
+		return strconv.FormatInt(amount, 10)
+	}
+	return money.Decimal()
+}
+
+// Decimal formats the amount in units of the currency, such as "-12.30".
+func (money Money) Decimal() string {
+	var sign = ""
+	var amount = uint64(money.Amount)
+	if money.Amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	var digits = strconv.FormatUint(amount, 10)
+	var minorUnits = money.Currency.MinorUnits
+	if minorUnits == 0 {
+		return sign + digits
+	}
+	if len(digits) <= minorUnits {
+		digits = strings.Repeat("0", minorUnits-len(digits)+1) + digits
+	}
+	var point = len(digits) - minorUnits
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// String formats the amount with its currency code, such as "12.30 USD".
+func (money Money) String() string {
+	return money.Decimal() + " " + money.Currency.Code
+}
+
+// Format formats the amount with the display symbol of its currency, such as "-$12.30".
+func (money Money) Format() string {
+	var decimal = money.Decimal()
+	if strings.HasPrefix(decimal, "-") {
+		return "-" + money.Currency.Symbol + decimal[1:]
+	}
+	return money.Currency.Symbol + decimal
+}
+
+// IsZero reports whether the amount is zero.
+func (money Money) IsZero() bool {
+	return money.Amount == 0
+}
+
+// IsNegative reports whether the amount is below zero.
+func (money Money) IsNegative() bool {
+	return money.Amount < 0
+}
+
+// Add returns money + other, which must be in the same currency.
+func (money Money) Add(other Money) (Money, error) {
+	if err := money.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	if (other.Amount > 0 && money.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && money.Amount < math.MinInt64-other.Amount) {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrAmountOverflow, money, other)
+	}
+	return Money{Amount: money.Amount + other.Amount, Currency: money.Currency}, nil
+}
+
+// Sub returns money - other, which must be in the same currency.
+func (money Money) Sub(other Money) (Money, error) {
+	if err := money.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	if (other.Amount < 0 && money.Amount > math.MaxInt64+other.Amount) ||
+		(other.Amount > 0 && money.Amount < math.MinInt64+other.Amount) {
+		return Money{}, fmt.Errorf("%w: %s - %s", ErrAmountOverflow, money, other)
+	}
+	return Money{Amount: money.Amount - other.Amount, Currency: money.Currency}, nil
+}
+
+// Neg returns -money.
+func (money Money) Neg() (Money, error) {
+	if money.Amount == math.MinInt64 {
+		return Money{}, fmt.Errorf("%w: -(%s)", ErrAmountOverflow, money)
+	}
+	return Money{Amount: -money.Amount, Currency: money.Currency}, nil
+}
+
+// Mul returns money * n.
+func (money Money) Mul(n int64) (Money, error) {
+	if money.Amount == 0 || n == 0 {
+		return Money{Currency: money.Currency}, nil
+	}
+	var product = money.Amount * n
+	if product/n != money.Amount || (n == -1 && money.Amount == math.MinInt64) {
+		return Money{}, fmt.Errorf("%w: %s * %d", ErrAmountOverflow, money, n)
+	}
+	return Money{Amount: product, Currency: money.Currency}, nil
+}
+
+// Cmp compares money with other, which must be in the same currency, returning -1, 0 or +1.
+func (money Money) Cmp(other Money) (int, error) {
+	if err := money.sameCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case money.Amount < other.Amount:
+		return -1, nil
+	case money.Amount > other.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (money Money) sameCurrency(other Money) error {
+	if money.Currency.Code != other.Currency.Code {
+		return fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, money.Currency.Code, other.Currency.Code)
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
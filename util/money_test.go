@@ -0,0 +1,138 @@
+package util
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	var testCases = []struct {
+		input    string
+		currency string
+		amount   int64
+		err      error
+	}{
+		{input: "12.34", currency: USD, amount: 1234},
+		{input: "12.3", currency: USD, amount: 1230},
+		{input: "12", currency: USD, amount: 1200},
+		{input: "-0.05", currency: USD, amount: -5},
+		{input: "+7.50", currency: EUR, amount: 750},
+		{input: "500", currency: "JPY", amount: 500},
+		{input: "1.234", currency: "KWD", amount: 1234},
+		{input: "92233720368547758.07", currency: USD, amount: math.MaxInt64},
+		{input: "-92233720368547758.08", currency: USD, amount: math.MinInt64},
+		{input: "92233720368547758.08", currency: USD, err: ErrAmountOverflow},
+		{input: "1.234", currency: USD, err: ErrInvalidAmount},
+		{input: "1.5", currency: "JPY", err: ErrInvalidAmount},
+		{input: "", currency: USD, err: ErrInvalidAmount},
+		{input: "1.", currency: USD, err: ErrInvalidAmount},
+		{input: ".5", currency: USD, err: ErrInvalidAmount},
+		{input: "1,000.00", currency: USD, err: ErrInvalidAmount},
+		{input: "1e3", currency: USD, err: ErrInvalidAmount},
+		{input: "--1", currency: USD, err: ErrInvalidAmount},
+		{input: "1", currency: "ABC", err: ErrUnknownCurrency},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input+" "+tc.currency, func(t *testing.T) {
+			var money, err = ParseMoney(tc.input, tc.currency)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.amount, money.Amount)
+			require.Equal(t, tc.currency, money.Currency.Code)
+		})
+	}
+}
+
+func TestMoneyFormat(t *testing.T) {
+	var testCases = []struct {
+		amount   int64
+		currency string
+		decimal  string
+		format   string
+	}{
+		{amount: 1234, currency: USD, decimal: "12.34", format: "$12.34"},
+		{amount: -5, currency: USD, decimal: "-0.05", format: "-$0.05"},
+		{amount: 0, currency: EUR, decimal: "0.00", format: "€0.00"},
+		{amount: 500, currency: "JPY", decimal: "500", format: "¥500"},
+		{amount: 1, currency: "KWD", decimal: "0.001", format: "د.ك0.001"},
+		{amount: math.MinInt64, currency: USD, decimal: "-92233720368547758.08", format: "-$92233720368547758.08"},
+	}
+
+	for _, tc := range testCases {
+		var money, err = NewMoney(tc.amount, tc.currency)
+		require.NoError(t, err)
+		require.Equal(t, tc.decimal, money.Decimal())
+		require.Equal(t, tc.format, money.Format())
+		require.Equal(t, tc.decimal+" "+tc.currency, money.String())
+		require.Equal(t, tc.decimal, FormatMinorUnits(tc.amount, tc.currency))
+
+		parsed, err := ParseMoney(money.Decimal(), tc.currency)
+		require.NoError(t, err)
+		require.Equal(t, money, parsed)
+	}
+}
+
+func TestFormatMinorUnitsUnknownCurrency(t *testing.T) {
+	require.Equal(t, "-1234", FormatMinorUnits(-1234, "XXY"))
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	var a = MustParseMoney("10.50", USD)
+	var b = MustParseMoney("0.75", USD)
+
+	var sum, err = a.Add(b)
+	require.NoError(t, err)
+	require.Equal(t, "11.25", sum.Decimal())
+
+	diff, err := b.Sub(a)
+	require.NoError(t, err)
+	require.Equal(t, "-9.75", diff.Decimal())
+	require.True(t, diff.IsNegative())
+
+	neg, err := diff.Neg()
+	require.NoError(t, err)
+	require.Equal(t, "9.75", neg.Decimal())
+
+	product, err := b.Mul(-3)
+	require.NoError(t, err)
+	require.Equal(t, "-2.25", product.Decimal())
+
+	cmp, err := a.Cmp(b)
+	require.NoError(t, err)
+	require.Equal(t, 1, cmp)
+
+	_, err = a.Add(MustParseMoney("1", EUR))
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = a.Cmp(MustParseMoney("1", EUR))
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestMoneyOverflow(t *testing.T) {
+	var maxMoney = Money{Amount: math.MaxInt64, Currency: currencyRegistry[USD]}
+	var minMoney = Money{Amount: math.MinInt64, Currency: currencyRegistry[USD]}
+	var one = MustParseMoney("0.01", USD)
+
+	var _, err = maxMoney.Add(one)
+	require.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = minMoney.Sub(one)
+	require.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = one.Sub(minMoney)
+	require.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = minMoney.Neg()
+	require.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = minMoney.Mul(-1)
+	require.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = maxMoney.Mul(2)
+	require.ErrorIs(t, err, ErrAmountOverflow)
+
+	var sum Money
+	sum, err = maxMoney.Add(Money{Amount: -1, Currency: maxMoney.Currency})
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MaxInt64-1), sum.Amount)
+}
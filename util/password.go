@@ -1,21 +1,116 @@
 package util
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword returns the bcrypt hash of the password at the given cost.
+// Supported password hashing algorithms.
+const (
+	Argon2idAlgorithm = "argon2id"
+	BcryptAlgorithm   = "bcrypt"
+)
+
+var (
+	ErrMismatchedPassword  = errors.New("password does not match")
+	ErrUnknownPasswordHash = errors.New("unknown password hash format")
+)
+
+var defaultPasswordHasher PasswordHasher = NewArgon2idHasher(DefaultArgon2idParams)
+
+// PasswordHasher hashes passwords with a preferred algorithm and parameters.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of the password.
+	Hash(password string) (string, error)
+	// NeedsRehash reports whether the hash was made with another algorithm or other parameters.
+	NeedsRehash(hashedPassword string) bool
+}
+
+// NewPasswordHasher creates the password hasher selected by the config.
+// Zero parameters fall back to the defaults.
+func NewPasswordHasher(config Config) (PasswordHasher, error) {
+	switch config.PasswordHashAlgorithm {
+	case "", Argon2idAlgorithm:
+		var params = DefaultArgon2idParams
+		if config.Argon2Memory > 0 {
+			params.Memory = config.Argon2Memory
+		}
+		if config.Argon2Iterations > 0 {
+			params.Iterations = config.Argon2Iterations
+		}
+		if config.Argon2Parallelism > 0 {
+			params.Parallelism = config.Argon2Parallelism
+		}
+		return NewArgon2idHasher(params), nil
+	case BcryptAlgorithm:
+		var cost = config.BcryptCost
+		if cost == 0 {
+			cost = bcrypt.DefaultCost
+		}
+		if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("invalid bcrypt cost %d", cost)
+		}
+		return NewBcryptHasher(cost), nil
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", config.PasswordHashAlgorithm)
+	}
+}
+
+// HashPassword returns the hash of the password using argon2id with the default parameters.
 func HashPassword(password string) (string, error) {
-	var hashedPassword, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return defaultPasswordHasher.Hash(password)
+}
+
+// CheckPassword checks if the provided password matches the hashed password.
+// The algorithm is detected from the hash, so bcrypt and argon2id hashes are both accepted.
+func CheckPassword(password, hashedPassword string) error {
+	switch {
+	case strings.HasPrefix(hashedPassword, "$argon2id$"):
+		return checkArgon2id(password, hashedPassword)
+	case isBcryptHash(hashedPassword):
+		var err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatchedPassword
+		}
+		return err
+	default:
+		return ErrUnknownPasswordHash
+	}
+}
+
+// BcryptHasher hashes passwords with bcrypt at a fixed cost.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher creates a bcrypt hasher with the given cost.
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{cost: cost}
+}
+
+// Hash returns the bcrypt hash of the password.
+func (hasher *BcryptHasher) Hash(password string) (string, error) {
+	var hashedPassword, err = bcrypt.GenerateFromPassword([]byte(password), hasher.cost)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash password: %w", err)
 	}
 	return string(hashedPassword), nil
 }
 
-// CheckPassword checks if the provided password matches the hashed password.
-func CheckPassword(password, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+// NeedsRehash reports whether the hash is not a bcrypt hash of the configured cost.
+func (hasher *BcryptHasher) NeedsRehash(hashedPassword string) bool {
+	if !isBcryptHash(hashedPassword) {
+		return true
+	}
+	var cost, err = bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != hasher.cost
+}
+
+func isBcryptHash(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$2a$") ||
+		strings.HasPrefix(hashedPassword, "$2b$") ||
+		strings.HasPrefix(hashedPassword, "$2y$")
 }
@@ -0,0 +1,93 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// minPasswordLength is the floor of every password policy.
+const minPasswordLength = 6
+
+// ErrPasswordContainsIdentifier is returned when a password contains the username or email.
+var ErrPasswordContainsIdentifier = errors.New("must not contain the username or email")
+
+// PasswordPolicy describes what a new password must look like.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// NewPasswordPolicy creates the password policy from the config.
+func NewPasswordPolicy(config Config) PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:     max(config.PasswordMinLength, minPasswordLength),
+		RequireUpper:  config.PasswordRequireUpper,
+		RequireLower:  config.PasswordRequireLower,
+		RequireDigit:  config.PasswordRequireDigit,
+		RequireSymbol: config.PasswordRequireSymbol,
+	}
+}
+
+// Validate returns the first rule the password breaks, or nil.
+// identifiers are user details such as the username and email that must not appear in the password.
+func (policy PasswordPolicy) Validate(password string, identifiers ...string) error {
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("must be at least %d characters long", policy.MinLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	switch {
+	case policy.RequireUpper && !hasUpper:
+		return errors.New("must contain an uppercase letter")
+	case policy.RequireLower && !hasLower:
+		return errors.New("must contain a lowercase letter")
+	case policy.RequireDigit && !hasDigit:
+		return errors.New("must contain a digit")
+	case policy.RequireSymbol && !hasSymbol:
+		return errors.New("must contain a symbol")
+	}
+
+	var lowerPassword = strings.ToLower(password)
+	for _, identifier := range expandIdentifiers(identifiers) {
+		if strings.Contains(lowerPassword, identifier) {
+			return ErrPasswordContainsIdentifier
+		}
+	}
+	return nil
+}
+
+// expandIdentifiers lower-cases the identifiers and adds the local part of emails.
+// Very short values are skipped since they would reject too many passwords.
+func expandIdentifiers(identifiers []string) []string {
+	var result []string
+	for _, identifier := range identifiers {
+		identifier = strings.ToLower(strings.TrimSpace(identifier))
+		var candidates = []string{identifier}
+		if local, _, ok := strings.Cut(identifier, "@"); ok {
+			candidates = append(candidates, local)
+		}
+		for _, candidate := range candidates {
+			if len(candidate) >= 3 {
+				result = append(result, candidate)
+			}
+		}
+	}
+	return result
+}
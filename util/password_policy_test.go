@@ -0,0 +1,56 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	var policy = PasswordPolicy{
+		MinLength:     8,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}
+
+	var testCases = []struct {
+		name     string
+		password string
+		err      string
+	}{
+		{name: "OK", password: "Tr0ub4dor&3"},
+		{name: "TooShort", password: "Ab1!", err: "must be at least 8 characters long"},
+		{name: "NoUpper", password: "tr0ub4dor&3", err: "must contain an uppercase letter"},
+		{name: "NoLower", password: "TR0UB4DOR&3", err: "must contain a lowercase letter"},
+		{name: "NoDigit", password: "Troubador&x", err: "must contain a digit"},
+		{name: "NoSymbol", password: "Tr0ub4dor33", err: "must contain a symbol"},
+		{name: "ContainsUsername", password: "xX!1Alice99", err: ErrPasswordContainsIdentifier.Error()},
+		{name: "ContainsEmailLocalPart", password: "Bob.Smith!1", err: ErrPasswordContainsIdentifier.Error()},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err = policy.Validate(tc.password, "alice", "bob.smith@example.com")
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestPasswordPolicyShortIdentifier(t *testing.T) {
+	// two letter usernames would reject far too many passwords
+	require.NoError(t, PasswordPolicy{MinLength: 6}.Validate("jordan", "jo"))
+}
+
+func TestNewPasswordPolicy(t *testing.T) {
+	require.Equal(t, PasswordPolicy{MinLength: 6}, NewPasswordPolicy(Config{}))
+	require.Equal(t, PasswordPolicy{MinLength: 12, RequireDigit: true}, NewPasswordPolicy(Config{
+		PasswordMinLength:    12,
+		PasswordRequireDigit: true,
+	}))
+}
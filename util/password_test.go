@@ -1,6 +1,7 @@
 package util
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -12,6 +13,7 @@ func TestHashPassword(t *testing.T) {
 	var hashedPassword, err = HashPassword(password)
 	require.NoError(t, err)
 	require.NotEmpty(t, hashedPassword)
+	require.True(t, strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=19456,t=2,p=1$"))
 
 	err = CheckPassword(password, hashedPassword)
 	require.NoError(t, err)
@@ -19,9 +21,83 @@ func TestHashPassword(t *testing.T) {
 
 	var wrongPassword = RandomString(6)
 	err = CheckPassword(wrongPassword, hashedPassword)
-	require.EqualError(t, err, bcrypt.ErrMismatchedHashAndPassword.Error())
+	require.EqualError(t, err, ErrMismatchedPassword.Error())
+
+	// the same password gets a different salt every time
+	otherHashedPassword, err := HashPassword(password)
+	require.NoError(t, err)
+	require.NotEqual(t, hashedPassword, otherHashedPassword)
 }
 
 func TestCheckPassword(t *testing.T) {
+	var password = RandomString(6)
+
+	var bcryptHash, err = NewBcryptHasher(bcrypt.MinCost).Hash(password)
+	require.NoError(t, err)
+	require.NoError(t, CheckPassword(password, bcryptHash))
+	require.ErrorIs(t, CheckPassword(RandomString(6), bcryptHash), ErrMismatchedPassword)
+
+	argon2idHash, err := NewArgon2idHasher(Argon2idParams{
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  8,
+		KeyLength:   16,
+	}).Hash(password)
+	require.NoError(t, err)
+	require.NoError(t, CheckPassword(password, argon2idHash))
+	require.ErrorIs(t, CheckPassword(RandomString(6), argon2idHash), ErrMismatchedPassword)
+
+	require.ErrorIs(t, CheckPassword(password, password), ErrUnknownPasswordHash)
+	require.Error(t, CheckPassword(password, "$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5"))
+	require.Error(t, CheckPassword(password, "$argon2id$v=18$m=1024,t=1,p=1$c2FsdA$a2V5"))
+	require.Error(t, CheckPassword(password, "$argon2id$v=19$m=1024,t=1,p=1$!!$a2V5"))
+}
+
+func TestNeedsRehash(t *testing.T) {
+	var password = RandomString(6)
+	var argon2idHasher = NewArgon2idHasher(DefaultArgon2idParams)
+	var bcryptHasher = NewBcryptHasher(bcrypt.MinCost)
+
+	var argon2idHash, err = argon2idHasher.Hash(password)
+	require.NoError(t, err)
+	bcryptHash, err := bcryptHasher.Hash(password)
+	require.NoError(t, err)
+
+	require.False(t, argon2idHasher.NeedsRehash(argon2idHash))
+	require.True(t, argon2idHasher.NeedsRehash(bcryptHash))
+	require.False(t, bcryptHasher.NeedsRehash(bcryptHash))
+	require.True(t, bcryptHasher.NeedsRehash(argon2idHash))
+
+	var strongerParams = DefaultArgon2idParams
+	strongerParams.Iterations++
+	require.True(t, NewArgon2idHasher(strongerParams).NeedsRehash(argon2idHash))
+	require.True(t, NewBcryptHasher(bcrypt.MinCost+1).NeedsRehash(bcryptHash))
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	var hasher, err = NewPasswordHasher(Config{})
+	require.NoError(t, err)
+	require.Equal(t, NewArgon2idHasher(DefaultArgon2idParams), hasher)
+
+	hasher, err = NewPasswordHasher(Config{
+		PasswordHashAlgorithm: Argon2idAlgorithm,
+		Argon2Memory:          1024,
+		Argon2Iterations:      3,
+		Argon2Parallelism:     2,
+	})
+	require.NoError(t, err)
+	hashedPassword, err := hasher.Hash(RandomString(6))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=1024,t=3,p=2$"))
+
+	hasher, err = NewPasswordHasher(Config{PasswordHashAlgorithm: BcryptAlgorithm})
+	require.NoError(t, err)
+	require.Equal(t, NewBcryptHasher(bcrypt.DefaultCost), hasher)
+
+	_, err = NewPasswordHasher(Config{PasswordHashAlgorithm: BcryptAlgorithm, BcryptCost: 100})
+	require.Error(t, err)
 
+	_, err = NewPasswordHasher(Config{PasswordHashAlgorithm: "md5"})
+	require.Error(t, err)
 }
@@ -0,0 +1,9 @@
+package util
+
+// List of user roles
+const (
+	DepositorRole = "depositor"
+	AdminRole     = "admin"
+	// SystemRole is the role of the user owning the internal accounts of the bank, who never logs in.
+	SystemRole = "system"
+)
@@ -0,0 +1,87 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// RandomSecret generates a cryptographically secure random string from n random bytes.
+// Use it for anything that must not be guessable, such as verification codes.
+func RandomSecret(n int) (string, error) {
+	var buf = make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashSecret returns the hex encoded SHA-256 of a high entropy secret, so it can be stored and looked up
+// without keeping the secret itself. Do not use it for passwords.
+func HashSecret(secret string) string {
+	var sum = sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// recoveryCodeAlphabet avoids characters that are easy to confuse when typed from paper.
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// RandomRecoveryCode generates a one-time recovery code formatted as "xxxxx-xxxxx".
+func RandomRecoveryCode() (string, error) {
+	var alphabetSize = big.NewInt(int64(len(recoveryCodeAlphabet)))
+	var code = make([]byte, 0, 11)
+	for i := range 10 {
+		if i == 5 {
+			code = append(code, '-')
+		}
+		var n, err = rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code = append(code, recoveryCodeAlphabet[n.Int64()])
+	}
+	return string(code), nil
+}
+
+// NormalizeRecoveryCode lower-cases a recovery code and drops separators, so it hashes
+// the same however the user typed it.
+func NormalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, code)
+}
+
+const apiKeyScheme = "sbk"
+
+// RandomAPIKey generates an API key formatted as "sbk_<prefix>_<secret>".
+// The prefix is not secret and is stored as is to look the key up.
+func RandomAPIKey() (key string, prefix string, err error) {
+	var buf = make([]byte, 6)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	prefix = hex.EncodeToString(buf)
+
+	secret, err := RandomSecret(32)
+	if err != nil {
+		return "", "", err
+	}
+	return apiKeyScheme + "_" + prefix + "_" + secret, prefix, nil
+}
+
+// APIKeyPrefix returns the lookup prefix of an API key made by RandomAPIKey.
+func APIKeyPrefix(key string) (string, bool) {
+	var parts = strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyScheme || len(parts[1]) != 12 || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidCiphertext is returned when a sealed secret cannot be decrypted.
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// SecretBox encrypts small secrets such as TOTP seeds with AES-256-GCM before they are stored.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox creates a SecretBox from a 32 byte key.
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid key size: must be exactly 32 bytes")
+	}
+	var block, err = aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal encrypts the plaintext and returns the base64 encoded nonce and ciphertext.
+// The associated data, e.g. the owner's username, must be given again to Open,
+// so a sealed secret cannot be moved to another row.
+func (box *SecretBox) Seal(plaintext string, associatedData string) (string, error) {
+	var nonce = make([]byte, box.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	var sealed = box.aead.Seal(nonce, nonce, []byte(plaintext), []byte(associatedData))
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a secret produced by Seal.
+func (box *SecretBox) Open(sealed string, associatedData string) (string, error) {
+	var data, err = base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < box.aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+	var nonce, ciphertext = data[:box.aead.NonceSize()], data[box.aead.NonceSize():]
+	plaintext, err := box.aead.Open(nil, nonce, ciphertext, []byte(associatedData))
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
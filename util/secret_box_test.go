@@ -0,0 +1,43 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretBox(t *testing.T) {
+	var box, err = NewSecretBox([]byte(RandomString(32)))
+	require.NoError(t, err)
+
+	var secret = RandomString(20)
+	sealed, err := box.Seal(secret, "alice")
+	require.NoError(t, err)
+	require.NotContains(t, sealed, secret)
+
+	opened, err := box.Open(sealed, "alice")
+	require.NoError(t, err)
+	require.Equal(t, secret, opened)
+
+	// a fresh nonce is used every time
+	other, err := box.Seal(secret, "alice")
+	require.NoError(t, err)
+	require.NotEqual(t, sealed, other)
+
+	_, err = box.Open(sealed, "bob")
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+	_, err = box.Open("not base64!", "alice")
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+	_, err = box.Open("", "alice")
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	otherBox, err := NewSecretBox([]byte(RandomString(32)))
+	require.NoError(t, err)
+	_, err = otherBox.Open(sealed, "alice")
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+}
+
+func TestNewSecretBoxInvalidKey(t *testing.T) {
+	var _, err = NewSecretBox([]byte(RandomString(16)))
+	require.Error(t, err)
+}
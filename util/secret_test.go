@@ -0,0 +1,72 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandomSecret(t *testing.T) {
+	var secret1, err = RandomSecret(32)
+	require.NoError(t, err)
+	require.Len(t, secret1, 43)
+
+	secret2, err := RandomSecret(32)
+	require.NoError(t, err)
+	require.NotEqual(t, secret1, secret2)
+}
+
+func TestHashSecret(t *testing.T) {
+	var secret, err = RandomSecret(32)
+	require.NoError(t, err)
+
+	var hash = HashSecret(secret)
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, HashSecret(secret))
+	require.NotEqual(t, hash, HashSecret(secret+"x"))
+}
+
+func TestRandomRecoveryCode(t *testing.T) {
+	var code, err = RandomRecoveryCode()
+	require.NoError(t, err)
+	require.Regexp(t, `^[a-z2-9]{5}-[a-z2-9]{5}$`, code)
+
+	other, err := RandomRecoveryCode()
+	require.NoError(t, err)
+	require.NotEqual(t, code, other)
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	require.Equal(t, "abcde23456", NormalizeRecoveryCode("abcde-23456"))
+	require.Equal(t, "abcde23456", NormalizeRecoveryCode(" ABCDE 23456 "))
+}
+
+func TestRandomAPIKey(t *testing.T) {
+	var key, prefix, err = RandomAPIKey()
+	require.NoError(t, err)
+	require.Len(t, prefix, 12)
+	require.True(t, strings.HasPrefix(key, "sbk_"+prefix+"_"))
+
+	parsed, ok := APIKeyPrefix(key)
+	require.True(t, ok)
+	require.Equal(t, prefix, parsed)
+
+	key2, prefix2, err := RandomAPIKey()
+	require.NoError(t, err)
+	require.NotEqual(t, key, key2)
+	require.NotEqual(t, prefix, prefix2)
+}
+
+func TestAPIKeyPrefix(t *testing.T) {
+	for _, key := range []string{
+		"",
+		"sbk_0123456789ab",
+		"sbk_0123456789ab_",
+		"abc_0123456789ab_secret",
+		"sbk_0123_secret",
+	} {
+		var _, ok = APIKeyPrefix(key)
+		require.False(t, ok, key)
+	}
+}
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+)
+
+const defaultMaxAttempts = 10
+
+// TaskDistributor enqueues tasks for the TaskProcessor.
+// Tasks are rows in the tasks table, so passing the Querier of an open transaction
+// makes the task visible only if that transaction commits.
+type TaskDistributor interface {
+	DistributeTaskSendVerifyEmail(ctx context.Context, q db.Querier, payload *PayloadSendVerifyEmail, opts ...Option) error
+	DistributeTaskSendResetPassword(ctx context.Context, q db.Querier, payload *PayloadSendResetPassword, opts ...Option) error
+	DistributeTaskAccrueInterest(ctx context.Context, q db.Querier, payload *PayloadAccrueInterest, opts ...Option) error
+	DistributeTaskPostInterest(ctx context.Context, q db.Querier, payload *PayloadPostInterest, opts ...Option) error
+	DistributeTaskChargeMaintenanceFees(ctx context.Context, q db.Querier, payload *PayloadChargeMaintenanceFees, opts ...Option) error
+}
+
+// Option customizes how a task is enqueued.
+type Option func(arg *db.CreateTaskParams)
+
+// MaxAttempts sets how many times the task is tried before it is marked as failed.
+func MaxAttempts(n int32) Option {
+	return func(arg *db.CreateTaskParams) {
+		arg.MaxAttempts = n
+	}
+}
+
+// ProcessIn delays the first attempt of the task.
+func ProcessIn(delay time.Duration) Option {
+	return func(arg *db.CreateTaskParams) {
+		arg.RunAt = arg.RunAt.Add(delay)
+	}
+}
+
+// PGTaskDistributor is a TaskDistributor backed by the tasks table in Postgres
+type PGTaskDistributor struct{}
+
+// NewPGTaskDistributor creates a new PGTaskDistributor
+func NewPGTaskDistributor() TaskDistributor {
+	return &PGTaskDistributor{}
+}
+
+func (distributor *PGTaskDistributor) enqueue(
+	ctx context.Context,
+	q db.Querier,
+	taskType string,
+	payload any,
+	opts ...Option,
+) (db.Task, error) {
+	var data, err = json.Marshal(payload)
+	if err != nil {
+		return db.Task{}, fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+
+	var arg = db.CreateTaskParams{
+		Type:        taskType,
+		Payload:     data,
+		MaxAttempts: defaultMaxAttempts,
+		RunAt:       time.Now(),
+	}
+	for _, opt := range opts {
+		opt(&arg)
+	}
+
+	task, err := q.CreateTask(ctx, arg)
+	if err != nil {
+		return db.Task{}, fmt.Errorf("failed to enqueue task: %w", err)
+	}
+	return task, nil
+}
@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/mail"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestProcessTaskChargeMaintenanceFees(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var month = time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListMaintenanceFeeAccounts(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.ListMaintenanceFeeAccountsParams) ([]int64, error) {
+			require.Equal(t, time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC), arg.PeriodEnd)
+			require.Zero(t, arg.AfterID)
+			return []int64{4, 9}, nil
+		})
+	for _, id := range []int64{4, 9} {
+		store.EXPECT().ChargeMaintenanceFeeTx(gomock.Any(), gomock.Eq(db.ChargeMaintenanceFeeTxParams{AccountID: id, Period: month})).
+			Times(1).Return(db.FeeCharge{}, nil)
+	}
+
+	var processor = NewPGTaskProcessor(util.Config{}, store, mail.NewMemoryMailer())
+	var task = newTask(t, TaskChargeMaintenanceFees, PayloadChargeMaintenanceFees{Month: "2023-02"}, 1)
+	require.NoError(t, processor.ProcessTaskChargeMaintenanceFees(context.Background(), task))
+
+	store.EXPECT().ListMaintenanceFeeAccounts(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+	var err = processor.ProcessTaskChargeMaintenanceFees(context.Background(), task)
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.NotErrorIs(t, err, ErrSkipRetry)
+
+	task = newTask(t, TaskChargeMaintenanceFees, PayloadChargeMaintenanceFees{Month: "2023-2"}, 1)
+	err = processor.ProcessTaskChargeMaintenanceFees(context.Background(), task)
+	require.ErrorIs(t, err, ErrSkipRetry)
+}
+
+func TestScheduleMaintenanceFees(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var now = time.Date(2023, time.April, 2, 8, 0, 0, 0, time.UTC)
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetLatestFeeRun(gomock.Any()).Times(1).
+		Return(db.FeeRun{Period: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)}, nil)
+
+	var scheduled []string
+	store.EXPECT().CreateFeeRunTx(gomock.Any(), gomock.Any()).Times(2).
+		DoAndReturn(func(_ context.Context, arg db.CreateFeeRunTxParams) (bool, error) {
+			var querier = mockdb.NewMockStore(ctrl)
+			querier.EXPECT().CreateTask(gomock.Any(), gomock.Any()).Times(1).
+				DoAndReturn(func(_ context.Context, task db.CreateTaskParams) (db.Task, error) {
+					require.Equal(t, TaskChargeMaintenanceFees, task.Type)
+					var payload PayloadChargeMaintenanceFees
+					require.NoError(t, json.Unmarshal(task.Payload, &payload))
+					require.Equal(t, arg.Period.Format(monthLayout), payload.Month)
+					return db.Task{}, nil
+				})
+			scheduled = append(scheduled, arg.Period.Format(monthLayout))
+			return true, arg.AfterCreate(querier)
+		})
+
+	var processor = NewPGTaskProcessor(util.Config{}, store, mail.NewMemoryMailer())
+	require.NoError(t, processor.scheduleMaintenanceFees(context.Background(), now))
+	require.Equal(t, []string{"2023-02", "2023-03"}, scheduled)
+
+	// nothing is looked up again until the month changes
+	require.NoError(t, processor.scheduleMaintenanceFees(context.Background(), now.AddDate(0, 0, 20)))
+
+	store.EXPECT().GetLatestFeeRun(gomock.Any()).Times(1).Return(db.FeeRun{}, sql.ErrConnDone)
+	require.ErrorIs(t, processor.scheduleMaintenanceFees(context.Background(), now.AddDate(0, 1, 0)), sql.ErrConnDone)
+}
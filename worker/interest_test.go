@@ -0,0 +1,246 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/mail"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newInterestProcessor(store db.Store) *PGTaskProcessor {
+	var processor = NewPGTaskProcessor(util.Config{}, store, mail.NewMemoryMailer())
+	processor.interestRates = map[string]int64{util.USD: 250}
+	return processor
+}
+
+func TestProcessTaskAccrueInterest(t *testing.T) {
+	var date = time.Date(2023, time.March, 14, 0, 0, 0, 0, time.UTC)
+
+	var testCases = []struct {
+		name       string
+		date       time.Time
+		buildStubs func(store *mockdb.MockStore)
+		checkErr   func(t *testing.T, err error)
+	}{
+		{
+			name: "OK",
+			date: date,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountBalancesAt(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ListAccountBalancesAtParams) ([]db.ListAccountBalancesAtRow, error) {
+						require.Equal(t, date.AddDate(0, 0, 1), arg.At)
+						require.Equal(t, db.AccountTypeSavings, arg.Type)
+						require.Zero(t, arg.AfterID)
+						return []db.ListAccountBalancesAtRow{
+							{ID: 1, Currency: util.USD, Balance: 100_000},
+							{ID: 2, Currency: util.USD, Balance: 0},
+							{ID: 3, Currency: util.EUR, Balance: 100_000},
+							{ID: 4, Currency: util.USD, Balance: -500},
+						}, nil
+					})
+				store.EXPECT().CreateInterestAccrual(gomock.Any(), gomock.Eq(db.CreateInterestAccrualParams{
+					AccountID:    1,
+					AccrualDate:  date,
+					Balance:      100_000,
+					RateBps:      250,
+					AmountMicros: 6_849_315,
+				})).Times(1).Return(int64(1), nil)
+				store.EXPECT().CreateTask(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "EndOfMonth",
+			date: time.Date(2023, time.March, 31, 0, 0, 0, 0, time.UTC),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountBalancesAt(gomock.Any(), gomock.Any()).Times(1).Return([]db.ListAccountBalancesAtRow{}, nil)
+				store.EXPECT().CreateTask(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateTaskParams) (db.Task, error) {
+						require.Equal(t, TaskPostInterest, arg.Type)
+						require.JSONEq(t, `{"month":"2023-03"}`, string(arg.Payload))
+						require.WithinDuration(t, time.Now().Add(postingDelay), arg.RunAt, time.Second)
+						return db.Task{}, nil
+					})
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "InternalError",
+			date: date,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountBalancesAt(gomock.Any(), gomock.Any()).Times(1).
+					Return([]db.ListAccountBalancesAtRow{{ID: 1, Currency: util.USD, Balance: 100_000}}, nil)
+				store.EXPECT().CreateInterestAccrual(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), sql.ErrConnDone)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+				require.NotErrorIs(t, err, ErrSkipRetry)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var store = mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			var processor = newInterestProcessor(store)
+			var task = newTask(t, TaskAccrueInterest, PayloadAccrueInterest{Date: tc.date.Format(time.DateOnly)}, 1)
+			tc.checkErr(t, processor.ProcessTaskAccrueInterest(context.Background(), task))
+		})
+	}
+}
+
+func TestProcessTaskAccrueInterestPages(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var page = make([]db.ListAccountBalancesAtRow, listPageSize)
+	for i := range page {
+		page[i] = db.ListAccountBalancesAtRow{ID: int64(i + 1), Currency: util.USD, Balance: 100}
+	}
+
+	var store = mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().ListAccountBalancesAt(gomock.Any(), gomock.Any()).Times(1).Return(page, nil),
+		store.EXPECT().ListAccountBalancesAt(gomock.Any(), gomock.Any()).Times(1).
+			DoAndReturn(func(_ context.Context, arg db.ListAccountBalancesAtParams) ([]db.ListAccountBalancesAtRow, error) {
+				require.Equal(t, int64(listPageSize), arg.AfterID)
+				return []db.ListAccountBalancesAtRow{}, nil
+			}),
+	)
+	store.EXPECT().CreateInterestAccrual(gomock.Any(), gomock.Any()).Times(listPageSize).Return(int64(1), nil)
+
+	var task = newTask(t, TaskAccrueInterest, PayloadAccrueInterest{Date: "2023-03-14"}, 1)
+	require.NoError(t, newInterestProcessor(store).ProcessTaskAccrueInterest(context.Background(), task))
+}
+
+func TestProcessTaskAccrueInterestInvalidDate(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var store = mockdb.NewMockStore(ctrl)
+	var task = newTask(t, TaskAccrueInterest, PayloadAccrueInterest{Date: "2023-13-01"}, 1)
+	var err = newInterestProcessor(store).ProcessTaskAccrueInterest(context.Background(), task)
+	require.ErrorIs(t, err, ErrSkipRetry)
+}
+
+func TestProcessTaskPostInterest(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var month = time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListUnpostedInterestAccounts(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.ListUnpostedInterestAccountsParams) ([]int64, error) {
+			require.Equal(t, month.AddDate(0, 1, 0), arg.ToDate)
+			return []int64{3, 8}, nil
+		})
+	for _, id := range []int64{3, 8} {
+		store.EXPECT().PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{AccountID: id, Period: month})).
+			Times(1).Return(db.PostInterestTxResult{}, nil)
+	}
+
+	var task = newTask(t, TaskPostInterest, PayloadPostInterest{Month: "2023-03"}, 1)
+	require.NoError(t, newInterestProcessor(store).ProcessTaskPostInterest(context.Background(), task))
+
+	store.EXPECT().ListUnpostedInterestAccounts(gomock.Any(), gomock.Any()).Times(1).Return([]int64{3}, nil)
+	store.EXPECT().PostInterestTx(gomock.Any(), gomock.Any()).Times(1).Return(db.PostInterestTxResult{}, sql.ErrConnDone)
+	var err = newInterestProcessor(store).ProcessTaskPostInterest(context.Background(), task)
+	require.ErrorIs(t, err, sql.ErrConnDone)
+
+	task = newTask(t, TaskPostInterest, PayloadPostInterest{Month: "March"}, 1)
+	err = newInterestProcessor(store).ProcessTaskPostInterest(context.Background(), task)
+	require.ErrorIs(t, err, ErrSkipRetry)
+}
+
+func TestScheduleInterestAccruals(t *testing.T) {
+	var now = time.Date(2023, time.March, 14, 9, 30, 0, 0, time.UTC)
+
+	var testCases = []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore) []string
+	}{
+		{
+			name: "FirstRun",
+			buildStubs: func(store *mockdb.MockStore) []string {
+				store.EXPECT().GetLatestInterestRun(gomock.Any()).Times(1).Return(db.InterestRun{}, db.ErrRecordNotFound)
+				return []string{"2023-03-13"}
+			},
+		},
+		{
+			name: "CatchUp",
+			buildStubs: func(store *mockdb.MockStore) []string {
+				store.EXPECT().GetLatestInterestRun(gomock.Any()).Times(1).
+					Return(db.InterestRun{AccrualDate: time.Date(2023, time.March, 10, 0, 0, 0, 0, time.UTC)}, nil)
+				return []string{"2023-03-11", "2023-03-12", "2023-03-13"}
+			},
+		},
+		{
+			name: "UpToDate",
+			buildStubs: func(store *mockdb.MockStore) []string {
+				store.EXPECT().GetLatestInterestRun(gomock.Any()).Times(1).
+					Return(db.InterestRun{AccrualDate: time.Date(2023, time.March, 13, 0, 0, 0, 0, time.UTC)}, nil)
+				return nil
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var store = mockdb.NewMockStore(ctrl)
+			var days = tc.buildStubs(store)
+
+			var scheduled []string
+			store.EXPECT().CreateInterestRunTx(gomock.Any(), gomock.Any()).Times(len(days)).
+				DoAndReturn(func(ctx context.Context, arg db.CreateInterestRunTxParams) (bool, error) {
+					var querier = mockdb.NewMockStore(ctrl)
+					querier.EXPECT().CreateTask(gomock.Any(), gomock.Any()).Times(1).
+						DoAndReturn(func(_ context.Context, task db.CreateTaskParams) (db.Task, error) {
+							require.Equal(t, TaskAccrueInterest, task.Type)
+							var payload PayloadAccrueInterest
+							require.NoError(t, json.Unmarshal(task.Payload, &payload))
+							require.Equal(t, arg.AccrualDate.Format(time.DateOnly), payload.Date)
+							return db.Task{}, nil
+						})
+					scheduled = append(scheduled, arg.AccrualDate.Format(time.DateOnly))
+					return true, arg.AfterCreate(querier)
+				})
+
+			var processor = newInterestProcessor(store)
+			require.NoError(t, processor.scheduleInterestAccruals(context.Background(), now))
+			require.Equal(t, days, scheduled)
+
+			// the day is only looked up again once it changes
+			require.NoError(t, processor.scheduleInterestAccruals(context.Background(), now.Add(time.Hour)))
+		})
+	}
+}
+
+func TestProcessorStartInvalidInterestRates(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var config = util.Config{SavingsInterestRates: "USD=abc"}
+	var processor = NewPGTaskProcessor(config, mockdb.NewMockStore(ctrl), mail.NewMemoryMailer())
+	require.Error(t, processor.Start())
+	require.Error(t, processor.Check(context.Background()))
+}
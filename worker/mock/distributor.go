@@ -0,0 +1,138 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/Ma-hiru/simplebank/worker (interfaces: TaskDistributor)
+//
+// Generated by this command:
+//
+//	mockgen -package mockwk -destination worker/mock/distributor.go github.com/Ma-hiru/simplebank/worker TaskDistributor
+//
+
+// Package mockwk is a generated GoMock package.
+package mockwk
+
+import (
+	context "context"
+	reflect "reflect"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	worker "github.com/Ma-hiru/simplebank/worker"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTaskDistributor is a mock of TaskDistributor interface.
+type MockTaskDistributor struct {
+	ctrl     *gomock.Controller
+	recorder *MockTaskDistributorMockRecorder
+	isgomock struct{}
+}
+
+// MockTaskDistributorMockRecorder is the mock recorder for MockTaskDistributor.
+type MockTaskDistributorMockRecorder struct {
+	mock *MockTaskDistributor
+}
+
+// NewMockTaskDistributor creates a new mock instance.
+func NewMockTaskDistributor(ctrl *gomock.Controller) *MockTaskDistributor {
+	mock := &MockTaskDistributor{ctrl: ctrl}
+	mock.recorder = &MockTaskDistributorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTaskDistributor) EXPECT() *MockTaskDistributorMockRecorder {
+	return m.recorder
+}
+
+// DistributeTaskAccrueInterest mocks base method.
+func (m *MockTaskDistributor) DistributeTaskAccrueInterest(ctx context.Context, q db.Querier, payload *worker.PayloadAccrueInterest, opts ...worker.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, q, payload}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskAccrueInterest", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskAccrueInterest indicates an expected call of DistributeTaskAccrueInterest.
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskAccrueInterest(ctx, q, payload any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, q, payload}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskAccrueInterest", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskAccrueInterest), varargs...)
+}
+
+// DistributeTaskChargeMaintenanceFees mocks base method.
+func (m *MockTaskDistributor) DistributeTaskChargeMaintenanceFees(ctx context.Context, q db.Querier, payload *worker.PayloadChargeMaintenanceFees, opts ...worker.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, q, payload}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskChargeMaintenanceFees", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskChargeMaintenanceFees indicates an expected call of DistributeTaskChargeMaintenanceFees.
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskChargeMaintenanceFees(ctx, q, payload any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, q, payload}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskChargeMaintenanceFees", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskChargeMaintenanceFees), varargs...)
+}
+
+// DistributeTaskPostInterest mocks base method.
+func (m *MockTaskDistributor) DistributeTaskPostInterest(ctx context.Context, q db.Querier, payload *worker.PayloadPostInterest, opts ...worker.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, q, payload}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskPostInterest", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskPostInterest indicates an expected call of DistributeTaskPostInterest.
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskPostInterest(ctx, q, payload any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, q, payload}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskPostInterest", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskPostInterest), varargs...)
+}
+
+// DistributeTaskSendResetPassword mocks base method.
+func (m *MockTaskDistributor) DistributeTaskSendResetPassword(ctx context.Context, q db.Querier, payload *worker.PayloadSendResetPassword, opts ...worker.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, q, payload}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskSendResetPassword", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskSendResetPassword indicates an expected call of DistributeTaskSendResetPassword.
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendResetPassword(ctx, q, payload any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, q, payload}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendResetPassword", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendResetPassword), varargs...)
+}
+
+// DistributeTaskSendVerifyEmail mocks base method.
+func (m *MockTaskDistributor) DistributeTaskSendVerifyEmail(ctx context.Context, q db.Querier, payload *worker.PayloadSendVerifyEmail, opts ...worker.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, q, payload}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskSendVerifyEmail", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskSendVerifyEmail indicates an expected call of DistributeTaskSendVerifyEmail.
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendVerifyEmail(ctx, q, payload any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, q, payload}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendVerifyEmail", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendVerifyEmail), varargs...)
+}
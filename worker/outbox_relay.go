@@ -0,0 +1,183 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/outbox"
+	"github.com/Ma-hiru/simplebank/util"
+)
+
+const (
+	outboxBatchSize = 100
+	// outboxRetention is how long the published events are kept, to look into what was sent.
+	outboxRetention     = 7 * 24 * time.Hour
+	outboxCleanInterval = time.Hour
+	// outboxMaxAttempts is how many times an event is tried before it is dead-lettered, about 5 hours with retryDelay.
+	outboxMaxAttempts = 15
+)
+
+// OutboxRelay publishes the events of the outbox table with an outbox.Publisher, at least once.
+// The events of an account are published in order: once one of them fails,
+// the following ones wait until it is retried with backoff and published.
+// An event still failing after outboxMaxAttempts is dead-lettered and kept in the table,
+// so the events after it go on and the other accounts are never held back.
+type OutboxRelay struct {
+	store        db.Store
+	publisher    outbox.Publisher
+	pollInterval time.Duration
+	cleanedAt    time.Time
+
+	started  atomic.Bool
+	lastPoll atomic.Int64
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewOutboxRelay creates a new OutboxRelay
+func NewOutboxRelay(config util.Config, store db.Store, publisher outbox.Publisher) *OutboxRelay {
+	var relay = &OutboxRelay{
+		store:        store,
+		publisher:    publisher,
+		pollInterval: config.TaskPollInterval,
+	}
+	if relay.pollInterval <= 0 {
+		relay.pollInterval = defaultPollInterval
+	}
+	return relay
+}
+
+// Start begins polling the outbox. It returns immediately.
+func (relay *OutboxRelay) Start() error {
+	if !relay.started.CompareAndSwap(false, true) {
+		return errors.New("outbox relay already started")
+	}
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	relay.cancel = cancel
+
+	relay.wg.Add(1)
+	go func() {
+		defer relay.wg.Done()
+
+		var ticker = time.NewTicker(relay.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := relay.relay(ctx); err != nil && ctx.Err() == nil {
+				log.Println("cannot relay outbox events:", err)
+			}
+			if err := relay.clean(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				log.Println("cannot clean outbox events:", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Shutdown stops polling, waits for the batch being published and closes the publisher.
+func (relay *OutboxRelay) Shutdown() {
+	if relay.cancel != nil {
+		relay.cancel()
+	}
+	relay.wg.Wait()
+	if err := relay.publisher.Close(); err != nil {
+		log.Println("cannot close outbox publisher:", err)
+	}
+}
+
+// Check reports whether the relay is polling, for readiness probes.
+func (relay *OutboxRelay) Check(_ context.Context) error {
+	if !relay.started.Load() {
+		return errors.New("outbox relay is not started")
+	}
+
+	var lastPoll = time.Unix(0, relay.lastPoll.Load())
+	if since := time.Since(lastPoll); since > 3*relay.pollInterval+pollGracePeriod {
+		return fmt.Errorf("outbox relay has not polled for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
+// relay publishes batches of events until no more events are due.
+func (relay *OutboxRelay) relay(ctx context.Context) error {
+	for {
+		var listed, err = relay.store.RelayOutboxTx(ctx, db.RelayOutboxTxParams{
+			BatchSize: outboxBatchSize,
+			Publish: func(events []db.OutboxEvent) db.OutboxPublishResult {
+				return relay.publish(ctx, events)
+			},
+		})
+		if err != nil {
+			return err
+		}
+		relay.lastPoll.Store(time.Now().UnixNano())
+
+		if listed < outboxBatchSize {
+			return nil
+		}
+	}
+}
+
+// publish publishes the events in order and returns those published and those that failed.
+// The events of an account after one that failed are skipped, to keep them in order.
+func (relay *OutboxRelay) publish(ctx context.Context, events []db.OutboxEvent) db.OutboxPublishResult {
+	var result = db.OutboxPublishResult{Published: make([]int64, 0, len(events))}
+	var failed = make(map[int64]bool)
+	for _, event := range events {
+		if failed[event.AccountID] {
+			continue
+		}
+		var err = relay.publisher.Publish(ctx, outbox.Event{
+			ID:        event.ID,
+			AccountID: event.AccountID,
+			Type:      event.Type,
+			Payload:   event.Payload,
+			CreatedAt: event.CreatedAt,
+		})
+		if err != nil {
+			var attempts = event.Attempts + 1
+			var dead = attempts >= outboxMaxAttempts
+			if dead {
+				log.Printf("giving up on outbox event %d (%s) after %d attempts: %v", event.ID, event.Type, attempts, err)
+			} else {
+				log.Printf("cannot publish outbox event %d (%s): %v", event.ID, event.Type, err)
+			}
+			result.Failed = append(result.Failed, db.FailOutboxEventParams{
+				ID:            event.ID,
+				LastError:     err.Error(),
+				NextAttemptAt: time.Now().Add(retryDelay(attempts)),
+				Dead:          dead,
+			})
+			failed[event.AccountID] = true
+			continue
+		}
+		result.Published = append(result.Published, event.ID)
+	}
+	return result
+}
+
+// clean deletes the events published before the retention period, at most once per clean interval.
+func (relay *OutboxRelay) clean(ctx context.Context, now time.Time) error {
+	if now.Sub(relay.cleanedAt) < outboxCleanInterval {
+		return nil
+	}
+	if _, err := relay.store.DeletePublishedOutboxEvents(ctx, now.Add(-outboxRetention)); err != nil {
+		return err
+	}
+	relay.cleanedAt = now
+	return nil
+}
@@ -0,0 +1,198 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/outbox"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// failingPublisher fails to publish the events with the given ids.
+type failingPublisher struct {
+	*outbox.MemoryPublisher
+	failing map[int64]bool
+}
+
+func (publisher failingPublisher) Publish(ctx context.Context, event outbox.Event) error {
+	if publisher.failing[event.ID] {
+		return errors.New("publisher unavailable")
+	}
+	return publisher.MemoryPublisher.Publish(ctx, event)
+}
+
+func publishedIDs(events []outbox.Event) []int64 {
+	var ids = make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return ids
+}
+
+func TestOutboxRelayPublish(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var events = []db.OutboxEvent{
+		{ID: 1, AccountID: 10, Type: db.OutboxEntryCreated},
+		{ID: 2, AccountID: 20, Type: db.OutboxEntryCreated},
+		{ID: 3, AccountID: 10, Type: db.OutboxEntryCreated},
+		{ID: 4, AccountID: 30, Type: db.OutboxAccountCreated},
+		{ID: 5, AccountID: 20, Type: db.OutboxEntryCreated},
+		{ID: 6, AccountID: 40, Type: db.OutboxEntryCreated, Attempts: outboxMaxAttempts - 1},
+	}
+	var publisher = failingPublisher{
+		MemoryPublisher: outbox.NewMemoryPublisher(),
+		failing:         map[int64]bool{2: true, 6: true},
+	}
+
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().RelayOutboxTx(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.RelayOutboxTxParams) (int, error) {
+			require.Equal(t, int32(outboxBatchSize), arg.BatchSize)
+			var before = time.Now()
+			var result = arg.Publish(events)
+			// account 20 stops at its failed event, the other accounts go on
+			require.Equal(t, []int64{1, 3, 4}, result.Published)
+			require.Len(t, result.Failed, 2)
+
+			require.Equal(t, int64(2), result.Failed[0].ID)
+			require.Equal(t, "publisher unavailable", result.Failed[0].LastError)
+			require.WithinDuration(t, before.Add(retryDelay(1)), result.Failed[0].NextAttemptAt, time.Second)
+			require.False(t, result.Failed[0].Dead)
+
+			// the last attempt dead-letters the event
+			require.Equal(t, int64(6), result.Failed[1].ID)
+			require.True(t, result.Failed[1].Dead)
+			return len(events), nil
+		})
+
+	var relay = NewOutboxRelay(util.Config{}, store, publisher)
+	require.NoError(t, relay.relay(context.Background()))
+	require.Equal(t, []int64{1, 3, 4}, publishedIDs(publisher.Events()))
+}
+
+func TestOutboxRelayFailingAccount(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// more events of an account that always fails than fit in a batch, then the events of others
+	var outboxEvents []*db.OutboxEvent
+	var failing = make(map[int64]bool)
+	for i := range outboxBatchSize + 50 {
+		var id = int64(i + 1)
+		outboxEvents = append(outboxEvents, &db.OutboxEvent{ID: id, AccountID: 10, Type: db.OutboxEntryCreated})
+		failing[id] = true
+	}
+	for i := range 3 {
+		var id = int64(outboxBatchSize + 51 + i)
+		outboxEvents = append(outboxEvents, &db.OutboxEvent{ID: id, AccountID: int64(20 + i), Type: db.OutboxEntryCreated})
+	}
+	var publisher = failingPublisher{
+		MemoryPublisher: outbox.NewMemoryPublisher(),
+		failing:         failing,
+	}
+
+	// the store lists the events due like ListUnpublishedOutboxEvents and records what became of them
+	var published = make(map[int64]bool)
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().RelayOutboxTx(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.RelayOutboxTxParams) (int, error) {
+			var events []db.OutboxEvent
+			var blocked = make(map[int64]bool)
+			for _, event := range outboxEvents {
+				if published[event.ID] || event.DeadAt.Valid {
+					continue
+				}
+				if blocked[event.AccountID] || event.NextAttemptAt.After(time.Now()) {
+					blocked[event.AccountID] = true
+					continue
+				}
+				if len(events) < int(arg.BatchSize) {
+					events = append(events, *event)
+				}
+			}
+
+			var result = arg.Publish(events)
+			for _, id := range result.Published {
+				published[id] = true
+			}
+			for _, failed := range result.Failed {
+				var event = outboxEvents[failed.ID-1]
+				event.Attempts++
+				event.NextAttemptAt = failed.NextAttemptAt
+				event.DeadAt.Valid = failed.Dead
+			}
+			return len(events), nil
+		})
+
+	var relay = NewOutboxRelay(util.Config{}, store, publisher)
+	require.NoError(t, relay.relay(context.Background()))
+
+	// the failing account waits behind its first event, the others are all published
+	require.Equal(t, []int64{outboxBatchSize + 51, outboxBatchSize + 52, outboxBatchSize + 53}, publishedIDs(publisher.Events()))
+	require.Equal(t, int32(1), outboxEvents[0].Attempts)
+	require.Equal(t, int32(0), outboxEvents[1].Attempts)
+}
+
+func TestOutboxRelayDrains(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var store = mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().RelayOutboxTx(gomock.Any(), gomock.Any()).Times(2).Return(outboxBatchSize, nil),
+		store.EXPECT().RelayOutboxTx(gomock.Any(), gomock.Any()).Times(1).Return(3, nil),
+	)
+
+	var relay = NewOutboxRelay(util.Config{}, store, outbox.NewMemoryPublisher())
+	require.NoError(t, relay.relay(context.Background()))
+
+	store.EXPECT().RelayOutboxTx(gomock.Any(), gomock.Any()).Times(1).Return(0, sql.ErrConnDone)
+	require.ErrorIs(t, relay.relay(context.Background()), sql.ErrConnDone)
+}
+
+func TestOutboxRelayClean(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var now = time.Now()
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().DeletePublishedOutboxEvents(gomock.Any(), gomock.Eq(now.Add(-outboxRetention))).Times(1).Return(int64(7), nil)
+
+	var relay = NewOutboxRelay(util.Config{}, store, outbox.NewMemoryPublisher())
+	require.NoError(t, relay.clean(context.Background(), now))
+	// cleaned at most once per interval
+	require.NoError(t, relay.clean(context.Background(), now.Add(time.Minute)))
+
+	var later = now.Add(outboxCleanInterval)
+	store.EXPECT().DeletePublishedOutboxEvents(gomock.Any(), gomock.Eq(later.Add(-outboxRetention))).Times(1).Return(int64(0), sql.ErrConnDone)
+	require.ErrorIs(t, relay.clean(context.Background(), later), sql.ErrConnDone)
+}
+
+func TestOutboxRelayCheck(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().RelayOutboxTx(gomock.Any(), gomock.Any()).AnyTimes().Return(0, nil)
+	store.EXPECT().DeletePublishedOutboxEvents(gomock.Any(), gomock.Any()).AnyTimes().Return(int64(0), nil)
+
+	var relay = NewOutboxRelay(util.Config{TaskPollInterval: 10 * time.Millisecond}, store, outbox.NewMemoryPublisher())
+	require.Error(t, relay.Check(context.Background()))
+
+	require.NoError(t, relay.Start())
+	defer relay.Shutdown()
+	require.Eventually(t, func() bool {
+		return relay.Check(context.Background()) == nil
+	}, time.Second, 10*time.Millisecond)
+
+	require.Error(t, relay.Start())
+}
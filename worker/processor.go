@@ -0,0 +1,236 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/mail"
+	"github.com/Ma-hiru/simplebank/util"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 10
+	// listPageSize is how many rows the scheduled tasks read at a time when they go through all accounts.
+	listPageSize = 500
+	// staleTaskTimeout is how long a task may stay running before another processor reclaims it.
+	staleTaskTimeout = 5 * time.Minute
+	// pollGracePeriod is how long a batch may take before a missed poll marks the processor unhealthy.
+	pollGracePeriod = time.Minute
+	baseRetryDelay  = 5 * time.Second
+	maxRetryDelay   = time.Hour
+)
+
+// ErrSkipRetry marks a task error that will not go away by retrying.
+// Wrap it to fail the task immediately.
+var ErrSkipRetry = errors.New("skip retry")
+
+// TaskProcessor runs the tasks enqueued by a TaskDistributor in the background.
+type TaskProcessor interface {
+	// Start begins polling for tasks. It returns immediately.
+	Start() error
+	// Shutdown stops polling and waits for the running tasks to finish.
+	Shutdown()
+	// Check reports whether the processor is polling, for readiness probes.
+	Check(ctx context.Context) error
+	ProcessTaskSendVerifyEmail(ctx context.Context, task db.Task) error
+	ProcessTaskSendResetPassword(ctx context.Context, task db.Task) error
+	ProcessTaskAccrueInterest(ctx context.Context, task db.Task) error
+	ProcessTaskPostInterest(ctx context.Context, task db.Task) error
+	ProcessTaskChargeMaintenanceFees(ctx context.Context, task db.Task) error
+}
+
+type taskHandler func(ctx context.Context, task db.Task) error
+
+// PGTaskProcessor is a TaskProcessor that polls the tasks table in Postgres
+type PGTaskProcessor struct {
+	config       util.Config
+	store        db.Store
+	mailer       mail.Mailer
+	distributor  *PGTaskDistributor
+	handlers     map[string]taskHandler
+	pollInterval time.Duration
+
+	// interestRates are the savings rates in basis points per currency, parsed by Start.
+	interestRates map[string]int64
+	// scheduledUntil is the last day whose interest accrual is known to be scheduled.
+	scheduledUntil time.Time
+	// feesScheduledUntil is the last month whose maintenance fees are known to be scheduled.
+	feesScheduledUntil time.Time
+
+	// webhookSecrets opens the signing secrets of the webhook subscriptions.
+	// Webhooks are not delivered when it is nil, see Config.WebhookEncryptionKey.
+	webhookSecrets *util.SecretBox
+	httpClient     *http.Client
+
+	started  atomic.Bool
+	lastPoll atomic.Int64
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewPGTaskProcessor creates a new PGTaskProcessor
+func NewPGTaskProcessor(config util.Config, store db.Store, mailer mail.Mailer) *PGTaskProcessor {
+	var processor = &PGTaskProcessor{
+		config:       config,
+		store:        store,
+		mailer:       mailer,
+		distributor:  &PGTaskDistributor{},
+		pollInterval: config.TaskPollInterval,
+		httpClient:   newWebhookClient(),
+	}
+	if processor.pollInterval <= 0 {
+		processor.pollInterval = defaultPollInterval
+	}
+
+	processor.handlers = map[string]taskHandler{
+		TaskSendVerifyEmail:       processor.ProcessTaskSendVerifyEmail,
+		TaskSendResetPassword:     processor.ProcessTaskSendResetPassword,
+		TaskAccrueInterest:        processor.ProcessTaskAccrueInterest,
+		TaskPostInterest:          processor.ProcessTaskPostInterest,
+		TaskChargeMaintenanceFees: processor.ProcessTaskChargeMaintenanceFees,
+	}
+
+	return processor
+}
+
+func (processor *PGTaskProcessor) Start() error {
+	var rates, err = util.ParseInterestRates(processor.config.SavingsInterestRates)
+	if err != nil {
+		return err
+	}
+	var webhookSecrets *util.SecretBox
+	if processor.config.WebhookEncryptionKey != "" {
+		webhookSecrets, err = util.NewSecretBox([]byte(processor.config.WebhookEncryptionKey))
+		if err != nil {
+			return fmt.Errorf("cannot create webhook secret box: %w", err)
+		}
+	}
+	if !processor.started.CompareAndSwap(false, true) {
+		return errors.New("task processor already started")
+	}
+	processor.interestRates = rates
+	processor.webhookSecrets = webhookSecrets
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	processor.cancel = cancel
+
+	processor.wg.Add(1)
+	go func() {
+		defer processor.wg.Done()
+
+		var ticker = time.NewTicker(processor.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			if len(processor.interestRates) > 0 {
+				if err := processor.scheduleInterestAccruals(ctx, time.Now()); err != nil && ctx.Err() == nil {
+					log.Println("cannot schedule interest accruals:", err)
+				}
+			}
+			if err := processor.scheduleMaintenanceFees(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				log.Println("cannot schedule maintenance fees:", err)
+			}
+			if err := processor.poll(ctx); err != nil && ctx.Err() == nil {
+				log.Println("cannot poll tasks:", err)
+			}
+			if processor.webhookSecrets != nil {
+				if err := processor.deliverWebhooks(ctx); err != nil && ctx.Err() == nil {
+					log.Println("cannot deliver webhooks:", err)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (processor *PGTaskProcessor) Shutdown() {
+	if processor.cancel != nil {
+		processor.cancel()
+	}
+	processor.wg.Wait()
+}
+
+func (processor *PGTaskProcessor) Check(_ context.Context) error {
+	if !processor.started.Load() {
+		return errors.New("task processor is not started")
+	}
+
+	var lastPoll = time.Unix(0, processor.lastPoll.Load())
+	if since := time.Since(lastPoll); since > 3*processor.pollInterval+pollGracePeriod {
+		return fmt.Errorf("task processor has not polled for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
+// poll claims a batch of due tasks and runs them one by one.
+func (processor *PGTaskProcessor) poll(ctx context.Context) error {
+	var tasks, err = processor.store.ClaimTasks(ctx, db.ClaimTasksParams{
+		StaleBefore: time.Now().Add(-staleTaskTimeout),
+		MaxTasks:    defaultBatchSize,
+	})
+	if err != nil {
+		return err
+	}
+	processor.lastPoll.Store(time.Now().UnixNano())
+
+	for _, task := range tasks {
+		if err := processor.processTask(ctx, task); err != nil {
+			log.Printf("cannot update task %d: %v", task.ID, err)
+		}
+	}
+	return nil
+}
+
+// processTask runs the handler of the task and records the outcome:
+// completed on success, rescheduled with exponential backoff on failure,
+// or failed once the attempts are exhausted or the error is ErrSkipRetry.
+func (processor *PGTaskProcessor) processTask(ctx context.Context, task db.Task) error {
+	var err error
+	if handler, ok := processor.handlers[task.Type]; ok {
+		err = handler(ctx, task)
+	} else {
+		err = fmt.Errorf("unknown task type %q: %w", task.Type, ErrSkipRetry)
+	}
+
+	if err == nil {
+		return processor.store.CompleteTask(ctx, task.ID)
+	}
+
+	log.Printf("task %d (%s) attempt %d/%d failed: %v", task.ID, task.Type, task.Attempts, task.MaxAttempts, err)
+	if errors.Is(err, ErrSkipRetry) || task.Attempts >= task.MaxAttempts {
+		return processor.store.FailTask(ctx, db.FailTaskParams{
+			ID:        task.ID,
+			LastError: err.Error(),
+		})
+	}
+
+	return processor.store.RetryTask(ctx, db.RetryTaskParams{
+		ID:        task.ID,
+		LastError: err.Error(),
+		RunAt:     time.Now().Add(retryDelay(task.Attempts)),
+	})
+}
+
+// retryDelay returns the backoff before the next attempt: 5s, 10s, 20s, ... capped at an hour.
+func retryDelay(attempts int32) time.Duration {
+	var delay = baseRetryDelay
+	for i := int32(1); i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
@@ -0,0 +1,259 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/mail"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomUser() db.User {
+	return db.User{
+		Username: util.RandomOwner(),
+		FullName: util.RandomOwner(),
+		Email:    util.RandomEmail(),
+	}
+}
+
+func newTask(t *testing.T, taskType string, payload any, attempts int32) db.Task {
+	var data, err = json.Marshal(payload)
+	require.NoError(t, err)
+
+	return db.Task{
+		ID:          util.RandomInt(1, 1000),
+		Type:        taskType,
+		Payload:     data,
+		Status:      "running",
+		Attempts:    attempts,
+		MaxAttempts: 3,
+	}
+}
+
+func TestProcessTaskSendVerifyEmail(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var user = randomUser()
+	var store = mockdb.NewMockStore(ctrl)
+	var mailer = mail.NewMemoryMailer()
+	var config = util.Config{VerifyEmailURL: "http://localhost:8080/verify_email"}
+	var processor = NewPGTaskProcessor(config, store, mailer)
+
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().CreateVerifyEmail(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateVerifyEmailParams) (db.VerifyEmail, error) {
+			require.Equal(t, user.Username, arg.Username)
+			require.Equal(t, user.Email, arg.Email)
+			require.NotEmpty(t, arg.SecretCode)
+			return db.VerifyEmail{ID: 7, Username: arg.Username, Email: arg.Email, SecretCode: arg.SecretCode}, nil
+		})
+
+	var task = newTask(t, TaskSendVerifyEmail, PayloadSendVerifyEmail{Username: user.Username}, 1)
+	require.NoError(t, processor.ProcessTaskSendVerifyEmail(context.Background(), task))
+
+	var messages = mailer.Messages()
+	require.Len(t, messages, 1)
+	require.Equal(t, []string{user.Email}, messages[0].To)
+	require.Contains(t, messages[0].Body, config.VerifyEmailURL+"?email_id=7&amp;secret_code=")
+}
+
+func TestProcessTaskSendVerifyEmailAlreadyVerified(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var user = randomUser()
+	user.IsEmailVerified = true
+	var store = mockdb.NewMockStore(ctrl)
+	var mailer = mail.NewMemoryMailer()
+	var processor = NewPGTaskProcessor(util.Config{}, store, mailer)
+
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().CreateVerifyEmail(gomock.Any(), gomock.Any()).Times(0)
+
+	var task = newTask(t, TaskSendVerifyEmail, PayloadSendVerifyEmail{Username: user.Username}, 1)
+	require.NoError(t, processor.ProcessTaskSendVerifyEmail(context.Background(), task))
+	require.Empty(t, mailer.Messages())
+}
+
+func TestProcessTaskSendVerifyEmailUserNotFound(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var store = mockdb.NewMockStore(ctrl)
+	var processor = NewPGTaskProcessor(util.Config{}, store, mail.NewMemoryMailer())
+
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, db.ErrRecordNotFound)
+
+	var task = newTask(t, TaskSendVerifyEmail, PayloadSendVerifyEmail{Username: util.RandomOwner()}, 1)
+	var err = processor.ProcessTaskSendVerifyEmail(context.Background(), task)
+	require.ErrorIs(t, err, ErrSkipRetry)
+}
+
+func TestProcessTask(t *testing.T) {
+	var user = randomUser()
+
+	var testCases = []struct {
+		name       string
+		task       db.Task
+		buildStubs func(store *mockdb.MockStore)
+	}{
+		{
+			name: "Completed",
+			task: newTask(t, TaskSendVerifyEmail, PayloadSendVerifyEmail{Username: user.Username}, 1),
+			buildStubs: func(store *mockdb.MockStore) {
+				var verifiedUser = user
+				verifiedUser.IsEmailVerified = true
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(verifiedUser, nil)
+				store.EXPECT().CompleteTask(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+		},
+		{
+			name: "Retried",
+			task: newTask(t, TaskSendVerifyEmail, PayloadSendVerifyEmail{Username: user.Username}, 2),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+				store.EXPECT().RetryTask(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RetryTaskParams) error {
+						require.Contains(t, arg.LastError, sql.ErrConnDone.Error())
+						require.WithinDuration(t, time.Now().Add(retryDelay(2)), arg.RunAt, time.Second)
+						return nil
+					})
+			},
+		},
+		{
+			name: "AttemptsExhausted",
+			task: newTask(t, TaskSendVerifyEmail, PayloadSendVerifyEmail{Username: user.Username}, 3),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+				store.EXPECT().RetryTask(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().FailTask(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+		},
+		{
+			name: "UnknownType",
+			task: newTask(t, "task:unknown", struct{}{}, 1),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().FailTask(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.FailTaskParams) error {
+						require.True(t, strings.HasPrefix(arg.LastError, "unknown task type"))
+						return nil
+					})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var store = mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			var processor = NewPGTaskProcessor(util.Config{}, store, mail.NewMemoryMailer())
+			require.NoError(t, processor.processTask(context.Background(), tc.task))
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	require.Equal(t, baseRetryDelay, retryDelay(1))
+	require.Equal(t, 2*baseRetryDelay, retryDelay(2))
+	require.Equal(t, 4*baseRetryDelay, retryDelay(3))
+	require.Equal(t, maxRetryDelay, retryDelay(50))
+}
+
+func TestProcessorCheck(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().ClaimTasks(gomock.Any(), gomock.Any()).AnyTimes().Return([]db.Task{}, nil)
+	var lastMonth = startOfMonth(time.Now()).AddDate(0, -1, 0)
+	store.EXPECT().GetLatestFeeRun(gomock.Any()).Times(1).Return(db.FeeRun{Period: lastMonth}, nil)
+
+	var processor = NewPGTaskProcessor(util.Config{TaskPollInterval: 10 * time.Millisecond}, store, mail.NewMemoryMailer())
+	require.Error(t, processor.Check(context.Background()))
+
+	require.NoError(t, processor.Start())
+	defer processor.Shutdown()
+	require.Eventually(t, func() bool {
+		return processor.Check(context.Background()) == nil
+	}, time.Second, 10*time.Millisecond)
+
+	require.Error(t, processor.Start())
+}
+
+func TestPGTaskDistributor(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var payload = &PayloadSendVerifyEmail{Username: util.RandomOwner()}
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().CreateTask(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateTaskParams) (db.Task, error) {
+			require.Equal(t, TaskSendVerifyEmail, arg.Type)
+			require.JSONEq(t, `{"username":"`+payload.Username+`"}`, string(arg.Payload))
+			require.Equal(t, int32(5), arg.MaxAttempts)
+			require.WithinDuration(t, time.Now().Add(time.Minute), arg.RunAt, time.Second)
+			return db.Task{}, nil
+		})
+
+	var distributor = NewPGTaskDistributor()
+	var err = distributor.DistributeTaskSendVerifyEmail(context.Background(), store, payload, MaxAttempts(5), ProcessIn(time.Minute))
+	require.NoError(t, err)
+
+	store.EXPECT().CreateTask(gomock.Any(), gomock.Any()).Times(1).Return(db.Task{}, sql.ErrConnDone)
+	err = distributor.DistributeTaskSendVerifyEmail(context.Background(), store, payload)
+	require.True(t, errors.Is(err, sql.ErrConnDone))
+}
+
+func TestProcessTaskSendResetPassword(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var user = randomUser()
+	var store = mockdb.NewMockStore(ctrl)
+	var mailer = mail.NewMemoryMailer()
+	var config = util.Config{
+		ResetPasswordURL:           "http://localhost:8080/reset_password",
+		PasswordResetTokenDuration: 10 * time.Minute,
+	}
+	var processor = NewPGTaskProcessor(config, store, mailer)
+
+	var tokenHash string
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().CreatePasswordReset(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreatePasswordResetParams) (db.PasswordReset, error) {
+			require.Equal(t, user.Username, arg.Username)
+			require.Len(t, arg.TokenHash, 64)
+			require.WithinDuration(t, time.Now().Add(config.PasswordResetTokenDuration), arg.ExpiredAt, time.Second)
+			tokenHash = arg.TokenHash
+			return db.PasswordReset{Username: arg.Username, TokenHash: arg.TokenHash, ExpiredAt: arg.ExpiredAt}, nil
+		})
+
+	var task = newTask(t, TaskSendResetPassword, PayloadSendResetPassword{Username: user.Username}, 1)
+	require.NoError(t, processor.ProcessTaskSendResetPassword(context.Background(), task))
+
+	var messages = mailer.Messages()
+	require.Len(t, messages, 1)
+	require.Equal(t, []string{user.Email}, messages[0].To)
+
+	// the emailed token is the one whose hash was stored
+	var prefix = config.ResetPasswordURL + "?token="
+	var start = strings.Index(messages[0].Body, prefix)
+	require.GreaterOrEqual(t, start, 0)
+	var token = messages[0].Body[start+len(prefix):]
+	token = token[:strings.IndexByte(token, '"')]
+	require.Equal(t, tokenHash, util.HashSecret(token))
+}
@@ -0,0 +1,90 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+)
+
+// scheduleInterestAccruals enqueues the accrual of every day that ended since the last day scheduled, so days
+// missed while no processor was running are caught up. The day is recorded in the same transaction as its task,
+// which schedules it exactly once however many processors run.
+func (processor *PGTaskProcessor) scheduleInterestAccruals(ctx context.Context, now time.Time) error {
+	var yesterday = startOfDay(now).AddDate(0, 0, -1)
+	if !processor.scheduledUntil.Before(yesterday) {
+		return nil
+	}
+
+	var first = yesterday
+	var latest, err = processor.store.GetLatestInterestRun(ctx)
+	switch {
+	case err == nil:
+		first = latest.AccrualDate.AddDate(0, 0, 1)
+	case !errors.Is(err, db.ErrRecordNotFound):
+		return err
+	}
+
+	for day := first; !day.After(yesterday); day = day.AddDate(0, 0, 1) {
+		var payload = &PayloadAccrueInterest{Date: day.Format(time.DateOnly)}
+		_, err = processor.store.CreateInterestRunTx(ctx, db.CreateInterestRunTxParams{
+			AccrualDate: day,
+			AfterCreate: func(q db.Querier) error {
+				return processor.distributor.DistributeTaskAccrueInterest(ctx, q, payload)
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	processor.scheduledUntil = yesterday
+	return nil
+}
+
+// scheduleMaintenanceFees enqueues the maintenance fees of every month that ended since the last month scheduled.
+// Like the interest accruals, each month is scheduled exactly once however many processors run.
+func (processor *PGTaskProcessor) scheduleMaintenanceFees(ctx context.Context, now time.Time) error {
+	var lastMonth = startOfMonth(now).AddDate(0, -1, 0)
+	if !processor.feesScheduledUntil.Before(lastMonth) {
+		return nil
+	}
+
+	var first = lastMonth
+	var latest, err = processor.store.GetLatestFeeRun(ctx)
+	switch {
+	case err == nil:
+		first = latest.Period.AddDate(0, 1, 0)
+	case !errors.Is(err, db.ErrRecordNotFound):
+		return err
+	}
+
+	for month := first; !month.After(lastMonth); month = month.AddDate(0, 1, 0) {
+		var payload = &PayloadChargeMaintenanceFees{Month: month.Format(monthLayout)}
+		_, err = processor.store.CreateFeeRunTx(ctx, db.CreateFeeRunTxParams{
+			Period: month,
+			AfterCreate: func(q db.Querier) error {
+				return processor.distributor.DistributeTaskChargeMaintenanceFees(ctx, q, payload)
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	processor.feesScheduledUntil = lastMonth
+	return nil
+}
+
+// startOfDay returns midnight UTC of the day of t. Interest accrues on UTC days.
+func startOfDay(t time.Time) time.Time {
+	var year, month, day = t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// startOfMonth returns midnight UTC of the first day of the month of t.
+func startOfMonth(t time.Time) time.Time {
+	var year, month, _ = t.UTC().Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/util"
+)
+
+const TaskAccrueInterest = "task:accrue_interest"
+
+// postingDelay gives the accruals of the last days of a month that run on other replicas time to finish
+// before the month is posted.
+const postingDelay = time.Hour
+
+// PayloadAccrueInterest is the payload of TaskAccrueInterest
+type PayloadAccrueInterest struct {
+	// Date is the day the interest accrues for, as YYYY-MM-DD in UTC.
+	Date string `json:"date"`
+}
+
+func (distributor *PGTaskDistributor) DistributeTaskAccrueInterest(
+	ctx context.Context,
+	q db.Querier,
+	payload *PayloadAccrueInterest,
+	opts ...Option,
+) error {
+	var _, err = distributor.enqueue(ctx, q, TaskAccrueInterest, payload, opts...)
+	return err
+}
+
+// ProcessTaskAccrueInterest records the interest every savings account earned on the day, computed on its balance
+// at the end of the day at the rate of its currency. Accruing a day again skips the accounts already accrued.
+// The last day of a month enqueues the posting of the month.
+func (processor *PGTaskProcessor) ProcessTaskAccrueInterest(ctx context.Context, task db.Task) error {
+	var payload PayloadAccrueInterest
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", ErrSkipRetry)
+	}
+	var date, err = time.Parse(time.DateOnly, payload.Date)
+	if err != nil {
+		return fmt.Errorf("invalid date %q: %w", payload.Date, ErrSkipRetry)
+	}
+	var endOfDay = date.AddDate(0, 0, 1)
+
+	var afterID int64
+	for {
+		balances, err := processor.store.ListAccountBalancesAt(ctx, db.ListAccountBalancesAtParams{
+			At:       endOfDay,
+			Type:     db.AccountTypeSavings,
+			AfterID:  afterID,
+			PageSize: listPageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list savings balances: %w", err)
+		}
+
+		for _, balance := range balances {
+			afterID = balance.ID
+			var rate = processor.interestRates[balance.Currency]
+			if rate <= 0 || balance.Balance <= 0 {
+				continue
+			}
+
+			micros, err := util.DailyInterest(balance.Balance, rate, date.Year())
+			if err != nil {
+				// one account out of range must not hold back the others
+				log.Printf("cannot accrue interest of account %d on %s: %v", balance.ID, payload.Date, err)
+				continue
+			}
+			_, err = processor.store.CreateInterestAccrual(ctx, db.CreateInterestAccrualParams{
+				AccountID:    balance.ID,
+				AccrualDate:  date,
+				Balance:      balance.Balance,
+				RateBps:      rate,
+				AmountMicros: micros,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to accrue interest of account %d: %w", balance.ID, err)
+			}
+		}
+
+		if len(balances) < listPageSize {
+			break
+		}
+	}
+
+	if endOfDay.Day() != 1 {
+		return nil
+	}
+	err = processor.distributor.DistributeTaskPostInterest(ctx, processor.store, &PayloadPostInterest{
+		Month: date.Format(monthLayout),
+	}, ProcessIn(postingDelay))
+	if err != nil {
+		return fmt.Errorf("failed to enqueue interest posting: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+)
+
+const TaskChargeMaintenanceFees = "task:charge_maintenance_fees"
+
+// PayloadChargeMaintenanceFees is the payload of TaskChargeMaintenanceFees
+type PayloadChargeMaintenanceFees struct {
+	// Month is the month the fees are charged for, as YYYY-MM.
+	Month string `json:"month"`
+}
+
+func (distributor *PGTaskDistributor) DistributeTaskChargeMaintenanceFees(
+	ctx context.Context,
+	q db.Querier,
+	payload *PayloadChargeMaintenanceFees,
+	opts ...Option,
+) error {
+	var _, err = distributor.enqueue(ctx, q, TaskChargeMaintenanceFees, payload, opts...)
+	return err
+}
+
+// ProcessTaskChargeMaintenanceFees charges the maintenance fee of the month to every account with a fee schedule,
+// one transaction per account. Accounts charged before a failure are not charged twice when the task is retried.
+func (processor *PGTaskProcessor) ProcessTaskChargeMaintenanceFees(ctx context.Context, task db.Task) error {
+	var payload PayloadChargeMaintenanceFees
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", ErrSkipRetry)
+	}
+	var month, err = time.Parse(monthLayout, payload.Month)
+	if err != nil {
+		return fmt.Errorf("invalid month %q: %w", payload.Month, ErrSkipRetry)
+	}
+
+	var afterID int64
+	for {
+		accountIDs, err := processor.store.ListMaintenanceFeeAccounts(ctx, db.ListMaintenanceFeeAccountsParams{
+			PeriodEnd: month.AddDate(0, 1, 0),
+			AfterID:   afterID,
+			PageSize:  listPageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list accounts with maintenance fees: %w", err)
+		}
+
+		for _, accountID := range accountIDs {
+			afterID = accountID
+			_, err = processor.store.ChargeMaintenanceFeeTx(ctx, db.ChargeMaintenanceFeeTxParams{
+				AccountID: accountID,
+				Period:    month,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to charge maintenance fee of account %d: %w", accountID, err)
+			}
+		}
+
+		if len(accountIDs) < listPageSize {
+			return nil
+		}
+	}
+}
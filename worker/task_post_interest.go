@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+)
+
+const TaskPostInterest = "task:post_interest"
+
+const monthLayout = "2006-01"
+
+// PayloadPostInterest is the payload of TaskPostInterest
+type PayloadPostInterest struct {
+	// Month is the month whose accrued interest is credited, as YYYY-MM.
+	Month string `json:"month"`
+}
+
+func (distributor *PGTaskDistributor) DistributeTaskPostInterest(
+	ctx context.Context,
+	q db.Querier,
+	payload *PayloadPostInterest,
+	opts ...Option,
+) error {
+	var _, err = distributor.enqueue(ctx, q, TaskPostInterest, payload, opts...)
+	return err
+}
+
+// ProcessTaskPostInterest credits every account with interest accrued up to the end of the month and not posted yet,
+// one transaction per account. Accounts posted before a failure are not posted twice when the task is retried.
+func (processor *PGTaskProcessor) ProcessTaskPostInterest(ctx context.Context, task db.Task) error {
+	var payload PayloadPostInterest
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", ErrSkipRetry)
+	}
+	var month, err = time.Parse(monthLayout, payload.Month)
+	if err != nil {
+		return fmt.Errorf("invalid month %q: %w", payload.Month, ErrSkipRetry)
+	}
+
+	var afterID int64
+	for {
+		accountIDs, err := processor.store.ListUnpostedInterestAccounts(ctx, db.ListUnpostedInterestAccountsParams{
+			ToDate:   month.AddDate(0, 1, 0),
+			AfterID:  afterID,
+			PageSize: listPageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list accounts with interest: %w", err)
+		}
+
+		for _, accountID := range accountIDs {
+			afterID = accountID
+			_, err = processor.store.PostInterestTx(ctx, db.PostInterestTxParams{
+				AccountID: accountID,
+				Period:    month,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to post interest of account %d: %w", accountID, err)
+			}
+		}
+
+		if len(accountIDs) < listPageSize {
+			return nil
+		}
+	}
+}
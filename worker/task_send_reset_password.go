@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/mail"
+	"github.com/Ma-hiru/simplebank/util"
+)
+
+const TaskSendResetPassword = "task:send_reset_password"
+
+const defaultPasswordResetTokenDuration = 15 * time.Minute
+
+// PayloadSendResetPassword is the payload of TaskSendResetPassword
+type PayloadSendResetPassword struct {
+	Username string `json:"username"`
+}
+
+func (distributor *PGTaskDistributor) DistributeTaskSendResetPassword(
+	ctx context.Context,
+	q db.Querier,
+	payload *PayloadSendResetPassword,
+	opts ...Option,
+) error {
+	var _, err = distributor.enqueue(ctx, q, TaskSendResetPassword, payload, opts...)
+	return err
+}
+
+// ProcessTaskSendResetPassword issues a single-use reset token for the user and emails it to them.
+// Only the hash of the token is stored, so the token never leaves this function except in the email.
+func (processor *PGTaskProcessor) ProcessTaskSendResetPassword(ctx context.Context, task db.Task) error {
+	var payload PayloadSendResetPassword
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", ErrSkipRetry)
+	}
+
+	var user, err = processor.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return fmt.Errorf("user %q does not exist: %w", payload.Username, ErrSkipRetry)
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var duration = processor.config.PasswordResetTokenDuration
+	if duration <= 0 {
+		duration = defaultPasswordResetTokenDuration
+	}
+
+	token, err := util.RandomSecret(32)
+	if err != nil {
+		return err
+	}
+	_, err = processor.store.CreatePasswordReset(ctx, db.CreatePasswordResetParams{
+		Username:  user.Username,
+		TokenHash: util.HashSecret(token),
+		ExpiredAt: time.Now().Add(duration),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create password reset: %w", err)
+	}
+
+	var query = url.Values{}
+	query.Set("token", token)
+	var resetURL = processor.config.ResetPasswordURL + "?" + query.Encode()
+
+	err = processor.mailer.Send(ctx, mail.Message{
+		To:      []string{user.Email},
+		Subject: "Reset your Simple Bank password",
+		Body: fmt.Sprintf(`Hello %s,<br/>
+We received a request to reset your password.<br/>
+Please <a href="%s">click here</a> to choose a new one. The link expires in %s and can only be used once.<br/>
+If you did not ask for this, you can safely ignore this email.<br/>`,
+			html.EscapeString(user.FullName), html.EscapeString(resetURL), duration),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send reset password email: %w", err)
+	}
+
+	return nil
+}
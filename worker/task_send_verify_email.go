@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/mail"
+	"github.com/Ma-hiru/simplebank/util"
+)
+
+const TaskSendVerifyEmail = "task:send_verify_email"
+
+// PayloadSendVerifyEmail is the payload of TaskSendVerifyEmail
+type PayloadSendVerifyEmail struct {
+	Username string `json:"username"`
+}
+
+func (distributor *PGTaskDistributor) DistributeTaskSendVerifyEmail(
+	ctx context.Context,
+	q db.Querier,
+	payload *PayloadSendVerifyEmail,
+	opts ...Option,
+) error {
+	var _, err = distributor.enqueue(ctx, q, TaskSendVerifyEmail, payload, opts...)
+	return err
+}
+
+// ProcessTaskSendVerifyEmail creates a verification code for the user and emails them the link to use it.
+func (processor *PGTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Context, task db.Task) error {
+	var payload PayloadSendVerifyEmail
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", ErrSkipRetry)
+	}
+
+	var user, err = processor.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return fmt.Errorf("user %q does not exist: %w", payload.Username, ErrSkipRetry)
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.IsEmailVerified {
+		return nil
+	}
+
+	secretCode, err := util.RandomSecret(32)
+	if err != nil {
+		return err
+	}
+	verifyEmail, err := processor.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: secretCode,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create verify email: %w", err)
+	}
+
+	var query = url.Values{}
+	query.Set("email_id", strconv.FormatInt(verifyEmail.ID, 10))
+	query.Set("secret_code", verifyEmail.SecretCode)
+	var verifyURL = processor.config.VerifyEmailURL + "?" + query.Encode()
+
+	err = processor.mailer.Send(ctx, mail.Message{
+		To:      []string{user.Email},
+		Subject: "Welcome to Simple Bank",
+		Body: fmt.Sprintf(`Hello %s,<br/>
+Thank you for registering with us!<br/>
+Please <a href="%s">click here</a> to verify your email address.<br/>`, html.EscapeString(user.FullName), html.EscapeString(verifyURL)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send verify email: %w", err)
+	}
+
+	return nil
+}
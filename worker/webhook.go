@@ -0,0 +1,262 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+)
+
+const (
+	// webhookMaxAttempts with the task backoff keeps retrying a delivery for about 5 hours before it is dead.
+	webhookMaxAttempts = 15
+	webhookTimeout     = 10 * time.Second
+	// maxWebhookResponse is how much of a response body is read before the connection is reused.
+	maxWebhookResponse = 64 << 10
+)
+
+var (
+	errWebhookNotHTTPS       = errors.New("url is not https")
+	errWebhookAddressBlocked = errors.New("address is not public")
+	errUnexpectedStatus      = errors.New("unexpected status")
+)
+
+// blockedPrefixes are the non-public ranges not covered by the netip.Addr predicates used by publicAddress.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// Headers of a webhook request.
+const (
+	WebhookIDHeader        = "Webhook-Id"
+	WebhookEventHeader     = "Webhook-Event"
+	WebhookSignatureHeader = "Webhook-Signature"
+)
+
+// WebhookBody is the JSON body posted to the subscribers.
+type WebhookBody struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// SignWebhook returns the signature header of a webhook body, "t=<unix time>,v1=<hex HMAC-SHA256>".
+// The HMAC covers the time, a dot and the body, so receivers can reject replayed requests by their age.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	var unix = strconv.FormatInt(timestamp.Unix(), 10)
+	var mac = hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverWebhooks claims a batch of due webhook deliveries and sends them one by one.
+func (processor *PGTaskProcessor) deliverWebhooks(ctx context.Context) error {
+	var deliveries, err = processor.store.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+		StaleBefore:   time.Now().Add(-staleTaskTimeout),
+		MaxDeliveries: defaultBatchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if err := processor.deliverWebhook(ctx, delivery); err != nil {
+			log.Printf("cannot deliver webhook %d: %v", delivery.ID, err)
+		}
+	}
+	return nil
+}
+
+// deliverWebhook posts the event of a delivery to its subscription and records the attempt.
+// A failed delivery is retried with the backoff of the tasks and dead-lettered once the attempts are exhausted.
+func (processor *PGTaskProcessor) deliverWebhook(ctx context.Context, delivery db.WebhookDelivery) error {
+	var subscription, err = processor.store.GetWebhookSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return err
+	}
+	event, err := processor.store.GetWebhookEvent(ctx, delivery.EventID)
+	if err != nil {
+		return err
+	}
+	secret, err := processor.webhookSecrets.Open(subscription.Secret, subscription.ID.String())
+	if err != nil {
+		return processor.store.DeadLetterWebhookDelivery(ctx, db.DeadLetterWebhookDeliveryParams{
+			ID:        delivery.ID,
+			LastError: fmt.Sprintf("cannot open signing secret: %v", err),
+		})
+	}
+	// subscriptions created before https was required are not sent in the clear
+	if target, err := url.Parse(subscription.Url); err != nil || target.Scheme != "https" {
+		return processor.store.DeadLetterWebhookDelivery(ctx, db.DeadLetterWebhookDeliveryParams{
+			ID:        delivery.ID,
+			LastError: errWebhookNotHTTPS.Error(),
+		})
+	}
+	body, err := json.Marshal(WebhookBody{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	var start = time.Now()
+	var code, sendErr = processor.sendWebhook(ctx, subscription.Url, delivery.ID, event.Type, secret, body)
+	var attempt = db.CreateWebhookAttemptParams{
+		DeliveryID:   delivery.ID,
+		Attempt:      delivery.Attempts,
+		ResponseCode: int32(code),
+		DurationMs:   time.Since(start).Milliseconds(),
+	}
+	var failure string
+	if sendErr != nil {
+		failure = webhookFailure(sendErr)
+		log.Printf("webhook delivery %d attempt %d failed: %v", delivery.ID, delivery.Attempts, sendErr)
+	}
+	attempt.Error = failure
+	if err = processor.store.CreateWebhookAttempt(ctx, attempt); err != nil {
+		return err
+	}
+
+	switch {
+	case sendErr == nil:
+		return processor.store.CompleteWebhookDelivery(ctx, delivery.ID)
+	case delivery.Attempts >= webhookMaxAttempts:
+		return processor.store.DeadLetterWebhookDelivery(ctx, db.DeadLetterWebhookDeliveryParams{
+			ID:        delivery.ID,
+			LastError: failure,
+		})
+	default:
+		return processor.store.RetryWebhookDelivery(ctx, db.RetryWebhookDeliveryParams{
+			ID:            delivery.ID,
+			LastError:     failure,
+			NextAttemptAt: time.Now().Add(retryDelay(delivery.Attempts)),
+		})
+	}
+}
+
+// webhookFailure describes a failed request for the subscriber, who sees it in the delivery attempts.
+// The dial errors are left out, they would tell the addresses and ports reachable from the worker.
+func webhookFailure(err error) string {
+	var certErr *tls.CertificateVerificationError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errUnexpectedStatus):
+		return err.Error()
+	case errors.Is(err, errWebhookAddressBlocked):
+		return errWebhookAddressBlocked.Error()
+	case errors.As(err, &certErr):
+		return "certificate is not trusted"
+	case errors.As(err, &dnsErr):
+		return "host cannot be resolved"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "request timed out"
+	default:
+		return "request failed"
+	}
+}
+
+// sendWebhook posts a signed body and returns the response status code, or 0 when there was no response.
+// Any status but 2xx is an error, redirects are not followed.
+func (processor *PGTaskProcessor) sendWebhook(
+	ctx context.Context,
+	url string,
+	deliveryID int64,
+	eventType string,
+	secret string,
+	body []byte,
+) (int, error) {
+	var req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "simplebank-webhooks")
+	req.Header.Set(WebhookIDHeader, strconv.FormatInt(deliveryID, 10))
+	req.Header.Set(WebhookEventHeader, eventType)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, time.Now(), body))
+
+	rsp, err := processor.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(rsp.Body, maxWebhookResponse))
+
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return rsp.StatusCode, fmt.Errorf("%w %d", errUnexpectedStatus, rsp.StatusCode)
+	}
+	return rsp.StatusCode, nil
+}
+
+// newWebhookClient returns the client sending the webhooks. It only connects to public addresses,
+// checked when dialing so a host cannot resolve to a public address when subscribed and to an internal one later.
+func newWebhookClient() *http.Client {
+	var dialer = &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: refuseNonPublicAddress,
+	}
+	var transport = http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be dialed instead of the subscriber, bypassing the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// refuseNonPublicAddress is the net.Dialer Control of the webhook client, it runs with the resolved address.
+func refuseNonPublicAddress(network, address string, _ syscall.RawConn) error {
+	var addrPort, err = netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", errWebhookAddressBlocked, addrPort.Addr())
+	}
+	return nil
+}
+
+// publicAddress reports whether the address may receive webhooks: loopback, private, link-local,
+// multicast and unspecified addresses are refused.
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,349 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ma-hiru/simplebank/db/mock"
+	db "github.com/Ma-hiru/simplebank/db/sqlc"
+	"github.com/Ma-hiru/simplebank/mail"
+	"github.com/Ma-hiru/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSignWebhook(t *testing.T) {
+	var timestamp = time.Unix(1700000000, 0)
+	var body = []byte(`{"id":1}`)
+
+	var signature = SignWebhook("whsec_test", timestamp, body)
+	require.Equal(t, "t=1700000000,v1=", signature[:len("t=1700000000,v1=")])
+	require.Len(t, signature, len("t=1700000000,v1=")+64)
+	require.Equal(t, signature, SignWebhook("whsec_test", timestamp, body))
+	require.NotEqual(t, signature, SignWebhook("whsec_other", timestamp, body))
+	require.NotEqual(t, signature, SignWebhook("whsec_test", timestamp.Add(time.Second), body))
+	require.NotEqual(t, signature, SignWebhook("whsec_test", timestamp, []byte(`{"id":2}`)))
+}
+
+func TestDeliverWebhook(t *testing.T) {
+	var box, err = util.NewSecretBox([]byte(util.RandomString(32)))
+	require.NoError(t, err)
+
+	var secret = "whsec_" + util.RandomString(32)
+	var subscription = db.WebhookSubscription{
+		ID:       uuid.New(),
+		Username: util.RandomOwner(),
+	}
+	subscription.Secret, err = box.Seal(secret, subscription.ID.String())
+	require.NoError(t, err)
+	var event = db.WebhookEvent{
+		ID:        7,
+		Username:  subscription.Username,
+		Type:      db.EventTransferCreated,
+		Data:      json.RawMessage(`{"transfer":{"id":3}}`),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	testCases := []struct {
+		name     string
+		attempts int32
+		status   int
+		sealed   string
+		url      string
+		// publicOnly sends with the client of the processor, which refuses the loopback address of the test server.
+		publicOnly   bool
+		buildStubs   func(store *mockdb.MockStore, delivery db.WebhookDelivery)
+		checkRequest func(t *testing.T, r *http.Request, body []byte)
+		checkError   func(t *testing.T, err error)
+	}{
+		{
+			name:     "OK",
+			attempts: 1,
+			status:   http.StatusNoContent,
+			buildStubs: func(store *mockdb.MockStore, delivery db.WebhookDelivery) {
+				store.EXPECT().CreateWebhookAttempt(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateWebhookAttemptParams) error {
+						require.Equal(t, delivery.ID, arg.DeliveryID)
+						require.Equal(t, int32(1), arg.Attempt)
+						require.Equal(t, int32(http.StatusNoContent), arg.ResponseCode)
+						require.Empty(t, arg.Error)
+						return nil
+					})
+				store.EXPECT().CompleteWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(nil)
+			},
+			checkRequest: func(t *testing.T, r *http.Request, body []byte) {
+				require.Equal(t, http.MethodPost, r.Method)
+				require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				require.Equal(t, db.EventTransferCreated, r.Header.Get(WebhookEventHeader))
+				require.Equal(t, "5", r.Header.Get(WebhookIDHeader))
+
+				var signature = r.Header.Get(WebhookSignatureHeader)
+				var timestamp, _, ok = strings.Cut(strings.TrimPrefix(signature, "t="), ",")
+				require.True(t, ok)
+				unix, err := strconv.ParseInt(timestamp, 10, 64)
+				require.NoError(t, err)
+				require.Equal(t, SignWebhook(secret, time.Unix(unix, 0), body), signature)
+
+				var got WebhookBody
+				require.NoError(t, json.Unmarshal(body, &got))
+				require.Equal(t, event.ID, got.ID)
+				require.Equal(t, event.Type, got.Type)
+				require.WithinDuration(t, event.CreatedAt, got.CreatedAt, 0)
+				require.JSONEq(t, string(event.Data), string(got.Data))
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:     "Retry",
+			attempts: 2,
+			status:   http.StatusInternalServerError,
+			buildStubs: func(store *mockdb.MockStore, delivery db.WebhookDelivery) {
+				store.EXPECT().CreateWebhookAttempt(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateWebhookAttemptParams) error {
+						require.Equal(t, int32(http.StatusInternalServerError), arg.ResponseCode)
+						require.Equal(t, "unexpected status 500", arg.Error)
+						return nil
+					})
+				store.EXPECT().RetryWebhookDelivery(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RetryWebhookDeliveryParams) error {
+						require.Equal(t, delivery.ID, arg.ID)
+						require.Equal(t, "unexpected status 500", arg.LastError)
+						require.WithinDuration(t, time.Now().Add(10*time.Second), arg.NextAttemptAt, time.Second)
+						return nil
+					})
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:     "Redirect",
+			attempts: 1,
+			status:   http.StatusFound,
+			buildStubs: func(store *mockdb.MockStore, delivery db.WebhookDelivery) {
+				store.EXPECT().CreateWebhookAttempt(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().RetryWebhookDelivery(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:     "DeadLetter",
+			attempts: webhookMaxAttempts,
+			status:   http.StatusBadGateway,
+			buildStubs: func(store *mockdb.MockStore, delivery db.WebhookDelivery) {
+				store.EXPECT().CreateWebhookAttempt(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().RetryWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeadLetterWebhookDelivery(gomock.Any(), gomock.Eq(db.DeadLetterWebhookDeliveryParams{
+					ID:        delivery.ID,
+					LastError: "unexpected status 502",
+				})).Times(1).Return(nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:     "InvalidSecret",
+			attempts: 1,
+			sealed:   "invalid",
+			buildStubs: func(store *mockdb.MockStore, delivery db.WebhookDelivery) {
+				store.EXPECT().CreateWebhookAttempt(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeadLetterWebhookDelivery(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkRequest: func(t *testing.T, r *http.Request, body []byte) {
+				t.Error("webhook must not be sent without its secret")
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:     "NotHTTPS",
+			attempts: 1,
+			url:      "http://example.com/hooks",
+			buildStubs: func(store *mockdb.MockStore, delivery db.WebhookDelivery) {
+				store.EXPECT().CreateWebhookAttempt(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeadLetterWebhookDelivery(gomock.Any(), gomock.Eq(db.DeadLetterWebhookDeliveryParams{
+					ID:        delivery.ID,
+					LastError: errWebhookNotHTTPS.Error(),
+				})).Times(1).Return(nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:       "BlockedAddress",
+			attempts:   1,
+			status:     http.StatusOK,
+			publicOnly: true,
+			buildStubs: func(store *mockdb.MockStore, delivery db.WebhookDelivery) {
+				store.EXPECT().CreateWebhookAttempt(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateWebhookAttemptParams) error {
+						require.Zero(t, arg.ResponseCode)
+						require.Equal(t, errWebhookAddressBlocked.Error(), arg.Error)
+						return nil
+					})
+				store.EXPECT().RetryWebhookDelivery(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RetryWebhookDeliveryParams) error {
+						require.Equal(t, errWebhookAddressBlocked.Error(), arg.LastError)
+						return nil
+					})
+			},
+			checkRequest: func(t *testing.T, r *http.Request, body []byte) {
+				t.Error("webhook must not be sent to a loopback address")
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:     "AttemptError",
+			attempts: 1,
+			status:   http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore, delivery db.WebhookDelivery) {
+				store.EXPECT().CreateWebhookAttempt(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrConnDone)
+				store.EXPECT().CompleteWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			var ctrl = gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body, err = io.ReadAll(r.Body)
+				require.NoError(t, err)
+				if tc.checkRequest != nil {
+					tc.checkRequest(t, r, body)
+				}
+				if tc.status == http.StatusFound {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			var target = subscription
+			target.Url = server.URL
+			if tc.url != "" {
+				target.Url = tc.url
+			}
+			if tc.sealed != "" {
+				target.Secret = tc.sealed
+			}
+			var delivery = db.WebhookDelivery{
+				ID:             5,
+				SubscriptionID: target.ID,
+				EventID:        event.ID,
+				Status:         db.WebhookDeliverySending,
+				Attempts:       tc.attempts,
+			}
+
+			var store = mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(target.ID)).Times(1).Return(target, nil)
+			store.EXPECT().GetWebhookEvent(gomock.Any(), gomock.Eq(event.ID)).Times(1).Return(event, nil)
+			tc.buildStubs(store, delivery)
+
+			var processor = NewPGTaskProcessor(util.Config{}, store, mail.NewMemoryMailer())
+			processor.webhookSecrets = box
+			if !tc.publicOnly {
+				processor.httpClient = server.Client()
+				processor.httpClient.CheckRedirect = newWebhookClient().CheckRedirect
+			}
+			tc.checkError(t, processor.deliverWebhook(context.Background(), delivery))
+		})
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	for address, public := range map[string]bool{
+		"93.184.216.34":          true,
+		"2606:2800:220:1::":      true,
+		"127.0.0.1":              false,
+		"::1":                    false,
+		"10.1.2.3":               false,
+		"172.16.0.1":             false,
+		"192.168.1.1":            false,
+		"169.254.169.254":        false,
+		"100.100.100.200":        false,
+		"0.0.0.0":                false,
+		"0.1.2.3":                false,
+		"::":                     false,
+		"fd00::1":                false,
+		"fe80::1":                false,
+		"224.0.0.1":              false,
+		"::ffff:127.0.0.1":       false,
+		"::ffff:169.254.169.254": false,
+	} {
+		require.Equal(t, public, publicAddress(netip.MustParseAddr(address)), address)
+	}
+}
+
+func TestWebhookFailure(t *testing.T) {
+	require.Equal(t, "unexpected status 500", webhookFailure(fmt.Errorf("%w %d", errUnexpectedStatus, 500)))
+	require.Equal(t, errWebhookAddressBlocked.Error(), webhookFailure(&url.Error{
+		Op:  "Post",
+		URL: "https://internal.example.com",
+		Err: &net.OpError{Op: "dial", Err: fmt.Errorf("%w: 10.0.0.1", errWebhookAddressBlocked)},
+	}))
+	require.Equal(t, "host cannot be resolved", webhookFailure(&net.DNSError{Err: "no such host", Name: "internal.example.com"}))
+	// the address and port of a refused connection are not shown
+	var refused = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connect: connection refused")}
+	require.Equal(t, "request failed", webhookFailure(refused))
+}
+
+func TestDeliverWebhooks(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var store = mockdb.NewMockStore(ctrl)
+	store.EXPECT().ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.ClaimWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+			require.Equal(t, int32(defaultBatchSize), arg.MaxDeliveries)
+			require.WithinDuration(t, time.Now().Add(-staleTaskTimeout), arg.StaleBefore, time.Second)
+			return []db.WebhookDelivery{{ID: 1, SubscriptionID: uuid.New()}}, nil
+		})
+	// a delivery that cannot be read is left to be reclaimed once stale
+	store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Any()).Times(1).Return(db.WebhookSubscription{}, sql.ErrConnDone)
+
+	var processor = NewPGTaskProcessor(util.Config{}, store, mail.NewMemoryMailer())
+	require.NoError(t, processor.deliverWebhooks(context.Background()))
+
+	store.EXPECT().ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+	require.ErrorIs(t, processor.deliverWebhooks(context.Background()), sql.ErrConnDone)
+}
+
+func TestProcessorWebhookKey(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var config = util.Config{WebhookEncryptionKey: "too short"}
+	var processor = NewPGTaskProcessor(config, mockdb.NewMockStore(ctrl), mail.NewMemoryMailer())
+	require.Error(t, processor.Start())
+}